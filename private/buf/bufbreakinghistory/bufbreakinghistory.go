@@ -0,0 +1,97 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufbreakinghistory checks for breaking changes across a range of git commits.
+package bufbreakinghistory
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"go.uber.org/zap"
+)
+
+// Checker checks for breaking changes across a range of git commits.
+type Checker interface {
+	// Check checks every commit in the range for breaking changes against the commit before it.
+	//
+	// The range starts at the to commit, and goes backwards in time always choosing the first
+	// parent, until the from commit is found. The from commit must be a first-parent ancestor of
+	// the to commit. The from commit is not checked itself, it is only checked against.
+	//
+	// Commits are visited in chronological order, and f is called for every commit that introduced
+	// breaking changes. Each commit is checked with the breaking configuration of the module at that
+	// commit, without the rules of breaking plugins, as the plugins configured by past commits are
+	// not run. Commits where the module is not present or does not build are skipped, and the next
+	// commit is checked against the last commit where the module was built.
+	//
+	// If an error is seen, the loop is stopped and the error is returned.
+	Check(
+		ctx context.Context,
+		from git.Hash,
+		to git.Hash,
+		f func(commit git.Commit, fileAnnotations []bufanalysis.FileAnnotation) error,
+	) error
+}
+
+// NewChecker returns a new Checker that reads the commits of a repository with the ObjectReader.
+//
+// The ModuleReader is used to read the dependencies of the module.
+func NewChecker(
+	logger *zap.Logger,
+	objectReader git.ObjectReader,
+	moduleReader bufmodule.ModuleReader,
+	options ...CheckerOption,
+) Checker {
+	return newChecker(logger, objectReader, moduleReader, options...)
+}
+
+// CheckerOption is an option for a new Checker.
+type CheckerOption func(*checker)
+
+// CheckerWithModuleDir returns a new CheckerOption that checks the module in the given
+// directory, relative to the root of the repository.
+//
+// The default is to check the module at the root of the repository.
+func CheckerWithModuleDir(moduleDir string) CheckerOption {
+	return func(checker *checker) {
+		checker.moduleDir = moduleDir
+	}
+}
+
+// CheckerWithExcludeImports returns a new CheckerOption that excludes imports from
+// breaking change detection.
+func CheckerWithExcludeImports() CheckerOption {
+	return func(checker *checker) {
+		checker.excludeImports = true
+	}
+}
+
+// ResolveCommitHash resolves the name to the hash of a commit in the repository with the
+// `.git` directory.
+//
+// The name is resolved with `git rev-parse`, so it can be anything that git accepts as the
+// name of a commit, such as a full or short commit hash, a tag, a local or remote branch,
+// or HEAD.
+func ResolveCommitHash(
+	ctx context.Context,
+	runner command.Runner,
+	gitDirPath string,
+	name string,
+) (git.Hash, error) {
+	return resolveCommitHash(ctx, runner, gitDirPath, name)
+}
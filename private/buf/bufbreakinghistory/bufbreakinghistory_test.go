@@ -0,0 +1,225 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreakinghistory
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const testBufYAML = `version: v1
+breaking:
+  use:
+    - FIELD_NO_DELETE
+`
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	// The git cat-file process of the ObjectReader holds one slot of the runner.
+	runner := command.NewRunner(command.RunnerWithParallelism(2))
+	dir := t.TempDir()
+	runGit(t, runner, dir, "init")
+	runGit(t, runner, dir, "config", "user.name", "Buf TestBot")
+	runGit(t, runner, dir, "config", "user.email", "testbot@buf.build")
+	commitFiles(t, runner, dir, "initial", map[string]string{
+		"proto/buf.yaml": testBufYAML,
+		"proto/a.proto":  `syntax = "proto3"; package a; message Foo { string one = 1; string two = 2; }`,
+	})
+	runGit(t, runner, dir, "tag", "v1")
+	commitFiles(t, runner, dir, "add field", map[string]string{
+		"proto/a.proto": `syntax = "proto3"; package a; message Foo { string one = 1; string two = 2; string three = 3; }`,
+	})
+	commitFiles(t, runner, dir, "delete field", map[string]string{
+		"proto/a.proto": `syntax = "proto3"; package a; message Foo { string one = 1; string three = 3; }`,
+	})
+	commitFiles(t, runner, dir, "does not build", map[string]string{
+		"proto/a.proto": `syntax = "proto3"; package a; message Foo {`,
+	})
+	commitFiles(t, runner, dir, "delete another field", map[string]string{
+		"proto/a.proto": `syntax = "proto3"; package a; message Foo { string three = 3; }`,
+	})
+	runGit(t, runner, dir, "tag", "v2")
+
+	runGit(t, runner, dir, "branch", "release", "HEAD~1")
+	gitDirPath := filepath.Join(dir, git.DotGitDir)
+	objectReader, err := git.OpenObjectReader(gitDirPath, runner)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, objectReader.Close())
+	})
+	ctx := context.Background()
+	from, err := ResolveCommitHash(ctx, runner, gitDirPath, "v1")
+	require.NoError(t, err)
+	to, err := ResolveCommitHash(ctx, runner, gitDirPath, "v2")
+	require.NoError(t, err)
+	for _, name := range []string{to.Hex(), to.Hex()[:7], "HEAD"} {
+		hash, err := ResolveCommitHash(ctx, runner, gitDirPath, name)
+		require.NoError(t, err, name)
+		assert.Equal(t, to.Hex(), hash.Hex(), name)
+	}
+	release, err := ResolveCommitHash(ctx, runner, gitDirPath, "release")
+	require.NoError(t, err)
+	previous, err := ResolveCommitHash(ctx, runner, gitDirPath, "HEAD~1")
+	require.NoError(t, err)
+	assert.Equal(t, previous.Hex(), release.Hex())
+	_, err = ResolveCommitHash(ctx, runner, gitDirPath, "v3")
+	assert.Error(t, err)
+	_, err = ResolveCommitHash(ctx, runner, gitDirPath, "--all")
+	assert.Error(t, err)
+
+	var messages []string
+	var fileAnnotations []bufanalysis.FileAnnotation
+	err = NewChecker(
+		zap.NewNop(),
+		objectReader,
+		bufmodule.NewNopModuleReader(),
+		CheckerWithModuleDir("proto"),
+	).Check(
+		ctx,
+		from,
+		to,
+		func(commit git.Commit, commitFileAnnotations []bufanalysis.FileAnnotation) error {
+			messages = append(messages, commit.Message())
+			fileAnnotations = append(fileAnnotations, commitFileAnnotations...)
+			return nil
+		},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete field", "delete another field"}, messages)
+	require.Len(t, fileAnnotations, 2)
+	for _, fileAnnotation := range fileAnnotations {
+		assert.Equal(t, "FIELD_NO_DELETE", fileAnnotation.Type())
+		assert.Equal(t, "a.proto", fileAnnotation.FileInfo().Path())
+	}
+
+	// The range must follow first parents from to back to from.
+	err = NewChecker(
+		zap.NewNop(),
+		objectReader,
+		bufmodule.NewNopModuleReader(),
+		CheckerWithModuleDir("proto"),
+	).Check(
+		ctx,
+		to,
+		from,
+		func(git.Commit, []bufanalysis.FileAnnotation) error { return nil },
+	)
+	assert.Error(t, err)
+}
+
+func TestCheckPlugins(t *testing.T) {
+	t.Parallel()
+	runner := command.NewRunner(command.RunnerWithParallelism(2))
+	dir := t.TempDir()
+	runGit(t, runner, dir, "init")
+	runGit(t, runner, dir, "config", "user.name", "Buf TestBot")
+	runGit(t, runner, dir, "config", "user.email", "testbot@buf.build")
+	// The plugin is never run, so it does not need to exist.
+	commitFiles(t, runner, dir, "initial", map[string]string{
+		"buf.yaml": `version: v1
+breaking:
+  use:
+    - FIELD_NO_DELETE
+    - ACME_FOO
+  except:
+    - ACME_BAR
+  severity:
+    ACME_FOO: warning
+  plugins:
+    - plugin: protoc-gen-acme-breaking
+`,
+		"a.proto": `syntax = "proto3"; package a; message Foo { string one = 1; string two = 2; string three = 3; }`,
+	})
+	commitFiles(t, runner, dir, "delete field", map[string]string{
+		"a.proto": `syntax = "proto3"; package a; message Foo { string one = 1; string three = 3; }`,
+	})
+	commitFiles(t, runner, dir, "only plugin rules", map[string]string{
+		"buf.yaml": `version: v1
+breaking:
+  use:
+    - ACME_FOO
+  plugins:
+    - plugin: protoc-gen-acme-breaking
+`,
+	})
+	commitFiles(t, runner, dir, "delete another field", map[string]string{
+		"a.proto": `syntax = "proto3"; package a; message Foo { string three = 3; }`,
+	})
+
+	gitDirPath := filepath.Join(dir, git.DotGitDir)
+	objectReader, err := git.OpenObjectReader(gitDirPath, runner)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, objectReader.Close())
+	})
+	ctx := context.Background()
+	from, err := ResolveCommitHash(ctx, runner, gitDirPath, "HEAD~3")
+	require.NoError(t, err)
+	to, err := ResolveCommitHash(ctx, runner, gitDirPath, "HEAD")
+	require.NoError(t, err)
+	var messages []string
+	err = NewChecker(
+		zap.NewNop(),
+		objectReader,
+		bufmodule.NewNopModuleReader(),
+	).Check(
+		ctx,
+		from,
+		to,
+		func(commit git.Commit, fileAnnotations []bufanalysis.FileAnnotation) error {
+			messages = append(messages, commit.Message())
+			for _, fileAnnotation := range fileAnnotations {
+				assert.Equal(t, "FIELD_NO_DELETE", fileAnnotation.Type())
+			}
+			return nil
+		},
+	)
+	// The built-in rules are still checked, and commits that only use the rules
+	// of plugins are not checked.
+	require.NoError(t, err)
+	assert.Equal(t, []string{"delete field"}, messages)
+}
+
+func commitFiles(t *testing.T, runner command.Runner, dir string, message string, files map[string]string) {
+	for path, contents := range files {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, filepath.Dir(path)), 0700))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, path), []byte(contents), 0600))
+	}
+	runGit(t, runner, dir, "add", ".")
+	runGit(t, runner, dir, "commit", "-m", message)
+}
+
+func runGit(t *testing.T, runner command.Runner, dir string, args ...string) {
+	stderr := bytes.NewBuffer(nil)
+	err := runner.Run(
+		context.Background(),
+		"git",
+		command.RunWithArgs(args...),
+		command.RunWithDir(dir),
+		command.RunWithStderr(stderr),
+	)
+	require.NoError(t, err, stderr.String())
+}
@@ -0,0 +1,281 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufbreakinghistory
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagegit"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+type checker struct {
+	logger             *zap.Logger
+	objectReader       git.ObjectReader
+	moduleReader       bufmodule.ModuleReader
+	storageGitProvider storagegit.Provider
+	moduleDir          string
+	excludeImports     bool
+}
+
+func newChecker(
+	logger *zap.Logger,
+	objectReader git.ObjectReader,
+	moduleReader bufmodule.ModuleReader,
+	options ...CheckerOption,
+) *checker {
+	checker := &checker{
+		logger:       logger,
+		objectReader: objectReader,
+		moduleReader: moduleReader,
+		storageGitProvider: storagegit.NewProvider(
+			objectReader,
+			storagegit.ProviderWithSymlinks(),
+		),
+		moduleDir: ".",
+	}
+	for _, option := range options {
+		option(checker)
+	}
+	checker.moduleDir = normalpath.Normalize(checker.moduleDir)
+	return checker
+}
+
+func (c *checker) Check(
+	ctx context.Context,
+	from git.Hash,
+	to git.Hash,
+	f func(commit git.Commit, fileAnnotations []bufanalysis.FileAnnotation) error,
+) (retErr error) {
+	commits, err := c.commitsInRange(from, to)
+	if err != nil {
+		return err
+	}
+	handler := bufbreaking.NewHandler(c.logger)
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
+	var againstImage bufimage.Image
+	for i, commit := range commits {
+		config, image, err := c.buildImageAt(ctx, commit)
+		if err != nil {
+			return err
+		}
+		if image == nil {
+			continue
+		}
+		breakingConfig := config.Breaking
+		if breakingConfig != nil && len(breakingConfig.Plugins) > 0 {
+			// Plugins are run from the local filesystem, so we do not run the plugins
+			// configured by past commits, and only check their built-in rules.
+			c.logger.Warn(
+				"skipping the breaking plugin rules of commit",
+				zap.String("commit", commit.Hash().Hex()),
+			)
+			breakingConfig = breakingConfigWithoutPlugins(breakingConfig)
+		}
+		// The from commit is only checked against.
+		if i > 0 && againstImage != nil && breakingConfig != nil {
+			fileAnnotations, err := handler.Check(
+				ctx,
+				breakingConfig,
+				againstImage,
+				image,
+			)
+			if err != nil {
+				return fmt.Errorf("check commit %s: %w", commit.Hash().Hex(), err)
+			}
+			if len(fileAnnotations) > 0 {
+				if err := f(commit, fileAnnotations); err != nil {
+					return err
+				}
+			}
+		}
+		againstImage = image
+	}
+	return nil
+}
+
+// commitsInRange returns the commits from the from commit to the to commit, inclusive,
+// in chronological order.
+func (c *checker) commitsInRange(from git.Hash, to git.Hash) ([]git.Commit, error) {
+	commit, err := c.objectReader.Commit(to)
+	if err != nil {
+		return nil, fmt.Errorf("read commit %s: %w", to.Hex(), err)
+	}
+	commits := []git.Commit{commit}
+	for commit.Hash().Hex() != from.Hex() {
+		if len(commit.Parents()) == 0 {
+			return nil, fmt.Errorf("commit %s is not a first-parent ancestor of commit %s", from.Hex(), to.Hex())
+		}
+		// Like Repository.ForEachCommit, only follow the first parent of merge commits, as
+		// the other parents were commits on the merged branch.
+		parentHash := commit.Parents()[0]
+		commit, err = c.objectReader.Commit(parentHash)
+		if err != nil {
+			return nil, fmt.Errorf("read commit %s: %w", parentHash.Hex(), err)
+		}
+		commits = append(commits, commit)
+	}
+	for i, j := 0, len(commits)-1; i < j; i, j = i+1, j-1 {
+		commits[i], commits[j] = commits[j], commits[i]
+	}
+	return commits, nil
+}
+
+// buildImageAt builds the image of the module at the commit.
+//
+// Returns a nil image if the module is not present at the commit, or does not build.
+func (c *checker) buildImageAt(ctx context.Context, commit git.Commit) (*bufconfig.Config, bufimage.Image, error) {
+	logger := c.logger.With(zap.String("commit", commit.Hash().Hex()))
+	commitBucket, err := c.storageGitProvider.NewReadBucket(
+		commit.Tree(),
+		storagegit.ReadBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("read commit %s: %w", commit.Hash().Hex(), err)
+	}
+	moduleBucket := storage.MapReadBucket(commitBucket, storage.MapOnPrefix(c.moduleDir))
+	configFilePath, err := bufconfig.ExistingConfigFilePath(ctx, moduleBucket)
+	if err != nil {
+		return nil, nil, err
+	}
+	if configFilePath == "" {
+		logger.Warn("skipping commit without a module", zap.String("module_dir", c.moduleDir))
+		return nil, nil, nil
+	}
+	config, err := bufconfig.GetConfigForBucket(ctx, moduleBucket)
+	if err != nil {
+		logger.Warn("skipping commit with an invalid module config", zap.Error(err))
+		return nil, nil, nil
+	}
+	module, err := bufmodulebuild.NewModuleBucketBuilder().BuildForBucket(
+		ctx,
+		moduleBucket,
+		config.Build,
+	)
+	if err != nil {
+		logger.Warn("skipping commit where the module could not be read", zap.Error(err))
+		return nil, nil, nil
+	}
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(c.logger, c.moduleReader).Build(
+		ctx,
+		module,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build commit %s: %w", commit.Hash().Hex(), err)
+	}
+	if len(fileAnnotations) > 0 {
+		logger.Warn("skipping commit where the module does not build", zap.Int("num_errors", len(fileAnnotations)))
+		return nil, nil, nil
+	}
+	if c.excludeImports {
+		image = bufimage.ImageWithoutImports(image)
+	}
+	return config, image, nil
+}
+
+// breakingConfigWithoutPlugins returns a copy of the config without the plugins and the
+// rules of the plugins, which are the IDs that are not built-in rules or categories.
+//
+// Returns nil if only the rules of the plugins were used, as the default rules would
+// be used otherwise.
+func breakingConfigWithoutPlugins(config *bufbreakingconfig.Config) *bufbreakingconfig.Config {
+	// Plugins are only supported by v1 configs.
+	builtinIDs := stringutil.SliceToMap(bufbreaking.GetAllRulesAndCategoriesV1())
+	use := builtinIDsOf(config.Use, builtinIDs)
+	if len(config.Use) > 0 && len(use) == 0 {
+		return nil
+	}
+	ignoreIDOrCategoryToRootPaths := make(map[string][]string)
+	for id, rootPaths := range config.IgnoreIDOrCategoryToRootPaths {
+		if _, ok := builtinIDs[id]; ok {
+			ignoreIDOrCategoryToRootPaths[id] = rootPaths
+		}
+	}
+	idOrCategoryToSeverity := make(map[string]string)
+	for id, severity := range config.IDOrCategoryToSeverity {
+		if _, ok := builtinIDs[id]; ok {
+			idOrCategoryToSeverity[id] = severity
+		}
+	}
+	return &bufbreakingconfig.Config{
+		Use:                           use,
+		Except:                        builtinIDsOf(config.Except, builtinIDs),
+		IgnoreRootPaths:               config.IgnoreRootPaths,
+		IgnoreIDOrCategoryToRootPaths: ignoreIDOrCategoryToRootPaths,
+		IgnoreUnstablePackages:        config.IgnoreUnstablePackages,
+		Version:                       config.Version,
+		IDOrCategoryToSeverity:        idOrCategoryToSeverity,
+	}
+}
+
+// builtinIDsOf returns the IDs that are built-in rules or categories.
+func builtinIDsOf(ids []string, builtinIDs map[string]struct{}) []string {
+	var builtin []string
+	for _, id := range ids {
+		if _, ok := builtinIDs[id]; ok {
+			builtin = append(builtin, id)
+		}
+	}
+	return builtin
+}
+
+func resolveCommitHash(
+	ctx context.Context,
+	runner command.Runner,
+	gitDirPath string,
+	name string,
+) (git.Hash, error) {
+	// Do not let the name be parsed as a flag of git rev-parse.
+	if strings.HasPrefix(name, "-") {
+		return nil, fmt.Errorf("%q is not a commit in the repository", name)
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	if err := runner.Run(
+		ctx,
+		"git",
+		command.RunWithArgs("rev-parse", "--verify", "--quiet", name+"^{commit}"),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(stderr),
+		command.RunWithDir(gitDirPath), // exec command at the root of the git repo
+	); err != nil {
+		// With --quiet, git rev-parse only prints to stderr if it failed for another reason
+		// than that the name could not be resolved.
+		if stderr.Len() > 0 {
+			return nil, fmt.Errorf("git rev-parse: %w (%s)", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("%q is not a commit in the repository", name)
+	}
+	return git.NewHashFromHex(strings.TrimSpace(stdout.String()))
+}
@@ -20,16 +20,24 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufapp"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwatch"
 	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufapimodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconnect"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
@@ -48,6 +56,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appname"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/httpauth"
 	"github.com/bufbuild/buf/private/pkg/netrc"
@@ -59,6 +68,7 @@ import (
 	"github.com/bufbuild/connect-go"
 	otelconnect "github.com/bufbuild/connect-opentelemetry-go"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"golang.org/x/term"
 )
@@ -78,6 +88,30 @@ const (
 	// AlphaEnableWASMEnvKey is an env var to enable WASM local plugin execution
 	AlphaEnableWASMEnvKey = "BUF_ALPHA_ENABLE_WASM"
 
+	// CacheMaxSizeEnvKey is an env var to set the maximum size of the module cache.
+	//
+	// If set, the least recently used modules are evicted automatically.
+	CacheMaxSizeEnvKey = "BUF_CACHE_MAX_SIZE"
+	// CacheMaxAgeEnvKey is an env var to set the maximum time since the last access of
+	// modules in the module cache.
+	//
+	// If set, modules that have not been accessed within this duration are evicted automatically.
+	CacheMaxAgeEnvKey = "BUF_CACHE_MAX_AGE"
+
+	// OfflineEnvKey is an env var to run in offline mode.
+	//
+	// In offline mode, buf never sends requests to the Buf Schema Registry. Modules are
+	// only read from the module cache or vendor directory, and remote plugins without a
+	// cached response and module references are rejected.
+	OfflineEnvKey = "BUF_OFFLINE"
+	// OfflineFlagName is the name of the root flag that is equivalent to setting
+	// OfflineEnvKey to true.
+	OfflineFlagName = "offline"
+
+	// cacheAutoGCInterval is the minimum interval between automatic garbage collections
+	// of the module cache.
+	cacheAutoGCInterval = time.Hour
+
 	inputHashtagFlagName      = "__hashtag__"
 	inputHashtagFlagShortName = "#"
 
@@ -125,6 +159,16 @@ var (
 		v1CacheModuleLockRelDirPath,
 		v1CacheModuleSumRelDirPath,
 		v2CacheModuleRelDirPath,
+		v2CacheModuleLockRelDirPath,
+	}
+	// AllCacheGenerateRelDirPaths are all directory paths for all time concerning the generate cache.
+	//
+	// These are normalized.
+	// These are relative to container.CacheDirPath().
+	//
+	// This variable is used for clearing the cache.
+	AllCacheGenerateRelDirPaths = []string{
+		v1CacheGenerateRelDirPath,
 	}
 
 	// ErrNotATTY is returned when an input io.Reader is not a TTY where it is expected.
@@ -164,6 +208,33 @@ var (
 	// This directory replaces the use of v1CacheModuleDataRelDirPath, v1CacheModuleLockRelDirPath, and
 	// v1CacheModuleSumRelDirPath with a cache implementation using content addressable storage.
 	v2CacheModuleRelDirPath = normalpath.Join("v2", "module")
+	// v2CacheModuleLockRelDirPath is the relative path to the cache directory where the lock file
+	// for v2CacheModuleRelDirPath is stored.
+	//
+	// Normalized.
+	// The lock file is used to make sure that the cache is not garbage collected while
+	// other buf processes read from it.
+	v2CacheModuleLockRelDirPath = normalpath.Join("v2", "lock", "module")
+	// v1CacheGenerateRelDirPath is the relative path to the cache directory where plugin responses
+	// are stored by buf generate.
+	//
+	// Normalized.
+	v1CacheGenerateRelDirPath = normalpath.Join("v1", "generate")
+
+	// byteSizeUnitToMultiplier maps the lowercase units accepted by ParseByteSize to
+	// their number of bytes.
+	byteSizeUnitToMultiplier = map[string]int64{
+		"":    1,
+		"b":   1,
+		"kb":  1000,
+		"mb":  1000 * 1000,
+		"gb":  1000 * 1000 * 1000,
+		"tb":  1000 * 1000 * 1000 * 1000,
+		"kib": 1 << 10,
+		"mib": 1 << 20,
+		"gib": 1 << 30,
+		"tib": 1 << 40,
+	}
 
 	// allVisibiltyStrings are the possible options that a user can set the visibility flag with.
 	allVisibiltyStrings = []string{
@@ -261,6 +332,17 @@ By default, symlinks are followed in this CLI, but never followed on the Buf Sch
 	)
 }
 
+// BindWatch binds the watch flag.
+func BindWatch(flagSet *pflag.FlagSet, addr *bool, flagName string) {
+	flagSet.BoolVar(
+		addr,
+		flagName,
+		false,
+		`Watch the modules of the input, and run again each time a .proto or configuration file changes until interrupted
+Only local directory and .proto file inputs can be watched, and --timeout does not apply`,
+	)
+}
+
 // BindVisibility binds the visibility flag.
 func BindVisibility(flagSet *pflag.FlagSet, addr *string, flagName string) {
 	flagSet.StringVar(
@@ -391,10 +473,10 @@ func NewWireImageConfigReader(
 	clientConfig *connectclient.Config,
 ) (bufwire.ImageConfigReader, error) {
 	logger := container.Logger()
-	moduleResolver := bufapimodule.NewModuleResolver(
-		logger,
-		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
-	)
+	moduleResolver, err := NewModuleResolver(container, clientConfig)
+	if err != nil {
+		return nil, err
+	}
 	moduleReader, err := NewModuleReaderAndCreateCacheDirs(container, clientConfig)
 	if err != nil {
 		return nil, err
@@ -416,10 +498,10 @@ func NewWireModuleConfigReader(
 	clientConfig *connectclient.Config,
 ) (bufwire.ModuleConfigReader, error) {
 	logger := container.Logger()
-	moduleResolver := bufapimodule.NewModuleResolver(
-		logger,
-		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
-	)
+	moduleResolver, err := NewModuleResolver(container, clientConfig)
+	if err != nil {
+		return nil, err
+	}
 	moduleReader, err := NewModuleReaderAndCreateCacheDirs(container, clientConfig)
 	if err != nil {
 		return nil, err
@@ -442,10 +524,10 @@ func NewWireModuleConfigReaderForModuleReader(
 	moduleReader bufmodule.ModuleReader,
 ) (bufwire.ModuleConfigReader, error) {
 	logger := container.Logger()
-	moduleResolver := bufapimodule.NewModuleResolver(
-		logger,
-		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
-	)
+	moduleResolver, err := NewModuleResolver(container, clientConfig)
+	if err != nil {
+		return nil, err
+	}
 	return bufwire.NewModuleConfigReader(
 		logger,
 		storageosProvider,
@@ -462,10 +544,10 @@ func NewWireFileLister(
 	clientConfig *connectclient.Config,
 ) (bufwire.FileLister, error) {
 	logger := container.Logger()
-	moduleResolver := bufapimodule.NewModuleResolver(
-		logger,
-		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
-	)
+	moduleResolver, err := NewModuleResolver(container, clientConfig)
+	if err != nil {
+		return nil, err
+	}
 	moduleReader, err := NewModuleReaderAndCreateCacheDirs(container, clientConfig)
 	if err != nil {
 		return nil, err
@@ -521,6 +603,26 @@ func NewWireProtoEncodingWriter(
 	)
 }
 
+// NewModuleResolver returns a new ModuleResolver.
+//
+// In offline mode, the ModuleResolver returns an error for every module reference.
+func NewModuleResolver(
+	container appflag.Container,
+	clientConfig *connectclient.Config,
+) (bufmodule.ModuleResolver, error) {
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
+	if offline {
+		return newOfflineModuleResolver(), nil
+	}
+	return bufapimodule.NewModuleResolver(
+		container.Logger(),
+		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
+	), nil
+}
+
 // NewModuleReaderAndCreateCacheDirs returns a new ModuleReader while creating the
 // required cache directories.
 func NewModuleReaderAndCreateCacheDirs(
@@ -534,33 +636,160 @@ func newModuleReaderAndCreateCacheDirs(
 	container appflag.Container,
 	clientConfig *connectclient.Config,
 ) (bufmodule.ModuleReader, error) {
-	cacheModuleDirPathV2 := normalpath.Join(container.CacheDirPath(), v2CacheModuleRelDirPath)
-	if err := checkExistingCacheDirs(container.CacheDirPath(), cacheModuleDirPathV2); err != nil {
+	casModuleBucket, fileLocker, err := NewModuleCacheReadWriteBucketAndFileLocker(container)
+	if err != nil {
 		return nil, err
 	}
-	if err := createCacheDirs(cacheModuleDirPathV2); err != nil {
+	moduleReaderOptions := []bufmodulecache.ModuleReaderOption{
+		bufmodulecache.ModuleReaderWithFileLocker(fileLocker),
+	}
+	gcOptions, err := NewModuleCacheGCOptions(container)
+	if err != nil {
 		return nil, err
 	}
-	delegateReader := bufapimodule.NewModuleReader(
-		bufapimodule.NewDownloadServiceClientFactory(clientConfig),
-	)
-	repositoryClientFactory := bufmodulecache.NewRepositoryServiceClientFactory(clientConfig)
-	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
-	var moduleReader bufmodule.ModuleReader
-	casModuleBucket, err := storageosProvider.NewReadWriteBucket(cacheModuleDirPathV2)
+	if len(gcOptions) > 0 {
+		moduleReaderOptions = append(
+			moduleReaderOptions,
+			bufmodulecache.ModuleReaderWithAutoGC(cacheAutoGCInterval, gcOptions...),
+		)
+	}
+	offline, err := IsOffline(container)
 	if err != nil {
 		return nil, err
 	}
+	var delegateReader bufmodule.ModuleReader
+	if offline {
+		delegateReader = newOfflineModuleReader()
+	} else {
+		delegateReader = bufapimodule.NewModuleReader(
+			bufapimodule.NewDownloadServiceClientFactory(clientConfig),
+		)
+	}
+	repositoryClientFactory := bufmodulecache.NewRepositoryServiceClientFactory(clientConfig)
+	var moduleReader bufmodule.ModuleReader
 	moduleReader = bufmodulecache.NewModuleReader(
 		container.Logger(),
 		container.VerbosePrinter(),
 		casModuleBucket,
 		delegateReader,
 		repositoryClientFactory,
+		moduleReaderOptions...,
 	)
 	return moduleReader, nil
 }
 
+// IsOffline returns true if buf is in offline mode, that is if OfflineEnvKey is set
+// to true, either directly or with the root flag OfflineFlagName.
+func IsOffline(container app.EnvContainer) (bool, error) {
+	offline, err := app.EnvBool(container, OfflineEnvKey, false)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for $%s: %w", OfflineEnvKey, err)
+	}
+	return offline, nil
+}
+
+// GCModuleCache evicts the least recently used modules from the module cache
+// according to the options.
+func GCModuleCache(
+	ctx context.Context,
+	container appflag.Container,
+	options ...bufmodulecache.GCOption,
+) (*bufmodulecache.GCResult, error) {
+	casModuleBucket, fileLocker, err := NewModuleCacheReadWriteBucketAndFileLocker(container)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodulecache.GC(ctx, container.Logger(), casModuleBucket, fileLocker, options...)
+}
+
+// NewModuleCacheGCOptions returns the options for automatic garbage collection of
+// the module cache from the BUF_CACHE_MAX_SIZE and BUF_CACHE_MAX_AGE env vars.
+//
+// Returns no options if neither is set.
+func NewModuleCacheGCOptions(container app.EnvContainer) ([]bufmodulecache.GCOption, error) {
+	var gcOptions []bufmodulecache.GCOption
+	if maxSizeString := container.Env(CacheMaxSizeEnvKey); maxSizeString != "" {
+		maxSize, err := ParseByteSize(maxSizeString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for $%s: %w", CacheMaxSizeEnvKey, err)
+		}
+		gcOptions = append(gcOptions, bufmodulecache.GCWithMaxSize(maxSize))
+	}
+	if maxAgeString := container.Env(CacheMaxAgeEnvKey); maxAgeString != "" {
+		maxAge, err := time.ParseDuration(maxAgeString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for $%s: %w", CacheMaxAgeEnvKey, err)
+		}
+		if maxAge <= 0 {
+			return nil, fmt.Errorf("invalid value for $%s: must be positive", CacheMaxAgeEnvKey)
+		}
+		gcOptions = append(gcOptions, bufmodulecache.GCWithMaxAge(maxAge))
+	}
+	return gcOptions, nil
+}
+
+// ParseByteSize parses a size in bytes such as "512", "100MB", or "2GiB".
+//
+// Decimal (KB, MB, GB, TB) and binary (KiB, MiB, GiB, TiB) units are supported, and
+// units are case-insensitive.
+func ParseByteSize(value string) (int64, error) {
+	trimmed := strings.TrimSpace(value)
+	number := strings.TrimRightFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != ' '
+	})
+	unit := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(trimmed, number)))
+	multiplier, ok := byteSizeUnitToMultiplier[unit]
+	if !ok {
+		return 0, fmt.Errorf("invalid size %q: unknown unit %q", value, unit)
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(number), 10, 64)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid size %q: must be a positive integer followed by an optional unit", value)
+	}
+	if size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q: too large", value)
+	}
+	return size * multiplier, nil
+}
+
+// NewModuleCacheReadWriteBucketAndFileLocker returns a new ReadWriteBucket for the module
+// cache and the Locker for its file lock, and creates the required cache directories.
+func NewModuleCacheReadWriteBucketAndFileLocker(
+	container appflag.Container,
+) (storage.ReadWriteBucket, filelock.Locker, error) {
+	cacheModuleDirPathV2 := normalpath.Join(container.CacheDirPath(), v2CacheModuleRelDirPath)
+	cacheModuleLockDirPathV2 := normalpath.Join(container.CacheDirPath(), v2CacheModuleLockRelDirPath)
+	if err := checkExistingCacheDirs(container.CacheDirPath(), cacheModuleDirPathV2, cacheModuleLockDirPathV2); err != nil {
+		return nil, nil, err
+	}
+	if err := createCacheDirs(cacheModuleDirPathV2, cacheModuleLockDirPathV2); err != nil {
+		return nil, nil, err
+	}
+	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
+	casModuleBucket, err := storageosProvider.NewReadWriteBucket(cacheModuleDirPathV2)
+	if err != nil {
+		return nil, nil, err
+	}
+	fileLocker, err := filelock.NewLocker(cacheModuleLockDirPathV2)
+	if err != nil {
+		return nil, nil, err
+	}
+	return casModuleBucket, fileLocker, nil
+}
+
+// NewGenerateCacheReadWriteBucket returns a new ReadWriteBucket for the generate cache,
+// and creates the required cache directories.
+func NewGenerateCacheReadWriteBucket(container appflag.Container) (storage.ReadWriteBucket, error) {
+	cacheGenerateDirPath := normalpath.Join(container.CacheDirPath(), v1CacheGenerateRelDirPath)
+	if err := checkExistingCacheDirs(container.CacheDirPath(), cacheGenerateDirPath); err != nil {
+		return nil, err
+	}
+	if err := createCacheDirs(cacheGenerateDirPath); err != nil {
+		return nil, err
+	}
+	return storageos.NewProvider().NewReadWriteBucket(cacheGenerateDirPath)
+}
+
 // NewConfig creates a new Config.
 func NewConfig(container appflag.Container) (*bufapp.Config, error) {
 	externalConfig := bufapp.ExternalConfig{}
@@ -576,7 +805,16 @@ func newConnectClientConfigWithOptions(container appflag.Container, opts ...conn
 	if err != nil {
 		return nil, err
 	}
+	offline, err := IsOffline(container)
+	if err != nil {
+		return nil, err
+	}
 	client := httpclient.NewClient(config.TLS)
+	if offline {
+		// This guarantees that no request is sent in offline mode, even by commands
+		// that do not check for offline mode themselves.
+		client.Transport = newOfflineRoundTripper()
+	}
 	options := []connectclient.ConfigOption{
 		connectclient.WithAddressMapper(func(address string) string {
 			if config.TLS == nil {
@@ -812,6 +1050,61 @@ func NewImageForSource(
 	return bufimage.MergeImages(images...)
 }
 
+// WatchInput runs f for the input, and then runs f again each time a .proto or
+// configuration file changes within the modules of the input, until the command
+// is interrupted.
+//
+// If rebuildChangedModules is true and the input is the root of a workspace, f is
+// run again for the directory of each module that changed instead of the input,
+// so that only the affected modules are rebuilt. Errors returned by f are printed
+// and do not stop watching.
+func WatchInput(
+	ctx context.Context,
+	container appflag.Container,
+	input string,
+	disableSymlinks bool,
+	rebuildChangedModules bool,
+	watchFlagName string,
+	f func(ctx context.Context, input string) error,
+) error {
+	logger := container.Logger()
+	ref, err := buffetch.NewRefParser(logger).GetRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	sourceRef, ok := ref.(buffetch.SourceRef)
+	if !ok || !buffetch.IsLocalSourceRef(sourceRef) {
+		return appcmd.NewInvalidArgumentErrorf("--%s can only be used with a local directory or .proto file input", watchFlagName)
+	}
+	sourceDirPath, _ := buffetch.LocalSourceRefDirPath(sourceRef)
+	moduleDirPaths, isWorkspaceRoot, err := getModuleDirPathsForSource(
+		ctx,
+		logger,
+		container,
+		NewStorageosProvider(disableSymlinks),
+		sourceRef,
+		sourceDirPath,
+	)
+	if err != nil {
+		return err
+	}
+	rebuildChangedModules = rebuildChangedModules && isWorkspaceRoot
+	return bufwatch.NewWatcher(logger).Watch(
+		newWatchContext(ctx),
+		moduleDirPaths,
+		func(ctx context.Context, changedDirPaths []string) error {
+			if changedDirPaths == nil || !rebuildChangedModules {
+				runWatched(ctx, container, input, f)
+				return nil
+			}
+			for _, changedDirPath := range changedDirPaths {
+				runWatched(ctx, container, changedDirPath, f)
+			}
+			return nil
+		},
+	)
+}
+
 // WellKnownTypeImage returns the image for the well known type (google.protobuf.Duration for example).
 func WellKnownTypeImage(ctx context.Context, logger *zap.Logger, wellKnownType string) (bufimage.Image, error) {
 	sourceConfig, err := bufconfig.GetConfigForBucket(
@@ -919,6 +1212,88 @@ func SelectReferenceForRemote(references []bufmoduleref.ModuleReference) bufmodu
 	return references[0]
 }
 
+// GetConfigWithCheckPluginPaths returns the config of the ImageConfig for the input ref,
+// with the relative paths of its check plugins resolved against the directory of the
+// configuration that defines them.
+//
+// Check plugins are run from the local filesystem, so an error is returned if the config
+// defines plugins but was read from a git repository or archive.
+func GetConfigWithCheckPluginPaths(
+	ref buffetch.Ref,
+	configOverride string,
+	imageConfig bufwire.ImageConfig,
+) (*bufconfig.Config, error) {
+	config := imageConfig.Config()
+	if !hasCheckPlugins(config) {
+		return config, nil
+	}
+	if configOverride != "" {
+		return ConfigWithCheckPluginDirPath(config, ConfigOverrideDirPath(configOverride))
+	}
+	sourceRef, ok := ref.(buffetch.SourceRef)
+	if !ok {
+		// The configuration of images and modules is read from the current directory.
+		return ConfigWithCheckPluginDirPath(config, ".")
+	}
+	if !buffetch.IsLocalSourceRef(sourceRef) {
+		return nil, errors.New("plugins can only be configured for local inputs, use --config to configure plugins for a git repository or archive")
+	}
+	return ConfigWithCheckPluginDirPath(config, getModuleDirPathForLocalSource(sourceRef, imageConfig.Image()))
+}
+
+// ConfigWithCheckPluginDirPath returns a copy of the config with the relative paths of
+// its check plugins resolved against dirPath.
+func ConfigWithCheckPluginDirPath(config *bufconfig.Config, dirPath string) (*bufconfig.Config, error) {
+	if !hasCheckPlugins(config) {
+		return config, nil
+	}
+	resolvedConfig := *config
+	if config.Lint != nil && len(config.Lint.Plugins) > 0 {
+		lintConfig := *config.Lint
+		lintConfig.Plugins = make([]*buflintconfig.PluginConfig, len(config.Lint.Plugins))
+		for i, pluginConfig := range config.Lint.Plugins {
+			pluginPath, err := bufcheckplugin.ResolvePluginPath(pluginConfig.Plugin, dirPath)
+			if err != nil {
+				return nil, err
+			}
+			lintConfig.Plugins[i] = &buflintconfig.PluginConfig{
+				Plugin:  pluginPath,
+				Options: pluginConfig.Options,
+			}
+		}
+		resolvedConfig.Lint = &lintConfig
+	}
+	if config.Breaking != nil && len(config.Breaking.Plugins) > 0 {
+		breakingConfig := *config.Breaking
+		breakingConfig.Plugins = make([]*bufbreakingconfig.PluginConfig, len(config.Breaking.Plugins))
+		for i, pluginConfig := range config.Breaking.Plugins {
+			pluginPath, err := bufcheckplugin.ResolvePluginPath(pluginConfig.Plugin, dirPath)
+			if err != nil {
+				return nil, err
+			}
+			breakingConfig.Plugins[i] = &bufbreakingconfig.PluginConfig{
+				Plugin:  pluginPath,
+				Options: pluginConfig.Options,
+			}
+		}
+		resolvedConfig.Breaking = &breakingConfig
+	}
+	return &resolvedConfig, nil
+}
+
+// ConfigOverrideDirPath returns the directory of the configuration that is read from the
+// current directory with the given --config override.
+//
+// This is the directory of the override if it is a file, and the current directory otherwise.
+func ConfigOverrideDirPath(configOverride string) string {
+	switch filepath.Ext(configOverride) {
+	case ".json", ".yaml", ".yml":
+		return filepath.Dir(configOverride)
+	default:
+		return "."
+	}
+}
+
 func validateErrorFormatFlag(validFormatStrings []string, errorFormatString string, errorFormatFlagName string) error {
 	for _, formatString := range validFormatStrings {
 		if errorFormatString == formatString {
@@ -1008,8 +1383,108 @@ func newFetchSourceReader(
 	)
 }
 
+// getModuleDirPathsForSource returns the directories of the modules of the local source.
+func getModuleDirPathsForSource(
+	ctx context.Context,
+	logger *zap.Logger,
+	container app.EnvStdinContainer,
+	storageosProvider storageos.Provider,
+	sourceRef buffetch.SourceRef,
+	sourceDirPath string,
+) (_ []string, _ bool, retErr error) {
+	sourceBucket, err := newFetchSourceReader(
+		logger,
+		storageosProvider,
+		command.NewRunner(),
+	).GetSourceBucket(
+		ctx,
+		container,
+		sourceRef,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, sourceBucket.Close())
+	}()
+	return bufwatch.GetModuleDirPaths(ctx, sourceBucket, sourceDirPath)
+}
+
+// runWatched runs f for the input, and prints the error returned by f, if any.
+//
+// File annotations are printed by f, so ErrFileAnnotation is not printed again.
+// Errors caused by an interrupt are not printed.
+func runWatched(
+	ctx context.Context,
+	container app.StderrContainer,
+	input string,
+	f func(ctx context.Context, input string) error,
+) {
+	if err := f(ctx, input); err != nil && ctx.Err() == nil {
+		if errString := wrapError(err).Error(); errString != "" {
+			_, _ = fmt.Fprintln(container.Stderr(), errString)
+		}
+	}
+}
+
+// watchContext is a context with the values of its parent, but without its deadline
+// or cancellation, so that the --timeout flag does not stop watching.
+type watchContext struct {
+	parent context.Context
+}
+
+func newWatchContext(parent context.Context) watchContext {
+	return watchContext{
+		parent: parent,
+	}
+}
+
+func (watchContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (watchContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (watchContext) Err() error {
+	return nil
+}
+
+func (c watchContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
 // newFetchImageReader creates a new buffetch.ImageReader with the default HTTP client
 // and git cloner.
+// hasCheckPlugins returns true if the config has lint or breaking plugins.
+func hasCheckPlugins(config *bufconfig.Config) bool {
+	return (config.Lint != nil && len(config.Lint.Plugins) > 0) ||
+		(config.Breaking != nil && len(config.Breaking.Plugins) > 0)
+}
+
+// getModuleDirPathForLocalSource returns the directory of the module that the files of
+// the image were built from.
+//
+// Check plugins are only supported for v1 configurations, which do not have roots, so
+// the module directory is the external path of a file without its path.
+func getModuleDirPathForLocalSource(sourceRef buffetch.SourceRef, image bufimage.Image) string {
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		externalPath := normalpath.Normalize(imageFile.ExternalPath())
+		if externalPath == imageFile.Path() {
+			return "."
+		}
+		if strings.HasSuffix(externalPath, "/"+imageFile.Path()) {
+			return normalpath.Unnormalize(strings.TrimSuffix(externalPath, "/"+imageFile.Path()))
+		}
+	}
+	dirPath, _ := buffetch.LocalSourceRefDirPath(sourceRef)
+	return normalpath.Unnormalize(dirPath)
+}
+
 func newFetchImageReader(
 	logger *zap.Logger,
 	storageosProvider storageos.Provider,
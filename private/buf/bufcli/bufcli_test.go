@@ -23,10 +23,12 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/pkg/app"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/connect-go"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -133,6 +135,58 @@ func TestDiscoverRemote(t *testing.T) {
 	}
 }
 
+func TestParseByteSize(t *testing.T) {
+	t.Parallel()
+	for value, expected := range map[string]int64{
+		"512":     512,
+		"512B":    512,
+		"100MB":   100 * 1000 * 1000,
+		"100 mb":  100 * 1000 * 1000,
+		"2GiB":    2 << 30,
+		" 1TiB ":  1 << 40,
+		"64kib":   64 << 10,
+		"3000000": 3000000,
+	} {
+		size, err := bufcli.ParseByteSize(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+	for _, value := range []string{"", "0", "-1", "1.5GB", "GB", "10XB", "9999999TiB"} {
+		_, err := bufcli.ParseByteSize(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestIsOffline(t *testing.T) {
+	t.Parallel()
+	for value, expected := range map[string]bool{
+		"":      false,
+		"0":     false,
+		"false": false,
+		"1":     true,
+		"true":  true,
+	} {
+		offline, err := bufcli.IsOffline(app.NewEnvContainer(map[string]string{bufcli.OfflineEnvKey: value}))
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, offline, value)
+	}
+	_, err := bufcli.IsOffline(app.NewEnvContainer(map[string]string{bufcli.OfflineEnvKey: "maybe"}))
+	assert.Error(t, err)
+}
+
+func TestOfflineErrorInterceptor(t *testing.T) {
+	t.Parallel()
+	run := bufcli.NewErrorInterceptor()(func(context.Context, appflag.Container) error {
+		return connect.NewError(
+			connect.CodeUnavailable,
+			fmt.Errorf(`cannot send a request to "buf.build": %w`, bufcli.ErrOffline),
+		)
+	})
+	err := run(context.Background(), nil)
+	assert.ErrorIs(t, err, bufcli.ErrOffline)
+	assert.Equal(t, `Failure: cannot send a request to "buf.build": buf is in offline mode`, err.Error())
+}
+
 func TestBucketAndConfigForSource(t *testing.T) {
 	t.Parallel()
 	testBucketAndConfigForSource(
@@ -51,6 +51,11 @@ var (
 	// We also exit with 100 to be able to distinguish user-parsable errors from
 	// system errors.
 	ErrFileAnnotation = app.NewError(ExitCodeFileAnnotation, "")
+
+	// ErrOffline is used when buf is in offline mode and an operation requires the network.
+	//
+	// See OfflineEnvKey for details on offline mode.
+	ErrOffline = errors.New("buf is in offline mode")
 )
 
 // errInternal is returned when the user encounters an unexpected internal buf error.
@@ -152,6 +157,14 @@ func wrapError(err error) error {
 	if err == nil {
 		return nil
 	}
+	// Requests are rejected before they are sent in offline mode, and the Connect error
+	// for this would be reported as an unavailable server, so report the rejection instead.
+	if errors.Is(err, ErrOffline) {
+		if connectErr, ok := asConnectError(err); ok {
+			err = connectErr.Unwrap()
+		}
+		return fmt.Errorf("Failure: %w", err)
+	}
 	connectErr, ok := asConnectError(err)
 
 	// If error is empty and not a Connect error, we return it as-is.
@@ -0,0 +1,68 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcli
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+)
+
+type offlineModuleReader struct{}
+
+func newOfflineModuleReader() *offlineModuleReader {
+	return &offlineModuleReader{}
+}
+
+func (*offlineModuleReader) GetModule(
+	_ context.Context,
+	modulePin bufmoduleref.ModulePin,
+) (bufmodule.Module, error) {
+	return nil, fmt.Errorf(
+		`dependency %q is not in the module cache and cannot be downloaded: %w. Run "buf mod vendor" to vendor it, or run without offline mode to download it to the module cache`,
+		modulePin.String(),
+		ErrOffline,
+	)
+}
+
+type offlineModuleResolver struct{}
+
+func newOfflineModuleResolver() *offlineModuleResolver {
+	return &offlineModuleResolver{}
+}
+
+func (*offlineModuleResolver) GetModulePin(
+	_ context.Context,
+	moduleReference bufmoduleref.ModuleReference,
+) (bufmoduleref.ModulePin, error) {
+	return nil, fmt.Errorf(
+		"module reference %q cannot be resolved: %w. Use a local input instead",
+		moduleReference.String(),
+		ErrOffline,
+	)
+}
+
+type offlineRoundTripper struct{}
+
+func newOfflineRoundTripper() *offlineRoundTripper {
+	return &offlineRoundTripper{}
+}
+
+func (*offlineRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	return nil, fmt.Errorf("cannot send a request to %q: %w", request.URL.Host, ErrOffline)
+}
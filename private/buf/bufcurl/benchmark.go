@@ -0,0 +1,436 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// BenchmarkFormatText is the text format for BenchmarkResults.
+	BenchmarkFormatText = "text"
+	// BenchmarkFormatJSON is the JSON format for BenchmarkResults.
+	BenchmarkFormatJSON = "json"
+)
+
+var (
+	// AllBenchmarkFormatStrings is all benchmark format strings.
+	AllBenchmarkFormatStrings = []string{
+		BenchmarkFormatText,
+		BenchmarkFormatJSON,
+	}
+
+	benchmarkPercentiles = []float64{50, 90, 95, 99}
+)
+
+// BenchmarkResult is the result of a benchmark.
+type BenchmarkResult struct {
+	// Requests is the number of requests sent.
+	Requests int
+	// Duration is the time from the start of the first request to the end of the last request.
+	Duration time.Duration
+	// Codes is the number of requests that completed with each code, keyed by
+	// the name of the code, including "ok" for successful requests.
+	Codes map[string]int
+	// Latencies are the sorted latencies of the requests.
+	Latencies []time.Duration
+}
+
+// Throughput is the number of requests per second.
+func (b *BenchmarkResult) Throughput() float64 {
+	if b.Duration <= 0 {
+		return 0
+	}
+	return float64(b.Requests) / b.Duration.Seconds()
+}
+
+// Percentile returns the latency at the percentile, between 0 and 100.
+//
+// This uses the nearest-rank method. If there are no latencies, this returns 0.
+func (b *BenchmarkResult) Percentile(percentile float64) time.Duration {
+	if len(b.Latencies) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(b.Latencies))))
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(b.Latencies) {
+		rank = len(b.Latencies)
+	}
+	return b.Latencies[rank-1]
+}
+
+// Mean returns the mean latency.
+//
+// If there are no latencies, this returns 0.
+func (b *BenchmarkResult) Mean() time.Duration {
+	if len(b.Latencies) == 0 {
+		return 0
+	}
+	var total time.Duration
+	for _, latency := range b.Latencies {
+		total += latency
+	}
+	return total / time.Duration(len(b.Latencies))
+}
+
+// RunBenchmark invokes the method described by the given descriptor repeatedly, and
+// returns the throughput, codes, and latencies of the requests.
+//
+// A single invoker is created for the method and used for all requests, which each send the
+// given request data and headers. Responses and RPC errors are not printed. The benchmark
+// stops at the first error that is not an RPC error, such as an invalid request message, and
+// returns that error.
+//
+// At least one of BenchmarkWithRequests or BenchmarkWithDuration must be given.
+func RunBenchmark(
+	ctx context.Context,
+	container appflag.Container,
+	md protoreflect.MethodDescriptor,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	url string,
+	dataSource string,
+	data []byte,
+	headers http.Header,
+	options ...BenchmarkOption,
+) (*BenchmarkResult, error) {
+	benchmarkOptions := newBenchmarkOptions()
+	for _, option := range options {
+		option(benchmarkOptions)
+	}
+	return runBenchmark(ctx, container.VerbosePrinter(), md, res, httpClient, opts, url, dataSource, data, headers, benchmarkOptions)
+}
+
+// BenchmarkOption is an option for RunBenchmark.
+type BenchmarkOption func(*benchmarkOptions)
+
+// BenchmarkWithRequests returns a new BenchmarkOption that stops the benchmark after
+// the number of requests.
+func BenchmarkWithRequests(requests int) BenchmarkOption {
+	return func(benchmarkOptions *benchmarkOptions) {
+		benchmarkOptions.requests = requests
+	}
+}
+
+// BenchmarkWithDuration returns a new BenchmarkOption that stops sending requests
+// after the duration.
+//
+// If a number of requests is also given, the benchmark stops at whichever comes first.
+func BenchmarkWithDuration(duration time.Duration) BenchmarkOption {
+	return func(benchmarkOptions *benchmarkOptions) {
+		benchmarkOptions.duration = duration
+	}
+}
+
+// BenchmarkWithConcurrency returns a new BenchmarkOption that sets the number of
+// concurrent requests.
+//
+// The default is 1.
+func BenchmarkWithConcurrency(concurrency int) BenchmarkOption {
+	return func(benchmarkOptions *benchmarkOptions) {
+		benchmarkOptions.concurrency = concurrency
+	}
+}
+
+// BenchmarkWithRate returns a new BenchmarkOption that limits the rate at which
+// requests are started, in requests per second.
+//
+// The default is to not limit the rate.
+func BenchmarkWithRate(rate float64) BenchmarkOption {
+	return func(benchmarkOptions *benchmarkOptions) {
+		benchmarkOptions.rate = rate
+	}
+}
+
+// PrintBenchmarkResult prints the BenchmarkResult in the format.
+func PrintBenchmarkResult(writer io.Writer, result *BenchmarkResult, format string) error {
+	switch format {
+	case BenchmarkFormatText:
+		return printBenchmarkResultAsText(writer, result)
+	case BenchmarkFormatJSON:
+		return printBenchmarkResultAsJSON(writer, result)
+	default:
+		return fmt.Errorf("unknown benchmark format: %q", format)
+	}
+}
+
+// ParseBenchmarkFormat validates the benchmark format string, returning the normalized format.
+func ParseBenchmarkFormat(format string) (string, error) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	for _, knownFormat := range AllBenchmarkFormatStrings {
+		if format == knownFormat {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("unknown benchmark format: %q", format)
+}
+
+type benchmarkOptions struct {
+	requests    int
+	duration    time.Duration
+	concurrency int
+	rate        float64
+}
+
+func newBenchmarkOptions() *benchmarkOptions {
+	return &benchmarkOptions{
+		concurrency: 1,
+	}
+}
+
+func runBenchmark(
+	ctx context.Context,
+	printer verbose.Printer,
+	md protoreflect.MethodDescriptor,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	url string,
+	dataSource string,
+	data []byte,
+	headers http.Header,
+	benchmarkOptions *benchmarkOptions,
+) (*BenchmarkResult, error) {
+	if benchmarkOptions.requests <= 0 && benchmarkOptions.duration <= 0 {
+		return nil, errors.New("a number of requests or a duration is required to run a benchmark")
+	}
+	if benchmarkOptions.concurrency < 1 {
+		benchmarkOptions.concurrency = 1
+	}
+	invoker := newInvoker(printer, md, res, false, httpClient, opts, url, io.Discard, io.Discard)
+	return newBenchmarkRunner(invoker, dataSource, data, headers, benchmarkOptions).run(ctx)
+}
+
+type benchmarkRunner struct {
+	invoker    *invoker
+	dataSource string
+	data       []byte
+	headers    http.Header
+	options    *benchmarkOptions
+
+	// next is the index of the next request to start.
+	next int64
+}
+
+func newBenchmarkRunner(
+	invoker *invoker,
+	dataSource string,
+	data []byte,
+	headers http.Header,
+	options *benchmarkOptions,
+) *benchmarkRunner {
+	return &benchmarkRunner{
+		invoker:    invoker,
+		dataSource: dataSource,
+		data:       data,
+		headers:    headers,
+		options:    options,
+	}
+}
+
+// benchmarkWorkerResult is the result of the requests of one worker.
+type benchmarkWorkerResult struct {
+	codes     map[connect.Code]int
+	latencies []time.Duration
+	err       error
+}
+
+func (b *benchmarkRunner) run(ctx context.Context) (*BenchmarkResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	start := time.Now()
+	var deadline time.Time
+	if b.options.duration > 0 {
+		deadline = start.Add(b.options.duration)
+	}
+	workerResults := make([]*benchmarkWorkerResult, b.options.concurrency)
+	var waitGroup sync.WaitGroup
+	for i := range workerResults {
+		workerResult := &benchmarkWorkerResult{
+			codes: make(map[connect.Code]int),
+		}
+		workerResults[i] = workerResult
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if err := b.runWorker(ctx, start, deadline, workerResult); err != nil {
+				workerResult.err = err
+				// Stop the other workers, since their requests will fail the same way.
+				cancel()
+			}
+		}()
+	}
+	waitGroup.Wait()
+	result := &BenchmarkResult{
+		Duration: time.Since(start),
+		Codes:    make(map[string]int),
+	}
+	for _, workerResult := range workerResults {
+		if workerResult.err != nil {
+			return nil, workerResult.err
+		}
+		for code, count := range workerResult.codes {
+			result.Codes[codeString(code)] += count
+			result.Requests += count
+		}
+		result.Latencies = append(result.Latencies, workerResult.latencies...)
+	}
+	sort.Slice(result.Latencies, func(i, j int) bool { return result.Latencies[i] < result.Latencies[j] })
+	return result, nil
+}
+
+func (b *benchmarkRunner) runWorker(
+	ctx context.Context,
+	start time.Time,
+	deadline time.Time,
+	workerResult *benchmarkWorkerResult,
+) error {
+	for {
+		index := atomic.AddInt64(&b.next, 1) - 1
+		if b.options.requests > 0 && index >= int64(b.options.requests) {
+			return nil
+		}
+		if b.options.rate > 0 {
+			// Requests are scheduled at a fixed interval from the start, so that the rate
+			// does not drift when requests are slow.
+			scheduled := start.Add(time.Duration(float64(index) / b.options.rate * float64(time.Second)))
+			if !deadline.IsZero() && scheduled.After(deadline) {
+				return nil
+			}
+			timer := time.NewTimer(time.Until(scheduled))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		} else if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		var data io.Reader
+		if b.data != nil {
+			data = bytes.NewReader(b.data)
+		}
+		requestStart := time.Now()
+		err := b.invoker.Invoke(ctx, b.dataSource, data, b.headers)
+		latency := time.Since(requestStart)
+		var code connect.Code
+		if err != nil {
+			var rpcErr *rpcError
+			if !errors.As(err, &rpcErr) {
+				return err
+			}
+			code = rpcErr.connErr.Code()
+			if code == connect.CodeCanceled && ctx.Err() != nil {
+				// The benchmark was stopped while the request was in flight.
+				return nil
+			}
+		}
+		workerResult.codes[code]++
+		workerResult.latencies = append(workerResult.latencies, latency)
+	}
+}
+
+type externalBenchmarkResult struct {
+	Requests          int                `json:"requests"`
+	DurationSeconds   float64            `json:"duration_seconds"`
+	RequestsPerSecond float64            `json:"requests_per_second"`
+	Codes             map[string]int     `json:"codes"`
+	LatencyMillis     map[string]float64 `json:"latency_ms"`
+}
+
+func printBenchmarkResultAsJSON(writer io.Writer, result *BenchmarkResult) error {
+	latencyMillis := map[string]float64{
+		"min":  durationToMillis(result.Percentile(0)),
+		"mean": durationToMillis(result.Mean()),
+		"max":  durationToMillis(result.Percentile(100)),
+	}
+	for _, percentile := range benchmarkPercentiles {
+		latencyMillis[fmt.Sprintf("p%g", percentile)] = durationToMillis(result.Percentile(percentile))
+	}
+	data, err := json.MarshalIndent(
+		&externalBenchmarkResult{
+			Requests:          result.Requests,
+			DurationSeconds:   result.Duration.Seconds(),
+			RequestsPerSecond: result.Throughput(),
+			Codes:             result.Codes,
+			LatencyMillis:     latencyMillis,
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func printBenchmarkResultAsText(writer io.Writer, result *BenchmarkResult) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(tabWriter, "Requests:\t%d\n", result.Requests)
+	_, _ = fmt.Fprintf(tabWriter, "Duration:\t%v\n", result.Duration.Round(time.Millisecond))
+	_, _ = fmt.Fprintf(tabWriter, "Throughput:\t%.2f requests/s\n", result.Throughput())
+	_, _ = fmt.Fprintln(tabWriter, "Codes:")
+	codeNames := make([]string, 0, len(result.Codes))
+	for codeName := range result.Codes {
+		codeNames = append(codeNames, codeName)
+	}
+	sort.Strings(codeNames)
+	for _, codeName := range codeNames {
+		_, _ = fmt.Fprintf(tabWriter, "  %s\t%d\n", codeName, result.Codes[codeName])
+	}
+	_, _ = fmt.Fprintln(tabWriter, "Latency:")
+	_, _ = fmt.Fprintf(tabWriter, "  min\t%v\n", roundLatency(result.Percentile(0)))
+	_, _ = fmt.Fprintf(tabWriter, "  mean\t%v\n", roundLatency(result.Mean()))
+	for _, percentile := range benchmarkPercentiles {
+		_, _ = fmt.Fprintf(tabWriter, "  p%g\t%v\n", percentile, roundLatency(result.Percentile(percentile)))
+	}
+	_, _ = fmt.Fprintf(tabWriter, "  max\t%v\n", roundLatency(result.Percentile(100)))
+	return tabWriter.Flush()
+}
+
+func durationToMillis(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func roundLatency(latency time.Duration) time.Duration {
+	return latency.Round(time.Microsecond)
+}
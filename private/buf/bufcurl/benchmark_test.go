@@ -0,0 +1,260 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmock"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+const testBenchmarkProto = `syntax = "proto3";
+package a.v1;
+message GetRequest {
+  string id = 1;
+}
+message GetResponse {
+  string name = 1;
+}
+service FooService {
+  rpc Get(GetRequest) returns (GetResponse);
+  rpc Fail(GetRequest) returns (GetResponse);
+}
+`
+
+func TestRunBenchmarkHTTP1(t *testing.T) {
+	t.Parallel()
+	res, handler := testNewBenchmarkServer(t)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	testRunBenchmark(t, res, server.Client(), nil, server.URL)
+}
+
+func TestRunBenchmarkH2C(t *testing.T) {
+	t.Parallel()
+	res, handler := testNewBenchmarkServer(t)
+	server := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	t.Cleanup(server.Close)
+	httpClient := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+	testRunBenchmark(t, res, httpClient, []connect.ClientOption{connect.WithGRPC()}, server.URL)
+}
+
+func TestRunBenchmarkUnixSocket(t *testing.T) {
+	t.Parallel()
+	res, handler := testNewBenchmarkServer(t)
+	socketPath := filepath.Join(t.TempDir(), "server.sock")
+	listener, err := net.Listen("unix", socketPath)
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}
+	testRunBenchmark(t, res, httpClient, []connect.ClientOption{connect.WithGRPCWeb()}, "http://localhost")
+}
+
+func TestRunBenchmarkDurationAndRate(t *testing.T) {
+	t.Parallel()
+	res, handler := testNewBenchmarkServer(t)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	result, err := testRunBenchmarkForMethod(
+		t,
+		res,
+		server.Client(),
+		nil,
+		server.URL,
+		"Get",
+		nil,
+		BenchmarkWithDuration(200*time.Millisecond),
+		BenchmarkWithRate(50),
+		BenchmarkWithConcurrency(2),
+	)
+	require.NoError(t, err)
+	// Requests are started at 0ms, 20ms, ..., 200ms.
+	assert.Equal(t, 11, result.Requests)
+	assert.Equal(t, map[string]int{"ok": 11}, result.Codes)
+}
+
+func TestRunBenchmarkInvalidRequest(t *testing.T) {
+	t.Parallel()
+	res, handler := testNewBenchmarkServer(t)
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	_, err := testRunBenchmarkForMethod(
+		t,
+		res,
+		server.Client(),
+		nil,
+		server.URL,
+		"Get",
+		[]byte(`{"id":`),
+		BenchmarkWithRequests(10),
+		BenchmarkWithConcurrency(4),
+	)
+	assert.Error(t, err)
+}
+
+func TestPrintBenchmarkResult(t *testing.T) {
+	t.Parallel()
+	result := &BenchmarkResult{
+		Requests: 4,
+		Duration: 2 * time.Second,
+		Codes:    map[string]int{"ok": 3, "unavailable": 1},
+		Latencies: []time.Duration{
+			time.Millisecond,
+			2 * time.Millisecond,
+			3 * time.Millisecond,
+			10 * time.Millisecond,
+		},
+	}
+	assert.Equal(t, float64(2), result.Throughput())
+	assert.Equal(t, time.Millisecond, result.Percentile(0))
+	assert.Equal(t, 2*time.Millisecond, result.Percentile(50))
+	assert.Equal(t, 10*time.Millisecond, result.Percentile(99))
+	assert.Equal(t, 4*time.Millisecond, result.Mean())
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintBenchmarkResult(buffer, result, BenchmarkFormatJSON))
+	assert.JSONEq(
+		t,
+		`{
+			"requests": 4,
+			"duration_seconds": 2,
+			"requests_per_second": 2,
+			"codes": {"ok": 3, "unavailable": 1},
+			"latency_ms": {"min": 1, "mean": 4, "p50": 2, "p90": 10, "p95": 10, "p99": 10, "max": 10}
+		}`,
+		buffer.String(),
+	)
+	buffer.Reset()
+	require.NoError(t, PrintBenchmarkResult(buffer, result, BenchmarkFormatText))
+	assert.Contains(t, buffer.String(), "Throughput:  2.00 requests/s\n")
+	assert.Contains(t, buffer.String(), "  unavailable  1\n")
+	assert.Error(t, PrintBenchmarkResult(buffer, result, "xml"))
+}
+
+func testRunBenchmark(
+	t *testing.T,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	baseURL string,
+) {
+	result, err := testRunBenchmarkForMethod(
+		t,
+		res,
+		httpClient,
+		opts,
+		baseURL,
+		"Get",
+		[]byte(`{"id": "1"}`),
+		BenchmarkWithRequests(20),
+		BenchmarkWithConcurrency(4),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 20, result.Requests)
+	assert.Equal(t, map[string]int{"ok": 20}, result.Codes)
+	assert.Len(t, result.Latencies, 20)
+	result, err = testRunBenchmarkForMethod(
+		t,
+		res,
+		httpClient,
+		opts,
+		baseURL,
+		"Fail",
+		nil,
+		BenchmarkWithRequests(5),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"unimplemented": 5}, result.Codes)
+}
+
+func testRunBenchmarkForMethod(
+	t *testing.T,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	opts []connect.ClientOption,
+	baseURL string,
+	method string,
+	data []byte,
+	options ...BenchmarkOption,
+) (*BenchmarkResult, error) {
+	methodDescriptor, err := ResolveMethodDescriptor(res, "a.v1.FooService", method)
+	require.NoError(t, err)
+	benchmarkOptions := newBenchmarkOptions()
+	for _, option := range options {
+		option(benchmarkOptions)
+	}
+	return runBenchmark(
+		context.Background(),
+		verbose.NopPrinter,
+		methodDescriptor,
+		res,
+		httpClient,
+		opts,
+		baseURL+"/a.v1.FooService/"+method,
+		"(argument)",
+		data,
+		nil,
+		benchmarkOptions,
+	)
+}
+
+// testNewBenchmarkServer returns a handler that serves the Get method with default
+// responses, and fails the Fail method with a 404.
+func testNewBenchmarkServer(t *testing.T) (protoencoding.Resolver, http.Handler) {
+	image, res := testBuildImage(t, map[string]string{"a/v1/a.proto": testBenchmarkProto})
+	mockHandler, err := bufmock.NewHandler(context.Background(), zaptest.NewLogger(t), image)
+	require.NoError(t, err)
+	return res, http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		if strings.HasSuffix(request.URL.Path, "/Fail") {
+			http.NotFound(responseWriter, request)
+			return
+		}
+		mockHandler.ServeHTTP(responseWriter, request)
+	})
+}
@@ -0,0 +1,496 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	// ProtocolHTTPJSON is the protocol for calling the HTTP/JSON endpoints of methods
+	// with google.api.http annotations, as served by gRPC-Gateway and other transcoders.
+	ProtocolHTTPJSON = "http-json"
+
+	httpRuleExtensionName protoreflect.FullName = "google.api.http"
+)
+
+// NewHTTPJSONInvoker creates a new invoker for invoking the method described by the
+// given descriptor with ProtocolHTTPJSON.
+//
+// The HTTP method, path, query, and body of the request are built from the request
+// message according to the google.api.http annotation of the method, which must be
+// resolvable by the given resolver. The path of the annotation is appended to the
+// given base URL. The JSON response is decoded into the response message, and written
+// to the given writer in the same JSON format as by NewInvoker. Errors are decoded from
+// the JSON representation of google.rpc.Status.
+//
+// Only unary methods are supported.
+func NewHTTPJSONInvoker(container appflag.Container, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, baseURL string, out io.Writer) (Invoker, error) {
+	return newHTTPJSONInvoker(container.VerbosePrinter(), md, res, emitDefaults, httpClient, baseURL, out, container.Stderr())
+}
+
+func newHTTPJSONInvoker(printer verbose.Printer, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, baseURL string, out io.Writer, errOut io.Writer) (*httpJSONInvoker, error) {
+	if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, fmt.Errorf("method %s is a streaming RPC, but the %s protocol only supports unary RPCs", md.FullName(), ProtocolHTTPJSON)
+	}
+	rule, err := getHTTPRule(md, res)
+	if err != nil {
+		return nil, err
+	}
+	return &httpJSONInvoker{
+		invoker: &invoker{
+			md:           md,
+			res:          res,
+			emitDefaults: emitDefaults,
+			output:       out,
+			printer:      printer,
+			errOutput:    errOut,
+		},
+		httpClient: httpClient,
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		rule:       rule,
+	}, nil
+}
+
+type httpJSONInvoker struct {
+	// invoker is used to print responses and errors. Its client is not set.
+	*invoker
+	httpClient connect.HTTPClient
+	baseURL    string
+	rule       *httpRule
+}
+
+func (inv *httpJSONInvoker) Invoke(ctx context.Context, dataSource string, data io.Reader, headers http.Header) error {
+	inv.printer.Printf("* Invoking RPC %s with %s %s\n", inv.md.FullName(), inv.rule.method, inv.rule.path)
+	provider := newMessageProvider(dataSource, data, inv.res)
+	msg := dynamicpb.NewMessage(inv.md.Input())
+	if err := provider.next(msg); err != nil {
+		return err
+	}
+	// make sure input does not contain a second message
+	dummy := dynamicpb.NewMessage(inv.md.Input())
+	if err := provider.next(dummy); err != io.EOF {
+		return fmt.Errorf("method %s is a unary RPC, but input contained more than one request message", inv.md.Name())
+	}
+	request, err := inv.newRequest(ctx, msg)
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		request.Header[key] = values
+	}
+	request.Header.Set("Accept", "application/json")
+	if request.Body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := inv.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return inv.handleErrorResponse(inv.newConnectError(response.StatusCode, body))
+	}
+	return inv.handleHTTPJSONResponse(body)
+}
+
+// newRequest returns the HTTP request for the request message.
+//
+// Fields that are bound by the path template are set in the path. If the body of the
+// rule is "*", the remaining fields are the body. Otherwise, the field named by the body,
+// if any, is the body, and the remaining fields are set as query parameters.
+func (inv *httpJSONInvoker) newRequest(ctx context.Context, msg *dynamicpb.Message) (*http.Request, error) {
+	path, boundFieldPaths, err := expandHTTPPathTemplate(inv.rule.path, msg)
+	if err != nil {
+		return nil, err
+	}
+	remaining := proto.Clone(msg)
+	for _, fieldPath := range boundFieldPaths {
+		clearFieldPath(remaining.ProtoReflect(), fieldPath)
+	}
+	remainingJSON, err := protoencoding.NewJSONMarshaler(inv.res, protoencoding.JSONMarshalerWithUseProtoNames()).Marshal(remaining)
+	if err != nil {
+		return nil, err
+	}
+	var body io.Reader
+	var query url.Values
+	if inv.rule.body == "*" {
+		body = bytes.NewReader(remainingJSON)
+	} else {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(remainingJSON, &fields); err != nil {
+			return nil, fmt.Errorf("the JSON representation of %s is not an object, so it cannot be sent as query parameters", inv.md.Input().FullName())
+		}
+		if inv.rule.body != "" {
+			bodyJSON, ok := fields[inv.rule.body]
+			if !ok {
+				bodyJSON = json.RawMessage("{}")
+			}
+			delete(fields, inv.rule.body)
+			body = bytes.NewReader(bodyJSON)
+		}
+		query = url.Values{}
+		for name, value := range fields {
+			if err := addHTTPQueryParameters(query, name, value); err != nil {
+				return nil, err
+			}
+		}
+	}
+	requestURL := inv.baseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+	return http.NewRequestWithContext(ctx, inv.rule.method, requestURL, body)
+}
+
+func (inv *httpJSONInvoker) handleHTTPJSONResponse(body []byte) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		body = []byte("{}")
+	}
+	if inv.rule.responseBody != "" {
+		// The body is only the named field of the response message.
+		wrapped, err := json.Marshal(map[string]json.RawMessage{inv.rule.responseBody: body})
+		if err != nil {
+			return err
+		}
+		body = wrapped
+	}
+	msg := dynamicpb.NewMessage(inv.md.Output())
+	if err := protoencoding.NewJSONUnmarshaler(inv.res).Unmarshal(body, msg); err != nil {
+		return fmt.Errorf("failed to decode response as %s: %w", inv.md.Output().FullName(), err)
+	}
+	return inv.printResponse(msg)
+}
+
+// newConnectError returns the error for a response with a non-2xx status code.
+//
+// The body is expected to be the JSON representation of google.rpc.Status. If it is
+// not, the code is derived from the HTTP status code.
+func (inv *httpJSONInvoker) newConnectError(statusCode int, body []byte) *connect.Error {
+	var status struct {
+		Code    int               `json:"code"`
+		Message string            `json:"message"`
+		Details []json.RawMessage `json:"details"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil || (status.Code == 0 && status.Message == "") {
+		message := strings.TrimSpace(string(body))
+		if message == "" {
+			message = http.StatusText(statusCode)
+		}
+		return connect.NewError(httpStatusToCode(statusCode), errors.New(message))
+	}
+	code := connect.Code(status.Code)
+	if status.Code <= 0 || status.Code > int(connect.CodeUnauthenticated) {
+		code = httpStatusToCode(statusCode)
+	}
+	connErr := connect.NewError(code, errors.New(status.Message))
+	for _, detailJSON := range status.Details {
+		detail, err := inv.newErrorDetail(detailJSON)
+		if err != nil {
+			inv.printer.Printf("* Could not decode error detail %s: %v\n", string(detailJSON), err)
+			continue
+		}
+		connErr.AddDetail(detail)
+	}
+	return connErr
+}
+
+// newErrorDetail returns the error detail for the JSON representation of a google.protobuf.Any.
+func (inv *httpJSONInvoker) newErrorDetail(detailJSON []byte) (*connect.ErrorDetail, error) {
+	var typeURL struct {
+		Type string `json:"@type"`
+	}
+	if err := json.Unmarshal(detailJSON, &typeURL); err != nil {
+		return nil, err
+	}
+	messageType, err := inv.res.FindMessageByURL(typeURL.Type)
+	if err != nil {
+		return nil, err
+	}
+	// The JSON representation of a google.protobuf.Any includes the fields of the
+	// message, so the resolver is needed to unmarshal it.
+	anyMessage := &anypb.Any{}
+	if err := protoencoding.NewJSONUnmarshaler(inv.res).Unmarshal(detailJSON, anyMessage); err != nil {
+		return nil, err
+	}
+	msg := messageType.New().Interface()
+	if err := protoencoding.NewWireUnmarshaler(inv.res).Unmarshal(anyMessage.GetValue(), msg); err != nil {
+		return nil, err
+	}
+	return connect.NewErrorDetail(msg)
+}
+
+// httpRule is the primary binding of a google.api.HttpRule.
+type httpRule struct {
+	method       string
+	path         string
+	body         string
+	responseBody string
+}
+
+// getHTTPRule returns the httpRule of the google.api.http option of the method.
+//
+// The google.api.HttpRule message is read dynamically with the resolver, since the
+// option is defined in the schema and not linked in.
+func getHTTPRule(md protoreflect.MethodDescriptor, res protoencoding.Resolver) (*httpRule, error) {
+	extensionType, err := res.FindExtensionByName(httpRuleExtensionName)
+	if err != nil {
+		return nil, fmt.Errorf("method %s has no %s option: %w", md.FullName(), httpRuleExtensionName, err)
+	}
+	// The options are re-parsed with the resolver, since they may have been parsed
+	// without the extension, in which case it is an unknown field.
+	data, err := protoencoding.NewWireMarshaler().Marshal(md.Options())
+	if err != nil {
+		return nil, err
+	}
+	options := md.Options().ProtoReflect().Type().New().Interface()
+	if err := protoencoding.NewWireUnmarshaler(res).Unmarshal(data, options); err != nil {
+		return nil, err
+	}
+	if !options.ProtoReflect().Has(extensionType.TypeDescriptor()) {
+		return nil, fmt.Errorf("method %s has no %s option", md.FullName(), httpRuleExtensionName)
+	}
+	ruleMessage := options.ProtoReflect().Get(extensionType.TypeDescriptor()).Message()
+	rule := &httpRule{
+		body:         getStringField(ruleMessage, "body"),
+		responseBody: getStringField(ruleMessage, "response_body"),
+	}
+	for _, method := range []string{"get", "put", "post", "delete", "patch"} {
+		if path := getStringField(ruleMessage, protoreflect.Name(method)); path != "" {
+			rule.method = strings.ToUpper(method)
+			rule.path = path
+		}
+	}
+	if customField := ruleMessage.Descriptor().Fields().ByName("custom"); customField != nil && ruleMessage.Has(customField) {
+		custom := ruleMessage.Get(customField).Message()
+		rule.method = getStringField(custom, "kind")
+		rule.path = getStringField(custom, "path")
+	}
+	if rule.method == "" || rule.path == "" {
+		return nil, fmt.Errorf("method %s has a %s option without a pattern", md.FullName(), httpRuleExtensionName)
+	}
+	return rule, nil
+}
+
+func getStringField(msg protoreflect.Message, name protoreflect.Name) string {
+	field := msg.Descriptor().Fields().ByName(name)
+	if field == nil || field.Kind() != protoreflect.StringKind || field.IsList() {
+		return ""
+	}
+	return msg.Get(field).String()
+}
+
+// expandHTTPPathTemplate returns the path for the path template, with each variable
+// replaced by the value of the field that it binds, and the paths of the bound fields.
+//
+// Variables that match a single path segment, such as {name} or {name=*}, are escaped
+// as a single segment. Variables that match multiple segments, such as
+// {name=projects/*/books/*}, are escaped segment by segment, keeping the slashes.
+func expandHTTPPathTemplate(template string, msg protoreflect.Message) (string, []string, error) {
+	var path strings.Builder
+	var fieldPaths []string
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			path.WriteString(template)
+			return path.String(), fieldPaths, nil
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			return "", nil, fmt.Errorf("invalid path template %q: unterminated variable", template)
+		}
+		end += start
+		path.WriteString(template[:start])
+		fieldPath, pattern, _ := strings.Cut(template[start+1:end], "=")
+		value, err := getFieldPathString(msg, fieldPath)
+		if err != nil {
+			return "", nil, err
+		}
+		if value == "" {
+			return "", nil, fmt.Errorf("request field %q must be set, since it is bound by the path template", fieldPath)
+		}
+		if strings.Contains(pattern, "/") || strings.Contains(pattern, "**") {
+			segments := strings.Split(value, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+			path.WriteString(strings.Join(segments, "/"))
+		} else {
+			path.WriteString(url.PathEscape(value))
+		}
+		fieldPaths = append(fieldPaths, fieldPath)
+		template = template[end+1:]
+	}
+}
+
+// getFieldPathString returns the string representation of the value of the field at the
+// dot-separated path of field names, as used in path templates.
+func getFieldPathString(msg protoreflect.Message, fieldPath string) (string, error) {
+	names := strings.Split(fieldPath, ".")
+	for i, name := range names {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			return "", fmt.Errorf("path template references unknown field %q of %s", fieldPath, msg.Descriptor().FullName())
+		}
+		if field.IsList() || field.IsMap() {
+			return "", fmt.Errorf("path template references repeated field %q", fieldPath)
+		}
+		if i < len(names)-1 {
+			if field.Message() == nil {
+				return "", fmt.Errorf("path template references field %q, but %q is not a message", fieldPath, name)
+			}
+			msg = msg.Get(field).Message()
+			continue
+		}
+		value := msg.Get(field)
+		switch field.Kind() {
+		case protoreflect.MessageKind, protoreflect.GroupKind:
+			return "", fmt.Errorf("path template references field %q, which is a message", fieldPath)
+		case protoreflect.EnumKind:
+			if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+				return string(enumValue.Name()), nil
+			}
+			return strconv.Itoa(int(value.Enum())), nil
+		case protoreflect.BytesKind:
+			return base64.URLEncoding.EncodeToString(value.Bytes()), nil
+		default:
+			return value.String(), nil
+		}
+	}
+	return "", fmt.Errorf("invalid field path %q", fieldPath)
+}
+
+// clearFieldPath clears the field at the dot-separated path of field names.
+func clearFieldPath(msg protoreflect.Message, fieldPath string) {
+	names := strings.Split(fieldPath, ".")
+	for _, name := range names[:len(names)-1] {
+		field := msg.Descriptor().Fields().ByName(protoreflect.Name(name))
+		if field == nil || !msg.Has(field) {
+			return
+		}
+		msg = msg.Mutable(field).Message()
+	}
+	if field := msg.Descriptor().Fields().ByName(protoreflect.Name(names[len(names)-1])); field != nil {
+		msg.Clear(field)
+	}
+}
+
+// addHTTPQueryParameters adds the query parameters for the JSON value of the field.
+//
+// Fields of nested messages are added with dot-separated names, and repeated fields are
+// added as repeated parameters.
+func addHTTPQueryParameters(query url.Values, name string, value json.RawMessage) error {
+	var decoded interface{}
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	// Numbers are kept as they were encoded, so that large numbers are not changed.
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+	return addHTTPQueryParameterValue(query, name, decoded, false)
+}
+
+func addHTTPQueryParameterValue(query url.Values, name string, value interface{}, inList bool) error {
+	switch typedValue := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		if inList {
+			return fmt.Errorf("repeated message field %q cannot be sent as a query parameter", name)
+		}
+		keys := make([]string, 0, len(typedValue))
+		for key := range typedValue {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if err := addHTTPQueryParameterValue(query, name+"."+key, typedValue[key], false); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, element := range typedValue {
+			if err := addHTTPQueryParameterValue(query, name, element, true); err != nil {
+				return err
+			}
+		}
+	case string:
+		query.Add(name, typedValue)
+	case json.Number:
+		query.Add(name, typedValue.String())
+	case bool:
+		query.Add(name, strconv.FormatBool(typedValue))
+	default:
+		return fmt.Errorf("field %q cannot be sent as a query parameter", name)
+	}
+	return nil
+}
+
+// httpStatusToCode returns the code for the HTTP status code, as the inverse of
+// the mapping of codes to HTTP status codes used by gRPC-Gateway.
+func httpStatusToCode(statusCode int) connect.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return connect.CodeInvalidArgument
+	case http.StatusUnauthorized:
+		return connect.CodeUnauthenticated
+	case http.StatusForbidden:
+		return connect.CodePermissionDenied
+	case http.StatusNotFound:
+		return connect.CodeNotFound
+	case http.StatusConflict:
+		return connect.CodeAborted
+	case http.StatusPreconditionFailed:
+		return connect.CodeFailedPrecondition
+	case http.StatusTooManyRequests:
+		return connect.CodeResourceExhausted
+	case 499:
+		return connect.CodeCanceled
+	case http.StatusInternalServerError:
+		return connect.CodeInternal
+	case http.StatusNotImplemented:
+		return connect.CodeUnimplemented
+	case http.StatusServiceUnavailable:
+		return connect.CodeUnavailable
+	case http.StatusGatewayTimeout:
+		return connect.CodeDeadlineExceeded
+	default:
+		return connect.CodeUnknown
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	testHTTPProto = `syntax = "proto3";
+package google.api;
+message HttpRule {
+  string selector = 1;
+  oneof pattern {
+    string get = 2;
+    string put = 3;
+    string post = 4;
+    string delete = 5;
+    string patch = 6;
+    CustomHttpPattern custom = 8;
+  }
+  string body = 7;
+  string response_body = 12;
+  repeated HttpRule additional_bindings = 11;
+}
+message CustomHttpPattern {
+  string kind = 1;
+  string path = 2;
+}
+`
+	testAnnotationsProto = `syntax = "proto3";
+package google.api;
+import "google/api/http.proto";
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.MethodOptions {
+  HttpRule http = 72295728;
+}
+`
+	testHTTPJSONProto = `syntax = "proto3";
+package a.v1;
+import "google/api/annotations.proto";
+message Book {
+  string name = 1;
+  string title = 2;
+}
+message GetBookRequest {
+  string name = 1;
+  Filter filter = 2;
+  repeated string tags = 3;
+  int64 version = 4;
+}
+message Filter {
+  bool deleted = 1;
+}
+message CreateBookRequest {
+  string parent = 1;
+  Book book = 2;
+  string request_id = 3;
+}
+message UpdateBookRequest {
+  Book book = 1;
+}
+message ErrorDetail {
+  string reason = 1;
+}
+service BookService {
+  rpc GetBook(GetBookRequest) returns (Book) {
+    option (google.api.http) = { get: "/v1/{name=shelves/*/books/*}" };
+  }
+  rpc CreateBook(CreateBookRequest) returns (Book) {
+    option (google.api.http) = { post: "/v1/{parent}/books:create" body: "book" response_body: "title" };
+  }
+  rpc UpdateBook(UpdateBookRequest) returns (Book) {
+    option (google.api.http) = { custom: { kind: "PUT" path: "/v1/{book.name=**}" } body: "*" };
+  }
+  rpc Unannotated(Book) returns (Book);
+  rpc Stream(Book) returns (stream Book) {
+    option (google.api.http) = { get: "/v1/stream" };
+  }
+}
+`
+)
+
+func TestHTTPJSONInvoker(t *testing.T) {
+	t.Parallel()
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		body, err := io.ReadAll(request.Body)
+		require.NoError(t, err)
+		requests = append(requests, request.Method+" "+request.URL.RequestURI()+" "+string(body))
+		switch {
+		case strings.HasSuffix(request.URL.Path, ":create"):
+			_, _ = responseWriter.Write([]byte(`"Dune"`))
+		case strings.Contains(request.URL.Path, "missing"):
+			responseWriter.WriteHeader(http.StatusNotFound)
+			_, _ = responseWriter.Write([]byte(`{"code": 5, "message": "book not found", "details": [{"@type": "type.googleapis.com/a.v1.ErrorDetail", "reason": "MISSING"}]}`))
+		case strings.Contains(request.URL.Path, "broken"):
+			responseWriter.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = responseWriter.Write([]byte(`upstream unavailable`))
+		default:
+			_, _ = responseWriter.Write([]byte(`{"name": "shelves/1/books/2", "title": "Dune", "unknown": true}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	res := testBuildHTTPJSONResolver(t)
+
+	output := testHTTPJSONInvoke(t, res, server.URL, "GetBook", `{"name": "shelves/1/books/2", "filter": {"deleted": true}, "tags": ["a", "b c"], "version": "9007199254740993"}`)
+	assert.JSONEq(t, `{"name": "shelves/1/books/2", "title": "Dune"}`, output)
+	output = testHTTPJSONInvoke(t, res, server.URL, "CreateBook", `{"parent": "shelves/1", "book": {"title": "Dune"}, "request_id": "x"}`)
+	assert.JSONEq(t, `{"title": "Dune"}`, output)
+	testHTTPJSONInvoke(t, res, server.URL, "UpdateBook", `{"book": {"name": "shelves/1/books/2", "title": "Dune"}}`)
+	assert.Equal(
+		t,
+		[]string{
+			"GET /v1/shelves/1/books/2?filter.deleted=true&tags=a&tags=b+c&version=9007199254740993 ",
+			`POST /v1/shelves%2F1/books:create?request_id=x {"title":"Dune"}`,
+			`PUT /v1/shelves/1/books/2 {"book":{"title":"Dune"}}`,
+		},
+		requests,
+	)
+
+	errOutput := bytes.NewBuffer(nil)
+	invoker := testNewHTTPJSONInvoker(t, res, server.URL, "GetBook", errOutput)
+	err := invoker.Invoke(context.Background(), "(argument)", strings.NewReader(`{"name": "shelves/1/books/missing"}`), nil)
+	var rpcErr *rpcError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, connect.CodeNotFound, rpcErr.connErr.Code())
+	var wireError wireError
+	require.NoError(t, json.Unmarshal(errOutput.Bytes(), &wireError))
+	assert.Equal(t, "not_found", wireError.Code)
+	assert.Equal(t, "book not found", wireError.Message)
+	require.Len(t, wireError.Details, 1)
+	assert.Equal(t, "a.v1.ErrorDetail", wireError.Details[0].Type)
+	assert.JSONEq(t, `{"reason": "MISSING"}`, string(wireError.Details[0].Debug))
+
+	err = invoker.Invoke(context.Background(), "(argument)", strings.NewReader(`{"name": "shelves/1/books/broken"}`), nil)
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, connect.CodeUnavailable, rpcErr.connErr.Code())
+	assert.Equal(t, "upstream unavailable", rpcErr.connErr.Message())
+
+	// Fields bound by the path must be set.
+	err = invoker.Invoke(context.Background(), "(argument)", strings.NewReader(`{}`), nil)
+	assert.Error(t, err)
+}
+
+func TestNewHTTPJSONInvokerInvalidMethod(t *testing.T) {
+	t.Parallel()
+	res := testBuildHTTPJSONResolver(t)
+	for _, method := range []string{"Unannotated", "Stream"} {
+		methodDescriptor, err := ResolveMethodDescriptor(res, "a.v1.BookService", method)
+		require.NoError(t, err)
+		_, err = newHTTPJSONInvoker(verbose.NopPrinter, methodDescriptor, res, false, http.DefaultClient, "http://localhost", io.Discard, io.Discard)
+		assert.Error(t, err, method)
+	}
+}
+
+func testHTTPJSONInvoke(t *testing.T, res protoencoding.Resolver, baseURL string, method string, data string) string {
+	output := bytes.NewBuffer(nil)
+	invoker := testNewHTTPJSONInvoker(t, res, baseURL, method, io.Discard)
+	invoker.output = output
+	require.NoError(t, invoker.Invoke(context.Background(), "(argument)", strings.NewReader(data), nil))
+	return output.String()
+}
+
+func testNewHTTPJSONInvoker(t *testing.T, res protoencoding.Resolver, baseURL string, method string, errOutput io.Writer) *httpJSONInvoker {
+	methodDescriptor, err := ResolveMethodDescriptor(res, "a.v1.BookService", method)
+	require.NoError(t, err)
+	invoker, err := newHTTPJSONInvoker(verbose.NopPrinter, methodDescriptor, res, false, http.DefaultClient, baseURL+"/", io.Discard, errOutput)
+	require.NoError(t, err)
+	return invoker
+}
+
+func testBuildHTTPJSONResolver(t *testing.T) protoencoding.Resolver {
+	_, res := testBuildImage(
+		t,
+		map[string]string{
+			"google/api/http.proto":        testHTTPProto,
+			"google/api/annotations.proto": testAnnotationsProto,
+			"a/v1/a.proto":                 testHTTPJSONProto,
+		},
+	)
+	return res
+}
+
+// testBuildImage builds an image of the files, and returns it with its resolver.
+//
+// This is shared by all tests of the package.
+func testBuildImage(t *testing.T, pathToData map[string]string) (bufimage.Image, protoencoding.Resolver) {
+	ctx := context.Background()
+	pathToBytes := make(map[string][]byte, len(pathToData))
+	for path, data := range pathToData {
+		pathToBytes[path] = []byte(data)
+	}
+	bucket, err := storagemem.NewReadBucket(pathToBytes)
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	res, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptors(image)...)
+	require.NoError(t, err)
+	return image, res
+}
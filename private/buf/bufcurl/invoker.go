@@ -17,12 +17,12 @@ package bufcurl
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"net/http/httptest"
 	"sync"
 
 	"github.com/bufbuild/buf/private/pkg/app"
@@ -85,7 +85,11 @@ type invoker struct {
 // extensions that appear in the input or output. Other parameters are used
 // to create a Connect client, for issuing the RPC.
 func NewInvoker(container appflag.Container, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, url string, out io.Writer) Invoker {
-	opts = append(opts, connect.WithCodec(protoCodec{}))
+	return newInvoker(container.VerbosePrinter(), md, res, emitDefaults, httpClient, opts, url, out, container.Stderr())
+}
+
+func newInvoker(printer verbose.Printer, md protoreflect.MethodDescriptor, res protoencoding.Resolver, emitDefaults bool, httpClient connect.HTTPClient, opts []connect.ClientOption, url string, out io.Writer, errOut io.Writer) *invoker {
+	opts = append(opts[:len(opts):len(opts)], connect.WithCodec(protoCodec{}))
 	// TODO: could also provide custom compressor implementations that could give us
 	//  optics into when request and response messages are compressed (which could be
 	//  useful to include in verbose output).
@@ -94,8 +98,8 @@ func NewInvoker(container appflag.Container, md protoreflect.MethodDescriptor, r
 		res:          res,
 		emitDefaults: emitDefaults,
 		output:       out,
-		printer:      container.VerbosePrinter(),
-		errOutput:    container.Stderr(),
+		printer:      printer,
+		errOutput:    errOut,
 		client:       connect.NewClient[dynamicpb.Message, deferredMessage](httpClient, url, opts...),
 	}
 }
@@ -281,6 +285,10 @@ func (inv *invoker) handleResponse(data []byte, msg *dynamicpb.Message) error {
 	if err := protoencoding.NewWireUnmarshaler(inv.res).Unmarshal(data, msg); err != nil {
 		return err
 	}
+	return inv.printResponse(msg)
+}
+
+func (inv *invoker) printResponse(msg proto.Message) error {
 	jsonMarshalerOptions := []protoencoding.JSONMarshalerOption{
 		protoencoding.JSONMarshalerWithIndent(),
 	}
@@ -362,32 +370,87 @@ func (inv *invoker) handleStreamResponse(stream serverStream) (retError error) {
 }
 
 func (inv *invoker) handleErrorResponse(connErr *connect.Error) error {
-	// NB: This is a nasty hack: we create a fake request that looks
-	//     like a unary Connect request, so that the ErrorWriter will
-	//     print the error in the format we want, which is just the
-	//     JSON representation of the Connect error. (We don't need
-	//     an end-stream message representation or for the content
-	//     to be put into response headers, which is what it may
-	//     choose to do if it detects a different protocol in the
-	//     request).
-	req := &http.Request{
-		Header: http.Header{},
-	}
-	req.Header.Add("content-type", "application/json")
-
-	w := connect.NewErrorWriter()
-	responseWriter := httptest.NewRecorder()
-	err := w.Write(responseWriter, req, connErr)
+	// The error is printed in the JSON format of Connect errors, regardless of
+	// the protocol. The details are decoded with the same resolver as request
+	// and response messages, which can resolve types that the Connect runtime
+	// cannot, since it only knows about linked-in types.
+	wireError := &wireError{
+		Code:    connErr.Code().String(),
+		Message: connErr.Message(),
+	}
+	for _, detail := range connErr.Details() {
+		wireError.Details = append(wireError.Details, inv.newWireErrorDetail(detail))
+	}
+	errorBytes, err := json.MarshalIndent(wireError, "", "   ")
 	if err != nil {
 		return err
 	}
-	var prettyPrinted bytes.Buffer
-	if err := json.Indent(&prettyPrinted, responseWriter.Body.Bytes(), "", "   "); err != nil {
-		return err
-	}
-	_, _ = inv.errOutput.Write(prettyPrinted.Bytes())
+	_, _ = inv.errOutput.Write(errorBytes)
 	_, _ = inv.errOutput.Write([]byte("\n"))
-	return app.NewError(int(connErr.Code()*8), "")
+	return &rpcError{
+		connErr: connErr,
+		appErr:  app.NewError(int(connErr.Code()*8), ""),
+	}
+}
+
+// rpcError is the error returned by the invoker when the RPC fails.
+//
+// The RPC error has already been printed, so the message of the rpcError is empty, and
+// its exit code is derived from the code of the RPC error.
+type rpcError struct {
+	connErr *connect.Error
+	appErr  error
+}
+
+func (e *rpcError) Error() string {
+	return e.appErr.Error()
+}
+
+func (e *rpcError) Unwrap() error {
+	return e.appErr
+}
+
+// newWireErrorDetail returns the wireErrorDetail for the detail. If the type of the
+// detail can be resolved, the decoded message is included as its JSON representation.
+func (inv *invoker) newWireErrorDetail(detail *connect.ErrorDetail) *wireErrorDetail {
+	wireErrorDetail := &wireErrorDetail{
+		Type:  detail.Type(),
+		Value: base64.RawStdEncoding.EncodeToString(detail.Bytes()),
+	}
+	messageType, err := inv.res.FindMessageByName(protoreflect.FullName(detail.Type()))
+	if err != nil {
+		inv.printer.Printf("* Could not resolve type of error detail %s: %v\n", detail.Type(), err)
+		return wireErrorDetail
+	}
+	msg := messageType.New().Interface()
+	if err := protoencoding.NewWireUnmarshaler(inv.res).Unmarshal(detail.Bytes(), msg); err != nil {
+		inv.printer.Printf("* Could not decode error detail %s: %v\n", detail.Type(), err)
+		return wireErrorDetail
+	}
+	debug, err := protoencoding.NewJSONMarshaler(inv.res).Marshal(msg)
+	if err != nil {
+		inv.printer.Printf("* Could not encode error detail %s as JSON: %v\n", detail.Type(), err)
+		return wireErrorDetail
+	}
+	wireErrorDetail.Debug = debug
+	return wireErrorDetail
+}
+
+// wireError is the JSON representation of a Connect error.
+type wireError struct {
+	Code    string             `json:"code"`
+	Message string             `json:"message,omitempty"`
+	Details []*wireErrorDetail `json:"details,omitempty"`
+}
+
+// wireErrorDetail is the JSON representation of a Connect error detail.
+//
+// Value is always the base64-encoded bytes of the detail, and Debug is the JSON
+// representation of the detail if its type could be resolved.
+type wireErrorDetail struct {
+	Type  string          `json:"type"`
+	Value string          `json:"value"`
+	Debug json.RawMessage `json:"debug,omitempty"`
 }
 
 func newStreamMessageProvider(dataSource string, data io.Reader, res protoencoding.Resolver) messageProvider {
@@ -0,0 +1,128 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/verbose"
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	testInvokerProto = `syntax = "proto3";
+package a.v1;
+message GetRequest {
+  string id = 1;
+}
+message GetResponse {
+  string name = 1;
+}
+message ErrorDetail {
+  string reason = 1;
+}
+service FooService {
+  rpc Get(GetRequest) returns (GetResponse);
+}
+`
+	testUnknownDetailProto = `syntax = "proto3";
+package b.v1;
+message UnknownDetail {
+  string reason = 1;
+}
+`
+)
+
+func TestInvokerErrorDetails(t *testing.T) {
+	t.Parallel()
+	// The schema passed with --schema contains a.v1.ErrorDetail, but not b.v1.UnknownDetail.
+	_, res := testBuildImage(t, map[string]string{"a/v1/a.proto": testInvokerProto})
+	_, unknownRes := testBuildImage(t, map[string]string{"b/v1/b.proto": testUnknownDetailProto})
+	detail := testNewErrorDetail(t, res, "a.v1.ErrorDetail", `{"reason": "MISSING"}`)
+	unknownDetail := testNewErrorDetail(t, unknownRes, "b.v1.UnknownDetail", `{"reason": "UNKNOWN"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(http.StatusNotFound)
+		_ = json.NewEncoder(responseWriter).Encode(
+			map[string]interface{}{
+				"code":    "not_found",
+				"message": "foo not found",
+				"details": []map[string]string{
+					{"type": detail.Type(), "value": base64.RawStdEncoding.EncodeToString(detail.Bytes())},
+					{"type": unknownDetail.Type(), "value": base64.RawStdEncoding.EncodeToString(unknownDetail.Bytes())},
+				},
+			},
+		)
+	}))
+	t.Cleanup(server.Close)
+	methodDescriptor, err := ResolveMethodDescriptor(res, "a.v1.FooService", "Get")
+	require.NoError(t, err)
+	verboseOutput := bytes.NewBuffer(nil)
+	errOutput := bytes.NewBuffer(nil)
+	invoker := newInvoker(
+		verbose.NewWritePrinter(verboseOutput, "buf"),
+		methodDescriptor,
+		res,
+		false,
+		server.Client(),
+		nil,
+		server.URL+"/a.v1.FooService/Get",
+		bytes.NewBuffer(nil),
+		errOutput,
+	)
+	err = invoker.Invoke(context.Background(), "(argument)", strings.NewReader(`{"id": "1"}`), nil)
+	var rpcErr *rpcError
+	require.True(t, errors.As(err, &rpcErr))
+	assert.Equal(t, connect.CodeNotFound, rpcErr.connErr.Code())
+
+	var wireError wireError
+	require.NoError(t, json.Unmarshal(errOutput.Bytes(), &wireError))
+	assert.Equal(t, "not_found", wireError.Code)
+	assert.Equal(t, "foo not found", wireError.Message)
+	require.Len(t, wireError.Details, 2)
+	// The detail is decoded with the resolver of the schema.
+	assert.Equal(t, "a.v1.ErrorDetail", wireError.Details[0].Type)
+	assert.Equal(t, base64.RawStdEncoding.EncodeToString(detail.Bytes()), wireError.Details[0].Value)
+	assert.JSONEq(t, `{"reason": "MISSING"}`, string(wireError.Details[0].Debug))
+	// The detail that cannot be resolved is only printed as raw bytes.
+	assert.Equal(t, "b.v1.UnknownDetail", wireError.Details[1].Type)
+	assert.Equal(t, base64.RawStdEncoding.EncodeToString(unknownDetail.Bytes()), wireError.Details[1].Value)
+	assert.Empty(t, wireError.Details[1].Debug)
+	assert.Contains(t, verboseOutput.String(), "Could not resolve type of error detail b.v1.UnknownDetail")
+}
+
+func testNewErrorDetail(t *testing.T, res protoencoding.Resolver, messageName string, messageJSON string) *connect.ErrorDetail {
+	messageType, err := res.FindMessageByName(protoreflect.FullName(messageName))
+	require.NoError(t, err)
+	msg := dynamicpb.NewMessage(messageType.Descriptor())
+	require.NoError(t, protojson.Unmarshal([]byte(messageJSON), msg))
+	detail, err := connect.NewErrorDetail(msg)
+	require.NoError(t, err)
+	return detail
+}
@@ -0,0 +1,577 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/connect-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"gopkg.in/yaml.v3"
+)
+
+// TestSuite is a suite of calls read from a test file.
+//
+// A test file is a YAML file of the form:
+//
+//	calls:
+//	  - name: get-foo
+//	    method: foo.v1.FooService/GetFoo
+//	    headers:
+//	      - "Authorization: Bearer token"
+//	    request:
+//	      id: "1"
+//	    expect:
+//	      code: ok
+//	      responses:
+//	        - foo.id: "1"
+//	          foo.tags.0: "blue"
+//
+// The request is a single request message. Client and bidirectional streaming calls use
+// requests instead, which is a list of request messages. Messages use the Protobuf JSON
+// mapping. The expected code is the name of a Connect error code, and defaults to ok.
+// If responses is set, the call must return exactly one response message for each entry,
+// and each entry maps the dot-separated paths of fields in the JSON representation of the
+// response, with list elements referenced by index, to their expected JSON values.
+//
+// Test files with the .txtpb extension are read in the Protobuf text format instead. The
+// fields are the same as for YAML, but request, requests, and responses are strings that
+// contain JSON.
+type TestSuite interface {
+	// Path is the path of the test file.
+	Path() string
+
+	isTestSuite()
+}
+
+// ReadTestSuite reads the TestSuite in the test file at the path.
+func ReadTestSuite(path string) (TestSuite, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, ErrorHasFilename(err, path)
+	}
+	var calls []*testCall
+	if filepath.Ext(path) == ".txtpb" {
+		calls, err = readTxtpbTestCalls(data)
+	} else {
+		calls, err = readYAMLTestCalls(data)
+	}
+	if err != nil {
+		return nil, ErrorHasFilename(err, path)
+	}
+	if len(calls) == 0 {
+		return nil, fmt.Errorf("%s: no calls in test file", path)
+	}
+	return &testSuite{
+		path:  path,
+		calls: calls,
+	}, nil
+}
+
+// RunTestSuite runs the calls of the TestSuite in order, and returns a FileAnnotation for
+// each call. The FileAnnotations of failed calls have SeverityError, and the FileAnnotations
+// of passed calls have SeverityInfo.
+//
+// All calls use the given resolver and HTTP client. The URL of each call is the base URL
+// followed by the service and method of the call. The given headers are sent with every
+// call, unless the call sets headers with the same name.
+func RunTestSuite(
+	ctx context.Context,
+	container appflag.Container,
+	suite TestSuite,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	clientOptions []connect.ClientOption,
+	baseURL string,
+	headers http.Header,
+) []bufanalysis.FileAnnotation {
+	testSuite := suite.(*testSuite)
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	fileAnnotations := make([]bufanalysis.FileAnnotation, 0, len(testSuite.calls))
+	for _, call := range testSuite.calls {
+		container.VerbosePrinter().Printf("* Running test call %s\n", call.name)
+		var fileAnnotation bufanalysis.FileAnnotation
+		if err := call.run(ctx, container, res, httpClient, clientOptions, baseURL, headers); err != nil {
+			fileAnnotation = testSuite.newFileAnnotation(
+				call,
+				fmt.Sprintf("Call %s failed: %v", call.name, err),
+				bufanalysis.SeverityError,
+			)
+		} else {
+			fileAnnotation = testSuite.newFileAnnotation(
+				call,
+				fmt.Sprintf("Call %s passed.", call.name),
+				bufanalysis.SeverityInfo,
+			)
+		}
+		fileAnnotations = append(fileAnnotations, fileAnnotation)
+	}
+	return fileAnnotations
+}
+
+type testSuite struct {
+	path  string
+	calls []*testCall
+}
+
+func (t *testSuite) Path() string {
+	return t.path
+}
+
+func (t *testSuite) ExternalPath() string {
+	return t.path
+}
+
+func (t *testSuite) newFileAnnotation(call *testCall, message string, severity bufanalysis.Severity) bufanalysis.FileAnnotation {
+	return bufanalysis.NewFileAnnotation(
+		t,
+		call.line,
+		call.column,
+		call.line,
+		call.column,
+		call.name,
+		message,
+		bufanalysis.FileAnnotationWithSeverity(severity),
+	)
+}
+
+func (*testSuite) isTestSuite() {}
+
+type testCall struct {
+	name    string
+	line    int
+	column  int
+	service string
+	method  string
+	headers []string
+	// requests are the JSON request messages.
+	requests [][]byte
+	code     connect.Code
+	// responses are the expected values of the fields of each response, keyed by path.
+	//
+	// If nil, responses are not checked.
+	responses []map[string]interface{}
+}
+
+func (c *testCall) run(
+	ctx context.Context,
+	container appflag.Container,
+	res protoencoding.Resolver,
+	httpClient connect.HTTPClient,
+	clientOptions []connect.ClientOption,
+	baseURL string,
+	headers http.Header,
+) error {
+	methodDescriptor, err := ResolveMethodDescriptor(res, c.service, c.method)
+	if err != nil {
+		return err
+	}
+	callHeaders, _, err := LoadHeaders(c.headers, "", nil)
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		if _, ok := callHeaders[key]; !ok {
+			callHeaders[key] = values
+		}
+	}
+	var data io.Reader
+	if len(c.requests) > 0 {
+		data = bytes.NewReader(bytes.Join(c.requests, []byte("\n")))
+	}
+	output := bytes.NewBuffer(nil)
+	invoker := newInvoker(
+		container.VerbosePrinter(),
+		methodDescriptor,
+		res,
+		true, // emit defaults so that fields with default values can be checked
+		httpClient,
+		clientOptions,
+		baseURL+"/"+c.service+"/"+c.method,
+		output,
+		io.Discard,
+	)
+	var code connect.Code
+	var errorMessage string
+	if err := invoker.Invoke(ctx, c.name, data, callHeaders); err != nil {
+		var rpcErr *rpcError
+		if !errors.As(err, &rpcErr) {
+			return err
+		}
+		code = rpcErr.connErr.Code()
+		errorMessage = rpcErr.connErr.Message()
+	}
+	if code != c.code {
+		if errorMessage != "" {
+			return fmt.Errorf("expected code %s but got %s: %s", codeString(c.code), codeString(code), errorMessage)
+		}
+		return fmt.Errorf("expected code %s but got %s", codeString(c.code), codeString(code))
+	}
+	if c.responses == nil {
+		return nil
+	}
+	var responses []interface{}
+	decoder := json.NewDecoder(output)
+	for {
+		var response interface{}
+		if err := decoder.Decode(&response); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+		responses = append(responses, response)
+	}
+	if len(responses) != len(c.responses) {
+		return fmt.Errorf("expected %d responses but got %d", len(c.responses), len(responses))
+	}
+	for i, fields := range c.responses {
+		paths := make([]string, 0, len(fields))
+		for path := range fields {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		for _, path := range paths {
+			value, ok := getJSONPathValue(responses[i], path)
+			if !ok {
+				return fmt.Errorf("response %d: field %q is not present", i+1, path)
+			}
+			if !reflect.DeepEqual(fields[path], value) {
+				return fmt.Errorf(
+					"response %d: expected field %q to be %s but got %s",
+					i+1,
+					path,
+					jsonValueString(fields[path]),
+					jsonValueString(value),
+				)
+			}
+		}
+	}
+	return nil
+}
+
+type externalTestSuite struct {
+	Calls []*externalTestCall `json:"calls,omitempty" yaml:"calls,omitempty"`
+}
+
+type externalTestCall struct {
+	Name     string              `json:"name,omitempty" yaml:"name,omitempty"`
+	Method   string              `json:"method,omitempty" yaml:"method,omitempty"`
+	Headers  []string            `json:"headers,omitempty" yaml:"headers,omitempty"`
+	Request  interface{}         `json:"request,omitempty" yaml:"request,omitempty"`
+	Requests []interface{}       `json:"requests,omitempty" yaml:"requests,omitempty"`
+	Expect   *externalTestExpect `json:"expect,omitempty" yaml:"expect,omitempty"`
+}
+
+type externalTestExpect struct {
+	Code      string        `json:"code,omitempty" yaml:"code,omitempty"`
+	Responses []interface{} `json:"responses,omitempty" yaml:"responses,omitempty"`
+}
+
+func readYAMLTestCalls(data []byte) ([]*testCall, error) {
+	var externalTestSuite externalTestSuite
+	if err := encoding.UnmarshalYAMLStrict(data, &externalTestSuite); err != nil {
+		return nil, err
+	}
+	// The calls are also read as nodes to get the positions of the calls for FileAnnotations.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return nil, err
+	}
+	callNodes := getYAMLCallNodes(&node)
+	calls := make([]*testCall, len(externalTestSuite.Calls))
+	for i, externalTestCall := range externalTestSuite.Calls {
+		call, err := newTestCall(externalTestCall, getYAMLJSONValue)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i+1, err)
+		}
+		if i < len(callNodes) {
+			call.line = callNodes[i].Line
+			call.column = callNodes[i].Column
+		}
+		calls[i] = call
+	}
+	return calls, nil
+}
+
+func getYAMLCallNodes(node *yaml.Node) []*yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "calls" && node.Content[i+1].Kind == yaml.SequenceNode {
+			return node.Content[i+1].Content
+		}
+	}
+	return nil
+}
+
+// getYAMLJSONValue converts the YAML value to the value it would have if it were decoded
+// from JSON, so that it can be compared to the values of decoded responses.
+func getYAMLJSONValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var jsonValue interface{}
+	if err := json.Unmarshal(data, &jsonValue); err != nil {
+		return nil, err
+	}
+	return jsonValue, nil
+}
+
+func readTxtpbTestCalls(data []byte) ([]*testCall, error) {
+	messageDescriptor, err := getTxtpbTestSuiteMessageDescriptor()
+	if err != nil {
+		return nil, err
+	}
+	message := dynamicpb.NewMessage(messageDescriptor)
+	if err := prototext.Unmarshal(data, message); err != nil {
+		return nil, err
+	}
+	// The message is converted to JSON to read it the same way as YAML.
+	jsonData, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	if err != nil {
+		return nil, err
+	}
+	var externalTestSuite externalTestSuite
+	if err := encoding.UnmarshalJSONStrict(jsonData, &externalTestSuite); err != nil {
+		return nil, err
+	}
+	calls := make([]*testCall, len(externalTestSuite.Calls))
+	for i, externalTestCall := range externalTestSuite.Calls {
+		calls[i], err = newTestCall(externalTestCall, getTxtpbJSONValue)
+		if err != nil {
+			return nil, fmt.Errorf("call %d: %w", i+1, err)
+		}
+	}
+	return calls, nil
+}
+
+// getTxtpbJSONValue decodes the value, which must be a string that contains JSON.
+func getTxtpbJSONValue(value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a string that contains JSON but got %T", value)
+	}
+	var jsonValue interface{}
+	if err := json.Unmarshal([]byte(s), &jsonValue); err != nil {
+		return nil, err
+	}
+	return jsonValue, nil
+}
+
+// getTxtpbTestSuiteMessageDescriptor returns the descriptor of the message that test files
+// in the Protobuf text format are read as.
+func getTxtpbTestSuiteMessageDescriptor() (protoreflect.MessageDescriptor, error) {
+	fileDescriptor, err := protodesc.NewFile(
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("buf/curl/test_suite.proto"),
+			Package: proto.String("buf.curl"),
+			Syntax:  proto.String("proto3"),
+			MessageType: []*descriptorpb.DescriptorProto{
+				{
+					Name: proto.String("TestSuite"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						newTxtpbMessageField("calls", 1, ".buf.curl.Call", true),
+					},
+				},
+				{
+					Name: proto.String("Call"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						newTxtpbStringField("name", 1, false),
+						newTxtpbStringField("method", 2, false),
+						newTxtpbStringField("headers", 3, true),
+						newTxtpbStringField("request", 4, false),
+						newTxtpbStringField("requests", 5, true),
+						newTxtpbMessageField("expect", 6, ".buf.curl.Expect", false),
+					},
+				},
+				{
+					Name: proto.String("Expect"),
+					Field: []*descriptorpb.FieldDescriptorProto{
+						newTxtpbStringField("code", 1, false),
+						newTxtpbStringField("responses", 2, true),
+					},
+				},
+			},
+		},
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return fileDescriptor.Messages().ByName("TestSuite"), nil
+}
+
+func newTxtpbStringField(name string, number int32, repeated bool) *descriptorpb.FieldDescriptorProto {
+	return newTxtpbField(name, number, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", repeated)
+}
+
+func newTxtpbMessageField(name string, number int32, typeName string, repeated bool) *descriptorpb.FieldDescriptorProto {
+	return newTxtpbField(name, number, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, typeName, repeated)
+}
+
+func newTxtpbField(
+	name string,
+	number int32,
+	fieldType descriptorpb.FieldDescriptorProto_Type,
+	typeName string,
+	repeated bool,
+) *descriptorpb.FieldDescriptorProto {
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		Number:   proto.Int32(number),
+		Type:     fieldType.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String(name),
+	}
+	if repeated {
+		field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+	}
+	if typeName != "" {
+		field.TypeName = proto.String(typeName)
+	}
+	return field
+}
+
+// newTestCall returns the testCall for the externalTestCall. The getJSONValue function
+// converts request messages and expected values to their JSON values.
+func newTestCall(
+	externalTestCall *externalTestCall,
+	getJSONValue func(interface{}) (interface{}, error),
+) (*testCall, error) {
+	if externalTestCall == nil {
+		return nil, errors.New("call is empty")
+	}
+	methodString := strings.TrimPrefix(externalTestCall.Method, "/")
+	service, method, ok := strings.Cut(methodString, "/")
+	if !ok || service == "" || method == "" || strings.Contains(method, "/") {
+		return nil, fmt.Errorf("method %q must be of the form package.Service/Method", externalTestCall.Method)
+	}
+	call := &testCall{
+		name:    externalTestCall.Name,
+		service: service,
+		method:  method,
+		headers: externalTestCall.Headers,
+	}
+	if call.name == "" {
+		call.name = methodString
+	}
+	requests := externalTestCall.Requests
+	if externalTestCall.Request != nil {
+		if len(requests) > 0 {
+			return nil, errors.New("only one of request and requests may be set")
+		}
+		requests = []interface{}{externalTestCall.Request}
+	}
+	for i, request := range requests {
+		jsonValue, err := getJSONValue(request)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+		data, err := json.Marshal(jsonValue)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i+1, err)
+		}
+		call.requests = append(call.requests, data)
+	}
+	if externalTestCall.Expect == nil {
+		return call, nil
+	}
+	if codeName := strings.ToLower(strings.TrimSpace(externalTestCall.Expect.Code)); codeName != "" && codeName != "ok" {
+		if err := call.code.UnmarshalText([]byte(codeName)); err != nil {
+			return nil, fmt.Errorf("unknown code %q", externalTestCall.Expect.Code)
+		}
+	}
+	if externalTestCall.Expect.Responses != nil {
+		call.responses = make([]map[string]interface{}, len(externalTestCall.Expect.Responses))
+		for i, response := range externalTestCall.Expect.Responses {
+			jsonValue, err := getJSONValue(response)
+			if err != nil {
+				return nil, fmt.Errorf("response %d: %w", i+1, err)
+			}
+			fields, ok := jsonValue.(map[string]interface{})
+			if !ok && jsonValue != nil {
+				return nil, fmt.Errorf("response %d: expected a map of field paths to values", i+1)
+			}
+			call.responses[i] = fields
+		}
+	}
+	return call, nil
+}
+
+// getJSONPathValue returns the value at the dot-separated path in the decoded JSON value.
+// Elements of lists are referenced by index.
+func getJSONPathValue(value interface{}, path string) (interface{}, bool) {
+	for _, component := range strings.Split(path, ".") {
+		switch typedValue := value.(type) {
+		case map[string]interface{}:
+			var ok bool
+			value, ok = typedValue[component]
+			if !ok {
+				return nil, false
+			}
+		case []interface{}:
+			index, err := strconv.Atoi(component)
+			if err != nil || index < 0 || index >= len(typedValue) {
+				return nil, false
+			}
+			value = typedValue[index]
+		default:
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+func jsonValueString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func codeString(code connect.Code) string {
+	if code == 0 {
+		return "ok"
+	}
+	return code.String()
+}
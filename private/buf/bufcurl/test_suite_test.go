@@ -0,0 +1,134 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTestSuiteYAML(t *testing.T) {
+	t.Parallel()
+	suite := testReadTestSuite(
+		t,
+		"test.yaml",
+		`calls:
+  - name: get
+    method: foo.v1.FooService/Get
+    headers:
+      - "X-Foo: bar"
+    request:
+      id: 1
+    expect:
+      responses:
+        - foo.id: "1"
+  - method: /foo.v1.FooService/Stream
+    requests:
+      - {}
+      - id: 2
+    expect:
+      code: NOT_FOUND
+`,
+	)
+	require.Len(t, suite.calls, 2)
+	get := suite.calls[0]
+	assert.Equal(t, "get", get.name)
+	assert.Equal(t, 2, get.line)
+	assert.Equal(t, 5, get.column)
+	assert.Equal(t, "foo.v1.FooService", get.service)
+	assert.Equal(t, "Get", get.method)
+	assert.Equal(t, []string{"X-Foo: bar"}, get.headers)
+	assert.Equal(t, [][]byte{[]byte(`{"id":1}`)}, get.requests)
+	assert.Equal(t, connect.Code(0), get.code)
+	assert.Equal(t, []map[string]interface{}{{"foo.id": "1"}}, get.responses)
+	stream := suite.calls[1]
+	assert.Equal(t, "foo.v1.FooService/Stream", stream.name)
+	assert.Equal(t, [][]byte{[]byte(`{}`), []byte(`{"id":2}`)}, stream.requests)
+	assert.Equal(t, connect.CodeNotFound, stream.code)
+	assert.Nil(t, stream.responses)
+}
+
+func TestReadTestSuiteTxtpb(t *testing.T) {
+	t.Parallel()
+	suite := testReadTestSuite(
+		t,
+		"test.txtpb",
+		`calls {
+  name: "get"
+  method: "foo.v1.FooService/Get"
+  request: '{"id": 1}'
+  expect {
+    code: "invalid_argument"
+    responses: '{"foo.id": "1"}'
+  }
+}
+`,
+	)
+	require.Len(t, suite.calls, 1)
+	get := suite.calls[0]
+	assert.Equal(t, "get", get.name)
+	assert.Equal(t, [][]byte{[]byte(`{"id":1}`)}, get.requests)
+	assert.Equal(t, connect.CodeInvalidArgument, get.code)
+	assert.Equal(t, []map[string]interface{}{{"foo.id": "1"}}, get.responses)
+}
+
+func TestReadTestSuiteInvalid(t *testing.T) {
+	t.Parallel()
+	for _, data := range []string{
+		``,
+		`calls: [{method: Get}]`,
+		`calls: [{method: foo.v1.FooService/Get, expect: {code: bad}}]`,
+		`calls: [{method: foo.v1.FooService/Get, request: {}, requests: [{}]}]`,
+		`calls: [{method: foo.v1.FooService/Get, expect: {responses: [1]}}]`,
+		`calls: [{method: foo.v1.FooService/Get, unknown: 1}]`,
+	} {
+		path := filepath.Join(t.TempDir(), "test.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+		_, err := ReadTestSuite(path)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestGetJSONPathValue(t *testing.T) {
+	t.Parallel()
+	value := map[string]interface{}{
+		"foo": map[string]interface{}{
+			"tags": []interface{}{"a", "b"},
+		},
+	}
+	tag, ok := getJSONPathValue(value, "foo.tags.1")
+	assert.True(t, ok)
+	assert.Equal(t, "b", tag)
+	_, ok = getJSONPathValue(value, "foo.tags.2")
+	assert.False(t, ok)
+	_, ok = getJSONPathValue(value, "foo.bar")
+	assert.False(t, ok)
+	_, ok = getJSONPathValue(value, "foo.tags.0.bar")
+	assert.False(t, ok)
+}
+
+func testReadTestSuite(t *testing.T, name string, data string) *testSuite {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	suite, err := ReadTestSuite(path)
+	require.NoError(t, err)
+	assert.Equal(t, path, suite.Path())
+	return suite.(*testSuite)
+}
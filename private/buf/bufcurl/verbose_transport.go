@@ -47,6 +47,10 @@ func withUserAgent(ctx context.Context, headers http.Header) context.Context {
 
 // DefaultUserAgent returns the default user agent for the given protocol.
 func DefaultUserAgent(protocol string, bufVersion string) string {
+	if protocol == ProtocolHTTPJSON {
+		// no client library is used for HTTP/JSON
+		return fmt.Sprintf("buf/%s (%s)", bufVersion, runtime.Version())
+	}
 	// mirror the default user agent for the Connect client library, but
 	// add "buf/<version>" in front of it.
 	libUserAgent := "connect-go"
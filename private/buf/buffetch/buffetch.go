@@ -24,6 +24,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app"
 	"github.com/bufbuild/buf/private/pkg/git"
 	"github.com/bufbuild/buf/private/pkg/httpauth"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"go.uber.org/zap"
@@ -130,6 +131,33 @@ type ProtoFileRef interface {
 	internalProtoFileRef() internal.ProtoFileRef
 }
 
+// IsLocalSourceRef returns true if the SourceRef is a local directory or a local .proto file.
+//
+// The files of a local source can be rewritten in place.
+func IsLocalSourceRef(sourceRef SourceRef) bool {
+	switch sourceRef.internalBucketRef().(type) {
+	case internal.DirRef, internal.ProtoFileRef:
+		return true
+	default:
+		return false
+	}
+}
+
+// LocalSourceRefDirPath returns the directory of a local directory SourceRef, or
+// the directory containing the file of a local .proto file SourceRef.
+//
+// Returns false if the SourceRef is not local.
+func LocalSourceRefDirPath(sourceRef SourceRef) (string, bool) {
+	switch t := sourceRef.internalBucketRef().(type) {
+	case internal.DirRef:
+		return t.Path(), true
+	case internal.ProtoFileRef:
+		return normalpath.Dir(t.Path()), true
+	default:
+		return "", false
+	}
+}
+
 // ImageRefParser is an image ref parser for Buf.
 type ImageRefParser interface {
 	// GetImageRef gets the reference for the image file.
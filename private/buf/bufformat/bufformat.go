@@ -16,11 +16,13 @@ package bufformat
 
 import (
 	"context"
+	"io"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/thread"
+	"github.com/bufbuild/protocompile/ast"
 	"github.com/bufbuild/protocompile/parser"
 	"github.com/bufbuild/protocompile/reporter"
 	"go.uber.org/multierr"
@@ -66,3 +68,11 @@ func Format(ctx context.Context, module bufmodule.Module) (_ storage.ReadBucket,
 	}
 	return readWriteBucket, nil
 }
+
+// FormatFileNode formats the given file node and writes the result to the writer.
+//
+// The file node may have been modified after parsing, as long as the modified
+// nodes retain the tokens they were parsed with.
+func FormatFileNode(writer io.Writer, fileNode *ast.FileNode) error {
+	return newFormatter(writer, fileNode).Run()
+}
@@ -20,7 +20,9 @@ package bufgen
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"strconv"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
@@ -55,6 +57,10 @@ const (
 	StrategyAll Strategy = 2
 )
 
+// ErrOutOfDate is returned by Generator.Generate in check mode if the generated files
+// differ from the files in the output directories.
+var ErrOutOfDate = errors.New("generated files are out of date")
+
 // Strategy is a generation stategy.
 type Strategy int
 
@@ -168,6 +174,43 @@ func GenerateWithWASMEnabled() GenerateOption {
 	}
 }
 
+// GenerateWithResponseCacheBucket returns a new GenerateOption that caches the
+// CodeGeneratorResponses of plugins in the given bucket, and replays cached responses
+// instead of invoking the plugins.
+//
+// Responses are keyed by a digest of the CodeGeneratorRequests, the plugin, and its
+// options. Local plugins are identified by the contents of the plugin binary or WASM
+// module, and remote plugins by their reference and revision. Local plugins with a
+// path that has arguments are not cached. Remote plugins without
+// both a version and a revision are not cached, as the latest version or revision may
+// change.
+func GenerateWithResponseCacheBucket(readWriteBucket storage.ReadWriteBucket) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.responseCacheBucket = readWriteBucket
+	}
+}
+
+// GenerateWithOffline says to never execute remote plugins.
+//
+// Remote plugins with a response in the response cache are still replayed, and an
+// error is returned for any other remote plugin.
+func GenerateWithOffline() GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.offline = true
+	}
+}
+
+// GenerateWithCheck returns a new GenerateOption that compares the generated files
+// with the files in the output directories instead of writing them.
+//
+// A unified diff of the stale, missing, and extra files is written to diffWriter, and
+// Generate returns ErrOutOfDate if there is any difference.
+func GenerateWithCheck(diffWriter io.Writer) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.checkDiffWriter = diffWriter
+	}
+}
+
 // Config is a configuration.
 type Config struct {
 	// Required
@@ -196,6 +239,17 @@ type PluginConfig struct {
 	Strategy Strategy
 	// Optional
 	ProtocPath string
+	// Optional, deletes the files previously generated by this plugin that
+	// were not generated again
+	Clean bool
+	// Optional, filters the image given to this plugin to these types
+	Types *TypesConfig
+	// Optional, filters the image given to this plugin to these root relative
+	// file or directory paths
+	Paths []string
+	// Optional, excludes these root relative file or directory paths from the
+	// image given to this plugin
+	ExcludePaths []string
 }
 
 // PluginName returns this PluginConfig's plugin name.
@@ -252,6 +306,9 @@ type ManagedConfig struct {
 	GoPackagePrefixConfig   *GoPackagePrefixConfig
 	ObjcClassPrefixConfig   *ObjcClassPrefixConfig
 	RubyPackageConfig       *RubyPackageConfig
+	SwiftPrefixConfig       *SwiftPrefixConfig
+	PhpClassPrefixConfig    *PhpClassPrefixConfig
+	JsTypeConfig            *JsTypeConfig
 	Override                map[string]map[string]string
 }
 
@@ -286,6 +343,35 @@ type ObjcClassPrefixConfig struct {
 	Override map[bufmoduleref.ModuleIdentity]string
 }
 
+// SwiftPrefixConfig is the swift_prefix configuration.
+type SwiftPrefixConfig struct {
+	// Optional, files without a value are left unchanged.
+	Default string
+	Except  []bufmoduleref.ModuleIdentity
+	// bufmoduleref.ModuleIdentity -> swift_prefix.
+	Override map[bufmoduleref.ModuleIdentity]string
+}
+
+// PhpClassPrefixConfig is the php_class_prefix configuration.
+type PhpClassPrefixConfig struct {
+	// Optional, files without a value are left unchanged.
+	Default string
+	Except  []bufmoduleref.ModuleIdentity
+	// bufmoduleref.ModuleIdentity -> php_class_prefix.
+	Override map[bufmoduleref.ModuleIdentity]string
+}
+
+// JsTypeConfig is the jstype configuration, which applies to all 64-bit integer fields.
+type JsTypeConfig struct {
+	// Optional, files without a value are left unchanged.
+	Default *descriptorpb.FieldOptions_JSType
+	Except  []bufmoduleref.ModuleIdentity
+	// bufmoduleref.ModuleIdentity -> jstype.
+	Override map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType
+	// package name -> jstype, which also applies to the sub-packages of the package.
+	PackageOverride map[string]descriptorpb.FieldOptions_JSType
+}
+
 // RubyPackgeConfig is the ruby_package configuration.
 type RubyPackageConfig struct {
 	Except []bufmoduleref.ModuleIdentity
@@ -355,15 +441,19 @@ type ExternalConfigV1 struct {
 
 // ExternalPluginConfigV1 is an external plugin configuration.
 type ExternalPluginConfigV1 struct {
-	Plugin     string      `json:"plugin,omitempty" yaml:"plugin,omitempty"`
-	Revision   int         `json:"revision,omitempty" yaml:"revision,omitempty"`
-	Name       string      `json:"name,omitempty" yaml:"name,omitempty"`
-	Remote     string      `json:"remote,omitempty" yaml:"remote,omitempty"`
-	Out        string      `json:"out,omitempty" yaml:"out,omitempty"`
-	Opt        interface{} `json:"opt,omitempty" yaml:"opt,omitempty"`
-	Path       interface{} `json:"path,omitempty" yaml:"path,omitempty"`
-	ProtocPath string      `json:"protoc_path,omitempty" yaml:"protoc_path,omitempty"`
-	Strategy   string      `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Plugin       string                `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Revision     int                   `json:"revision,omitempty" yaml:"revision,omitempty"`
+	Name         string                `json:"name,omitempty" yaml:"name,omitempty"`
+	Remote       string                `json:"remote,omitempty" yaml:"remote,omitempty"`
+	Out          string                `json:"out,omitempty" yaml:"out,omitempty"`
+	Opt          interface{}           `json:"opt,omitempty" yaml:"opt,omitempty"`
+	Path         interface{}           `json:"path,omitempty" yaml:"path,omitempty"`
+	ProtocPath   string                `json:"protoc_path,omitempty" yaml:"protoc_path,omitempty"`
+	Strategy     string                `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	Clean        bool                  `json:"clean,omitempty" yaml:"clean,omitempty"`
+	Types        ExternalTypesConfigV1 `json:"types,omitempty" yaml:"types,omitempty"`
+	Paths        []string              `json:"paths,omitempty" yaml:"paths,omitempty"`
+	ExcludePaths []string              `json:"exclude_paths,omitempty" yaml:"exclude_paths,omitempty"`
 }
 
 // ExternalManagedConfigV1 is an external managed mode configuration.
@@ -380,6 +470,9 @@ type ExternalManagedConfigV1 struct {
 	GoPackagePrefix     ExternalGoPackagePrefixConfigV1   `json:"go_package_prefix,omitempty" yaml:"go_package_prefix,omitempty"`
 	ObjcClassPrefix     ExternalObjcClassPrefixConfigV1   `json:"objc_class_prefix,omitempty" yaml:"objc_class_prefix,omitempty"`
 	RubyPackage         ExternalRubyPackageConfigV1       `json:"ruby_package,omitempty" yaml:"ruby_package,omitempty"`
+	SwiftPrefix         ExternalSwiftPrefixConfigV1       `json:"swift_prefix,omitempty" yaml:"swift_prefix,omitempty"`
+	PhpClassPrefix      ExternalPhpClassPrefixConfigV1    `json:"php_class_prefix,omitempty" yaml:"php_class_prefix,omitempty"`
+	JsType              ExternalJsTypeConfigV1            `json:"jstype,omitempty" yaml:"jstype,omitempty"`
 	Override            map[string]map[string]string      `json:"override,omitempty" yaml:"override,omitempty"`
 }
 
@@ -395,6 +488,9 @@ func (e ExternalManagedConfigV1) IsEmpty() bool {
 		e.GoPackagePrefix.IsEmpty() &&
 		e.ObjcClassPrefix.IsEmpty() &&
 		e.RubyPackage.IsEmpty() &&
+		e.SwiftPrefix.IsEmpty() &&
+		e.PhpClassPrefix.IsEmpty() &&
+		e.JsType.IsEmpty() &&
 		len(e.Override) == 0
 }
 
@@ -540,6 +636,50 @@ func (e ExternalObjcClassPrefixConfigV1) IsEmpty() bool {
 		len(e.Override) == 0
 }
 
+// ExternalSwiftPrefixConfigV1 is the external swift_prefix configuration.
+type ExternalSwiftPrefixConfigV1 struct {
+	Default  string            `json:"default,omitempty" yaml:"default,omitempty"`
+	Except   []string          `json:"except,omitempty" yaml:"except,omitempty"`
+	Override map[string]string `json:"override,omitempty" yaml:"override,omitempty"`
+}
+
+// IsEmpty returns true if the config is empty.
+func (e ExternalSwiftPrefixConfigV1) IsEmpty() bool {
+	return e.Default == "" &&
+		len(e.Except) == 0 &&
+		len(e.Override) == 0
+}
+
+// ExternalPhpClassPrefixConfigV1 is the external php_class_prefix configuration.
+type ExternalPhpClassPrefixConfigV1 struct {
+	Default  string            `json:"default,omitempty" yaml:"default,omitempty"`
+	Except   []string          `json:"except,omitempty" yaml:"except,omitempty"`
+	Override map[string]string `json:"override,omitempty" yaml:"override,omitempty"`
+}
+
+// IsEmpty returns true if the config is empty.
+func (e ExternalPhpClassPrefixConfigV1) IsEmpty() bool {
+	return e.Default == "" &&
+		len(e.Except) == 0 &&
+		len(e.Override) == 0
+}
+
+// ExternalJsTypeConfigV1 is the external jstype configuration.
+type ExternalJsTypeConfigV1 struct {
+	Default         string            `json:"default,omitempty" yaml:"default,omitempty"`
+	Except          []string          `json:"except,omitempty" yaml:"except,omitempty"`
+	Override        map[string]string `json:"override,omitempty" yaml:"override,omitempty"`
+	PackageOverride map[string]string `json:"package_override,omitempty" yaml:"package_override,omitempty"`
+}
+
+// IsEmpty returns true if the config is empty.
+func (e ExternalJsTypeConfigV1) IsEmpty() bool {
+	return e.Default == "" &&
+		len(e.Except) == 0 &&
+		len(e.Override) == 0 &&
+		len(e.PackageOverride) == 0
+}
+
 // ExternalConfigV1Beta1 is an external configuration.
 type ExternalConfigV1Beta1 struct {
 	Version string                        `json:"version,omitempty" yaml:"version,omitempty"`
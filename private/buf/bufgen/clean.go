@@ -0,0 +1,216 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// cleanManifestFilePath is the path of the manifest of the files generated by plugins
+// with clean set, relative to their output directory.
+const cleanManifestFilePath = ".buf.gen.manifest"
+
+// cleanOutDir is an output directory of plugins with clean set.
+type cleanOutDir struct {
+	// The output directory, as configured.
+	outDirPath string
+	// All paths generated into the output directory, by any plugin.
+	generatedPaths map[string]struct{}
+	// The paths generated into the output directory by plugins with clean set.
+	cleanPaths map[string]struct{}
+}
+
+// clean deletes the files recorded in the manifest of each output directory of plugins
+// with clean set that were not generated again, and records the newly generated files
+// in the manifest.
+//
+// Only files recorded in a manifest are ever deleted, and only if they were not modified
+// since they were generated.
+func (g *generator) clean(
+	ctx context.Context,
+	config *Config,
+	responses []*pluginpb.CodeGeneratorResponse,
+	baseOutDirPath string,
+) error {
+	var cleanOutDirs []*cleanOutDir
+	absOutDirPathToCleanOutDir := make(map[string]*cleanOutDir)
+	for i, pluginConfig := range config.PluginConfigs {
+		outDirPath := pluginConfig.Out
+		if baseOutDirPath != "" && baseOutDirPath != "." {
+			outDirPath = filepath.Join(baseOutDirPath, outDirPath)
+		}
+		// Use the same key for output directories as the response writer.
+		absOutDirPath, err := filepath.Abs(normalpath.Unnormalize(outDirPath))
+		if err != nil {
+			return err
+		}
+		outDir, ok := absOutDirPathToCleanOutDir[absOutDirPath]
+		if !ok {
+			outDir = &cleanOutDir{
+				outDirPath:     outDirPath,
+				generatedPaths: make(map[string]struct{}),
+				cleanPaths:     make(map[string]struct{}),
+			}
+			absOutDirPathToCleanOutDir[absOutDirPath] = outDir
+			cleanOutDirs = append(cleanOutDirs, outDir)
+		}
+		for _, file := range responses[i].GetFile() {
+			path := normalpath.Normalize(file.GetName())
+			outDir.generatedPaths[path] = struct{}{}
+			if pluginConfig.Clean {
+				outDir.cleanPaths[path] = struct{}{}
+			}
+		}
+		if pluginConfig.Clean {
+			// Ensures that the manifest is written even if nothing was generated.
+			outDir.cleanPaths[cleanManifestFilePath] = struct{}{}
+		}
+	}
+	for _, outDir := range cleanOutDirs {
+		if _, ok := outDir.cleanPaths[cleanManifestFilePath]; !ok {
+			continue
+		}
+		delete(outDir.cleanPaths, cleanManifestFilePath)
+		if err := g.cleanOutDir(ctx, outDir); err != nil {
+			return fmt.Errorf("failed to clean %s: %w", outDir.outDirPath, err)
+		}
+	}
+	return nil
+}
+
+func (g *generator) cleanOutDir(ctx context.Context, outDir *cleanOutDir) error {
+	readWriteBucket, err := g.storageosProvider.NewReadWriteBucket(
+		outDir.outDirPath,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	previousManifest, err := readCleanManifest(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	if err := previousManifest.Range(func(path string, digest manifest.Digest) error {
+		if _, ok := outDir.generatedPaths[path]; ok {
+			return nil
+		}
+		currentDigest, err := digestPath(ctx, readWriteBucket, path)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !currentDigest.Equal(digest) {
+			g.logger.Warn(
+				"not deleting stale generated file as it was modified since it was generated",
+				zap.String("path", filepath.Join(outDir.outDirPath, normalpath.Unnormalize(path))),
+			)
+			return nil
+		}
+		if err := readWriteBucket.Delete(ctx, path); err != nil {
+			return err
+		}
+		g.logger.Debug("deleted stale generated file", zap.String("path", filepath.Join(outDir.outDirPath, normalpath.Unnormalize(path))))
+		removeEmptyParentDirs(outDir.outDirPath, path)
+		return nil
+	}); err != nil {
+		return err
+	}
+	cleanPaths := make([]string, 0, len(outDir.cleanPaths))
+	for path := range outDir.cleanPaths {
+		cleanPaths = append(cleanPaths, path)
+	}
+	sort.Strings(cleanPaths)
+	var cleanManifest manifest.Manifest
+	for _, path := range cleanPaths {
+		digest, err := digestPath(ctx, readWriteBucket, path)
+		if err != nil {
+			return err
+		}
+		if err := cleanManifest.AddEntry(path, *digest); err != nil {
+			return err
+		}
+	}
+	data, err := cleanManifest.MarshalText()
+	if err != nil {
+		return err
+	}
+	return storage.PutPath(ctx, readWriteBucket, cleanManifestFilePath, data)
+}
+
+// readCleanManifest reads the manifest of the output directory.
+//
+// Returns an empty manifest if there is no manifest.
+func readCleanManifest(ctx context.Context, readBucket storage.ReadBucket) (*manifest.Manifest, error) {
+	data, err := storage.ReadPath(ctx, readBucket, cleanManifestFilePath)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return &manifest.Manifest{}, nil
+		}
+		return nil, err
+	}
+	var cleanManifest manifest.Manifest
+	if err := cleanManifest.UnmarshalText(data); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", cleanManifestFilePath, err)
+	}
+	return &cleanManifest, nil
+}
+
+// digestPath returns the digest of the file at the path.
+//
+// Returns an error that wraps fs.ErrNotExist if the file does not exist.
+func digestPath(ctx context.Context, readBucket storage.ReadBucket, path string) (_ *manifest.Digest, retErr error) {
+	readObjectCloser, err := readBucket.Get(ctx, path)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, fmt.Errorf("%s: %w", path, fs.ErrNotExist)
+		}
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readObjectCloser.Close())
+	}()
+	digester, err := manifest.NewDigester(manifest.DigestTypeShake256)
+	if err != nil {
+		return nil, err
+	}
+	return digester.Digest(readObjectCloser)
+}
+
+// removeEmptyParentDirs removes the parent directories of the path within the output
+// directory that are empty after the file at the path was deleted.
+func removeEmptyParentDirs(outDirPath string, path string) {
+	for dirPath := normalpath.Dir(path); dirPath != "."; dirPath = normalpath.Dir(dirPath) {
+		// os.Remove only removes empty directories.
+		if err := os.Remove(filepath.Join(outDirPath, normalpath.Unnormalize(dirPath))); err != nil {
+			return
+		}
+	}
+}
@@ -157,6 +157,16 @@ func newConfigV1(logger *zap.Logger, externalConfig ExternalConfigV1, id string)
 			Path:       path,
 			ProtocPath: plugin.ProtocPath,
 			Strategy:   strategy,
+			Clean:      plugin.Clean,
+			Types:      newTypesConfigV1(plugin.Types),
+		}
+		pluginConfig.Paths, err = normalizePluginPaths(plugin.Paths)
+		if err != nil {
+			return nil, fmt.Errorf("%s: plugin %s has invalid paths: %w", id, pluginConfig.PluginName(), err)
+		}
+		pluginConfig.ExcludePaths, err = normalizePluginPaths(plugin.ExcludePaths)
+		if err != nil {
+			return nil, fmt.Errorf("%s: plugin %s has invalid exclude_paths: %w", id, pluginConfig.PluginName(), err)
 		}
 		if pluginConfig.IsRemote() {
 			// Always use StrategyAll for remote plugins
@@ -195,6 +205,12 @@ func validateExternalConfigV1(externalConfig ExternalConfigV1, id string) error
 		if plugin.Out == "" {
 			return fmt.Errorf("%s: plugin %s out is required", id, pluginIdentifier)
 		}
+		if plugin.Clean {
+			switch normalpath.Ext(plugin.Out) {
+			case ".zip", ".jar":
+				return fmt.Errorf("%s: plugin %s cannot set clean as out %s is an archive", id, pluginIdentifier, plugin.Out)
+			}
+		}
 		switch {
 		case plugin.Plugin != "":
 			if bufpluginref.IsPluginReferenceOrIdentity(pluginIdentifier) {
@@ -276,6 +292,18 @@ func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManage
 	if err != nil {
 		return nil, err
 	}
+	swiftPrefixConfig, err := newSwiftPrefixConfigV1(externalManagedConfig.SwiftPrefix)
+	if err != nil {
+		return nil, err
+	}
+	phpClassPrefixConfig, err := newPhpClassPrefixConfigV1(externalManagedConfig.PhpClassPrefix)
+	if err != nil {
+		return nil, err
+	}
+	jsTypeConfig, err := newJsTypeConfigV1(externalManagedConfig.JsType)
+	if err != nil {
+		return nil, err
+	}
 	override := externalManagedConfig.Override
 	for overrideID, overrideValue := range override {
 		for importPath := range overrideValue {
@@ -306,6 +334,9 @@ func newManagedConfigV1(logger *zap.Logger, externalManagedConfig ExternalManage
 		GoPackagePrefixConfig:   goPackagePrefixConfig,
 		ObjcClassPrefixConfig:   objcClassPrefixConfig,
 		RubyPackageConfig:       rubyPackageConfig,
+		SwiftPrefixConfig:       swiftPrefixConfig,
+		PhpClassPrefixConfig:    phpClassPrefixConfig,
+		JsTypeConfig:            jsTypeConfig,
 		Override:                override,
 	}, nil
 }
@@ -565,6 +596,143 @@ func newObjcClassPrefixConfigV1(externalObjcClassPrefixConfig ExternalObjcClassP
 	}, nil
 }
 
+func newSwiftPrefixConfigV1(externalSwiftPrefixConfig ExternalSwiftPrefixConfigV1) (*SwiftPrefixConfig, error) {
+	if externalSwiftPrefixConfig.IsEmpty() {
+		return nil, nil
+	}
+	// It's ok to have an empty default, in which case only the overridden files are modified.
+	except, override, err := newExceptAndOverrideV1(
+		"swift_prefix",
+		externalSwiftPrefixConfig.Except,
+		externalSwiftPrefixConfig.Override,
+		parseStringOverrideValue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &SwiftPrefixConfig{
+		Default:  externalSwiftPrefixConfig.Default,
+		Except:   except,
+		Override: override,
+	}, nil
+}
+
+func newPhpClassPrefixConfigV1(externalPhpClassPrefixConfig ExternalPhpClassPrefixConfigV1) (*PhpClassPrefixConfig, error) {
+	if externalPhpClassPrefixConfig.IsEmpty() {
+		return nil, nil
+	}
+	// It's ok to have an empty default, in which case only the overridden files are modified.
+	except, override, err := newExceptAndOverrideV1(
+		"php_class_prefix",
+		externalPhpClassPrefixConfig.Except,
+		externalPhpClassPrefixConfig.Override,
+		parseStringOverrideValue,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &PhpClassPrefixConfig{
+		Default:  externalPhpClassPrefixConfig.Default,
+		Except:   except,
+		Override: override,
+	}, nil
+}
+
+func newJsTypeConfigV1(externalJsTypeConfig ExternalJsTypeConfigV1) (*JsTypeConfig, error) {
+	if externalJsTypeConfig.IsEmpty() {
+		return nil, nil
+	}
+	// It's ok to have an empty default, in which case only the overridden files are modified.
+	var defaultJsType *descriptorpb.FieldOptions_JSType
+	if externalJsTypeConfig.Default != "" {
+		jsType, err := parseJsType(externalJsTypeConfig.Default)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jstype default value; %w", err)
+		}
+		defaultJsType = &jsType
+	}
+	except, override, err := newExceptAndOverrideV1(
+		"jstype",
+		externalJsTypeConfig.Except,
+		externalJsTypeConfig.Override,
+		parseJsType,
+	)
+	if err != nil {
+		return nil, err
+	}
+	packageOverride := make(map[string]descriptorpb.FieldOptions_JSType, len(externalJsTypeConfig.PackageOverride))
+	for packageName, value := range externalJsTypeConfig.PackageOverride {
+		if packageName == "" {
+			return nil, errors.New("invalid jstype package_override key: package name is empty")
+		}
+		jsType, err := parseJsType(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jstype package_override value for %q; %w", packageName, err)
+		}
+		packageOverride[packageName] = jsType
+	}
+	return &JsTypeConfig{
+		Default:         defaultJsType,
+		Except:          except,
+		Override:        override,
+		PackageOverride: packageOverride,
+	}, nil
+}
+
+// newExceptAndOverrideV1 parses the module identities of the except and override of the
+// managed mode option with the given name. The values of override are parsed with parseValue.
+//
+// A module identity may only be listed once, in either except or override.
+func newExceptAndOverrideV1[T any](
+	optionName string,
+	externalExcept []string,
+	externalOverride map[string]string,
+	parseValue func(string) (T, error),
+) ([]bufmoduleref.ModuleIdentity, map[bufmoduleref.ModuleIdentity]T, error) {
+	seenModuleIdentities := make(map[string]struct{}, len(externalExcept))
+	except := make([]bufmoduleref.ModuleIdentity, 0, len(externalExcept))
+	for _, moduleName := range externalExcept {
+		moduleIdentity, err := bufmoduleref.ModuleIdentityForString(moduleName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s except: %w", optionName, err)
+		}
+		if _, ok := seenModuleIdentities[moduleIdentity.IdentityString()]; ok {
+			return nil, nil, fmt.Errorf("invalid %s except: %q is defined multiple times", optionName, moduleIdentity.IdentityString())
+		}
+		seenModuleIdentities[moduleIdentity.IdentityString()] = struct{}{}
+		except = append(except, moduleIdentity)
+	}
+	override := make(map[bufmoduleref.ModuleIdentity]T, len(externalOverride))
+	for moduleName, externalValue := range externalOverride {
+		moduleIdentity, err := bufmoduleref.ModuleIdentityForString(moduleName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s override key: %w", optionName, err)
+		}
+		value, err := parseValue(externalValue)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid %s override value for %q; %w", optionName, moduleIdentity.IdentityString(), err)
+		}
+		if _, ok := seenModuleIdentities[moduleIdentity.IdentityString()]; ok {
+			return nil, nil, fmt.Errorf("invalid %s override: %q is already defined as an except", optionName, moduleIdentity.IdentityString())
+		}
+		seenModuleIdentities[moduleIdentity.IdentityString()] = struct{}{}
+		override[moduleIdentity] = value
+	}
+	return except, override, nil
+}
+
+func parseStringOverrideValue(value string) (string, error) {
+	return value, nil
+}
+
+func parseJsType(value string) (descriptorpb.FieldOptions_JSType, error) {
+	jsType, ok := descriptorpb.FieldOptions_JSType_value[value]
+	if !ok {
+		return 0, fmt.Errorf("expected one of %v", enumMapToStringSlice(descriptorpb.FieldOptions_JSType_value))
+	}
+	return descriptorpb.FieldOptions_JSType(jsType), nil
+}
+
 func newConfigV1Beta1(externalConfig ExternalConfigV1Beta1, id string) (*Config, error) {
 	managedConfig, err := newManagedConfigV1Beta1(externalConfig.Options, externalConfig.Managed)
 	if err != nil {
@@ -656,6 +824,31 @@ func newReadConfigOptions() *readConfigOptions {
 	return &readConfigOptions{}
 }
 
+// normalizePluginPaths normalizes and validates the root relative paths of a plugin,
+// and removes duplicates.
+func normalizePluginPaths(paths []string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	normalizedPaths := make([]string, 0, len(paths))
+	seen := make(map[string]struct{}, len(paths))
+	for _, path := range paths {
+		normalizedPath, err := normalpath.NormalizeAndValidate(path)
+		if err != nil {
+			return nil, err
+		}
+		if normalizedPath == "." {
+			return nil, errors.New(`"." is not a valid path value`)
+		}
+		if _, ok := seen[normalizedPath]; ok {
+			continue
+		}
+		seen[normalizedPath] = struct{}{}
+		normalizedPaths = append(normalizedPaths, normalizedPath)
+	}
+	return normalizedPaths, nil
+}
+
 func newTypesConfigV1(externalConfig ExternalTypesConfigV1) *TypesConfig {
 	if externalConfig.IsEmpty() {
 		return nil
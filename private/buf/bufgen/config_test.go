@@ -369,6 +369,36 @@ func TestReadConfigV1(t *testing.T) {
 			},
 		},
 	}
+	successConfig10 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "go",
+				Out:      "gen/go",
+				Strategy: StrategyDirectory,
+				Clean:    true,
+			},
+			{
+				Plugin:   "someremote.com/owner/myplugin",
+				Out:      "gen/go",
+				Strategy: StrategyAll,
+				Clean:    true,
+			},
+		},
+	}
+	successConfig11 := &Config{
+		PluginConfigs: []*PluginConfig{
+			{
+				Name:     "go",
+				Out:      "gen/go",
+				Strategy: StrategyDirectory,
+				Types: &TypesConfig{
+					Include: []string{"a.v1.Foo"},
+				},
+				Paths:        []string{"a/v1", "b/b.proto"},
+				ExcludePaths: []string{"a/v1/internal"},
+			},
+		},
+	}
 
 	ctx := context.Background()
 	nopLogger := zap.NewNop()
@@ -588,6 +618,15 @@ func TestReadConfigV1(t *testing.T) {
 	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(string(data)))
 	require.NoError(t, err)
 	assertConfigsWithEqualOptimizeFor(t, successConfig9, config)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success10.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig10.PluginConfigs, config.PluginConfigs)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success11.yaml")))
+	require.NoError(t, err)
+	require.Equal(t, successConfig11.PluginConfigs, config.PluginConfigs)
+	config, err = ReadConfig(ctx, nopLogger, provider, readBucket, ReadConfigWithOverride(filepath.Join("testdata", "v1", "gen_success12.yaml")))
+	require.NoError(t, err)
+	assertConfigWithManagedConfigV1Gen12(t, config)
 
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error1.yaml"))
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error2.yaml"))
@@ -605,6 +644,11 @@ func TestReadConfigV1(t *testing.T) {
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error14.yaml"))
 	testReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error15.yaml"))
 	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error15.yaml"), "the remote field no longer works")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error16.yaml"), "cannot set clean")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error17.yaml"), "invalid paths")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error18.yaml"), "invalid jstype default value")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error19.yaml"), "invalid swift_prefix override")
+	assertContainsReadConfigError(t, nopLogger, provider, readBucket, filepath.Join("testdata", "v1", "gen_error20.yaml"), "invalid jstype package_override value")
 
 	successConfig = &Config{
 		PluginConfigs: []*PluginConfig{
@@ -695,6 +739,37 @@ func assertConfigsWithEqualObjcPrefix(t *testing.T, successConfig *Config, confi
 	assertEqualModuleIdentityKeyedMaps(t, successObjcPrefixConfig.Override, objcPrefixConfig.Override)
 }
 
+func assertConfigWithManagedConfigV1Gen12(t *testing.T, config *Config) {
+	require.NotNil(t, config.ManagedConfig)
+	repoModuleIdentity := mustCreateModuleIdentity(t, "someremote.com", "owner", "repo")
+	fooModuleIdentity := mustCreateModuleIdentity(t, "someremote.com", "owner", "foo")
+	swiftPrefixConfig := config.ManagedConfig.SwiftPrefixConfig
+	require.NotNil(t, swiftPrefixConfig)
+	require.Equal(t, "DEF", swiftPrefixConfig.Default)
+	require.Equal(t, []bufmoduleref.ModuleIdentity{repoModuleIdentity}, swiftPrefixConfig.Except)
+	assertEqualModuleIdentityKeyedMaps(t, map[bufmoduleref.ModuleIdentity]string{fooModuleIdentity: "FOO"}, swiftPrefixConfig.Override)
+	phpClassPrefixConfig := config.ManagedConfig.PhpClassPrefixConfig
+	require.NotNil(t, phpClassPrefixConfig)
+	require.Empty(t, phpClassPrefixConfig.Default)
+	require.Empty(t, phpClassPrefixConfig.Except)
+	assertEqualModuleIdentityKeyedMaps(t, map[bufmoduleref.ModuleIdentity]string{fooModuleIdentity: "Foo"}, phpClassPrefixConfig.Override)
+	jsTypeConfig := config.ManagedConfig.JsTypeConfig
+	require.NotNil(t, jsTypeConfig)
+	require.Equal(t, descriptorpb.FieldOptions_JS_STRING.Enum(), jsTypeConfig.Default)
+	require.Equal(t, []bufmoduleref.ModuleIdentity{repoModuleIdentity}, jsTypeConfig.Except)
+	assertEqualModuleIdentityKeyedMaps(
+		t,
+		map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType{fooModuleIdentity: descriptorpb.FieldOptions_JS_NORMAL},
+		jsTypeConfig.Override,
+	)
+	require.Equal(
+		t,
+		map[string]descriptorpb.FieldOptions_JSType{"acme.billing": descriptorpb.FieldOptions_JS_STRING},
+		jsTypeConfig.PackageOverride,
+	)
+	require.Equal(t, map[string]map[string]string{bufimagemodify.JsTypeID: {"a.proto": "JS_NUMBER"}}, config.ManagedConfig.Override)
+}
+
 func assertConfigsWithEqualCsharpnamespace(t *testing.T, successConfig *Config, config *Config) {
 	require.Equal(t, successConfig.PluginConfigs, config.PluginConfigs)
 	require.NotNil(t, successConfig.ManagedConfig)
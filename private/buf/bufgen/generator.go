@@ -18,10 +18,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagemodify"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/bufpkg/bufplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
@@ -34,6 +36,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appproto/appprotoos"
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/thread"
 	connect "github.com/bufbuild/connect-go"
@@ -45,6 +48,7 @@ import (
 type generator struct {
 	logger              *zap.Logger
 	storageosProvider   storageos.Provider
+	runner              command.Runner
 	pluginexecGenerator bufpluginexec.Generator
 	clientConfig        *connectclient.Config
 }
@@ -59,6 +63,7 @@ func newGenerator(
 	return &generator{
 		logger:              logger,
 		storageosProvider:   storageosProvider,
+		runner:              runner,
 		pluginexecGenerator: bufpluginexec.NewGenerator(logger, storageosProvider, runner, wasmPluginExecutor),
 		clientConfig:        clientConfig,
 	}
@@ -92,6 +97,10 @@ func (g *generator) Generate(
 	for _, option := range options {
 		option(generateOptions)
 	}
+	var responseCache *responseCache
+	if generateOptions.responseCacheBucket != nil {
+		responseCache = newResponseCache(g.logger, generateOptions.responseCacheBucket)
+	}
 	return g.generate(
 		ctx,
 		container,
@@ -101,6 +110,9 @@ func (g *generator) Generate(
 		generateOptions.includeImports,
 		generateOptions.includeWellKnownTypes,
 		generateOptions.wasmEnabled,
+		responseCache,
+		generateOptions.offline,
+		generateOptions.checkDiffWriter,
 	)
 }
 
@@ -113,6 +125,9 @@ func (g *generator) generate(
 	includeImports bool,
 	includeWellKnownTypes bool,
 	wasmEnabled bool,
+	responseCache *responseCache,
+	offline bool,
+	checkDiffWriter io.Writer,
 ) error {
 	if err := modifyImage(ctx, g.logger, config, image); err != nil {
 		return err
@@ -125,15 +140,27 @@ func (g *generator) generate(
 		includeImports,
 		includeWellKnownTypes,
 		wasmEnabled,
+		responseCache,
+		offline,
 	)
 	if err != nil {
 		return err
 	}
+	responseWriterOptions := []appprotoos.ResponseWriterOption{
+		appprotoos.ResponseWriterWithCreateOutDirIfNotExists(),
+	}
+	if checkDiffWriter != nil {
+		responseWriterOptions = append(
+			responseWriterOptions,
+			appprotoos.ResponseWriterWithCheck(g.runner, checkDiffWriter),
+			appprotoos.ResponseWriterWithCheckManifestFilePath(cleanManifestFilePath),
+		)
+	}
 	// Apply the CodeGeneratorResponses in the order they were specified.
 	responseWriter := appprotoos.NewResponseWriter(
 		g.logger,
 		g.storageosProvider,
-		appprotoos.ResponseWriterWithCreateOutDirIfNotExists(),
+		responseWriterOptions...,
 	)
 	for i, pluginConfig := range config.PluginConfigs {
 		out := pluginConfig.Out
@@ -153,8 +180,16 @@ func (g *generator) generate(
 		}
 	}
 	if err := responseWriter.Close(); err != nil {
+		if errors.Is(err, appprotoos.ErrOutOfDate) {
+			return ErrOutOfDate
+		}
 		return err
 	}
+	if checkDiffWriter == nil {
+		if err := g.clean(ctx, config, responses, baseOutDirPath); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -166,6 +201,8 @@ func (g *generator) execPlugins(
 	includeImports bool,
 	includeWellKnownTypes bool,
 	wasmEnabled bool,
+	responseCache *responseCache,
+	offline bool,
 ) ([]*pluginpb.CodeGeneratorResponse, error) {
 	imageProvider := newImageProvider(image)
 	// Collect all of the plugin jobs so that they can be executed in parallel.
@@ -176,13 +213,52 @@ func (g *generator) execPlugins(
 	for i, pluginConfig := range config.PluginConfigs {
 		index := i
 		currentPluginConfig := pluginConfig
+		pluginImage, err := getPluginImage(image, currentPluginConfig)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", currentPluginConfig.PluginName(), err)
+		}
+		pluginImageProvider := imageProvider
+		if pluginImage != image {
+			pluginImageProvider = newImageProvider(pluginImage)
+		}
 		remote := currentPluginConfig.GetRemoteHostname()
 		if remote != "" {
+			var cacheKey string
+			if responseCache != nil {
+				cacheKey, err = getRemotePluginCacheKey(
+					currentPluginConfig,
+					pluginImage,
+					includeImports,
+					includeWellKnownTypes,
+				)
+				if err != nil {
+					return nil, err
+				}
+				if cacheKey != "" {
+					response, err := responseCache.Get(ctx, cacheKey)
+					if err != nil {
+						return nil, err
+					}
+					if response != nil {
+						g.logger.Debug("generate_cache_hit", zap.String("plugin", currentPluginConfig.PluginName()))
+						responses[index] = response
+						continue
+					}
+				}
+			}
+			if offline {
+				return nil, fmt.Errorf(
+					"plugin %s: remote plugins cannot be executed in offline mode, and no cached response was found, only responses of remote plugins with a version and revision are cached",
+					currentPluginConfig.PluginName(),
+				)
+			}
 			remotePluginConfigTable[remote] = append(
 				remotePluginConfigTable[remote],
 				&remotePluginExecArgs{
 					Index:        index,
 					PluginConfig: currentPluginConfig,
+					CacheKey:     cacheKey,
+					Image:        pluginImage,
 				},
 			)
 		} else {
@@ -190,11 +266,12 @@ func (g *generator) execPlugins(
 				response, err := g.execLocalPlugin(
 					ctx,
 					container,
-					imageProvider,
+					pluginImageProvider,
 					currentPluginConfig,
 					includeImports,
 					includeWellKnownTypes,
 					wasmEnabled,
+					responseCache,
 				)
 				if err != nil {
 					return err
@@ -204,18 +281,24 @@ func (g *generator) execPlugins(
 			})
 		}
 	}
-	// Batch for each remote.
+	// Batch for each remote and image, as plugins with types or paths set
+	// have their own image.
 	for remote, indexedPluginConfigs := range remotePluginConfigTable {
 		remote := remote
-		indexedPluginConfigs := indexedPluginConfigs
-		v2Args := make([]*remotePluginExecArgs, 0, len(indexedPluginConfigs))
+		var images []bufimage.Image
+		imageToV2Args := make(map[bufimage.Image][]*remotePluginExecArgs)
 		for _, param := range indexedPluginConfigs {
 			if param.PluginConfig.Remote != "" {
 				return nil, fmt.Errorf("invalid plugin reference: %s", param.PluginConfig.Remote)
 			}
-			v2Args = append(v2Args, param)
+			if _, ok := imageToV2Args[param.Image]; !ok {
+				images = append(images, param.Image)
+			}
+			imageToV2Args[param.Image] = append(imageToV2Args[param.Image], param)
 		}
-		if len(v2Args) > 0 {
+		for _, image := range images {
+			image := image
+			v2Args := imageToV2Args[image]
 			jobs = append(jobs, func(ctx context.Context) error {
 				results, err := g.execRemotePluginsV2(
 					ctx,
@@ -231,6 +314,11 @@ func (g *generator) execPlugins(
 				}
 				for _, result := range results {
 					responses[result.Index] = result.CodeGeneratorResponse
+					if result.CacheKey != "" {
+						if err := responseCache.Put(ctx, result.CacheKey, result.CodeGeneratorResponse); err != nil {
+							return err
+						}
+					}
 				}
 				return nil
 			})
@@ -275,11 +363,45 @@ func (g *generator) execLocalPlugin(
 	includeImports bool,
 	includeWellKnownTypes bool,
 	wasmEnabled bool,
+	responseCache *responseCache,
 ) (*pluginpb.CodeGeneratorResponse, error) {
 	pluginImages, err := imageProvider.GetImages(pluginConfig.Strategy)
 	if err != nil {
 		return nil, err
 	}
+	requests := bufimage.ImagesToCodeGeneratorRequests(
+		pluginImages,
+		pluginConfig.Opt,
+		nil,
+		includeImports,
+		includeWellKnownTypes,
+	)
+	var cacheKey string
+	if responseCache != nil {
+		handlerOptions := []bufpluginexec.HandlerOption{
+			bufpluginexec.HandlerWithPluginPath(pluginConfig.Path...),
+			bufpluginexec.HandlerWithProtocPath(pluginConfig.ProtocPath),
+		}
+		if wasmEnabled {
+			handlerOptions = append(handlerOptions, bufpluginexec.HandlerWithWASMEnabled())
+		}
+		// If the plugin cannot be found, we do not use the cache, and let
+		// the execution of the plugin return the error.
+		if pluginDigest, err := bufpluginexec.GetPluginDigest(pluginConfig.PluginName(), handlerOptions...); err == nil {
+			cacheKey, err = getLocalPluginCacheKey(pluginDigest, requests)
+			if err != nil {
+				return nil, err
+			}
+			response, err := responseCache.Get(ctx, cacheKey)
+			if err != nil {
+				return nil, err
+			}
+			if response != nil {
+				g.logger.Debug("generate_cache_hit", zap.String("plugin", pluginConfig.PluginName()))
+				return response, nil
+			}
+		}
+	}
 	generateOptions := []bufpluginexec.GenerateOption{
 		bufpluginexec.GenerateWithPluginPath(pluginConfig.Path...),
 		bufpluginexec.GenerateWithProtocPath(pluginConfig.ProtocPath),
@@ -294,29 +416,34 @@ func (g *generator) execLocalPlugin(
 		ctx,
 		container,
 		pluginConfig.PluginName(),
-		bufimage.ImagesToCodeGeneratorRequests(
-			pluginImages,
-			pluginConfig.Opt,
-			nil,
-			includeImports,
-			includeWellKnownTypes,
-		),
+		requests,
 		generateOptions...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("plugin %s: %v", pluginConfig.PluginName(), err)
 	}
+	if cacheKey != "" {
+		if err := responseCache.Put(ctx, cacheKey, response); err != nil {
+			return nil, err
+		}
+	}
 	return response, nil
 }
 
 type remotePluginExecArgs struct {
 	Index        int
 	PluginConfig *PluginConfig
+	// CacheKey is the key of the response in the response cache, if
+	// the response should be cached.
+	CacheKey string
+	// Image is the image to generate with.
+	Image bufimage.Image
 }
 
 type remotePluginExecutionResult struct {
 	CodeGeneratorResponse *pluginpb.CodeGeneratorResponse
 	Index                 int
+	CacheKey              string
 }
 
 func (g *generator) execRemotePluginsV2(
@@ -364,6 +491,7 @@ func (g *generator) execRemotePluginsV2(
 		result = append(result, &remotePluginExecutionResult{
 			CodeGeneratorResponse: codeGeneratorResponse,
 			Index:                 pluginConfigs[i].Index,
+			CacheKey:              pluginConfigs[i].CacheKey,
 		})
 	}
 	return result, nil
@@ -394,6 +522,28 @@ func getPluginGenerationRequest(
 	}, nil
 }
 
+// getPluginImage returns the image filtered by the paths, exclude paths, and types
+// of the plugin.
+//
+// Returns the image itself if the plugin sets none of these.
+func getPluginImage(image bufimage.Image, pluginConfig *PluginConfig) (bufimage.Image, error) {
+	if len(pluginConfig.Paths) > 0 || len(pluginConfig.ExcludePaths) > 0 {
+		var err error
+		image, err = bufimage.ImageWithOnlyPaths(image, pluginConfig.Paths, pluginConfig.ExcludePaths)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if pluginConfig.Types != nil && len(pluginConfig.Types.Include) > 0 {
+		var err error
+		image, err = bufimageutil.ImageFilteredByTypes(image, pluginConfig.Types.Include...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return image, nil
+}
+
 // modifyImage modifies the image according to the given configuration (i.e. managed mode).
 func modifyImage(
 	ctx context.Context,
@@ -579,6 +729,58 @@ func newModifier(
 		modifier,
 		rubyPackageModifier,
 	)
+	// Unlike the modifiers above, there is no default value for swift_prefix,
+	// php_class_prefix, and jstype, so these modifiers only run if configured.
+	if swiftPrefixConfig := managedConfig.SwiftPrefixConfig; swiftPrefixConfig != nil || len(managedConfig.Override[bufimagemodify.SwiftPrefixID]) > 0 {
+		if swiftPrefixConfig == nil {
+			swiftPrefixConfig = &SwiftPrefixConfig{}
+		}
+		modifier = bufimagemodify.Merge(
+			modifier,
+			bufimagemodify.SwiftPrefix(
+				logger,
+				sweeper,
+				swiftPrefixConfig.Default,
+				swiftPrefixConfig.Except,
+				swiftPrefixConfig.Override,
+				managedConfig.Override[bufimagemodify.SwiftPrefixID],
+			),
+		)
+	}
+	if phpClassPrefixConfig := managedConfig.PhpClassPrefixConfig; phpClassPrefixConfig != nil || len(managedConfig.Override[bufimagemodify.PhpClassPrefixID]) > 0 {
+		if phpClassPrefixConfig == nil {
+			phpClassPrefixConfig = &PhpClassPrefixConfig{}
+		}
+		modifier = bufimagemodify.Merge(
+			modifier,
+			bufimagemodify.PhpClassPrefix(
+				logger,
+				sweeper,
+				phpClassPrefixConfig.Default,
+				phpClassPrefixConfig.Except,
+				phpClassPrefixConfig.Override,
+				managedConfig.Override[bufimagemodify.PhpClassPrefixID],
+			),
+		)
+	}
+	if jsTypeConfig := managedConfig.JsTypeConfig; jsTypeConfig != nil || len(managedConfig.Override[bufimagemodify.JsTypeID]) > 0 {
+		if jsTypeConfig == nil {
+			jsTypeConfig = &JsTypeConfig{}
+		}
+		jsTypeModifier, err := bufimagemodify.JsType(
+			logger,
+			sweeper,
+			jsTypeConfig.Default,
+			jsTypeConfig.Except,
+			jsTypeConfig.Override,
+			jsTypeConfig.PackageOverride,
+			managedConfig.Override[bufimagemodify.JsTypeID],
+		)
+		if err != nil {
+			return nil, err
+		}
+		modifier = bufimagemodify.Merge(modifier, jsTypeModifier)
+	}
 	return modifier, nil
 }
 
@@ -618,6 +820,9 @@ type generateOptions struct {
 	includeImports        bool
 	includeWellKnownTypes bool
 	wasmEnabled           bool
+	responseCacheBucket   storage.ReadWriteBucket
+	offline               bool
+	checkDiffWriter       io.Writer
 }
 
 func newGenerateOptions() *generateOptions {
@@ -0,0 +1,166 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"hash"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufplugin/bufpluginref"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+// responseCacheKeyVersion is included in every cache key, and is changed
+// whenever the way keys are computed or responses are stored changes.
+const responseCacheKeyVersion = "v1"
+
+// responseCache caches CodeGeneratorResponses in a bucket by key.
+//
+// Each response is stored at the path "<key[:2]>/<key[2:]>".
+type responseCache struct {
+	logger *zap.Logger
+	bucket storage.ReadWriteBucket
+}
+
+func newResponseCache(logger *zap.Logger, bucket storage.ReadWriteBucket) *responseCache {
+	return &responseCache{
+		logger: logger,
+		bucket: bucket,
+	}
+}
+
+// Get returns the cached response for the key, or nil if there is none.
+//
+// Entries that cannot be read are treated as missing, and will be overwritten.
+func (c *responseCache) Get(ctx context.Context, key string) (*pluginpb.CodeGeneratorResponse, error) {
+	data, err := storage.ReadPath(ctx, c.bucket, getResponseCachePath(key))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	response := &pluginpb.CodeGeneratorResponse{}
+	if err := protoencoding.NewWireUnmarshaler(nil).Unmarshal(data, response); err != nil {
+		c.logger.Debug("invalid generate cache entry", zap.String("key", key), zap.Error(err))
+		return nil, nil
+	}
+	return response, nil
+}
+
+// Put caches the response for the key.
+//
+// Responses with errors are not cached, so that the plugin is executed again.
+func (c *responseCache) Put(ctx context.Context, key string, response *pluginpb.CodeGeneratorResponse) (retErr error) {
+	if response.GetError() != "" {
+		return nil
+	}
+	data, err := protoencoding.NewWireMarshaler().Marshal(response)
+	if err != nil {
+		return err
+	}
+	writeObjectCloser, err := c.bucket.Put(ctx, getResponseCachePath(key), storage.PutWithAtomic())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeObjectCloser.Close())
+	}()
+	_, err = writeObjectCloser.Write(data)
+	return err
+}
+
+// getLocalPluginCacheKey returns the cache key for the response of a local plugin
+// identified by the digest to the requests.
+func getLocalPluginCacheKey(pluginDigest string, requests []*pluginpb.CodeGeneratorRequest) (string, error) {
+	hash := newResponseCacheKeyHash("local", pluginDigest)
+	for _, request := range requests {
+		data, err := protoencoding.NewWireMarshaler().Marshal(request)
+		if err != nil {
+			return "", err
+		}
+		writeResponseCacheKeyValue(hash, data)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// getRemotePluginCacheKey returns the cache key for the response of a remote plugin
+// to the image, or the empty string if the response should not be cached.
+//
+// Only plugins with a version and a revision are cached. A revision of 0 refers to the
+// latest revision of the version, which changes when a new revision is published, and
+// resolving it would require a call to the remote.
+func getRemotePluginCacheKey(
+	pluginConfig *PluginConfig,
+	image bufimage.Image,
+	includeImports bool,
+	includeWellKnownTypes bool,
+) (string, error) {
+	if pluginConfig.Revision == 0 {
+		return "", nil
+	}
+	reference, err := bufpluginref.PluginReferenceForString(pluginConfig.Plugin, pluginConfig.Revision)
+	if err != nil {
+		return "", nil
+	}
+	hash := newResponseCacheKeyHash("remote", reference.ReferenceString())
+	writeResponseCacheKeyValue(hash, []byte(pluginConfig.Opt))
+	var flags byte
+	if includeImports {
+		flags |= 1
+	}
+	if includeWellKnownTypes {
+		flags |= 2
+	}
+	writeResponseCacheKeyValue(hash, []byte{flags})
+	data, err := protoencoding.NewWireMarshaler().Marshal(bufimage.ImageToProtoImage(image))
+	if err != nil {
+		return "", err
+	}
+	writeResponseCacheKeyValue(hash, data)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func newResponseCacheKeyHash(kind string, pluginIdentity string) hash.Hash {
+	hash := sha256.New()
+	writeResponseCacheKeyValue(hash, []byte(responseCacheKeyVersion))
+	writeResponseCacheKeyValue(hash, []byte(kind))
+	writeResponseCacheKeyValue(hash, []byte(pluginIdentity))
+	return hash
+}
+
+// writeResponseCacheKeyValue writes the length-prefixed value to the hash, so
+// that the boundaries between values are unambiguous.
+func writeResponseCacheKeyValue(hash hash.Hash, value []byte) {
+	var buffer bytes.Buffer
+	var length [binary.MaxVarintLen64]byte
+	buffer.Write(length[:binary.PutUvarint(length[:], uint64(len(value)))])
+	buffer.Write(value)
+	_, _ = hash.Write(buffer.Bytes())
+}
+
+func getResponseCachePath(key string) string {
+	return normalpath.Join(key[:2], key[2:])
+}
@@ -0,0 +1,104 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufgen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	responseCache := newResponseCache(zap.NewNop(), storagemem.NewReadWriteBucket())
+	request := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/v1/a.proto"},
+		Parameter:      proto.String("paths=source_relative"),
+	}
+	key, err := getLocalPluginCacheKey("digest", []*pluginpb.CodeGeneratorRequest{request})
+	require.NoError(t, err)
+	otherPluginKey, err := getLocalPluginCacheKey("other", []*pluginpb.CodeGeneratorRequest{request})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherPluginKey)
+	otherRequest := &pluginpb.CodeGeneratorRequest{
+		FileToGenerate: []string{"a/v1/a.proto"},
+	}
+	otherRequestKey, err := getLocalPluginCacheKey("digest", []*pluginpb.CodeGeneratorRequest{otherRequest})
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherRequestKey)
+
+	response, err := responseCache.Get(ctx, key)
+	require.NoError(t, err)
+	assert.Nil(t, response)
+	expectedResponse := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("a/v1/a.pb.go"),
+				Content: proto.String("package av1\n"),
+			},
+		},
+	}
+	require.NoError(t, responseCache.Put(ctx, key, expectedResponse))
+	response, err = responseCache.Get(ctx, key)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(expectedResponse, response))
+
+	// Responses with errors are not cached.
+	require.NoError(t, responseCache.Put(ctx, otherRequestKey, &pluginpb.CodeGeneratorResponse{Error: proto.String("error")}))
+	response, err = responseCache.Get(ctx, otherRequestKey)
+	require.NoError(t, err)
+	assert.Nil(t, response)
+}
+
+func TestGetRemotePluginCacheKey(t *testing.T) {
+	t.Parallel()
+	image, err := bufimage.NewImageForProto(
+		&imagev1.Image{
+			File: []*imagev1.ImageFile{
+				{
+					Name:   proto.String("a/v1/a.proto"),
+					Syntax: proto.String("proto3"),
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	key, err := getRemotePluginCacheKey(&PluginConfig{Plugin: "buf.build/protocolbuffers/go:v1.31.0", Revision: 1}, image, false, false)
+	require.NoError(t, err)
+	assert.NotEmpty(t, key)
+	otherRevisionKey, err := getRemotePluginCacheKey(&PluginConfig{Plugin: "buf.build/protocolbuffers/go:v1.31.0", Revision: 2}, image, false, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherRevisionKey)
+	otherOptKey, err := getRemotePluginCacheKey(&PluginConfig{Plugin: "buf.build/protocolbuffers/go:v1.31.0", Revision: 1, Opt: "paths=source_relative"}, image, false, false)
+	require.NoError(t, err)
+	assert.NotEqual(t, key, otherOptKey)
+	// Without a revision, the latest revision is used, which may change.
+	key, err = getRemotePluginCacheKey(&PluginConfig{Plugin: "buf.build/protocolbuffers/go:v1.31.0"}, image, false, false)
+	require.NoError(t, err)
+	assert.Empty(t, key)
+	// Without a version, the latest version is used, which may change.
+	key, err = getRemotePluginCacheKey(&PluginConfig{Plugin: "buf.build/protocolbuffers/go", Revision: 1}, image, false, false)
+	require.NoError(t, err)
+	assert.Empty(t, key)
+}
@@ -0,0 +1,68 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buflintfix fixes lint failures by rewriting Protobuf source files.
+//
+// Source files are parsed into an AST, the AST is modified, and the result is
+// printed with the same printer that is used by buf format.
+package buflintfix
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// AllFixableIDs are the IDs of the lint rules that can be fixed.
+//
+// Sorted in the order that fixes are applied.
+var AllFixableIDs = []string{
+	"SYNTAX_SPECIFIED",
+	"IMPORT_USED",
+	"ENUM_VALUE_PREFIX",
+	"ENUM_ZERO_VALUE_SUFFIX",
+	"FIELD_LOWER_SNAKE_CASE",
+	"SERVICE_SUFFIX",
+	"RPC_REQUEST_STANDARD_NAME",
+}
+
+// Fixer fixes lint FileAnnotations.
+type Fixer interface {
+	// Fix fixes the FileAnnotations produced by linting the image with the config.
+	//
+	// Only the non-import files of the image are rewritten, and they are read from
+	// their external paths, so the image must have been built from local sources.
+	// When an element is renamed, references to it are updated in all rewritten files.
+	// An element is not renamed if this could break an import of the image, but files
+	// that are not in the image are not seen, so the image must contain all the files
+	// that could refer to the non-import files.
+	//
+	// Returns a bucket containing only the files that changed, with external paths set,
+	// and the FileAnnotations that could not be fixed.
+	Fix(
+		ctx context.Context,
+		config *buflintconfig.Config,
+		image bufimage.Image,
+		fileAnnotations []bufanalysis.FileAnnotation,
+	) (storage.ReadBucket, []bufanalysis.FileAnnotation, error)
+}
+
+// NewFixer returns a new Fixer.
+func NewFixer(logger *zap.Logger) Fixer {
+	return newFixer(logger)
+}
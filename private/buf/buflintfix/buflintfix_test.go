@@ -0,0 +1,123 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflintfix
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis/bufanalysistesting"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/diff"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestFix(t *testing.T) {
+	t.Parallel()
+	testFix(
+		t,
+		"basic",
+		[]string{
+			"a/a.proto",
+			"a/c.proto",
+		},
+		// Other is used as an option value, so it is not renamed.
+		bufanalysistesting.NewFileAnnotation(t, "a/c.proto", 17, 3, 17, 8, "ENUM_VALUE_PREFIX"),
+		bufanalysistesting.NewFileAnnotation(t, "a/c.proto", 17, 3, 17, 8, "ENUM_VALUE_UPPER_SNAKE_CASE"),
+	)
+}
+
+func testFix(
+	t *testing.T,
+	relDirPath string,
+	expectedFixedPaths []string,
+	expectedUnfixedFileAnnotations ...bufanalysis.FileAnnotation,
+) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	storageosProvider := storageos.NewProvider()
+	inputReadWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		filepath.Join("testdata", relDirPath, "input"),
+	)
+	require.NoError(t, err)
+	goldenReadWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		filepath.Join("testdata", relDirPath, "golden"),
+	)
+	require.NoError(t, err)
+	config, err := bufconfig.GetConfigForBucket(ctx, inputReadWriteBucket)
+	require.NoError(t, err)
+	module, err := bufmodulebuild.NewModuleBucketBuilder().BuildForBucket(
+		ctx,
+		inputReadWriteBucket,
+		config.Build,
+	)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		logger,
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	fileAnnotations, err = buflint.NewHandler(logger).Check(
+		ctx,
+		config.Lint,
+		bufimage.ImageWithoutImports(image),
+	)
+	require.NoError(t, err)
+	fixedReadBucket, unfixedFileAnnotations, err := NewFixer(logger).Fix(
+		ctx,
+		config.Lint,
+		image,
+		fileAnnotations,
+	)
+	require.NoError(t, err)
+	bufanalysistesting.AssertFileAnnotationsEqual(
+		t,
+		expectedUnfixedFileAnnotations,
+		unfixedFileAnnotations,
+	)
+	paths, err := storage.AllPaths(ctx, fixedReadBucket, "")
+	require.NoError(t, err)
+	assert.Equal(t, expectedFixedPaths, paths)
+	runner := command.NewRunner()
+	for _, path := range paths {
+		fixedData, err := storage.ReadPath(ctx, fixedReadBucket, path)
+		require.NoError(t, err)
+		goldenReadObjectCloser, err := goldenReadWriteBucket.Get(ctx, path)
+		require.NoError(t, err)
+		goldenData, err := io.ReadAll(goldenReadObjectCloser)
+		require.NoError(t, err)
+		require.NoError(t, goldenReadObjectCloser.Close())
+		fileDiff, err := diff.Diff(ctx, runner, goldenData, fixedData, path+" (golden)", path+" (fixed)")
+		require.NoError(t, err)
+		assert.Empty(t, string(fileDiff))
+	}
+}
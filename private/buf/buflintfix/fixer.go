@@ -0,0 +1,882 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflintfix
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufformat"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/protocompile/ast"
+	"github.com/bufbuild/protocompile/parser"
+	"github.com/bufbuild/protocompile/reporter"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// These must match the defaults in bufcheck.
+	defaultEnumZeroValueSuffix = "_UNSPECIFIED"
+	defaultServiceSuffix       = "Service"
+
+	syntaxProto2Statement = `syntax = "proto2";`
+)
+
+type fixer struct {
+	logger *zap.Logger
+}
+
+func newFixer(logger *zap.Logger) *fixer {
+	return &fixer{
+		logger: logger.Named("buflintfix"),
+	}
+}
+
+func (f *fixer) Fix(
+	ctx context.Context,
+	config *buflintconfig.Config,
+	image bufimage.Image,
+	fileAnnotations []bufanalysis.FileAnnotation,
+) (storage.ReadBucket, []bufanalysis.FileAnnotation, error) {
+	state, err := newFixState(config, image)
+	if err != nil {
+		return nil, nil, err
+	}
+	idToFileAnnotations := make(map[string][]bufanalysis.FileAnnotation)
+	var unfixedFileAnnotations []bufanalysis.FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		if _, ok := idToFixFunc[fileAnnotation.Type()]; !ok {
+			unfixedFileAnnotations = append(unfixedFileAnnotations, fileAnnotation)
+			continue
+		}
+		idToFileAnnotations[fileAnnotation.Type()] = append(idToFileAnnotations[fileAnnotation.Type()], fileAnnotation)
+	}
+	for _, id := range AllFixableIDs {
+		for _, fileAnnotation := range idToFileAnnotations[id] {
+			if !idToFixFunc[id](state, fileAnnotation) {
+				f.logger.Debug(
+					"unfixable",
+					zap.String("id", id),
+					zap.String("annotation", fileAnnotation.String()),
+				)
+				unfixedFileAnnotations = append(unfixedFileAnnotations, fileAnnotation)
+			}
+		}
+	}
+	state.applyRenames()
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	for _, file := range state.files {
+		if !file.modified {
+			continue
+		}
+		if err := writeFile(ctx, readWriteBucket, file); err != nil {
+			return nil, nil, err
+		}
+	}
+	return readWriteBucket, bufanalysis.DeduplicateAndSortFileAnnotations(unfixedFileAnnotations), nil
+}
+
+// fixFunc attempts to fix the FileAnnotation, and returns false if it could not be fixed.
+type fixFunc func(*fixState, bufanalysis.FileAnnotation) bool
+
+var idToFixFunc = map[string]fixFunc{
+	"SYNTAX_SPECIFIED":          fixSyntaxSpecified,
+	"IMPORT_USED":               fixImportUsed,
+	"ENUM_VALUE_PREFIX":         fixEnumValuePrefix,
+	"ENUM_ZERO_VALUE_SUFFIX":    fixEnumZeroValueSuffix,
+	"FIELD_LOWER_SNAKE_CASE":    fixFieldLowerSnakeCase,
+	"SERVICE_SUFFIX":            fixServiceSuffix,
+	"RPC_REQUEST_STANDARD_NAME": fixRPCRequestStandardName,
+}
+
+func fixSyntaxSpecified(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	file := state.fileForFileAnnotation(fileAnnotation)
+	if file == nil || file.fileNode.Syntax != nil {
+		return false
+	}
+	file.addSyntax = true
+	file.modified = true
+	return true
+}
+
+func fixImportUsed(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	file := state.fileForFileAnnotation(fileAnnotation)
+	if file == nil {
+		return false
+	}
+	element, ok := file.positionToElement[newPosition(fileAnnotation.StartLine(), fileAnnotation.StartColumn())]
+	if !ok || element.importNode == nil {
+		return false
+	}
+	decls := make([]ast.FileElement, 0, len(file.fileNode.Decls))
+	for _, decl := range file.fileNode.Decls {
+		if decl != element.importNode {
+			decls = append(decls, decl)
+		}
+	}
+	file.fileNode.Decls = decls
+	file.modified = true
+	return true
+}
+
+func fixEnumValuePrefix(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	element := state.namedElementForFileAnnotation(fileAnnotation, elementKindEnumValue)
+	if element == nil {
+		return false
+	}
+	return state.rename(element, enumValuePrefix(element.parentName)+state.currentName(element))
+}
+
+func fixEnumZeroValueSuffix(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	element := state.namedElementForFileAnnotation(fileAnnotation, elementKindEnumValue)
+	if element == nil {
+		return false
+	}
+	suffix := state.config.EnumZeroValueSuffix
+	if suffix == "" {
+		suffix = defaultEnumZeroValueSuffix
+	}
+	name := state.currentName(element)
+	// FOO_NONE becomes FOO_UNSPECIFIED, while anything that does not
+	// have the expected prefix just gets the suffix appended.
+	newName := name + suffix
+	if prefix := enumValuePrefix(element.parentName); strings.HasPrefix(name, prefix) {
+		newName = strings.TrimSuffix(prefix, "_") + suffix
+	}
+	return state.rename(element, newName)
+}
+
+func fixFieldLowerSnakeCase(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	element := state.namedElementForFileAnnotation(fileAnnotation, elementKindField)
+	if element == nil {
+		return false
+	}
+	return state.rename(element, stringutil.ToLowerSnakeCase(state.currentName(element)))
+}
+
+func fixServiceSuffix(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	element := state.namedElementForFileAnnotation(fileAnnotation, elementKindService)
+	if element == nil {
+		return false
+	}
+	suffix := state.config.ServiceSuffix
+	if suffix == "" {
+		suffix = defaultServiceSuffix
+	}
+	return state.rename(element, state.currentName(element)+suffix)
+}
+
+func fixRPCRequestStandardName(state *fixState, fileAnnotation bufanalysis.FileAnnotation) bool {
+	element := state.namedElementForFileAnnotation(fileAnnotation, elementKindRPCInput)
+	if element == nil {
+		return false
+	}
+	messageFullName, ok := state.resolveType(element.scope, identValueString(element.rpcNode.Input.MessageType))
+	if !ok {
+		return false
+	}
+	// Renaming a message that is the request of multiple RPCs would only fix one of them.
+	if state.inputTypeToCount[messageFullName] != 1 {
+		return false
+	}
+	messageElement, ok := state.fullNameToMessage[messageFullName]
+	if !ok {
+		// The message is not defined in a file we rewrite.
+		return false
+	}
+	return state.rename(messageElement, stringutil.ToPascalCase(element.rpcNode.Name.Val)+"Request")
+}
+
+// fixState is the state of a single Fix call.
+type fixState struct {
+	config *buflintconfig.Config
+	files  []*fixFile
+	// pathToFile only contains files that will be rewritten.
+	pathToFile map[string]*fixFile
+	// scopes are the fully-qualified names of all packages, messages and enums
+	// in the image. These are used to resolve type references.
+	scopes map[string]struct{}
+	// types are the fully-qualified names of all messages and enums in the image.
+	types map[string]struct{}
+	// allNames are the fully-qualified names of all elements in the image,
+	// and are used to detect conflicts when renaming.
+	allNames map[string]struct{}
+	// externalReferences are the fully-qualified names of all types referenced
+	// from files that will not be rewritten.
+	externalReferences map[string]struct{}
+	// optionIdents are all identifiers used within option names and values
+	// in files that will be rewritten, other than for field defaults.
+	optionIdents      map[string]struct{}
+	inputTypeToCount  map[string]int
+	fullNameToMessage map[string]*element
+	// renamedFullNameToNewName maps the old fully-qualified name of a renamed
+	// element to its new simple name.
+	renamedFullNameToNewName map[string]string
+}
+
+func newFixState(config *buflintconfig.Config, image bufimage.Image) (*fixState, error) {
+	state := &fixState{
+		config:                   config,
+		pathToFile:               make(map[string]*fixFile),
+		scopes:                   make(map[string]struct{}),
+		types:                    make(map[string]struct{}),
+		allNames:                 make(map[string]struct{}),
+		externalReferences:       make(map[string]struct{}),
+		optionIdents:             make(map[string]struct{}),
+		inputTypeToCount:         make(map[string]int),
+		fullNameToMessage:        make(map[string]*element),
+		renamedFullNameToNewName: make(map[string]string),
+	}
+	for _, imageFile := range image.Files() {
+		state.addFileDescriptor(imageFile.FileDescriptor(), imageFile.IsImport())
+		if imageFile.IsImport() {
+			continue
+		}
+		file, err := newFixFile(imageFile)
+		if err != nil {
+			return nil, err
+		}
+		state.files = append(state.files, file)
+		state.pathToFile[imageFile.Path()] = file
+	}
+	for _, file := range state.files {
+		file.addElements(state)
+	}
+	return state, nil
+}
+
+func (s *fixState) addFileDescriptor(fileDescriptor interface {
+	GetPackage() string
+	GetMessageType() []*descriptorpb.DescriptorProto
+	GetEnumType() []*descriptorpb.EnumDescriptorProto
+	GetService() []*descriptorpb.ServiceDescriptorProto
+	GetExtension() []*descriptorpb.FieldDescriptorProto
+}, isImport bool) {
+	packageName := fileDescriptor.GetPackage()
+	if packageName != "" {
+		components := strings.Split(packageName, ".")
+		for i := range components {
+			s.scopes[strings.Join(components[:i+1], ".")] = struct{}{}
+		}
+	}
+	for _, descriptorProto := range fileDescriptor.GetMessageType() {
+		s.addDescriptorProto(packageName, descriptorProto, isImport)
+	}
+	for _, enumDescriptorProto := range fileDescriptor.GetEnumType() {
+		s.addEnumDescriptorProto(packageName, enumDescriptorProto)
+	}
+	for _, fieldDescriptorProto := range fileDescriptor.GetExtension() {
+		s.addFieldDescriptorProto(packageName, fieldDescriptorProto, isImport)
+	}
+	for _, serviceDescriptorProto := range fileDescriptor.GetService() {
+		serviceName := joinName(packageName, serviceDescriptorProto.GetName())
+		s.allNames[serviceName] = struct{}{}
+		for _, methodDescriptorProto := range serviceDescriptorProto.GetMethod() {
+			s.allNames[joinName(serviceName, methodDescriptorProto.GetName())] = struct{}{}
+			inputType := strings.TrimPrefix(methodDescriptorProto.GetInputType(), ".")
+			s.inputTypeToCount[inputType]++
+			if isImport {
+				s.externalReferences[inputType] = struct{}{}
+				s.externalReferences[strings.TrimPrefix(methodDescriptorProto.GetOutputType(), ".")] = struct{}{}
+			}
+		}
+	}
+}
+
+func (s *fixState) addDescriptorProto(scope string, descriptorProto *descriptorpb.DescriptorProto, isImport bool) {
+	name := joinName(scope, descriptorProto.GetName())
+	s.scopes[name] = struct{}{}
+	s.types[name] = struct{}{}
+	s.allNames[name] = struct{}{}
+	for _, fieldDescriptorProto := range descriptorProto.GetField() {
+		s.addFieldDescriptorProto(name, fieldDescriptorProto, isImport)
+	}
+	for _, fieldDescriptorProto := range descriptorProto.GetExtension() {
+		s.addFieldDescriptorProto(name, fieldDescriptorProto, isImport)
+	}
+	for _, oneofDescriptorProto := range descriptorProto.GetOneofDecl() {
+		s.allNames[joinName(name, oneofDescriptorProto.GetName())] = struct{}{}
+	}
+	for _, nestedDescriptorProto := range descriptorProto.GetNestedType() {
+		s.addDescriptorProto(name, nestedDescriptorProto, isImport)
+	}
+	for _, enumDescriptorProto := range descriptorProto.GetEnumType() {
+		s.addEnumDescriptorProto(name, enumDescriptorProto)
+	}
+}
+
+func (s *fixState) addEnumDescriptorProto(scope string, enumDescriptorProto *descriptorpb.EnumDescriptorProto) {
+	name := joinName(scope, enumDescriptorProto.GetName())
+	s.scopes[name] = struct{}{}
+	s.types[name] = struct{}{}
+	s.allNames[name] = struct{}{}
+	for _, enumValueDescriptorProto := range enumDescriptorProto.GetValue() {
+		// Enum values are siblings of their enum, not children.
+		s.allNames[joinName(scope, enumValueDescriptorProto.GetName())] = struct{}{}
+	}
+}
+
+func (s *fixState) addFieldDescriptorProto(scope string, fieldDescriptorProto *descriptorpb.FieldDescriptorProto, isImport bool) {
+	s.allNames[joinName(scope, fieldDescriptorProto.GetName())] = struct{}{}
+	if !isImport {
+		return
+	}
+	if typeName := fieldDescriptorProto.GetTypeName(); typeName != "" {
+		typeName = strings.TrimPrefix(typeName, ".")
+		s.externalReferences[typeName] = struct{}{}
+		if fieldDescriptorProto.GetType() == descriptorpb.FieldDescriptorProto_TYPE_ENUM && fieldDescriptorProto.DefaultValue != nil {
+			s.externalReferences[joinName(parentName(typeName), fieldDescriptorProto.GetDefaultValue())] = struct{}{}
+		}
+	}
+	if extendee := fieldDescriptorProto.GetExtendee(); extendee != "" {
+		s.externalReferences[strings.TrimPrefix(extendee, ".")] = struct{}{}
+	}
+}
+
+func (s *fixState) fileForFileAnnotation(fileAnnotation bufanalysis.FileAnnotation) *fixFile {
+	fileInfo := fileAnnotation.FileInfo()
+	if fileInfo == nil {
+		return nil
+	}
+	return s.pathToFile[fileInfo.Path()]
+}
+
+func (s *fixState) namedElementForFileAnnotation(fileAnnotation bufanalysis.FileAnnotation, kind elementKind) *element {
+	file := s.fileForFileAnnotation(fileAnnotation)
+	if file == nil {
+		return nil
+	}
+	element, ok := file.positionToElement[newPosition(fileAnnotation.StartLine(), fileAnnotation.StartColumn())]
+	if !ok || element.kind != kind {
+		return nil
+	}
+	return element
+}
+
+// currentName returns the name the element will have after renames are applied.
+func (s *fixState) currentName(element *element) string {
+	if newName, ok := s.renamedFullNameToNewName[element.fullName]; ok {
+		return newName
+	}
+	return element.nameNode.Val
+}
+
+// rename records that the element should be renamed to newName.
+//
+// Returns false if renaming the element could result in invalid Protobuf.
+func (s *fixState) rename(element *element, newName string) bool {
+	if newName == s.currentName(element) {
+		return false
+	}
+	if _, ok := s.optionIdents[element.nameNode.Val]; ok {
+		// We do not know what the identifier in the option refers to.
+		return false
+	}
+	if _, ok := s.externalReferences[element.fullName]; ok {
+		return false
+	}
+	if element.kind == elementKindMessage {
+		// References to nested types of the message are also affected.
+		for externalReference := range s.externalReferences {
+			if strings.HasPrefix(externalReference, element.fullName+".") {
+				return false
+			}
+		}
+	}
+	newFullName := joinName(parentName(element.fullName), newName)
+	if _, ok := s.allNames[newFullName]; ok {
+		return false
+	}
+	delete(s.allNames, joinName(parentName(element.fullName), s.currentName(element)))
+	s.allNames[newFullName] = struct{}{}
+	s.renamedFullNameToNewName[element.fullName] = newName
+	return true
+}
+
+// resolveType resolves the type reference within the scope to a fully-qualified name.
+//
+// This follows the Protobuf scoping rules: the first component of the reference is
+// searched from the innermost scope outwards, and the rest of the reference must
+// then be found within the matched scope.
+func (s *fixState) resolveType(scope string, reference string) (string, bool) {
+	if strings.HasPrefix(reference, ".") {
+		fullName := strings.TrimPrefix(reference, ".")
+		_, ok := s.types[fullName]
+		return fullName, ok
+	}
+	firstComponent := strings.SplitN(reference, ".", 2)[0]
+	for {
+		if _, ok := s.scopes[joinName(scope, firstComponent)]; ok {
+			fullName := joinName(scope, reference)
+			_, ok := s.types[fullName]
+			return fullName, ok
+		}
+		if scope == "" {
+			return "", false
+		}
+		scope = parentName(scope)
+	}
+}
+
+// applyRenames renames all recorded elements and updates all references to them.
+func (s *fixState) applyRenames() {
+	if len(s.renamedFullNameToNewName) == 0 {
+		return
+	}
+	for _, file := range s.files {
+		for _, element := range file.elements {
+			if newName, ok := s.renamedFullNameToNewName[element.fullName]; ok {
+				element.nameNode.Val = newName
+				file.modified = true
+			}
+		}
+		for _, reference := range file.references {
+			if s.applyRenamesToReference(reference) {
+				file.modified = true
+			}
+		}
+	}
+}
+
+func (s *fixState) applyRenamesToReference(reference *reference) bool {
+	fullName, ok := s.resolveType(reference.scope, identValueString(reference.identValueNode))
+	if !ok {
+		return false
+	}
+	if reference.enumValueNode != nil {
+		// This is an enum value used as a field default, the type
+		// reference is to the enum of the field.
+		enumValueFullName := joinName(parentName(fullName), reference.enumValueNode.Val)
+		newName, ok := s.renamedFullNameToNewName[enumValueFullName]
+		if !ok {
+			return false
+		}
+		reference.enumValueNode.Val = newName
+		return true
+	}
+	var identNodes []*ast.IdentNode
+	switch identValueNode := reference.identValueNode.(type) {
+	case *ast.IdentNode:
+		identNodes = []*ast.IdentNode{identValueNode}
+	case *ast.CompoundIdentNode:
+		identNodes = identValueNode.Components
+	default:
+		return false
+	}
+	// The components of the reference are the trailing components of the full name.
+	fullNameComponents := strings.Split(fullName, ".")
+	offset := len(fullNameComponents) - len(identNodes)
+	var modified bool
+	for i, identNode := range identNodes {
+		prefix := strings.Join(fullNameComponents[:offset+i+1], ".")
+		if newName, ok := s.renamedFullNameToNewName[prefix]; ok {
+			identNode.Val = newName
+			modified = true
+		}
+	}
+	if compoundIdentNode, ok := reference.identValueNode.(*ast.CompoundIdentNode); ok && modified {
+		components := make([]string, len(compoundIdentNode.Components))
+		for i, component := range compoundIdentNode.Components {
+			components[i] = component.Val
+		}
+		compoundIdentNode.Val = strings.Join(components, ".")
+		if compoundIdentNode.LeadingDot != nil {
+			compoundIdentNode.Val = "." + compoundIdentNode.Val
+		}
+	}
+	return modified
+}
+
+type elementKind int
+
+const (
+	elementKindImport elementKind = iota + 1
+	elementKindMessage
+	elementKindEnumValue
+	elementKindField
+	elementKindService
+	elementKindRPCInput
+)
+
+// element is an element of a file that a fix can apply to.
+type element struct {
+	kind elementKind
+	// The fully-qualified name of the element.
+	//
+	// Not set for imports and RPC inputs.
+	fullName string
+	// The node of the element name.
+	//
+	// Not set for imports and RPC inputs.
+	nameNode *ast.IdentNode
+	// The simple name of the enum for enum values.
+	parentName string
+	// The scope that type references of the element are resolved within.
+	scope      string
+	importNode *ast.ImportNode
+	rpcNode    *ast.RPCNode
+}
+
+// reference is a type reference within a file.
+type reference struct {
+	scope          string
+	identValueNode ast.IdentValueNode
+	// Set if this is a field default that references an enum value
+	// of the enum referenced by identValueNode.
+	enumValueNode *ast.IdentNode
+}
+
+type fixFile struct {
+	imageFile         bufimage.ImageFile
+	fileNode          *ast.FileNode
+	elements          []*element
+	positionToElement map[position]*element
+	references        []*reference
+	addSyntax         bool
+	modified          bool
+}
+
+func newFixFile(imageFile bufimage.ImageFile) (_ *fixFile, retErr error) {
+	file, err := os.Open(imageFile.ExternalPath())
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s, lint fixes can only be applied to local files: %w", imageFile.ExternalPath(), err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	fileNode, err := parser.Parse(imageFile.ExternalPath(), file, reporter.NewHandler(nil))
+	if err != nil {
+		return nil, err
+	}
+	return &fixFile{
+		imageFile:         imageFile,
+		fileNode:          fileNode,
+		positionToElement: make(map[position]*element),
+	}, nil
+}
+
+func (f *fixFile) addElements(state *fixState) {
+	packageName := f.imageFile.FileDescriptor().GetPackage()
+	for _, decl := range f.fileNode.Decls {
+		switch node := decl.(type) {
+		case *ast.ImportNode:
+			f.addElement(node, &element{kind: elementKindImport, importNode: node})
+		case *ast.OptionNode:
+			f.addOptionIdents(state, node)
+		case *ast.MessageNode:
+			f.addMessage(state, packageName, node.Name, node.Decls)
+		case *ast.EnumNode:
+			f.addEnum(state, packageName, node)
+		case *ast.ExtendNode:
+			f.addExtend(state, packageName, node)
+		case *ast.ServiceNode:
+			f.addService(state, packageName, node)
+		}
+	}
+}
+
+func (f *fixFile) addElement(node ast.Node, element *element) {
+	start := f.fileNode.NodeInfo(node).Start()
+	f.elements = append(f.elements, element)
+	f.positionToElement[newPosition(start.Line, start.Col)] = element
+}
+
+func (f *fixFile) addMessage(state *fixState, scope string, nameNode *ast.IdentNode, decls []ast.MessageElement) {
+	name := joinName(scope, nameNode.Val)
+	messageElement := &element{
+		kind:     elementKindMessage,
+		fullName: name,
+		nameNode: nameNode,
+	}
+	f.addElement(nameNode, messageElement)
+	state.fullNameToMessage[name] = messageElement
+	for _, decl := range decls {
+		switch node := decl.(type) {
+		case *ast.OptionNode:
+			f.addOptionIdents(state, node)
+		case *ast.FieldNode:
+			f.addField(state, name, name, node.FldType, node.Name, node.Options)
+		case *ast.MapFieldNode:
+			f.addField(state, name, name, node.MapType.ValueType, node.Name, node.Options)
+		case *ast.GroupNode:
+			f.addOptionIdentsForCompactOptions(state, node.Options)
+			f.addMessage(state, name, node.Name, node.Decls)
+		case *ast.OneofNode:
+			for _, oneofDecl := range node.Decls {
+				switch oneofNode := oneofDecl.(type) {
+				case *ast.OptionNode:
+					f.addOptionIdents(state, oneofNode)
+				case *ast.FieldNode:
+					f.addField(state, name, name, oneofNode.FldType, oneofNode.Name, oneofNode.Options)
+				case *ast.GroupNode:
+					f.addOptionIdentsForCompactOptions(state, oneofNode.Options)
+					f.addMessage(state, name, oneofNode.Name, oneofNode.Decls)
+				}
+			}
+		case *ast.MessageNode:
+			f.addMessage(state, name, node.Name, node.Decls)
+		case *ast.EnumNode:
+			f.addEnum(state, name, node)
+		case *ast.ExtendNode:
+			f.addExtend(state, name, node)
+		}
+	}
+}
+
+func (f *fixFile) addEnum(state *fixState, scope string, enumNode *ast.EnumNode) {
+	for _, decl := range enumNode.Decls {
+		switch node := decl.(type) {
+		case *ast.OptionNode:
+			f.addOptionIdents(state, node)
+		case *ast.EnumValueNode:
+			f.addOptionIdentsForCompactOptions(state, node.Options)
+			f.addElement(
+				node.Name,
+				&element{
+					kind: elementKindEnumValue,
+					// Enum values are siblings of their enum, not children.
+					fullName:   joinName(scope, node.Name.Val),
+					nameNode:   node.Name,
+					parentName: enumNode.Name.Val,
+				},
+			)
+		}
+	}
+}
+
+func (f *fixFile) addExtend(state *fixState, scope string, extendNode *ast.ExtendNode) {
+	f.references = append(f.references, &reference{scope: scope, identValueNode: extendNode.Extendee})
+	for _, decl := range extendNode.Decls {
+		switch node := decl.(type) {
+		case *ast.FieldNode:
+			// Extensions are not renamed, as they are referenced by name from options.
+			f.addFieldReferences(state, scope, node.FldType, node.Options)
+		case *ast.GroupNode:
+			f.addOptionIdentsForCompactOptions(state, node.Options)
+			f.addMessage(state, scope, node.Name, node.Decls)
+		}
+	}
+}
+
+func (f *fixFile) addService(state *fixState, scope string, serviceNode *ast.ServiceNode) {
+	name := joinName(scope, serviceNode.Name.Val)
+	f.addElement(
+		serviceNode.Name,
+		&element{
+			kind:     elementKindService,
+			fullName: name,
+			nameNode: serviceNode.Name,
+		},
+	)
+	for _, decl := range serviceNode.Decls {
+		switch node := decl.(type) {
+		case *ast.OptionNode:
+			f.addOptionIdents(state, node)
+		case *ast.RPCNode:
+			for _, rpcDecl := range node.Decls {
+				if optionNode, ok := rpcDecl.(*ast.OptionNode); ok {
+					f.addOptionIdents(state, optionNode)
+				}
+			}
+			f.references = append(
+				f.references,
+				&reference{scope: scope, identValueNode: node.Input.MessageType},
+				&reference{scope: scope, identValueNode: node.Output.MessageType},
+			)
+			f.addElement(
+				node.Input.MessageType,
+				&element{
+					kind:    elementKindRPCInput,
+					scope:   scope,
+					rpcNode: node,
+				},
+			)
+		}
+	}
+}
+
+func (f *fixFile) addField(
+	state *fixState,
+	messageName string,
+	scope string,
+	fieldType ast.IdentValueNode,
+	nameNode *ast.IdentNode,
+	compactOptionsNode *ast.CompactOptionsNode,
+) {
+	f.addElement(
+		nameNode,
+		&element{
+			kind:     elementKindField,
+			fullName: joinName(messageName, nameNode.Val),
+			nameNode: nameNode,
+		},
+	)
+	f.addFieldReferences(state, scope, fieldType, compactOptionsNode)
+}
+
+func (f *fixFile) addFieldReferences(
+	state *fixState,
+	scope string,
+	fieldType ast.IdentValueNode,
+	compactOptionsNode *ast.CompactOptionsNode,
+) {
+	f.references = append(f.references, &reference{scope: scope, identValueNode: fieldType})
+	if compactOptionsNode == nil {
+		return
+	}
+	for _, optionNode := range compactOptionsNode.Options {
+		if stringForOptionName(optionNode.Name) == "default" {
+			if identNode, ok := optionNode.Val.(*ast.IdentNode); ok {
+				f.references = append(
+					f.references,
+					&reference{scope: scope, identValueNode: fieldType, enumValueNode: identNode},
+				)
+				continue
+			}
+		}
+		f.addOptionIdents(state, optionNode)
+	}
+}
+
+func (f *fixFile) addOptionIdentsForCompactOptions(state *fixState, compactOptionsNode *ast.CompactOptionsNode) {
+	if compactOptionsNode == nil {
+		return
+	}
+	for _, optionNode := range compactOptionsNode.Options {
+		f.addOptionIdents(state, optionNode)
+	}
+}
+
+func (f *fixFile) addOptionIdents(state *fixState, optionNode *ast.OptionNode) {
+	_ = ast.Walk(
+		optionNode,
+		&ast.SimpleVisitor{
+			DoVisitIdentNode: func(identNode *ast.IdentNode) error {
+				state.optionIdents[identNode.Val] = struct{}{}
+				return nil
+			},
+		},
+	)
+}
+
+func writeFile(ctx context.Context, readWriteBucket storage.ReadWriteBucket, file *fixFile) (retErr error) {
+	buffer := bytes.NewBuffer(nil)
+	if err := bufformat.FormatFileNode(buffer, file.fileNode); err != nil {
+		return err
+	}
+	data := buffer.Bytes()
+	if file.addSyntax {
+		data = addSyntaxStatement(data)
+	}
+	writeObjectCloser, err := readWriteBucket.Put(ctx, file.imageFile.Path())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeObjectCloser.Close())
+	}()
+	if _, err := writeObjectCloser.Write(data); err != nil {
+		return err
+	}
+	return writeObjectCloser.SetExternalPath(file.imageFile.ExternalPath())
+}
+
+// addSyntaxStatement adds a proto2 syntax statement to the formatted file content.
+//
+// Files without a syntax statement are proto2, so this does not change the semantics
+// of the file. If the file starts with a detached comment block, such as a license
+// header, the statement is added after it.
+func addSyntaxStatement(data []byte) []byte {
+	lines := strings.SplitAfter(string(data), "\n")
+	insertIndex := 0
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "//") {
+			continue
+		}
+		if trimmedLine == "" && i > 0 {
+			insertIndex = i + 1
+		}
+		break
+	}
+	var result strings.Builder
+	for _, line := range lines[:insertIndex] {
+		_, _ = result.WriteString(line)
+	}
+	_, _ = result.WriteString(syntaxProto2Statement + "\n")
+	if insertIndex < len(lines) && strings.TrimSpace(lines[insertIndex]) != "" {
+		_, _ = result.WriteString("\n")
+	}
+	for _, line := range lines[insertIndex:] {
+		_, _ = result.WriteString(line)
+	}
+	return []byte(result.String())
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+func newPosition(line int, column int) position {
+	return position{
+		line:   line,
+		column: column,
+	}
+}
+
+func enumValuePrefix(enumName string) string {
+	return stringutil.ToUpperSnakeCase(enumName) + "_"
+}
+
+func identValueString(identValueNode ast.IdentValueNode) string {
+	return string(identValueNode.AsIdentifier())
+}
+
+func stringForOptionName(optionNameNode *ast.OptionNameNode) string {
+	var result string
+	for j, part := range optionNameNode.Parts {
+		if j > 0 {
+			// Add a dot between each of the parts.
+			result += "."
+		}
+		result += part.Value()
+	}
+	return result
+}
+
+func joinName(scope string, name string) string {
+	if scope == "" {
+		return name
+	}
+	return scope + "." + name
+}
+
+func parentName(name string) string {
+	if index := strings.LastIndex(name, "."); index >= 0 {
+		return name[:index]
+	}
+	return ""
+}
@@ -0,0 +1,125 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufwatch watches the directories of local modules for changes.
+//
+// Directories are polled for changes to .proto and configuration files, so
+// that watching works the same way on all platforms and filesystems.
+//
+// EVERYTHING IN THIS PACKAGE SHOULD ONLY BE CALLED BY THE CLI AND CANNOT BE USED IN SERVICES.
+package bufwatch
+
+import (
+	"context"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultPollInterval is the default interval at which directories are polled for changes.
+	DefaultPollInterval = 500 * time.Millisecond
+	// DefaultDebounceDelay is the default delay to wait for changes to stop before running.
+	DefaultDebounceDelay = 200 * time.Millisecond
+)
+
+// RunFunc is run by a Watcher.
+//
+// On the first run, changedDirPaths is nil. On subsequent runs, changedDirPaths
+// contains the watched directories in which files changed, sorted.
+type RunFunc func(ctx context.Context, changedDirPaths []string) error
+
+// Watcher watches directories for changes.
+type Watcher interface {
+	// Watch runs f, and then runs f again each time a .proto or configuration file
+	// changes within one of the dirPaths, until the context is cancelled or an
+	// interrupt signal is received.
+	//
+	// Changes are debounced, so that f is run once for a burst of changes.
+	// If f returns an error, watching stops and the error is returned.
+	// Returns nil once watching is stopped by the context or an interrupt signal.
+	Watch(ctx context.Context, dirPaths []string, f RunFunc) error
+}
+
+// NewWatcher returns a new Watcher.
+func NewWatcher(logger *zap.Logger, options ...WatcherOption) Watcher {
+	return newWatcher(logger, options...)
+}
+
+// WatcherOption is an option for a new Watcher.
+type WatcherOption func(*watcher)
+
+// WatcherWithPollInterval returns a new WatcherOption that sets the interval
+// at which directories are polled for changes.
+//
+// The default is DefaultPollInterval.
+func WatcherWithPollInterval(pollInterval time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.pollInterval = pollInterval
+	}
+}
+
+// WatcherWithDebounceDelay returns a new WatcherOption that sets how long
+// no further changes must be seen before running after a change.
+//
+// The default is DefaultDebounceDelay.
+func WatcherWithDebounceDelay(debounceDelay time.Duration) WatcherOption {
+	return func(watcher *watcher) {
+		watcher.debounceDelay = debounceDelay
+	}
+}
+
+// GetModuleDirPaths returns the directories of the modules of a local source, relative
+// to the current working directory unless the source was given as an absolute path.
+//
+// If the source is within a workspace, these are the directories of the workspace,
+// as modules may import from one another. Otherwise, this is the directory of the
+// module, or sourceDirPath if no configuration file was found for the source.
+//
+// isWorkspaceRoot is true if the source is the root directory of a workspace.
+func GetModuleDirPaths(
+	ctx context.Context,
+	sourceBucket buffetch.ReadBucketCloser,
+	sourceDirPath string,
+) (_ []string, isWorkspaceRoot bool, _ error) {
+	existingWorkspaceConfigFilePath, err := bufwork.ExistingConfigFilePath(ctx, sourceBucket)
+	if err != nil {
+		return nil, false, err
+	}
+	if existingWorkspaceConfigFilePath == "" {
+		existingModuleConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, sourceBucket)
+		if err != nil {
+			return nil, false, err
+		}
+		if existingModuleConfigFilePath == "" {
+			// The source bucket is rooted at the source directory if no
+			// configuration file was found.
+			return []string{normalpath.Normalize(sourceDirPath)}, false, nil
+		}
+		return []string{sourceBucket.RelativeRootPath()}, false, nil
+	}
+	workspaceConfig, err := bufwork.GetConfigForBucket(ctx, sourceBucket, sourceBucket.RelativeRootPath())
+	if err != nil {
+		return nil, false, err
+	}
+	dirPaths := make([]string, len(workspaceConfig.Directories))
+	for i, directory := range workspaceConfig.Directories {
+		dirPaths[i] = normalpath.Join(sourceBucket.RelativeRootPath(), directory)
+	}
+	return dirPaths, sourceBucket.SubDirPath() == ".", nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwatch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestWatch(t *testing.T) {
+	t.Parallel()
+	dirPath := normalpath.Normalize(t.TempDir())
+	writeFile(t, filepath.Join(dirPath, "a.proto"), "syntax = \"proto3\";")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runC := make(chan []string)
+	errC := make(chan error, 1)
+	go func() {
+		errC <- NewWatcher(
+			zap.NewNop(),
+			WatcherWithPollInterval(10*time.Millisecond),
+			WatcherWithDebounceDelay(20*time.Millisecond),
+		).Watch(
+			ctx,
+			[]string{dirPath},
+			func(ctx context.Context, changedDirPaths []string) error {
+				runC <- changedDirPaths
+				return nil
+			},
+		)
+	}()
+	require.Nil(t, receive(t, runC))
+	writeFile(t, filepath.Join(dirPath, "b.proto"), "syntax = \"proto3\";")
+	require.Equal(t, []string{dirPath}, receive(t, runC))
+	cancel()
+	select {
+	case err := <-errC:
+		require.NoError(t, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("watch did not stop")
+	}
+}
+
+func TestGetSnapshot(t *testing.T) {
+	t.Parallel()
+	dirPath := t.TempDir()
+	writeFile(t, filepath.Join(dirPath, "a.proto"), "")
+	writeFile(t, filepath.Join(dirPath, "buf.yaml"), "")
+	writeFile(t, filepath.Join(dirPath, "gen", "a.pb.go"), "")
+	snapshot, err := getSnapshot(normalpath.Normalize(dirPath))
+	require.NoError(t, err)
+	require.Len(t, snapshot, 2)
+	require.Contains(t, snapshot, filepath.Join(dirPath, "a.proto"))
+	require.Contains(t, snapshot, filepath.Join(dirPath, "buf.yaml"))
+	snapshot, err = getSnapshot(normalpath.Join(normalpath.Normalize(dirPath), "missing"))
+	require.NoError(t, err)
+	require.Empty(t, snapshot)
+}
+
+func TestGetModuleDirPaths(t *testing.T) {
+	t.Parallel()
+	workspaceDirPath := normalpath.Normalize(t.TempDir())
+	writeFile(t, filepath.Join(workspaceDirPath, "buf.work.yaml"), "version: v1\ndirectories:\n  - b\n  - a\n")
+	writeFile(t, filepath.Join(workspaceDirPath, "a", "buf.yaml"), "version: v1\n")
+	writeFile(t, filepath.Join(workspaceDirPath, "b", "buf.yaml"), "version: v1\n")
+	moduleDirPaths := []string{
+		normalpath.Join(workspaceDirPath, "a"),
+		normalpath.Join(workspaceDirPath, "b"),
+	}
+	testGetModuleDirPaths(t, workspaceDirPath, moduleDirPaths, true)
+	testGetModuleDirPaths(t, normalpath.Join(workspaceDirPath, "a"), moduleDirPaths, false)
+
+	moduleDirPath := normalpath.Normalize(t.TempDir())
+	writeFile(t, filepath.Join(moduleDirPath, "buf.yaml"), "version: v1\n")
+	writeFile(t, filepath.Join(moduleDirPath, "foo", "foo.proto"), "")
+	testGetModuleDirPaths(t, moduleDirPath, []string{moduleDirPath}, false)
+	testGetModuleDirPaths(t, normalpath.Join(moduleDirPath, "foo"), []string{moduleDirPath}, false)
+
+	noConfigDirPath := normalpath.Normalize(t.TempDir())
+	testGetModuleDirPaths(t, noConfigDirPath, []string{noConfigDirPath}, false)
+}
+
+func testGetModuleDirPaths(
+	t *testing.T,
+	sourceDirPath string,
+	expectedDirPaths []string,
+	expectedIsWorkspaceRoot bool,
+) {
+	ctx := context.Background()
+	logger := zap.NewNop()
+	sourceRef, err := buffetch.NewSourceRefParser(logger).GetSourceRef(ctx, sourceDirPath)
+	require.NoError(t, err)
+	sourceBucket, err := buffetch.NewSourceReader(
+		logger,
+		storageos.NewProvider(),
+		nil,
+		nil,
+		nil,
+	).GetSourceBucket(ctx, nil, sourceRef)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, sourceBucket.Close())
+	}()
+	dirPaths, isWorkspaceRoot, err := GetModuleDirPaths(ctx, sourceBucket, sourceDirPath)
+	require.NoError(t, err)
+	require.Equal(t, expectedDirPaths, dirPaths)
+	require.Equal(t, expectedIsWorkspaceRoot, isWorkspaceRoot)
+}
+
+func receive(t *testing.T, runC <-chan []string) []string {
+	select {
+	case changedDirPaths := <-runC:
+		return changedDirPaths
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for run")
+		return nil
+	}
+}
+
+func writeFile(t *testing.T, path string, data string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufwatch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufwork"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/pkg/interrupt"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"go.uber.org/zap"
+)
+
+// watchedFileNames are the names of the configuration files that are watched
+// in addition to .proto files.
+var watchedFileNames = stringutil.SliceToMap(
+	append(
+		append(
+			[]string{buflock.ExternalConfigFilePath},
+			bufconfig.AllConfigFilePaths...,
+		),
+		bufwork.AllConfigFilePaths...,
+	),
+)
+
+type watcher struct {
+	logger        *zap.Logger
+	pollInterval  time.Duration
+	debounceDelay time.Duration
+}
+
+func newWatcher(logger *zap.Logger, options ...WatcherOption) *watcher {
+	watcher := &watcher{
+		logger:        logger,
+		pollInterval:  DefaultPollInterval,
+		debounceDelay: DefaultDebounceDelay,
+	}
+	for _, option := range options {
+		option(watcher)
+	}
+	return watcher
+}
+
+func (w *watcher) Watch(ctx context.Context, dirPaths []string, f RunFunc) error {
+	ctx, cancel := interrupt.WithCancel(ctx)
+	defer cancel()
+	// Snapshots are taken before the first run so that changes made
+	// during the run are picked up afterwards.
+	dirPathToSnapshot := make(map[string]snapshot, len(dirPaths))
+	for _, dirPath := range dirPaths {
+		snapshot, err := getSnapshot(dirPath)
+		if err != nil {
+			return err
+		}
+		dirPathToSnapshot[dirPath] = snapshot
+	}
+	if err := f(ctx, nil); err != nil {
+		return err
+	}
+	w.logger.Info("watching for changes", zap.Strings("dirs", dirPaths))
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+	changedDirPaths := make(map[string]struct{})
+	var lastChangeTime time.Time
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return nil
+		case now = <-ticker.C:
+		}
+		for _, dirPath := range dirPaths {
+			snapshot, err := getSnapshot(dirPath)
+			if err != nil {
+				return err
+			}
+			if !snapshot.equal(dirPathToSnapshot[dirPath]) {
+				dirPathToSnapshot[dirPath] = snapshot
+				changedDirPaths[dirPath] = struct{}{}
+				lastChangeTime = now
+			}
+		}
+		if len(changedDirPaths) == 0 || now.Sub(lastChangeTime) < w.debounceDelay {
+			continue
+		}
+		sortedChangedDirPaths := stringutil.MapToSortedSlice(changedDirPaths)
+		changedDirPaths = make(map[string]struct{})
+		w.logger.Info("detected changes", zap.Strings("dirs", sortedChangedDirPaths))
+		if err := f(ctx, sortedChangedDirPaths); err != nil {
+			return err
+		}
+	}
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// snapshot is the state of the watched files within a directory, keyed by path.
+type snapshot map[string]fileState
+
+func (s snapshot) equal(other snapshot) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for path, fileState := range s {
+		otherFileState, ok := other[path]
+		if !ok || !fileState.modTime.Equal(otherFileState.modTime) || fileState.size != otherFileState.size {
+			return false
+		}
+	}
+	return true
+}
+
+// getSnapshot walks the directory and returns the state of its watched files.
+//
+// A directory that does not exist has an empty snapshot, and files that are
+// deleted during the walk are skipped.
+func getSnapshot(dirPath string) (snapshot, error) {
+	snapshot := make(snapshot)
+	if err := filepath.WalkDir(
+		normalpath.Unnormalize(dirPath),
+		func(path string, dirEntry fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if dirEntry.IsDir() || !isWatchedFileName(dirEntry.Name()) {
+				return nil
+			}
+			fileInfo, err := dirEntry.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			snapshot[path] = fileState{
+				modTime: fileInfo.ModTime(),
+				size:    fileInfo.Size(),
+			}
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func isWatchedFileName(name string) bool {
+	if filepath.Ext(name) == ".proto" {
+		return true
+	}
+	_, ok := watchedFileNames[name]
+	return ok
+}
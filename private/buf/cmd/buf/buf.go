@@ -16,12 +16,14 @@ package buf
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/package/goversion"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/package/mavenversion"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/package/npmversion"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/package/semverbump"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/package/swiftversion"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/protoc"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/registry/token/tokendelete"
@@ -30,6 +32,7 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/repo/reposync"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/alpha/workspace/workspacepush"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/graph"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/jsonschema"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/migratev1beta1"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/price"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/commit/commitget"
@@ -53,27 +56,34 @@ import (
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookcreate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhookdelete"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/registry/webhook/webhooklist"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/serve"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/servereflection"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/stats"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/beta/studioagent"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/breaking"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/build"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/convert"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/curl"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/diff"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/export"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/format"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/generate"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/lint"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/lsfiles"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modclearcache"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modgccache"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modinit"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modlsbreakingrules"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modlslintrules"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modopen"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modprune"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modupdate"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modvendor"
+	"github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/modverify"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/push"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogin"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/command/registry/registrylogout"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 )
@@ -91,6 +101,15 @@ func NewRootCommand(name string) *appcmd.Command {
 		name,
 		appflag.BuilderWithTimeout(120*time.Second),
 		appflag.BuilderWithTracing(),
+		appflag.BuilderWithEnvBoolFlag(
+			bufcli.OfflineFlagName,
+			bufcli.OfflineEnvKey,
+			fmt.Sprintf(
+				"Never access the Buf Schema Registry. Modules are only read from the module cache or the %s directory, and remote plugins and module references are rejected. Equivalent to setting $%s to true",
+				bufmodule.VendorDirPath,
+				bufcli.OfflineEnvKey,
+			),
+		),
 	)
 	return &appcmd.Command{
 		Use:                 name,
@@ -104,6 +123,7 @@ func NewRootCommand(name string) *appcmd.Command {
 			format.NewCommand("format", builder),
 			lint.NewCommand("lint", builder),
 			breaking.NewCommand("breaking", builder),
+			diff.NewCommand("diff", builder),
 			generate.NewCommand("generate", builder),
 			lsfiles.NewCommand("ls-files", builder),
 			push.NewCommand("push", builder),
@@ -116,8 +136,11 @@ func NewRootCommand(name string) *appcmd.Command {
 					modinit.NewCommand("init", builder),
 					modprune.NewCommand("prune", builder),
 					modupdate.NewCommand("update", builder),
+					modverify.NewCommand("verify", builder),
+					modvendor.NewCommand("vendor", builder),
 					modopen.NewCommand("open", builder),
 					modclearcache.NewCommand("clear-cache", builder, "cc"),
+					modgccache.NewCommand("gc-cache", builder),
 					modlslintrules.NewCommand("ls-lint-rules", builder),
 					modlsbreakingrules.NewCommand("ls-breaking-rules", builder),
 				},
@@ -135,7 +158,10 @@ func NewRootCommand(name string) *appcmd.Command {
 				Short: "Beta commands. Unstable and likely to change",
 				SubCommands: []*appcmd.Command{
 					graph.NewCommand("graph", builder),
+					jsonschema.NewCommand("json-schema", builder),
 					price.NewCommand("price", builder),
+					serve.NewCommand("serve", builder),
+					servereflection.NewCommand("serve-reflection", builder),
 					stats.NewCommand("stats", builder),
 					migratev1beta1.NewCommand("migrate-v1beta1", builder),
 					studioagent.NewCommand("studio-agent", builder),
@@ -239,6 +265,7 @@ func NewRootCommand(name string) *appcmd.Command {
 							mavenversion.NewCommand("maven-version", builder),
 							npmversion.NewCommand("npm-version", builder),
 							swiftversion.NewCommand("swift-version", builder),
+							semverbump.NewCommand("semver-bump", builder),
 						},
 					},
 					{
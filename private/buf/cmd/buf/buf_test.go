@@ -21,6 +21,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -398,6 +399,120 @@ func TestFail13(t *testing.T) {
 	)
 }
 
+func TestLintFixDiff(t *testing.T) {
+	t.Parallel()
+	// nothing is rewritten with --diff, so the fixed failures are still reported
+	stdout := bytes.NewBuffer(nil)
+	testRun(
+		t,
+		bufcli.ExitCodeFileAnnotation,
+		nil,
+		stdout,
+		"lint",
+		filepath.Join("testdata", "fail"),
+		"--fix",
+		"--diff",
+	)
+	require.Contains(t, stdout.String(), "+  int64 one_two = 1;")
+	require.Contains(
+		t,
+		stdout.String(),
+		filepath.FromSlash(`testdata/fail/buf/buf.proto:6:9:Field name "oneTwo" should be lower_snake_case, such as "one_two".`),
+	)
+}
+
+func TestLintFixInvalidInput(t *testing.T) {
+	t.Parallel()
+	// renames would break references from files that are not rewritten
+	testRunStderrContains(
+		t,
+		1,
+		`Failure: --fix cannot be used with --path or --exclude-path`,
+		"lint",
+		filepath.Join("testdata", "fail"),
+		"--fix",
+		"--path",
+		filepath.Join("testdata", "fail", "buf", "buf.proto"),
+	)
+	testRunStderrContains(
+		t,
+		1,
+		`Failure: --fix cannot be used with .proto file inputs`,
+		"lint",
+		filepath.Join("testdata", "fail", "buf", "buf.proto"),
+		"--fix",
+	)
+	testRunStderrContains(
+		t,
+		1,
+		`Failure: --fix cannot be used with modules in a multi-module workspace`,
+		"lint",
+		filepath.Join("testdata", "workspace", "success", "diamond", "proto"),
+		"--fix",
+		"--diff",
+	)
+}
+
+func TestLintPlugin(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugin is a shell script")
+	}
+	// the plugin path is relative to the buf.yaml, not the current directory
+	testRunStdout(
+		t,
+		nil,
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/lintplugin/acme/v1/acme.proto:3:1:Package "other.v1" should start with "acme.".`),
+		"lint",
+		filepath.Join("testdata", "lintplugin"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+		ID                   CATEGORIES  PURPOSE
+		ACME_PACKAGE_PREFIX  ACME        Checks that packages have the acme prefix.
+		`,
+		"mod",
+		"ls-lint-rules",
+		"--config",
+		filepath.Join("testdata", "lintplugin", "buf.yaml"),
+	)
+}
+
+func TestBreakingPlugin(t *testing.T) {
+	t.Parallel()
+	if runtime.GOOS == "windows" {
+		t.Skip("the test plugin is a shell script")
+	}
+	// the plugin path is relative to the buf.yaml, not the current directory
+	testRunStdout(
+		t,
+		nil,
+		bufcli.ExitCodeFileAnnotation,
+		filepath.FromSlash(`testdata/breakingplugin/acme/v1/acme.proto:3:1:Package "acme.v1" was checked against the previous image.`),
+		"breaking",
+		filepath.Join("testdata", "breakingplugin"),
+		"--against",
+		filepath.Join("testdata", "breakingplugin"),
+	)
+	testRunStdout(
+		t,
+		nil,
+		0,
+		`
+		ID                      CATEGORIES  PURPOSE
+		ACME_PACKAGE_NO_CHANGE  ACME        Checks that packages are not changed.
+		`,
+		"mod",
+		"ls-breaking-rules",
+		"--config",
+		filepath.Join("testdata", "breakingplugin", "buf.yaml"),
+	)
+}
+
 func TestFailCheckBreaking1(t *testing.T) {
 	t.Parallel()
 	testRunStdoutStderrNoWarn(
@@ -2528,6 +2643,23 @@ func testRunStdoutFile(t *testing.T, stdin io.Reader, expectedExitCode int, want
 	)
 }
 
+// testRunStderrContains runs the command and checks that stderr contains
+// expectedStderr, for invalid arguments that are printed after the usage.
+func testRunStderrContains(t *testing.T, expectedExitCode int, expectedStderr string, args ...string) {
+	stderr := bytes.NewBuffer(nil)
+	appcmdtesting.RunCommandExitCode(
+		t,
+		func(use string) *appcmd.Command { return NewRootCommand(use) },
+		expectedExitCode,
+		internaltesting.NewEnvFunc(t),
+		nil,
+		nil,
+		stderr,
+		args...,
+	)
+	assert.Contains(t, stderr.String(), expectedStderr)
+}
+
 func testRun(
 	t *testing.T,
 	expectedExitCode int,
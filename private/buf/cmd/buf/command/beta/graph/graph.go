@@ -22,7 +22,6 @@ import (
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
-	"github.com/bufbuild/buf/private/bufpkg/bufapimodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufgraph"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
@@ -115,10 +114,10 @@ func run(
 	if err != nil {
 		return err
 	}
-	moduleResolver := bufapimodule.NewModuleResolver(
-		container.Logger(),
-		bufapimodule.NewRepositoryCommitServiceClientFactory(clientConfig),
-	)
+	moduleResolver, err := bufcli.NewModuleResolver(container, clientConfig)
+	if err != nil {
+		return err
+	}
 	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
 	if err != nil {
 		return err
@@ -0,0 +1,158 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonschema
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/bufpkg/bufjsonschema"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflect"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	pathsFlagName           = "path"
+	configFlagName          = "config"
+	excludePathsFlagName    = "exclude-path"
+	disableSymlinksFlagName = "disable-symlinks"
+	typeFlagName            = "type"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Print the JSON Schema for the messages of an input",
+		Long: `Print a JSON Schema document for the messages of the input to stdout. ` +
+			`Messages and enums are defined in "$defs" by their fully-qualified names, and accept all values that the Protobuf JSON mapping accepts: ` +
+			`fields are named by their JSON name or by their field name, any field may be null, integers and floating point numbers are numbers or strings, ` +
+			`enums are the names or numbers of their values, at most one field of each oneof may be set, ` +
+			`and well-known types such as google.protobuf.Timestamp use their special JSON representation. ` +
+			`If --type is set to a single message, the document describes that message. ` +
+			bufcli.GetInputLong(`the source, module, or image to generate the JSON Schema for`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Paths           []string
+	Config          string
+	ExcludePaths    []string
+	DisableSymlinks bool
+	Types           []string
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
+	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors, which are printed to stderr. This does not change the JSON Schema document, which is always printed to stdout. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use to use for configuration`,
+	)
+	flagSet.StringSliceVar(
+		&f.Types,
+		typeFlagName,
+		nil,
+		"The types (package, message, enum, extension, service, method) to generate the JSON Schema for. When specified, only the messages and enums of the requested types and the messages and enums that they reference are included",
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		flags.Paths,
+		flags.ExcludePaths, // we exclude these paths
+		false,
+		false, // source info is used for descriptions
+	)
+	if err != nil {
+		return err
+	}
+	var generateOptions []bufjsonschema.GenerateOption
+	if len(flags.Types) > 0 {
+		image, err = bufimageutil.ImageFilteredByTypes(image, flags.Types...)
+		if err != nil {
+			return err
+		}
+		if len(flags.Types) == 1 {
+			// The type may also be a package, enum, extension, service, or method.
+			if _, err := bufreflect.NewMessage(ctx, image, flags.Types[0]); err == nil {
+				generateOptions = append(generateOptions, bufjsonschema.WithRootMessage(flags.Types[0]))
+			}
+		}
+	}
+	data, err := bufjsonschema.Generate(image, generateOptions...)
+	if err != nil {
+		return err
+	}
+	_, err = container.Stdout().Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,212 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package serve
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufmock"
+	"github.com/bufbuild/buf/private/bufpkg/bufreflectionserver"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/bufbuild/buf/private/pkg/transport/http/httpserver"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	bindFlagName            = "bind"
+	portFlagName            = "port"
+	fixturesFlagName        = "fixtures"
+	reflectFlagName         = "reflect"
+	errorFormatFlagName     = "error-format"
+	configFlagName          = "config"
+	pathsFlagName           = "path"
+	excludePathsFlagName    = "exclude-path"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input>",
+		Short: "Run a mock server for the services of an input",
+		Long: `Run an HTTP server that serves mock implementations of all of the services of the input ` +
+			`over the Connect, gRPC, and gRPC-Web protocols. HTTP/2 is served over cleartext (h2c).
+
+Responses come from fixture files in the directory set by --fixtures. Each fixture is at the path
+"<service>/<method>/<name>.json" or "<service>/<method>/<name>.txtpb", where service is the
+fully-qualified name of the service, such as "acme.user.v1.UserService/GetUser/alice.json", and
+contains the response in the JSON or text format. JSON fixtures of server and bidirectional
+streaming methods may contain multiple responses.
+
+A fixture may only match the requests with some field values, which are set in the request file
+"<service>/<method>/<name>.request.json" or "<service>/<method>/<name>.request.txtpb". Fixtures
+with request files are matched first, then fixtures without request files, each in the order of
+their names. Calls that match no fixture get a response with all fields set to their default values.
+Client streaming methods match the last request of the stream, and bidirectional streaming methods
+respond to each request of the stream.
+
+If --reflect is set, the grpc.reflection.v1 and grpc.reflection.v1alpha server reflection services
+are also served, so that the mock server can be called with "buf curl --reflect".
+` +
+			bufcli.GetInputLong(`the source, module, or image to serve the services of`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	BindAddress     string
+	Port            string
+	Fixtures        string
+	Reflect         bool
+	ErrorFormat     string
+	Config          string
+	Paths           []string
+	ExcludePaths    []string
+	DisableSymlinks bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
+	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.BindAddress,
+		bindFlagName,
+		"127.0.0.1",
+		"The address to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Port,
+		portFlagName,
+		"8080",
+		"The port to be exposed to accept HTTP requests",
+	)
+	flagSet.StringVar(
+		&f.Fixtures,
+		fixturesFlagName,
+		"",
+		"The directory of the fixtures to respond with. If not set, all responses have default values",
+	)
+	flagSet.BoolVar(
+		&f.Reflect,
+		reflectFlagName,
+		false,
+		"Also serve the server reflection services for the input",
+	)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stderr. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The file or data to use to use for configuration`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	image, err := bufcli.NewImageForSource(
+		ctx,
+		container,
+		input,
+		flags.ErrorFormat,
+		flags.DisableSymlinks,
+		flags.Config,
+		flags.Paths,
+		flags.ExcludePaths, // we exclude these paths
+		false,
+		!flags.Reflect, // source info is only needed for reflection
+	)
+	if err != nil {
+		return err
+	}
+	var handlerOptions []bufmock.HandlerOption
+	if flags.Fixtures != "" {
+		fixtureBucket, err := bufcli.NewStorageosProvider(flags.DisableSymlinks).NewReadWriteBucket(flags.Fixtures)
+		if err != nil {
+			return err
+		}
+		handlerOptions = append(handlerOptions, bufmock.HandlerWithFixtureBucket(fixtureBucket))
+	}
+	handler, err := bufmock.NewHandler(ctx, container.Logger(), image, handlerOptions...)
+	if err != nil {
+		return err
+	}
+	if flags.Reflect {
+		reflectionHandler, err := bufreflectionserver.NewHandler(container.Logger(), image)
+		if err != nil {
+			return err
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/", handler)
+		mux.Handle(bufreflectionserver.V1Procedure, reflectionHandler)
+		mux.Handle(bufreflectionserver.V1AlphaProcedure, reflectionHandler)
+		handler = mux
+	}
+	var httpListenConfig net.ListenConfig
+	httpListener, err := httpListenConfig.Listen(ctx, "tcp", fmt.Sprintf("%s:%s", flags.BindAddress, flags.Port))
+	if err != nil {
+		return err
+	}
+	return httpserver.Run(
+		ctx,
+		container.Logger(),
+		httpListener,
+		handler,
+	)
+}
@@ -23,7 +23,9 @@ import (
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/buf/bufwire"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
@@ -31,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 )
 
 const (
@@ -43,6 +46,7 @@ const (
 	againstConfigFlagName     = "against-config"
 	excludePathsFlagName      = "exclude-path"
 	disableSymlinksFlagName   = "disable-symlinks"
+	gitRangeFlagName          = "git-range"
 )
 
 // NewCommand returns a new Command.
@@ -77,6 +81,7 @@ type flags struct {
 	AgainstConfig     string
 	ExcludePaths      []string
 	DisableSymlinks   bool
+	GitRange          string
 	// special
 	InputHashtag string
 }
@@ -137,13 +142,28 @@ Overrides --%s`,
 		"",
 		`The buf.yaml file or data to use to configure the against source, module, or image`,
 	)
+	flagSet.StringVar(
+		&f.GitRange,
+		gitRangeFlagName,
+		"",
+		fmt.Sprintf(
+			`Check every commit in the git commit range <from>..<to> against the commit before it, instead of checking against --%s
+Each side of the range is any name of a commit that git accepts, such as a commit hash, a tag, a branch, or HEAD
+Only first parents are followed, and the json and sarif formats report the commit that introduced each failure
+<input> must be the module directory within the git repository`,
+			againstFlagName,
+		),
+	)
 }
 
 func run(
 	ctx context.Context,
 	container appflag.Container,
 	flags *flags,
-) error {
+) (retErr error) {
+	if flags.GitRange != "" {
+		return runGitRange(ctx, container, flags)
+	}
 	if flags.Against == "" {
 		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", againstFlagName)
 	}
@@ -241,11 +261,26 @@ func run(
 		// we're torched.
 		return fmt.Errorf("input contained %d images, whereas against contained %d images", len(imageConfigs), len(againstImageConfigs))
 	}
+	handler := bufbreaking.NewHandler(container.Logger())
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
 	var allFileAnnotations []bufanalysis.FileAnnotation
+	var allRules []bufcheck.Rule
 	for i, imageConfig := range imageConfigs {
+		config, err := bufcli.GetConfigWithCheckPluginPaths(ref, flags.Config, imageConfig)
+		if err != nil {
+			return err
+		}
+		rules, err := handler.Rules(ctx, config.Breaking)
+		if err != nil {
+			return err
+		}
+		allRules = append(allRules, rules...)
 		fileAnnotations, err := breakingForImage(
 			ctx,
-			container,
+			handler,
+			config,
 			imageConfig,
 			againstImageConfigs[i],
 			flags.ExcludeImports,
@@ -261,17 +296,22 @@ func run(
 			container.Stdout(),
 			bufanalysis.DeduplicateAndSortFileAnnotations(allFileAnnotations),
 			flags.ErrorFormat,
+			bufanalysis.PrintFileAnnotationsWithRules(bufcheck.RulesToRuleInfos(allRules)...),
 		); err != nil {
 			return err
 		}
-		return bufcli.ErrFileAnnotation
+		// Failures with a warning or info severity are printed, but do not fail the check.
+		if bufanalysis.FileAnnotationsContainError(allFileAnnotations) {
+			return bufcli.ErrFileAnnotation
+		}
 	}
 	return nil
 }
 
 func breakingForImage(
 	ctx context.Context,
-	container appflag.Container,
+	handler bufbreaking.Handler,
+	config *bufconfig.Config,
 	imageConfig bufwire.ImageConfig,
 	againstImageConfig bufwire.ImageConfig,
 	excludeImports bool,
@@ -285,9 +325,9 @@ func breakingForImage(
 	if excludeImports {
 		againstImage = bufimage.ImageWithoutImports(againstImage)
 	}
-	return bufbreaking.NewHandler(container.Logger()).Check(
+	return handler.Check(
 		ctx,
-		imageConfig.Config().Breaking,
+		config.Breaking,
 		againstImage,
 		image,
 	)
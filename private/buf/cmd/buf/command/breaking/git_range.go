@@ -0,0 +1,194 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaking
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/bufbuild/buf/private/buf/bufbreakinghistory"
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/git"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/thread"
+	"go.uber.org/multierr"
+)
+
+// runGitRange checks every commit in the git range against the commit before it.
+func runGitRange(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) (retErr error) {
+	for _, incompatibleFlag := range []struct {
+		name  string
+		isSet bool
+	}{
+		{name: againstFlagName, isSet: flags.Against != ""},
+		{name: againstConfigFlagName, isSet: flags.AgainstConfig != ""},
+		{name: configFlagName, isSet: flags.Config != ""},
+		{name: pathsFlagName, isSet: len(flags.Paths) > 0},
+		{name: excludePathsFlagName, isSet: len(flags.ExcludePaths) > 0},
+		{name: limitToInputFilesFlagName, isSet: flags.LimitToInputFiles},
+	} {
+		if incompatibleFlag.isSet {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", incompatibleFlag.name, gitRangeFlagName)
+		}
+	}
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	fromName, toName, ok := strings.Cut(flags.GitRange, "..")
+	if !ok || fromName == "" || toName == "" {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be of the form <from>..<to>: %q", gitRangeFlagName, flags.GitRange)
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	// The git cat-file process of the ObjectReader holds one slot of the runner for as long
+	// as the ObjectReader is open, so the runner needs another slot to run other commands.
+	parallelism := thread.Parallelism()
+	if parallelism < 2 {
+		parallelism = 2
+	}
+	runner := command.NewRunner(command.RunnerWithParallelism(parallelism))
+	gitDirPath, moduleDir, err := getGitDirPathAndModuleDir(ctx, runner, input)
+	if err != nil {
+		return appcmd.NewInvalidArgumentErrorf("<input> must be a module directory within a git repository when --%s is set: %v", gitRangeFlagName, err)
+	}
+	from, err := bufbreakinghistory.ResolveCommitHash(ctx, runner, gitDirPath, fromName)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	to, err := bufbreakinghistory.ResolveCommitHash(ctx, runner, gitDirPath, toName)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	objectReader, err := git.OpenObjectReader(gitDirPath, runner)
+	if err != nil {
+		return fmt.Errorf("open repository: %w", err)
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, objectReader.Close())
+	}()
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
+	if err != nil {
+		return err
+	}
+	checkerOptions := []bufbreakinghistory.CheckerOption{
+		bufbreakinghistory.CheckerWithModuleDir(moduleDir),
+	}
+	if flags.ExcludeImports {
+		checkerOptions = append(checkerOptions, bufbreakinghistory.CheckerWithExcludeImports())
+	}
+	var allFileAnnotations []bufanalysis.FileAnnotation
+	if err := bufbreakinghistory.NewChecker(
+		container.Logger(),
+		objectReader,
+		moduleReader,
+		checkerOptions...,
+	).Check(
+		ctx,
+		from,
+		to,
+		func(commit git.Commit, fileAnnotations []bufanalysis.FileAnnotation) error {
+			for _, fileAnnotation := range bufanalysis.DeduplicateAndSortFileAnnotations(fileAnnotations) {
+				allFileAnnotations = append(allFileAnnotations, fileAnnotationWithCommit(fileAnnotation, commit))
+			}
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+	if len(allFileAnnotations) > 0 {
+		// Annotations are kept in the order of the commits that introduced them.
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stdout(),
+			allFileAnnotations,
+			flags.ErrorFormat,
+		); err != nil {
+			return err
+		}
+		if bufanalysis.FileAnnotationsContainError(allFileAnnotations) {
+			return bufcli.ErrFileAnnotation
+		}
+	}
+	return nil
+}
+
+// getGitDirPathAndModuleDir returns the path to the `.git` directory of the repository that
+// contains the input directory, and the path of the input directory relative to the root of
+// the repository.
+func getGitDirPathAndModuleDir(
+	ctx context.Context,
+	runner command.Runner,
+	input string,
+) (string, string, error) {
+	inputDirPath := normalpath.Unnormalize(input)
+	fileInfo, err := os.Stat(inputDirPath)
+	if err != nil {
+		return "", "", err
+	}
+	if !fileInfo.IsDir() {
+		return "", "", fmt.Errorf("%q is not a directory", input)
+	}
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	if err := runner.Run(
+		ctx,
+		"git",
+		command.RunWithArgs("rev-parse", "--absolute-git-dir", "--show-prefix"),
+		command.RunWithStdout(stdout),
+		command.RunWithStderr(stderr),
+		command.RunWithDir(inputDirPath),
+	); err != nil {
+		return "", "", fmt.Errorf("git rev-parse: %w (%s)", err, strings.TrimSpace(stderr.String()))
+	}
+	// The prefix is printed as an empty line if the input directory is the root of the repository.
+	gitDirPath, prefix, ok := strings.Cut(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+	if !ok || gitDirPath == "" {
+		return "", "", fmt.Errorf("unexpected output of git rev-parse: %q", stdout.String())
+	}
+	return gitDirPath, normalpath.Normalize(prefix), nil
+}
+
+// fileAnnotationWithCommit returns a copy of the FileAnnotation with the commit that
+// introduced it.
+func fileAnnotationWithCommit(fileAnnotation bufanalysis.FileAnnotation, commit git.Commit) bufanalysis.FileAnnotation {
+	return bufanalysis.NewFileAnnotation(
+		fileAnnotation.FileInfo(),
+		fileAnnotation.StartLine(),
+		fileAnnotation.StartColumn(),
+		fileAnnotation.EndLine(),
+		fileAnnotation.EndColumn(),
+		fileAnnotation.Type(),
+		fileAnnotation.Message(),
+		bufanalysis.FileAnnotationWithElementPath(fileAnnotation.ElementPath()),
+		bufanalysis.FileAnnotationWithSeverity(fileAnnotation.Severity()),
+		bufanalysis.FileAnnotationWithCommit(commit.Hash().Hex()),
+	)
+}
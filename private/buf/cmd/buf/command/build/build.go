@@ -42,6 +42,7 @@ const (
 	excludePathsFlagName        = "exclude-path"
 	disableSymlinksFlagName     = "disable-symlinks"
 	typeFlagName                = "type"
+	watchFlagName               = "watch"
 )
 
 // NewCommand returns a new Command.
@@ -76,6 +77,7 @@ type flags struct {
 	ExcludePaths        []string
 	DisableSymlinks     bool
 	Types               []string
+	Watch               bool
 	// special
 	InputHashtag string
 }
@@ -92,6 +94,7 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
@@ -140,6 +143,28 @@ func run(
 	if err != nil {
 		return err
 	}
+	if flags.Watch {
+		return bufcli.WatchInput(
+			ctx,
+			container,
+			input,
+			flags.DisableSymlinks,
+			false, // the image always contains all modules of the input
+			watchFlagName,
+			func(ctx context.Context, input string) error {
+				return build(ctx, container, flags, input)
+			},
+		)
+	}
+	return build(ctx, container, flags, input)
+}
+
+func build(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	input string,
+) error {
 	image, err := bufcli.NewImageForSource(
 		ctx,
 		container,
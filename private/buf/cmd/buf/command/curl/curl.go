@@ -96,6 +96,17 @@ const (
 	outputFlagName       = "output"
 	outputFlagShortName  = "o"
 	emitDefaultsFlagName = "emit-defaults"
+
+	// Test flags
+	testFlagName       = "test"
+	testFormatFlagName = "test-format"
+
+	// Benchmark flags
+	benchRequestsFlagName    = "bench-requests"
+	benchDurationFlagName    = "bench-duration"
+	benchConcurrencyFlagName = "bench-concurrency"
+	benchRateFlagName        = "bench-rate"
+	benchFormatFlagName      = "bench-format"
 )
 
 // NewCommand returns a new Command.
@@ -124,6 +135,14 @@ during protocol negotiation and HTTP 1.1 used only if the server does not suppor
 The default RPC protocol used will be Connect. To use a different protocol (gRPC or gRPC-Web),
 use the --protocol flag. Note that the gRPC protocol cannot be used with HTTP 1.1.
 
+To call the REST endpoint of a method that has a google.api.http annotation, such as one served
+by gRPC-Gateway, use --protocol http-json. The URL still ends with the service and method names,
+which are replaced with the path of the annotation. The fields of the request message that are
+bound by the path template are set in the path, the body of the annotation is sent as the JSON
+request body, and any other fields are sent as query parameters. The JSON response is decoded
+into the response message, and errors are decoded from the JSON format of google.rpc.Status.
+Only unary methods can be called with this protocol.
+
 The input request is specified via the -d or --data flag. If absent, an empty request is sent. If
 the flag value starts with an at-sign (@), then the rest of the flag value is interpreted as a
 filename from which to read the request body. If that filename is just a dash (-), then the request
@@ -183,8 +202,54 @@ replaced with the service and method name for server reflection.
 If an error occurs that is due to incorrect usage or other unexpected error, this program will
 return an exit code that is less than 8. If the RPC fails otherwise, this program will return an
 exit code that is the gRPC code, shifted three bits to the left.
+
+If the RPC fails, the error is printed to stderr in the JSON format of Connect errors, regardless
+of the protocol. The details of the error, such as google.rpc.ErrorInfo or google.rpc.BadRequest
+messages, are decoded using the same schema or server reflection as the request and response
+messages, and their JSON representation is printed in the "debug" field of each detail. Details
+whose type cannot be resolved are printed only as the base64-encoded bytes in the "value" field.
+
+If the --test flag is set, the calls in the given test file are run instead, and the positional
+argument is the base URL of the server, without a service and method. The test file is a YAML file
+that lists the calls to run, in order:
+
+    calls:
+      - name: introduce
+        method: buf.connect.demo.eliza.v1.ElizaService/Introduce
+        headers:
+          - "Authorization: Bearer token"
+        request:
+          name: Bob Loblaw
+        expect:
+          code: ok
+          responses:
+            - sentence: "Hi Bob Loblaw. I'm Eliza."
+
+The request is a single request message in the JSON format, and client and bidirectional
+streaming calls use "requests", a list of request messages, instead. The expected code is the name
+of a Connect error code and defaults to "ok". If "responses" is set, the call must return exactly
+one response message per entry, and each entry maps dot-separated paths of fields of the JSON
+response, with list elements referenced by index, to their expected JSON values. Headers from the
+--header flags are sent with every call. Test files with the .txtpb extension are read in the
+Protobuf text format instead, where "request", "requests", and "responses" are strings of JSON.
+
+All calls share the schema and the HTTP client. The result of each call is printed in the format
+given by --test-format, which includes junit. If any call fails, this program returns exit code 100.
+
+If the --bench-requests or --bench-duration flag is set, the RPC is invoked repeatedly with the same
+request data and headers, and a summary of the throughput, the number of requests that completed
+with each code, and the latency percentiles is printed instead of the responses. The
+--bench-concurrency flag sets the number of concurrent requests and the --bench-rate flag limits
+the number of requests started per second. The schema is resolved and the method is looked up once
+for all requests. This program returns exit code 0 even if requests fail, since failures are
+included in the summary:
+
+    $ buf curl --schema . --http2-prior-knowledge --bench-duration 10 --bench-concurrency 8  \
+         http://localhost:8080/foo.bar.v1.FooService/DoSomething
 `,
-		Args: checkPositionalArgs,
+		Args: func(_ *cobra.Command, args []string) error {
+			return checkPositionalArgs(flags, args)
+		},
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
 				return run(ctx, container, flags)
@@ -231,6 +296,17 @@ type flags struct {
 	Output       string
 	EmitDefaults bool
 
+	// Test options
+	Test       string
+	TestFormat string
+
+	// Benchmark options
+	BenchRequests        int
+	BenchDurationSeconds float64
+	BenchConcurrency     int
+	BenchRate            float64
+	BenchFormat          string
+
 	// so we can inquire about which flags present on command-line
 	// TODO: ideally we'd use cobra directly instead of having the appcmd wrapper,
 	//  which prevents a lot of basic functionality by not exposing many cobra features
@@ -299,7 +375,7 @@ respectively`,
 		&f.Protocol,
 		protocolFlagName,
 		connect.ProtocolConnect,
-		`The RPC protocol to use. This can be one of "grpc", "grpcweb", or "connect"`,
+		`The RPC protocol to use. This can be one of "grpc", "grpcweb", "connect", or "http-json"`,
 	)
 	flagSet.StringVar(
 		&f.UnixSocket,
@@ -480,6 +556,66 @@ provided via stdin as a file descriptor set or image`,
 		false,
 		`Emit default values for JSON-encoded responses.`,
 	)
+	flagSet.StringVar(
+		&f.Test,
+		testFlagName,
+		"",
+		`Path to a YAML or txtpb test file with calls to run. If set, the positional argument is the
+base URL of the server, and the results of the calls are printed instead of responses`,
+	)
+	flagSet.StringVar(
+		&f.TestFormat,
+		testFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for the results of test calls. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.IntVar(
+		&f.BenchRequests,
+		benchRequestsFlagName,
+		0,
+		`The number of requests to send to benchmark the RPC. If set, a summary of the requests is
+printed instead of responses`,
+	)
+	flagSet.Float64Var(
+		&f.BenchDurationSeconds,
+		benchDurationFlagName,
+		0,
+		fmt.Sprintf(
+			`The duration, in seconds, to send requests for to benchmark the RPC. If set, a summary of
+the requests is printed instead of responses. If --%s is also set, the benchmark stops
+at whichever comes first`,
+			benchRequestsFlagName,
+		),
+	)
+	flagSet.IntVar(
+		&f.BenchConcurrency,
+		benchConcurrencyFlagName,
+		1,
+		`The number of concurrent requests to send when benchmarking the RPC`,
+	)
+	flagSet.Float64Var(
+		&f.BenchRate,
+		benchRateFlagName,
+		0,
+		`The maximum number of requests to start per second when benchmarking the RPC. If zero,
+the rate is not limited`,
+	)
+	flagSet.StringVar(
+		&f.BenchFormat,
+		benchFormatFlagName,
+		bufcurl.BenchmarkFormatText,
+		fmt.Sprintf(
+			"The format for the benchmark summary. Must be one of %s",
+			stringutil.SliceToString(bufcurl.AllBenchmarkFormatStrings),
+		),
+	)
+}
+
+func (f *flags) isBenchmark() bool {
+	return f.BenchRequests != 0 || f.BenchDurationSeconds != 0
 }
 
 func (f *flags) validate(isSecure bool) error {
@@ -536,10 +672,16 @@ func (f *flags) validate(isSecure bool) error {
 
 	switch f.Protocol {
 	case connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb:
+	case bufcurl.ProtocolHTTPJSON:
+		if f.Test != "" || f.isBenchmark() {
+			return fmt.Errorf(
+				"--%s=%s cannot be used with --%s, --%s, or --%s",
+				protocolFlagName, bufcurl.ProtocolHTTPJSON, testFlagName, benchRequestsFlagName, benchDurationFlagName)
+		}
 	default:
 		return fmt.Errorf(
-			"--%s value must be one of %q, %q, or %q",
-			protocolFlagName, connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb)
+			"--%s value must be one of %q, %q, %q, or %q",
+			protocolFlagName, connect.ProtocolConnect, connect.ProtocolGRPC, connect.ProtocolGRPCWeb, bufcurl.ProtocolHTTPJSON)
 	}
 
 	if f.NoKeepAlive && f.flagSet.Changed(keepAliveFlagName) {
@@ -553,6 +695,51 @@ func (f *flags) validate(isSecure bool) error {
 		return fmt.Errorf("--%s value must be positive", connectTimeoutFlagName)
 	}
 
+	if f.Test != "" {
+		if f.Data != "" {
+			return fmt.Errorf("--%s should not be specified if --%s is set", dataFlagName, testFlagName)
+		}
+		if _, err := bufanalysis.ParseFormat(f.TestFormat); err != nil {
+			return fmt.Errorf(
+				"--%s value must be one of %s",
+				testFormatFlagName,
+				stringutil.SliceToString(bufanalysis.AllFormatStrings),
+			)
+		}
+	} else if f.flagSet.Changed(testFormatFlagName) {
+		return fmt.Errorf("--%s should not be specified unless --%s is set", testFormatFlagName, testFlagName)
+	}
+
+	if f.isBenchmark() {
+		if f.Test != "" {
+			return fmt.Errorf("--%s and --%s flags are mutually exclusive; they may not both be specified", testFlagName, benchRequestsFlagName)
+		}
+		if f.BenchRequests < 0 {
+			return fmt.Errorf("--%s value must be positive", benchRequestsFlagName)
+		}
+		if f.BenchDurationSeconds < 0 {
+			return fmt.Errorf("--%s value must be positive", benchDurationFlagName)
+		}
+		if f.BenchConcurrency < 1 {
+			return fmt.Errorf("--%s value must be positive", benchConcurrencyFlagName)
+		}
+		if f.BenchRate < 0 {
+			return fmt.Errorf("--%s value must not be negative", benchRateFlagName)
+		}
+		if _, err := bufcurl.ParseBenchmarkFormat(f.BenchFormat); err != nil {
+			return fmt.Errorf(
+				"--%s value must be one of %s",
+				benchFormatFlagName,
+				stringutil.SliceToString(bufcurl.AllBenchmarkFormatStrings),
+			)
+		}
+	} else if f.flagSet.Changed(benchConcurrencyFlagName) || f.flagSet.Changed(benchRateFlagName) || f.flagSet.Changed(benchFormatFlagName) {
+		return fmt.Errorf(
+			"benchmark flags (--%s, --%s, --%s) should not be used unless --%s or --%s is set",
+			benchConcurrencyFlagName, benchRateFlagName, benchFormatFlagName, benchRequestsFlagName, benchDurationFlagName,
+		)
+	}
+
 	var dataFile string
 	if strings.HasPrefix(f.Data, "@") {
 		dataFile = strings.TrimPrefix(f.Data, "@")
@@ -763,7 +950,25 @@ func verifyEndpointURL(urlArg string) (endpointURL *url.URL, service, method, ba
 	return endpointURL, service, method, baseURL, nil
 }
 
-func checkPositionalArgs(_ *cobra.Command, args []string) error {
+func verifyBaseURL(urlArg string) (*url.URL, error) {
+	baseURL, err := url.Parse(urlArg)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a valid base URL: %w", urlArg, err)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL: scheme %q is not supported", baseURL.Scheme)
+	}
+	return baseURL, nil
+}
+
+func checkPositionalArgs(f *flags, args []string) error {
+	if f.Test != "" {
+		if len(args) != 1 {
+			return errors.New("expecting exactly one positional argument: the base URL of the server to test")
+		}
+		_, err := verifyBaseURL(args[0])
+		return err
+	}
 	if len(args) != 1 {
 		return errors.New("expecting exactly one positional argument: the URL of the endpoint to invoke")
 	}
@@ -772,7 +977,14 @@ func checkPositionalArgs(_ *cobra.Command, args []string) error {
 }
 
 func run(ctx context.Context, container appflag.Container, f *flags) (err error) {
-	endpointURL, service, method, baseURL, err := verifyEndpointURL(container.Arg(0))
+	var endpointURL *url.URL
+	var service, method, baseURL string
+	if f.Test != "" {
+		endpointURL, err = verifyBaseURL(container.Arg(0))
+		baseURL = container.Arg(0)
+	} else {
+		endpointURL, service, method, baseURL, err = verifyEndpointURL(container.Arg(0))
+	}
 	if err != nil {
 		return err
 	}
@@ -780,6 +992,13 @@ func run(ctx context.Context, container appflag.Container, f *flags) (err error)
 	if err := f.validate(isSecure); err != nil {
 		return err
 	}
+	var testSuite bufcurl.TestSuite
+	if f.Test != "" {
+		testSuite, err = bufcurl.ReadTestSuite(f.Test)
+		if err != nil {
+			return err
+		}
+	}
 
 	var clientOptions []connect.ClientOption
 	switch f.Protocol {
@@ -946,13 +1165,67 @@ func run(ctx context.Context, container appflag.Container, f *flags) (err error)
 		}
 	}
 
+	if testSuite != nil {
+		fileAnnotations := bufcurl.RunTestSuite(ctx, container, testSuite, res, transport, clientOptions, baseURL, requestHeaders)
+		if err := bufanalysis.PrintFileAnnotations(output, fileAnnotations, f.TestFormat); err != nil {
+			return err
+		}
+		if bufanalysis.FileAnnotationsContainError(fileAnnotations) {
+			return bufcli.ErrFileAnnotation
+		}
+		return nil
+	}
+
 	methodDescriptor, err := bufcurl.ResolveMethodDescriptor(res, service, method)
 	if err != nil {
 		return err
 	}
 
+	if f.isBenchmark() {
+		var data []byte
+		if dataReader != nil {
+			// The request data is read once and sent with every request.
+			data, err = io.ReadAll(dataReader)
+			if err != nil {
+				return bufcurl.ErrorHasFilename(err, dataSource)
+			}
+		}
+		benchmarkFormat, err := bufcurl.ParseBenchmarkFormat(f.BenchFormat)
+		if err != nil {
+			return err
+		}
+		result, err := bufcurl.RunBenchmark(
+			ctx,
+			container,
+			methodDescriptor,
+			res,
+			transport,
+			clientOptions,
+			container.Arg(0),
+			dataSource,
+			data,
+			requestHeaders,
+			bufcurl.BenchmarkWithRequests(f.BenchRequests),
+			bufcurl.BenchmarkWithDuration(secondsToDuration(f.BenchDurationSeconds)),
+			bufcurl.BenchmarkWithConcurrency(f.BenchConcurrency),
+			bufcurl.BenchmarkWithRate(f.BenchRate),
+		)
+		if err != nil {
+			return err
+		}
+		return bufcurl.PrintBenchmarkResult(output, result, benchmarkFormat)
+	}
+
 	// Now we can finally issue the RPC
-	invoker := bufcurl.NewInvoker(container, methodDescriptor, res, f.EmitDefaults, transport, clientOptions, container.Arg(0), output)
+	var invoker bufcurl.Invoker
+	if f.Protocol == bufcurl.ProtocolHTTPJSON {
+		invoker, err = bufcurl.NewHTTPJSONInvoker(container, methodDescriptor, res, f.EmitDefaults, transport, baseURL, output)
+		if err != nil {
+			return err
+		}
+	} else {
+		invoker = bufcurl.NewInvoker(container, methodDescriptor, res, f.EmitDefaults, transport, clientOptions, container.Arg(0), output)
+	}
 	return invoker.Invoke(ctx, dataSource, dataReader, requestHeaders)
 }
 
@@ -1027,13 +1300,20 @@ func makeHTTPClient(f *flags, isSecure bool, authority string, printer verbose.P
 			},
 		}
 	default:
-		transport = &http.Transport{
+		httpTransport := &http.Transport{
 			Proxy:             http.ProxyFromEnvironment,
 			DialContext:       dialFunc,
 			DialTLSContext:    dialTLSFunc,
 			ForceAttemptHTTP2: true,
 			MaxIdleConns:      1,
 		}
+		if f.isBenchmark() {
+			// Keep a connection for each concurrent request, so that HTTP/1.1 connections
+			// are reused instead of being redialed for every request.
+			httpTransport.MaxIdleConns = f.BenchConcurrency
+			httpTransport.MaxIdleConnsPerHost = f.BenchConcurrency
+		}
+		transport = httpTransport
 	}
 	return bufcurl.NewVerboseHTTPClient(transport, printer), nil
 }
@@ -0,0 +1,251 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package diff
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdiff"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	errorFormatFlagName     = "error-format"
+	formatFlagName          = "format"
+	excludeImportsFlagName  = "exclude-imports"
+	pathsFlagName           = "path"
+	configFlagName          = "config"
+	againstFlagName         = "against"
+	againstConfigFlagName   = "against-config"
+	excludePathsFlagName    = "exclude-path"
+	disableSymlinksFlagName = "disable-symlinks"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <input> --against <against-input>",
+		Short: "Print the changes between two inputs",
+		Long: `buf diff prints the added, removed, and modified messages, fields, enums, enum values, services, methods, and extensions ` +
+			`of the <input> location compared to the <against-input> location, grouped by package. ` +
+			`Elements are paired in the same way as by buf breaking, for example fields are paired by number. ` +
+			bufcli.GetInputLong(`the source, module, or image to diff`),
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat     string
+	Format          string
+	ExcludeImports  bool
+	Paths           []string
+	Config          string
+	Against         string
+	AgainstConfig   string
+	ExcludePaths    []string
+	DisableSymlinks bool
+	// special
+	InputHashtag string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
+	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
+	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for build errors printed to stdout. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+	flagSet.StringVar(
+		&f.Format,
+		formatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for the changes printed to stdout. Must be one of %s",
+			stringutil.SliceToString(bufdiff.AllFormatStrings),
+		),
+	)
+	flagSet.BoolVar(
+		&f.ExcludeImports,
+		excludeImportsFlagName,
+		false,
+		"Exclude imports from the diff.",
+	)
+	flagSet.StringVar(
+		&f.Config,
+		configFlagName,
+		"",
+		`The buf.yaml file or data to use for configuration`,
+	)
+	flagSet.StringVar(
+		&f.Against,
+		againstFlagName,
+		"",
+		fmt.Sprintf(
+			`Required. The source, module, or image to diff against. Must be one of format %s`,
+			buffetch.AllFormatsString,
+		),
+	)
+	flagSet.StringVar(
+		&f.AgainstConfig,
+		againstConfigFlagName,
+		"",
+		`The buf.yaml file or data to use to configure the against source, module, or image`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if flags.Against == "" {
+		return appcmd.NewInvalidArgumentErrorf("required flag %q not set", againstFlagName)
+	}
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	format, err := bufdiff.ParseFormat(flags.Format)
+	if err != nil {
+		return appcmd.NewInvalidArgumentError(err.Error())
+	}
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
+	if err != nil {
+		return err
+	}
+	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	if err != nil {
+		return err
+	}
+	againstRef, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, flags.Against)
+	if err != nil {
+		return err
+	}
+	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
+	runner := command.NewRunner()
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	imageConfigReader, err := bufcli.NewWireImageConfigReader(
+		container,
+		storageosProvider,
+		runner,
+		clientConfig,
+	)
+	if err != nil {
+		return err
+	}
+	imageConfigs, fileAnnotations, err := imageConfigReader.GetImageConfigs(
+		ctx,
+		container,
+		ref,
+		flags.Config,
+		flags.Paths,        // we filter the diff for files
+		flags.ExcludePaths, // we exclude these paths
+		false,              // files specified must exist on the main input
+		false,              // we must include source info to diff comments
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stdout(),
+			fileAnnotations,
+			flags.ErrorFormat,
+		); err != nil {
+			return err
+		}
+		return bufcli.ErrFileAnnotation
+	}
+	againstImageConfigs, fileAnnotations, err := imageConfigReader.GetImageConfigs(
+		ctx,
+		container,
+		againstRef,
+		flags.AgainstConfig,
+		flags.Paths,        // we filter the diff for files
+		flags.ExcludePaths, // we exclude these paths
+		true,               // files are allowed to not exist on the against input
+		false,              // we must include source info to diff comments
+	)
+	if err != nil {
+		return err
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(
+			container.Stdout(),
+			fileAnnotations,
+			flags.ErrorFormat,
+		); err != nil {
+			return err
+		}
+		return bufcli.ErrFileAnnotation
+	}
+	if len(imageConfigs) != len(againstImageConfigs) {
+		// Like buf breaking, the images of workspaces are paired by index, so the
+		// number of images must match.
+		return fmt.Errorf("input contained %d images, whereas against contained %d images", len(imageConfigs), len(againstImageConfigs))
+	}
+	var allChanges []bufdiff.Change
+	for i, imageConfig := range imageConfigs {
+		image := imageConfig.Image()
+		againstImage := againstImageConfigs[i].Image()
+		if flags.ExcludeImports {
+			image = bufimage.ImageWithoutImports(image)
+			againstImage = bufimage.ImageWithoutImports(againstImage)
+		}
+		changes, err := bufdiff.Diff(ctx, againstImage, image)
+		if err != nil {
+			return err
+		}
+		allChanges = append(allChanges, changes...)
+	}
+	if len(imageConfigs) > 1 {
+		bufdiff.SortChanges(allChanges)
+	}
+	return bufdiff.PrintChanges(container.Stdout(), allChanges, format)
+}
@@ -16,6 +16,7 @@ package generate
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"path/filepath"
 
@@ -48,6 +49,9 @@ const (
 	disableSymlinksFlagName     = "disable-symlinks"
 	typeFlagName                = "type"
 	typeDeprecatedFlagName      = "include-types"
+	disableCacheFlagName        = "disable-cache"
+	checkFlagName               = "check"
+	watchFlagName               = "watch"
 )
 
 // NewCommand returns a new Command.
@@ -109,6 +113,28 @@ func NewCommand(
         # If omitted, "directory" is used. Most users should not need to set this option.
         # Optional.
         strategy: directory
+        # Delete the files generated by this plugin in a previous run that were not generated again.
+        # The generated files are recorded in a ".buf.gen.manifest" file in the out directory.
+        # Files that were not generated by buf, or that were modified since they were
+        # generated, are never deleted. Cannot be used if out is a .zip or .jar file.
+        # Optional.
+        clean: true
+        # Only generate for these root relative files or directories.
+        # The "--path" and "--exclude-path" flags are applied first, for all plugins.
+        # Optional.
+        paths:
+          - acme/weather/v1
+        # Do not generate for these root relative files or directories.
+        # Optional.
+        exclude_paths:
+          - acme/weather/v1/internal
+        # Only generate for these types, and the types they depend on, after the
+        # paths and exclude_paths are applied. The "--type" flag and the top-level "types"
+        # configuration are applied first, for all plugins.
+        # Optional.
+        types:
+          include:
+            - acme.weather.v1.Units
       - plugin: java
         out: gen/java
         # Use the plugin hosted at buf.build/protocolbuffers/python at version v21.9.
@@ -182,6 +208,26 @@ has a per-directory parallel invocation, with results from each invocation combi
 before writing the result.
 
 Insertion points are processed in the order the plugins are specified in the template.
+
+The responses of plugins are cached in the Buf cache directory, keyed by the plugin, its options,
+and the files it generates for. For local plugins, the plugin is identified by the contents of
+its binary, and for remote plugins, by its reference. Remote plugins without a version are not
+cached. A cached response is used instead of invoking the plugin again, unless --disable-cache
+is set. The cache can be cleared with "buf mod clear-cache".
+
+If --check is set, nothing is written. Instead, the generated files are compared with the files
+in the output directories, and a unified diff of the stale, missing, and extra files is printed
+to stdout. Extra files are files in an output directory that were not generated, but are either
+recorded in the .buf.gen.manifest file of a plugin with clean set, or are in a directory with a
+generated file and have the file suffix of a generated file in that directory, such as ".pb.go".
+Hand-written files next to generated files are not reported. Exits with code 100 if there is any
+difference.
+
+If --watch is set, the modules of the input are watched, and code is generated again each time
+a .proto or configuration file changes, until the command is interrupted. If the input is the root
+of a workspace, only the modules that changed are generated again, unless --path or --exclude-path
+is set or a plugin sets clean. Changes to the generation template are not picked up. Only local
+directory and .proto file inputs can be watched, and --watch cannot be used with --check.
 `,
 		Args: cobra.MaximumNArgs(1),
 		Run: builder.NewRunFunc(
@@ -205,6 +251,9 @@ type flags struct {
 	IncludeWKT      bool
 	ExcludePaths    []string
 	DisableSymlinks bool
+	DisableCache    bool
+	Check           bool
+	Watch           bool
 	// We may be able to bind two flags to one string slice but I don't
 	// want to find out what will break if we do.
 	Types           []string
@@ -222,6 +271,19 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindInputHashtag(flagSet, &f.InputHashtag)
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
+	flagSet.BoolVar(
+		&f.DisableCache,
+		disableCacheFlagName,
+		false,
+		"Always invoke plugins instead of using the cached responses of plugins",
+	)
+	flagSet.BoolVar(
+		&f.Check,
+		checkFlagName,
+		false,
+		"Check that the files in the output directories are up to date instead of writing them. Prints a diff and exits with code 100 if they are not",
+	)
 	flagSet.BoolVar(
 		&f.IncludeImports,
 		includeImportsFlagName,
@@ -297,11 +359,10 @@ func run(
 	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
 		return err
 	}
-	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
-	if err != nil {
-		return err
+	if flags.Check && flags.Watch {
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", checkFlagName, watchFlagName)
 	}
-	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
+	input, err := bufcli.GetInputValue(container, flags.InputHashtag, ".")
 	if err != nil {
 		return err
 	}
@@ -324,6 +385,39 @@ func run(
 	if err != nil {
 		return err
 	}
+	if flags.Watch {
+		return bufcli.WatchInput(
+			ctx,
+			container,
+			input,
+			flags.DisableSymlinks,
+			// Paths must be contained within the input, and cleaning requires all files
+			// to be generated, so changed modules can only be generated on their own
+			// if there are neither.
+			len(flags.Paths) == 0 && len(flags.ExcludePaths) == 0 && !hasCleanPlugin(genConfig),
+			watchFlagName,
+			func(ctx context.Context, input string) error {
+				return generate(ctx, container, flags, storageosProvider, runner, genConfig, input)
+			},
+		)
+	}
+	return generate(ctx, container, flags, storageosProvider, runner, genConfig, input)
+}
+
+func generate(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	storageosProvider storageos.Provider,
+	runner command.Runner,
+	genConfig *bufgen.Config,
+	input string,
+) error {
+	logger := container.Logger()
+	ref, err := buffetch.NewRefParser(logger).GetRef(ctx, input)
+	if err != nil {
+		return err
+	}
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
 		return err
@@ -389,6 +483,26 @@ func run(
 			bufgen.GenerateWithWASMEnabled(),
 		)
 	}
+	offline, err := bufcli.IsOffline(container)
+	if err != nil {
+		return err
+	}
+	if offline {
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithOffline(),
+		)
+	}
+	if !flags.DisableCache {
+		generateCacheBucket, err := bufcli.NewGenerateCacheReadWriteBucket(container)
+		if err != nil {
+			return err
+		}
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithResponseCacheBucket(generateCacheBucket),
+		)
+	}
 	var includedTypes []string
 	if len(flags.Types) > 0 || len(flags.TypesDeprecated) > 0 {
 		// command-line flags take precedence
@@ -407,7 +521,13 @@ func run(
 	if err != nil {
 		return err
 	}
-	return bufgen.NewGenerator(
+	if flags.Check {
+		generateOptions = append(
+			generateOptions,
+			bufgen.GenerateWithCheck(container.Stdout()),
+		)
+	}
+	if err := bufgen.NewGenerator(
 		logger,
 		storageosProvider,
 		runner,
@@ -419,5 +539,21 @@ func run(
 		genConfig,
 		image,
 		generateOptions...,
-	)
+	); err != nil {
+		if errors.Is(err, bufgen.ErrOutOfDate) {
+			// The diff was already printed.
+			return bufcli.ErrFileAnnotation
+		}
+		return err
+	}
+	return nil
+}
+
+func hasCleanPlugin(genConfig *bufgen.Config) bool {
+	for _, pluginConfig := range genConfig.PluginConfigs {
+		if pluginConfig.Clean {
+			return true
+		}
+	}
+	return false
 }
@@ -22,8 +22,10 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/bufgen"
 	"github.com/bufbuild/buf/private/buf/cmd/buf/internal/internaltesting"
 	"github.com/bufbuild/buf/private/bufpkg/buftesting"
@@ -31,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appcmd/appcmdtesting"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/manifest"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
@@ -270,6 +273,148 @@ func TestGenerateInsertionPointMixedPathsFail(t *testing.T) {
 	testGenerateInsertionPointMixedPathsFail(t, wd, ".")
 }
 
+func TestGenerateCheck(t *testing.T) {
+	t.Parallel()
+	template := `
+version: v1
+plugins:
+  - name: insertion-point-receiver
+    out: .
+  - name: insertion-point-writer
+    out: .
+`
+	tempDir := t.TempDir()
+	testRunCheck := func(expectedExitCode int) string {
+		stdout := bytes.NewBuffer(nil)
+		appcmdtesting.RunCommandExitCode(
+			t,
+			func(name string) *appcmd.Command {
+				return NewCommand(
+					name,
+					appflag.NewBuilder(name),
+				)
+			},
+			expectedExitCode,
+			internaltesting.NewEnvFunc(t),
+			nil,
+			stdout,
+			nil,
+			filepath.Join("testdata", "simple"), // The input directory is irrelevant for these insertion points.
+			"--template",
+			template,
+			"-o",
+			tempDir,
+			"--check",
+		)
+		return stdout.String()
+	}
+	stdout := testRunCheck(bufcli.ExitCodeFileAnnotation)
+	assert.Contains(t, stdout, "+++ "+filepath.Join(tempDir, "test.txt"))
+	entries, err := os.ReadDir(tempDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "nothing is written in check mode")
+
+	testRunSuccess(
+		t,
+		filepath.Join("testdata", "simple"),
+		"--template",
+		template,
+		"-o",
+		tempDir,
+	)
+	assert.Empty(t, testRunCheck(0))
+
+	// Files with other extensions than the generated files are not compared.
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "other.md"), []byte("other\n"), 0600))
+	assert.Empty(t, testRunCheck(0))
+	// Files in directories without generated files are not compared.
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "sub", "other.txt"), []byte("other\n"), 0600))
+	assert.Empty(t, testRunCheck(0))
+
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "extra.txt"), []byte("extra\n"), 0600))
+	stdout = testRunCheck(bufcli.ExitCodeFileAnnotation)
+	assert.Contains(t, stdout, "-extra")
+	assert.NotContains(t, stdout, "test.txt")
+
+	require.NoError(t, os.Remove(filepath.Join(tempDir, "extra.txt")))
+	testFile, err := os.OpenFile(filepath.Join(tempDir, "test.txt"), os.O_APPEND|os.O_WRONLY, 0)
+	require.NoError(t, err)
+	_, err = testFile.WriteString("stale\n")
+	require.NoError(t, err)
+	require.NoError(t, testFile.Close())
+	stdout = testRunCheck(bufcli.ExitCodeFileAnnotation)
+	assert.Contains(t, stdout, "stale")
+}
+
+func TestGenerateClean(t *testing.T) {
+	t.Parallel()
+	template := `
+version: v1
+plugins:
+  - name: insertion-point-receiver
+    out: gen
+    clean: true
+  - name: insertion-point-writer
+    out: gen
+`
+	tempDir := t.TempDir()
+	outDirPath := filepath.Join(tempDir, "gen")
+	manifestFilePath := filepath.Join(outDirPath, ".buf.gen.manifest")
+	testRunSuccess(
+		t,
+		filepath.Join("testdata", "simple"),
+		"--template",
+		template,
+		"-o",
+		tempDir,
+	)
+	data, err := os.ReadFile(manifestFilePath)
+	require.NoError(t, err)
+	var cleanManifest manifest.Manifest
+	require.NoError(t, cleanManifest.UnmarshalText(data))
+	assert.Equal(t, []string{"test.txt"}, cleanManifest.Paths())
+
+	// Simulate files generated by a previous run that are not generated anymore.
+	digester, err := manifest.NewDigester(manifest.DigestTypeShake256)
+	require.NoError(t, err)
+	for path, content := range map[string]string{
+		"stale.txt":         "stale\n",
+		"sub/dir/stale.txt": "stale\n",
+		"modified.txt":      "generated\n",
+	} {
+		digest, err := digester.Digest(strings.NewReader(content))
+		require.NoError(t, err)
+		require.NoError(t, cleanManifest.AddEntry(path, *digest))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(outDirPath, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(outDirPath, path), []byte(content), 0600))
+	}
+	data, err = cleanManifest.MarshalText()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(manifestFilePath, data, 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(outDirPath, "modified.txt"), []byte("modified\n"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(outDirPath, "untracked.txt"), []byte("untracked\n"), 0600))
+
+	testRunSuccess(
+		t,
+		filepath.Join("testdata", "simple"),
+		"--template",
+		template,
+		"-o",
+		tempDir,
+	)
+	assert.NoFileExists(t, filepath.Join(outDirPath, "stale.txt"))
+	assert.NoDirExists(t, filepath.Join(outDirPath, "sub"))
+	assert.FileExists(t, filepath.Join(outDirPath, "test.txt"))
+	assert.FileExists(t, filepath.Join(outDirPath, "modified.txt"), "modified files are not deleted")
+	assert.FileExists(t, filepath.Join(outDirPath, "untracked.txt"), "files not generated by buf are not deleted")
+	data, err = os.ReadFile(manifestFilePath)
+	require.NoError(t, err)
+	cleanManifest = manifest.Manifest{}
+	require.NoError(t, cleanManifest.UnmarshalText(data))
+	assert.Equal(t, []string{"test.txt"}, cleanManifest.Paths())
+}
+
 func testGenerateInsertionPoint(
 	t *testing.T,
 	runner command.Runner,
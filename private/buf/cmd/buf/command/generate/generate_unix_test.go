@@ -196,3 +196,50 @@ func TestWorkspaceGenerateWithExcludeAndTargetPaths(t *testing.T) {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "no such file or directory")
 }
+
+func TestOutputWithPluginPathsAndTypes(t *testing.T) {
+	t.Parallel()
+	tempDirPath := t.TempDir()
+	testRunSuccess(
+		t,
+		filepath.Join("testdata", "paths"),
+		"--output",
+		tempDirPath,
+		"--template",
+		`
+version: v1
+plugins:
+  - name: java
+    out: all
+  - name: java
+    out: paths
+    paths:
+      - a
+    exclude_paths:
+      - a/v2
+  - name: java
+    out: types
+    types:
+      include:
+        - b.v1.Bar
+`,
+	)
+
+	_, err := os.Stat(filepath.Join(tempDirPath, "all", "a", "v2", "A.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "all", "b", "v1", "B.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "paths", "a", "v1", "A.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "paths", "a", "v2", "A.java"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no such file or directory")
+	_, err = os.Stat(filepath.Join(tempDirPath, "paths", "b", "v1", "B.java"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no such file or directory")
+	_, err = os.Stat(filepath.Join(tempDirPath, "types", "b", "v1", "B.java"))
+	require.NoError(t, err)
+	_, err = os.Stat(filepath.Join(tempDirPath, "types", "a", "v1", "A.java"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "no such file or directory")
+}
@@ -17,19 +17,28 @@ package lint
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
+	"github.com/bufbuild/buf/private/buf/buflintfix"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintbaseline"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
-	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/pkg/app/appcmd"
 	"github.com/bufbuild/buf/private/pkg/app/appflag"
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
+	"go.uber.org/multierr"
 )
 
 const (
@@ -38,6 +47,12 @@ const (
 	pathsFlagName           = "path"
 	excludePathsFlagName    = "exclude-path"
 	disableSymlinksFlagName = "disable-symlinks"
+	fixFlagName             = "fix"
+	diffFlagName            = "diff"
+	diffFlagShortName       = "d"
+	baselineFlagName        = "baseline"
+	writeBaselineFlagName   = "write-baseline"
+	watchFlagName           = "watch"
 )
 
 // NewCommand returns a new Command.
@@ -67,6 +82,11 @@ type flags struct {
 	Paths           []string
 	ExcludePaths    []string
 	DisableSymlinks bool
+	Fix             bool
+	Diff            bool
+	Baseline        string
+	WriteBaseline   bool
+	Watch           bool
 	// special
 	InputHashtag string
 }
@@ -80,6 +100,7 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 	bufcli.BindPaths(flagSet, &f.Paths, pathsFlagName)
 	bufcli.BindExcludePaths(flagSet, &f.ExcludePaths, excludePathsFlagName)
 	bufcli.BindDisableSymlinks(flagSet, &f.DisableSymlinks, disableSymlinksFlagName)
+	bufcli.BindWatch(flagSet, &f.Watch, watchFlagName)
 	flagSet.StringVar(
 		&f.ErrorFormat,
 		errorFormatFlagName,
@@ -95,6 +116,39 @@ func (f *flags) Bind(flagSet *pflag.FlagSet) {
 		"",
 		`The buf.yaml file or data to use for configuration`,
 	)
+	flagSet.BoolVar(
+		&f.Fix,
+		fixFlagName,
+		false,
+		fmt.Sprintf(
+			"Fix lint failures for the rules %s by rewriting files in-place. Rewritten files are formatted as with buf format. Only local directory inputs that are not part of a multi-module workspace can be fixed, and --%s and --%s cannot be used",
+			stringutil.SliceToString(buflintfix.AllFixableIDs),
+			pathsFlagName,
+			excludePathsFlagName,
+		),
+	)
+	flagSet.BoolVarP(
+		&f.Diff,
+		diffFlagName,
+		diffFlagShortName,
+		false,
+		fmt.Sprintf("Display diffs of the fixes instead of rewriting files. Requires --%s", fixFlagName),
+	)
+	flagSet.StringVar(
+		&f.Baseline,
+		baselineFlagName,
+		"",
+		"The path to a lint baseline file. Lint failures recorded in the baseline are not reported, so only new failures fail the command",
+	)
+	flagSet.BoolVar(
+		&f.WriteBaseline,
+		writeBaselineFlagName,
+		false,
+		fmt.Sprintf(
+			"Write all current lint failures to the --%s file instead of reporting them",
+			baselineFlagName,
+		),
+	)
 }
 
 func run(
@@ -109,16 +163,70 @@ func run(
 	if err != nil {
 		return err
 	}
+	if flags.Diff && !flags.Fix {
+		return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", diffFlagName, fixFlagName)
+	}
+	if flags.Fix && (len(flags.Paths) > 0 || len(flags.ExcludePaths) > 0) {
+		// Renames would break references from the files that are filtered out.
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s or --%s", fixFlagName, pathsFlagName, excludePathsFlagName)
+	}
+	if flags.WriteBaseline {
+		if flags.Baseline == "" {
+			return appcmd.NewInvalidArgumentErrorf("--%s requires --%s", writeBaselineFlagName, baselineFlagName)
+		}
+		if flags.Fix {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", writeBaselineFlagName, fixFlagName)
+		}
+		if flags.Watch {
+			return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with --%s", writeBaselineFlagName, watchFlagName)
+		}
+	}
+	if flags.Watch {
+		return bufcli.WatchInput(
+			ctx,
+			container,
+			input,
+			flags.DisableSymlinks,
+			// Paths must be contained within the input, so changed modules
+			// can only be linted on their own if no paths are given.
+			len(flags.Paths) == 0 && len(flags.ExcludePaths) == 0,
+			watchFlagName,
+			func(ctx context.Context, input string) error {
+				return lint(ctx, container, flags, input)
+			},
+		)
+	}
+	return lint(ctx, container, flags, input)
+}
+
+func lint(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+	input string,
+) (retErr error) {
 	ref, err := buffetch.NewRefParser(container.Logger()).GetRef(ctx, input)
 	if err != nil {
 		return err
 	}
+	var baseline buflintbaseline.Baseline
+	if flags.Baseline != "" && !flags.WriteBaseline {
+		baseline, err = readBaseline(flags.Baseline)
+		if err != nil {
+			return err
+		}
+	}
 	storageosProvider := bufcli.NewStorageosProvider(flags.DisableSymlinks)
 	runner := command.NewRunner()
 	clientConfig, err := bufcli.NewConnectClientConfig(container)
 	if err != nil {
 		return err
 	}
+	if flags.Fix {
+		if err := validateFixRef(ctx, container, storageosProvider, runner, clientConfig, ref, flags.Config); err != nil {
+			return err
+		}
+	}
 	imageConfigReader, err := bufcli.NewWireImageConfigReader(
 		container,
 		storageosProvider,
@@ -151,27 +259,219 @@ func run(
 		}
 		return bufcli.ErrFileAnnotation
 	}
+	handler := buflint.NewHandler(container.Logger())
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
 	var allFileAnnotations []bufanalysis.FileAnnotation
+	var allRules []bufcheck.Rule
+	var fixedReadBuckets []storage.ReadBucket
 	for _, imageConfig := range imageConfigs {
-		fileAnnotations, err := buflint.NewHandler(container.Logger()).Check(
+		config, err := bufcli.GetConfigWithCheckPluginPaths(ref, flags.Config, imageConfig)
+		if err != nil {
+			return err
+		}
+		rules, err := handler.Rules(ctx, config.Lint)
+		if err != nil {
+			return err
+		}
+		allRules = append(allRules, rules...)
+		fileAnnotations, err := handler.Check(
 			ctx,
-			imageConfig.Config().Lint,
-			bufimage.ImageWithoutImports(imageConfig.Image()),
+			config.Lint,
+			imageConfig.Image(),
 		)
 		if err != nil {
 			return err
 		}
+		if flags.Fix && len(fileAnnotations) > 0 {
+			// The fixer needs the imports to resolve and protect references
+			// from files that it does not rewrite.
+			fixedReadBucket, unfixedFileAnnotations, err := buflintfix.NewFixer(container.Logger()).Fix(
+				ctx,
+				config.Lint,
+				imageConfig.Image(),
+				fileAnnotations,
+			)
+			if err != nil {
+				return err
+			}
+			fixedReadBuckets = append(fixedReadBuckets, fixedReadBucket)
+			if !flags.Diff {
+				// With --diff nothing is rewritten, so the fixed lint failures are still present.
+				fileAnnotations = unfixedFileAnnotations
+			}
+		}
 		allFileAnnotations = append(allFileAnnotations, fileAnnotations...)
 	}
+	if len(fixedReadBuckets) > 0 {
+		if err := applyFixes(ctx, container, runner, fixedReadBuckets, flags.Diff); err != nil {
+			return err
+		}
+	}
+	allFileAnnotations = bufanalysis.DeduplicateAndSortFileAnnotations(allFileAnnotations)
+	if flags.WriteBaseline {
+		return writeBaseline(flags.Baseline, allFileAnnotations)
+	}
+	if baseline != nil {
+		allFileAnnotations = baseline.Filter(allFileAnnotations)
+	}
 	if len(allFileAnnotations) > 0 {
 		if err := buflintconfig.PrintFileAnnotations(
 			container.Stdout(),
-			bufanalysis.DeduplicateAndSortFileAnnotations(allFileAnnotations),
+			allFileAnnotations,
 			flags.ErrorFormat,
+			bufanalysis.PrintFileAnnotationsWithRules(bufcheck.RulesToRuleInfos(allRules)...),
 		); err != nil {
 			return err
 		}
-		return bufcli.ErrFileAnnotation
+		// Failures with a warning or info severity are printed, but do not fail the check.
+		if bufanalysis.FileAnnotationsContainError(allFileAnnotations) {
+			return bufcli.ErrFileAnnotation
+		}
+	}
+	return nil
+}
+
+// validateFixRef validates that the ref can be fixed.
+//
+// Renames update references in all files of the module, so the ref must be a
+// local directory containing the whole module. Modules of a workspace may refer
+// to each other, so modules in a workspace with other modules cannot be fixed.
+func validateFixRef(
+	ctx context.Context,
+	container appflag.Container,
+	storageosProvider storageos.Provider,
+	runner command.Runner,
+	clientConfig *connectclient.Config,
+	ref buffetch.Ref,
+	configOverride string,
+) error {
+	sourceRef, ok := ref.(buffetch.SourceRef)
+	if !ok || !buffetch.IsLocalSourceRef(sourceRef) {
+		return appcmd.NewInvalidArgumentErrorf("--%s can only be used with a local directory input", fixFlagName)
+	}
+	if _, ok := sourceRef.(buffetch.ProtoFileRef); ok {
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with .proto file inputs", fixFlagName)
+	}
+	moduleConfigReader, err := bufcli.NewWireModuleConfigReader(
+		container,
+		storageosProvider,
+		runner,
+		clientConfig,
+	)
+	if err != nil {
+		return err
+	}
+	moduleConfigSet, err := moduleConfigReader.GetModuleConfigSet(
+		ctx,
+		container,
+		sourceRef,
+		configOverride,
+		nil,
+		nil,
+		false,
+	)
+	if err != nil {
+		return err
+	}
+	if workspace := moduleConfigSet.Workspace(); workspace != nil && len(workspace.GetModules()) > 1 {
+		return appcmd.NewInvalidArgumentErrorf("--%s cannot be used with modules in a multi-module workspace", fixFlagName)
 	}
 	return nil
 }
+
+func readBaseline(path string) (_ buflintbaseline.Baseline, retErr error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	baseline, err := buflintbaseline.ReadBaseline(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return baseline, nil
+}
+
+func writeBaseline(path string, fileAnnotations []bufanalysis.FileAnnotation) (retErr error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	return buflintbaseline.WriteBaseline(file, fileAnnotations)
+}
+
+// applyFixes rewrites the fixed files in place, or writes the diff between
+// the original and fixed files to stdout if diff is true.
+func applyFixes(
+	ctx context.Context,
+	container appflag.Container,
+	runner command.Runner,
+	fixedReadBuckets []storage.ReadBucket,
+	diff bool,
+) error {
+	for _, fixedReadBucket := range fixedReadBuckets {
+		originalReadWriteBucket := storagemem.NewReadWriteBucket()
+		if err := storage.WalkReadObjects(
+			ctx,
+			fixedReadBucket,
+			"",
+			func(readObject storage.ReadObject) error {
+				if diff {
+					return copyExternalPathToBucket(ctx, originalReadWriteBucket, readObject)
+				}
+				data, err := io.ReadAll(readObject)
+				if err != nil {
+					return err
+				}
+				// We write to the external path for the same reasons as buf format -w,
+				// the files may come from any of the directories in a workspace.
+				return os.WriteFile(readObject.ExternalPath(), data, 0644)
+			},
+		); err != nil {
+			return err
+		}
+		if !diff {
+			continue
+		}
+		if err := storage.Diff(
+			ctx,
+			runner,
+			container.Stdout(),
+			originalReadWriteBucket,
+			fixedReadBucket,
+			storage.DiffWithExternalPaths(), // No need to set prefixes as the buckets are from the same location.
+		); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyExternalPathToBucket(
+	ctx context.Context,
+	readWriteBucket storage.ReadWriteBucket,
+	readObject storage.ReadObject,
+) (retErr error) {
+	data, err := os.ReadFile(readObject.ExternalPath())
+	if err != nil {
+		return err
+	}
+	writeObjectCloser, err := readWriteBucket.Put(ctx, readObject.Path())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeObjectCloser.Close())
+	}()
+	if _, err := writeObjectCloser.Write(data); err != nil {
+		return err
+	}
+	return writeObjectCloser.SetExternalPath(readObject.ExternalPath())
+}
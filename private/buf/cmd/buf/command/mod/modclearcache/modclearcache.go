@@ -38,7 +38,7 @@ func NewCommand(
 	return &appcmd.Command{
 		Use:     name,
 		Aliases: aliases,
-		Short:   "Clear Buf module cache",
+		Short:   "Clear Buf module and generate cache",
 		Args:    cobra.NoArgs,
 		Run: builder.NewRunFunc(
 			func(ctx context.Context, container appflag.Container) error {
@@ -62,8 +62,12 @@ func run(
 	container appflag.Container,
 	flags *flags,
 ) error {
-	for _, cacheModuleRelDirPath := range bufcli.AllCacheModuleRelDirPaths {
-		dirPath := filepath.Join(container.CacheDirPath(), normalpath.Unnormalize(cacheModuleRelDirPath))
+	cacheRelDirPaths := append(
+		append([]string{}, bufcli.AllCacheModuleRelDirPaths...),
+		bufcli.AllCacheGenerateRelDirPaths...,
+	)
+	for _, cacheRelDirPath := range cacheRelDirPaths {
+		dirPath := filepath.Join(container.CacheDirPath(), normalpath.Unnormalize(cacheRelDirPath))
 		fileInfo, err := os.Stat(dirPath)
 		if err != nil {
 			if os.IsNotExist(err) {
@@ -0,0 +1,137 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modgccache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	maxSizeFlagName = "max-size"
+	maxAgeFlagName  = "max-age"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+	aliases ...string,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:     name,
+		Aliases: aliases,
+		Short:   "Evict the least recently used modules from the Buf module cache",
+		Long: `Modules are evicted in order of their last access until the cache is at most --max-size,
+and modules that have not been accessed within --max-age are always evicted. Blobs that are
+still referenced by a retained module are never deleted.
+
+If neither flag is set, the values of the ` + bufcli.CacheMaxSizeEnvKey + ` and ` + bufcli.CacheMaxAgeEnvKey + ` environment
+variables are used. When either environment variable is set, buf also evicts modules
+automatically after it downloads a module, at most once per hour.
+
+Use "buf mod clear-cache" to delete the entire cache instead.`,
+		Args: cobra.NoArgs,
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	MaxSize string
+	MaxAge  time.Duration
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.MaxSize,
+		maxSizeFlagName,
+		"",
+		`The maximum size of the module cache, such as "512MB" or "2GiB"`,
+	)
+	flagSet.DurationVar(
+		&f.MaxAge,
+		maxAgeFlagName,
+		0,
+		`The maximum time since the last access of a module in the module cache, such as "720h"`,
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	var gcOptions []bufmodulecache.GCOption
+	if flags.MaxSize != "" {
+		maxSize, err := bufcli.ParseByteSize(flags.MaxSize)
+		if err != nil {
+			return appcmd.NewInvalidArgumentErrorf("--%s: %v", maxSizeFlagName, err)
+		}
+		gcOptions = append(gcOptions, bufmodulecache.GCWithMaxSize(maxSize))
+	}
+	if flags.MaxAge < 0 {
+		return appcmd.NewInvalidArgumentErrorf("--%s must be positive", maxAgeFlagName)
+	}
+	if flags.MaxAge > 0 {
+		gcOptions = append(gcOptions, bufmodulecache.GCWithMaxAge(flags.MaxAge))
+	}
+	if len(gcOptions) == 0 {
+		var err error
+		gcOptions, err = bufcli.NewModuleCacheGCOptions(container)
+		if err != nil {
+			return err
+		}
+	}
+	if len(gcOptions) == 0 {
+		return appcmd.NewInvalidArgumentErrorf(
+			"--%s or --%s must be set, or the %s or %s environment variables",
+			maxSizeFlagName,
+			maxAgeFlagName,
+			bufcli.CacheMaxSizeEnvKey,
+			bufcli.CacheMaxAgeEnvKey,
+		)
+	}
+	result, err := bufcli.GCModuleCache(ctx, container, gcOptions...)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(
+		container.Stderr(),
+		"evicted %d modules (%d bytes), retained %d modules (%d bytes)\n",
+		result.ModulesEvicted,
+		result.BytesFreed,
+		result.ModulesRetained,
+		result.BytesRetained,
+	)
+	return err
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	modinternal "github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
@@ -121,7 +122,11 @@ func run(
 			}
 		}
 	} else {
-		rules, err = bufbreaking.RulesForConfig(config.Breaking)
+		config, err = bufcli.ConfigWithCheckPluginDirPath(config, bufcli.ConfigOverrideDirPath(flags.Config))
+		if err != nil {
+			return err
+		}
+		rules, err = bufbreaking.RulesForConfig(ctx, config.Breaking)
 		if err != nil {
 			return err
 		}
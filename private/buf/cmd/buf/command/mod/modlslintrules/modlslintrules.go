@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
 	modinternal "github.com/bufbuild/buf/private/buf/cmd/buf/command/mod/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
@@ -121,7 +122,11 @@ func run(
 			}
 		}
 	} else {
-		rules, err = buflint.RulesForConfig(config.Lint)
+		config, err = bufcli.ConfigWithCheckPluginDirPath(config, bufcli.ConfigOverrideDirPath(flags.Config))
+		if err != nil {
+			return err
+		}
+		rules, err = buflint.RulesForConfig(ctx, config.Lint)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,135 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modvendor
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: fmt.Sprintf("Vendor the dependencies in the %s file into the %s directory", buflock.ExternalConfigFilePath, bufmodule.VendorDirPath),
+		Long: `The first argument is the directory of the local module to vendor dependencies for. Defaults to "." if no argument is specified.
+
+Every dependency pinned in the ` + buflock.ExternalConfigFilePath + ` file is written to the ` + bufmodule.VendorDirPath + ` directory of the module,
+together with its manifest and digests, replacing any previous contents of the directory.
+
+If the ` + bufmodule.VendorDirPath + ` directory exists, dependencies are only read from it, and never from the module cache
+or the network. It is an error if a dependency is not vendored, or if the vendored content does not match the
+digest in the ` + buflock.ExternalConfigFilePath + ` file. Run this command again after "buf mod update" to keep it up to date.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+	}
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+) error {
+	directoryInput, err := bufcli.GetInputValue(container, "", ".")
+	if err != nil {
+		return err
+	}
+	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
+	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		directoryInput,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	existingConfigFilePath, err := bufconfig.ExistingConfigFilePath(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	if existingConfigFilePath == "" {
+		return bufcli.ErrNoConfigFile
+	}
+	modulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	for _, modulePin := range modulePins {
+		if modulePin.Digest() == "" {
+			return fmt.Errorf(
+				`dependency %q has no digest in the %s file, run "buf mod update" to record it before vendoring`,
+				modulePin.String(),
+				buflock.ExternalConfigFilePath,
+			)
+		}
+	}
+	clientConfig, err := bufcli.NewConnectClientConfig(container)
+	if err != nil {
+		return err
+	}
+	moduleReader, err := bufcli.NewModuleReaderAndCreateCacheDirs(container, clientConfig)
+	if err != nil {
+		return err
+	}
+	// Read every dependency before touching the vendor directory, so that
+	// a failure does not leave a partially vendored module behind.
+	modules := make([]bufmodule.Module, len(modulePins))
+	for i, modulePin := range modulePins {
+		module, err := moduleReader.GetModule(ctx, modulePin)
+		if err != nil {
+			return err
+		}
+		modules[i] = module
+	}
+	vendorBucket := storage.MapReadWriteBucket(
+		readWriteBucket,
+		storage.MapOnPrefix(bufmodule.VendorDirPath),
+	)
+	if err := vendorBucket.DeleteAll(ctx, ""); err != nil {
+		return err
+	}
+	for i, modulePin := range modulePins {
+		if err := bufmodulecache.PutVendorModule(
+			ctx,
+			container.Logger(),
+			vendorBucket,
+			modulePin,
+			modules[i],
+		); err != nil {
+			return fmt.Errorf("failed to vendor dependency %q: %w", modulePin.String(), err)
+		}
+		container.Logger().Debug("vendored dependency", zap.String("dependency", modulePin.String()))
+	}
+	return nil
+}
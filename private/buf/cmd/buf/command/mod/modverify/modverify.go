@@ -0,0 +1,312 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modverify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/buflock"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/app/appcmd"
+	"github.com/bufbuild/buf/private/pkg/app/appflag"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	errorFormatFlagName = "error-format"
+
+	// missingTypeString is the annotation type for dependencies that are not in the cache
+	// or vendor directory.
+	missingTypeString = "MISSING"
+	// corruptTypeString is the annotation type for dependencies whose cached or vendored
+	// content does not match their digests.
+	corruptTypeString = "CORRUPT"
+	// digestMismatchTypeString is the annotation type for dependencies whose digest in the
+	// lock file does not match the digest recorded in the cache or vendor directory for
+	// their commit.
+	digestMismatchTypeString = "DIGEST_MISMATCH"
+	// staleDigestTypeString is the annotation type for dependencies without a digest in the
+	// lock file, or with a digest in a format that buf no longer uses.
+	staleDigestTypeString = "STALE_DIGEST"
+)
+
+// NewCommand returns a new Command.
+func NewCommand(
+	name string,
+	builder appflag.Builder,
+) *appcmd.Command {
+	flags := newFlags()
+	return &appcmd.Command{
+		Use:   name + " <directory>",
+		Short: fmt.Sprintf("Verify the cached or vendored dependencies of a module against the %s file", buflock.ExternalConfigFilePath),
+		Long: `The first argument is the directory of the local module to verify. Defaults to "." if no argument is specified.
+
+The digests of the manifest and files of every dependency in the ` + buflock.ExternalConfigFilePath + ` file are recomputed
+and compared to the digests in the ` + buflock.ExternalConfigFilePath + ` file. If the module has a ` + bufmodule.VendorDirPath + ` directory,
+the vendored dependencies are verified, as builds only read dependencies from it. Otherwise, the dependencies in
+the module cache are verified. No network access is required.
+
+Each problem is reported as one of:
+
+  ` + missingTypeString + `          The dependency, or some of its files, is not in the cache or ` + bufmodule.VendorDirPath + ` directory.
+  ` + corruptTypeString + `          The cached or vendored content does not match its digest.
+  ` + digestMismatchTypeString + `  The digest in the ` + buflock.ExternalConfigFilePath + ` file does not match the digest recorded for its commit.
+  ` + staleDigestTypeString + `     The ` + buflock.ExternalConfigFilePath + ` file has no digest for the dependency, or a digest in a format that buf
+                no longer uses. Run "buf mod update" to update it.
+
+Exits with code 0 if every dependency was verified, and code 100 if any problem was found.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: builder.NewRunFunc(
+			func(ctx context.Context, container appflag.Container) error {
+				return run(ctx, container, flags)
+			},
+			bufcli.NewErrorInterceptor(),
+		),
+		BindFlags: flags.Bind,
+	}
+}
+
+type flags struct {
+	ErrorFormat string
+}
+
+func newFlags() *flags {
+	return &flags{}
+}
+
+func (f *flags) Bind(flagSet *pflag.FlagSet) {
+	flagSet.StringVar(
+		&f.ErrorFormat,
+		errorFormatFlagName,
+		"text",
+		fmt.Sprintf(
+			"The format for problems printed to stdout. Must be one of %s",
+			stringutil.SliceToString(bufanalysis.AllFormatStrings),
+		),
+	)
+}
+
+func run(
+	ctx context.Context,
+	container appflag.Container,
+	flags *flags,
+) error {
+	if err := bufcli.ValidateErrorFormatFlag(flags.ErrorFormat, errorFormatFlagName); err != nil {
+		return err
+	}
+	directoryInput, err := bufcli.GetInputValue(container, "", ".")
+	if err != nil {
+		return err
+	}
+	storageosProvider := storageos.NewProvider(storageos.ProviderWithSymlinks())
+	readWriteBucket, err := storageosProvider.NewReadWriteBucket(
+		directoryInput,
+		storageos.ReadWriteBucketWithSymlinksIfSupported(),
+	)
+	if err != nil {
+		return err
+	}
+	modulePins, err := bufmoduleref.DependencyModulePinsForBucket(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	lockFileInfo := newLockFileInfo(directoryInput)
+	identityToLockDependency, err := getLockDependencies(ctx, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	verifyModule, err := newVerifyModuleFunc(ctx, container, readWriteBucket)
+	if err != nil {
+		return err
+	}
+	var fileAnnotations []bufanalysis.FileAnnotation
+	for _, modulePin := range modulePins {
+		lockDependency := identityToLockDependency[modulePin.IdentityString()]
+		line := lockDependency.line
+		newFileAnnotation := func(typeString string, message string) bufanalysis.FileAnnotation {
+			return bufanalysis.NewFileAnnotation(
+				lockFileInfo,
+				line,
+				0,
+				line,
+				0,
+				typeString,
+				message,
+				bufanalysis.FileAnnotationWithElementPath(modulePin.String()),
+			)
+		}
+		if staleDigestMessage := getStaleDigestMessage(modulePin, lockDependency.digest); staleDigestMessage != "" {
+			fileAnnotations = append(
+				fileAnnotations,
+				newFileAnnotation(staleDigestTypeString, staleDigestMessage),
+			)
+			if modulePin.Digest() != "" {
+				// The digest cannot be compared to the cache or vendor directory.
+				continue
+			}
+		}
+		err := verifyModule(ctx, modulePin)
+		var typeString string
+		switch {
+		case err == nil:
+			container.Logger().Debug("verified dependency", zap.String("dependency", modulePin.String()))
+			continue
+		case errors.Is(err, bufmodulecache.ErrModuleNotCached):
+			typeString = missingTypeString
+		case errors.Is(err, bufmodulecache.ErrModuleCorrupt):
+			typeString = corruptTypeString
+		case errors.Is(err, bufmodulecache.ErrDigestMismatch):
+			typeString = digestMismatchTypeString
+		default:
+			return fmt.Errorf("failed to verify dependency %q: %w", modulePin.String(), err)
+		}
+		fileAnnotations = append(
+			fileAnnotations,
+			newFileAnnotation(typeString, fmt.Sprintf("Dependency %q: %v.", modulePin.String(), err)),
+		)
+	}
+	if len(fileAnnotations) > 0 {
+		if err := bufanalysis.PrintFileAnnotations(container.Stdout(), fileAnnotations, flags.ErrorFormat); err != nil {
+			return err
+		}
+		return bufcli.ErrFileAnnotation
+	}
+	return nil
+}
+
+// newVerifyModuleFunc returns a function that verifies a dependency in the vendor directory of
+// the module if it has one, and in the module cache otherwise.
+func newVerifyModuleFunc(
+	ctx context.Context,
+	container appflag.Container,
+	readBucket storage.ReadBucket,
+) (func(context.Context, bufmoduleref.ModulePin) error, error) {
+	vendorBucket := storage.MapReadBucket(readBucket, storage.MapOnPrefix(bufmodule.VendorDirPath))
+	isVendorEmpty, err := storage.IsEmpty(ctx, vendorBucket, "")
+	if err != nil {
+		return nil, err
+	}
+	if !isVendorEmpty {
+		container.Logger().Debug("verifying vendored dependencies")
+		return func(ctx context.Context, modulePin bufmoduleref.ModulePin) error {
+			return bufmodulecache.VerifyVendorModule(ctx, vendorBucket, modulePin)
+		}, nil
+	}
+	casModuleBucket, fileLocker, err := bufcli.NewModuleCacheReadWriteBucketAndFileLocker(container)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, modulePin bufmoduleref.ModulePin) error {
+		return bufmodulecache.VerifyModule(ctx, casModuleBucket, fileLocker, modulePin)
+	}, nil
+}
+
+// getStaleDigestMessage returns the message for a dependency whose digest is missing from
+// the lock file or is not of the digest type that buf currently uses, and an empty string
+// otherwise.
+//
+// Legacy digests are dropped when the lock file is read, so the digest in the lock file is
+// used to tell them apart from missing digests.
+func getStaleDigestMessage(modulePin bufmoduleref.ModulePin, lockDigest string) string {
+	if modulePin.Digest() == "" {
+		if lockDigest == "" {
+			return fmt.Sprintf(`Dependency %q has no digest. Run "buf mod update" to record it.`, modulePin.String())
+		}
+		return fmt.Sprintf(`Dependency %q has digest %q in a format that buf no longer uses. Run "buf mod update" to update it.`, modulePin.String(), lockDigest)
+	}
+	digest, err := manifest.NewDigestFromString(modulePin.Digest())
+	if err != nil || digest.Type() != manifest.DigestTypeShake256 {
+		return fmt.Sprintf(`Dependency %q has digest %q in a format that buf no longer uses. Run "buf mod update" to update it.`, modulePin.String(), modulePin.Digest())
+	}
+	return ""
+}
+
+// lockDependency is the line and raw digest of a dependency in the lock file.
+type lockDependency struct {
+	line   int
+	digest string
+}
+
+// getLockDependencies returns the line and raw digest of every dependency in the lock file,
+// keyed by the identity of the dependency.
+//
+// Dependencies that cannot be found are reported without a line, so the lock file is
+// parsed leniently here. buflock.ReadConfig has already validated it.
+func getLockDependencies(ctx context.Context, readBucket storage.ReadBucket) (map[string]lockDependency, error) {
+	identityToLockDependency := make(map[string]lockDependency)
+	data, err := storage.ReadPath(ctx, readBucket, buflock.ExternalConfigFilePath)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return identityToLockDependency, nil
+		}
+		return nil, err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil || len(node.Content) == 0 {
+		return identityToLockDependency, nil
+	}
+	root := node.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != "deps" || root.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		for _, dependencyNode := range root.Content[i+1].Content {
+			keyToValue := make(map[string]string)
+			for j := 0; j+1 < len(dependencyNode.Content); j += 2 {
+				keyToValue[dependencyNode.Content[j].Value] = dependencyNode.Content[j+1].Value
+			}
+			identity := normalpath.Join(keyToValue["remote"], keyToValue["owner"], keyToValue["repository"])
+			identityToLockDependency[identity] = lockDependency{
+				line:   dependencyNode.Line,
+				digest: keyToValue["digest"],
+			}
+		}
+	}
+	return identityToLockDependency, nil
+}
+
+type lockFileInfo struct {
+	externalPath string
+}
+
+func newLockFileInfo(directoryInput string) *lockFileInfo {
+	return &lockFileInfo{
+		externalPath: normalpath.Unnormalize(
+			normalpath.Join(normalpath.Normalize(directoryInput), buflock.ExternalConfigFilePath),
+		),
+	}
+}
+
+func (l *lockFileInfo) Path() string {
+	return buflock.ExternalConfigFilePath
+}
+
+func (l *lockFileInfo) ExternalPath() string {
+	return l.externalPath
+}
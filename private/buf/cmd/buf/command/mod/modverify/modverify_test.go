@@ -0,0 +1,56 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package modverify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetStaleDigestMessage(t *testing.T) {
+	t.Parallel()
+	testGetStaleDigestMessage(t, "shake256:"+testShake256Hex, "shake256:"+testShake256Hex, "")
+	testGetStaleDigestMessage(t, "", "", "has no digest")
+	// Legacy digests are dropped when the lock file is read.
+	testGetStaleDigestMessage(t, "", "b1-gLO3B_5ClhdU52w1gMOxk4GokvCoM1OqjarxMfjStGQ=", "in a format that buf no longer uses")
+	testGetStaleDigestMessage(t, "sha1:abcd", "sha1:abcd", "in a format that buf no longer uses")
+	testGetStaleDigestMessage(t, "abcd", "abcd", "in a format that buf no longer uses")
+}
+
+const testShake256Hex = "a2dc3e6e8a3e08e4f2b5a7e8f0e4b6d8c1a9f3e5d7b9c2a4e6f8d0b2c4a6e8f0a2dc3e6e8a3e08e4f2b5a7e8f0e4b6d8c1a9f3e5d7b9c2a4e6f8d0b2c4a6e8f0"
+
+func testGetStaleDigestMessage(t *testing.T, pinDigest string, lockDigest string, expectedMessageSubstring string) {
+	t.Helper()
+	modulePin, err := bufmoduleref.NewModulePin(
+		"buf.build",
+		"acme",
+		"weather",
+		"",
+		"0123456789abcdef0123456789abcdef",
+		pinDigest,
+		time.Now(),
+	)
+	require.NoError(t, err)
+	message := getStaleDigestMessage(modulePin, lockDigest)
+	if expectedMessageSubstring == "" {
+		assert.Empty(t, message)
+		return
+	}
+	assert.Contains(t, message, expectedMessageSubstring)
+}
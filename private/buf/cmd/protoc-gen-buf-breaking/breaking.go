@@ -26,6 +26,7 @@ import (
 	"github.com/bufbuild/buf/private/buf/bufcli"
 	"github.com/bufbuild/buf/private/buf/buffetch"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
@@ -35,6 +36,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"go.uber.org/multierr"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -67,7 +69,7 @@ func handle(
 	container app.EnvStderrContainer,
 	responseWriter appproto.ResponseBuilder,
 	request *pluginpb.CodeGeneratorRequest,
-) error {
+) (retErr error) {
 	responseWriter.SetFeatureProto3Optional()
 	externalConfig := &externalConfig{}
 	if err := encoding.UnmarshalJSONOrYAMLStrict(
@@ -123,21 +125,28 @@ func handle(
 	if err != nil {
 		return err
 	}
+	configOverride := encoding.GetJSONStringOrStringValue(externalConfig.InputConfig)
 	config, err := bufconfig.ReadConfigOS(
 		ctx,
 		readWriteBucket,
-		bufconfig.ReadConfigOSWithOverride(
-			encoding.GetJSONStringOrStringValue(externalConfig.InputConfig),
-		),
+		bufconfig.ReadConfigOSWithOverride(configOverride),
 	)
 	if err != nil {
 		return err
 	}
+	config, err = bufcli.ConfigWithCheckPluginDirPath(config, bufcli.ConfigOverrideDirPath(configOverride))
+	if err != nil {
+		return err
+	}
 	image, err := bufimage.NewImageForCodeGeneratorRequest(request)
 	if err != nil {
 		return err
 	}
-	fileAnnotations, err := bufbreaking.NewHandler(logger).Check(
+	handler := bufbreaking.NewHandler(logger)
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
+	fileAnnotations, err := handler.Check(
 		ctx,
 		config.Breaking,
 		againstImage,
@@ -147,8 +156,22 @@ func handle(
 		return err
 	}
 	if len(fileAnnotations) > 0 {
+		rules, err := handler.Rules(ctx, config.Breaking)
+		if err != nil {
+			return err
+		}
 		buffer := bytes.NewBuffer(nil)
-		if err := bufanalysis.PrintFileAnnotations(buffer, fileAnnotations, externalConfig.ErrorFormat); err != nil {
+		if err := bufanalysis.PrintFileAnnotations(
+			buffer,
+			fileAnnotations,
+			externalConfig.ErrorFormat,
+			bufanalysis.PrintFileAnnotationsWithRules(bufcheck.RulesToRuleInfos(rules)...),
+		); err != nil {
+			return err
+		}
+		if !bufanalysis.FileAnnotationsContainError(fileAnnotations) {
+			// Failures with a warning or info severity should not fail protoc.
+			_, err := container.Stderr().Write(buffer.Bytes())
 			return err
 		}
 		responseWriter.AddError(strings.TrimSpace(buffer.String()))
@@ -21,6 +21,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/bufbuild/buf/private/buf/bufcli"
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
@@ -30,6 +33,7 @@ import (
 	"github.com/bufbuild/buf/private/pkg/app/appproto"
 	"github.com/bufbuild/buf/private/pkg/encoding"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"go.uber.org/multierr"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
@@ -62,7 +66,7 @@ func handle(
 	container app.EnvStderrContainer,
 	responseWriter appproto.ResponseBuilder,
 	request *pluginpb.CodeGeneratorRequest,
-) error {
+) (retErr error) {
 	responseWriter.SetFeatureProto3Optional()
 	externalConfig := &externalConfig{}
 	if err := encoding.UnmarshalJSONOrYAMLStrict(
@@ -89,16 +93,19 @@ func handle(
 	if err != nil {
 		return err
 	}
+	configOverride := encoding.GetJSONStringOrStringValue(externalConfig.InputConfig)
 	config, err := bufconfig.ReadConfigOS(
 		ctx,
 		readWriteBucket,
-		bufconfig.ReadConfigOSWithOverride(
-			encoding.GetJSONStringOrStringValue(externalConfig.InputConfig),
-		),
+		bufconfig.ReadConfigOSWithOverride(configOverride),
 	)
 	if err != nil {
 		return err
 	}
+	config, err = bufcli.ConfigWithCheckPluginDirPath(config, bufcli.ConfigOverrideDirPath(configOverride))
+	if err != nil {
+		return err
+	}
 	// With the "buf lint" command, we build the image and then the linter can report
 	// unused imports that the compiler reports. But with a plugin, we get descriptors
 	// that are already built and no access to any possible associated compiler warnings.
@@ -107,9 +114,12 @@ func handle(
 	if err != nil {
 		return err
 	}
-	// We don't want to lint transitive dependencies, only the files specified.
-	image = bufimage.ImageWithoutImports(image)
-	fileAnnotations, err := buflint.NewHandler(logger).Check(
+	handler := buflint.NewHandler(logger)
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
+	// The handler only lints the files specified, and not their transitive dependencies.
+	fileAnnotations, err := handler.Check(
 		ctx,
 		config.Lint,
 		image,
@@ -118,8 +128,22 @@ func handle(
 		return err
 	}
 	if len(fileAnnotations) > 0 {
+		rules, err := handler.Rules(ctx, config.Lint)
+		if err != nil {
+			return err
+		}
 		buffer := bytes.NewBuffer(nil)
-		if err := buflintconfig.PrintFileAnnotations(buffer, fileAnnotations, externalConfig.ErrorFormat); err != nil {
+		if err := buflintconfig.PrintFileAnnotations(
+			buffer,
+			fileAnnotations,
+			externalConfig.ErrorFormat,
+			bufanalysis.PrintFileAnnotationsWithRules(bufcheck.RulesToRuleInfos(rules)...),
+		); err != nil {
+			return err
+		}
+		if !bufanalysis.FileAnnotationsContainError(fileAnnotations) {
+			// Failures with a warning or info severity should not fail protoc.
+			_, err := container.Stderr().Write(buffer.Bytes())
 			return err
 		}
 		responseWriter.AddError(strings.TrimSpace(buffer.String()))
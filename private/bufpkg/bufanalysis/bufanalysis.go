@@ -36,6 +36,10 @@ const (
 	//
 	// See https://docs.github.com/en/actions/using-workflows/workflow-commands-for-github-actions#setting-an-error-message.
 	FormatGithubActions
+	// FormatSARIF is the SARIF format for FileAnnotations.
+	//
+	// See https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html.
+	FormatSARIF
 )
 
 var (
@@ -48,6 +52,7 @@ var (
 		"msvs",
 		"junit",
 		"github-actions",
+		"sarif",
 	}
 	// AllFormatStringsWithAliases is all format strings with aliases.
 	//
@@ -59,6 +64,7 @@ var (
 		"msvs",
 		"junit",
 		"github-actions",
+		"sarif",
 	}
 
 	stringToFormat = map[string]Format{
@@ -69,6 +75,7 @@ var (
 		"msvs":           FormatMSVS,
 		"junit":          FormatJUnit,
 		"github-actions": FormatGithubActions,
+		"sarif":          FormatSARIF,
 	}
 	formatToString = map[Format]string{
 		FormatText:          "text",
@@ -76,6 +83,7 @@ var (
 		FormatMSVS:          "msvs",
 		FormatJUnit:         "junit",
 		FormatGithubActions: "github-actions",
+		FormatSARIF:         "sarif",
 	}
 )
 
@@ -106,12 +114,81 @@ func ParseFormat(s string) (Format, error) {
 	return 0, fmt.Errorf("unknown format: %q", s)
 }
 
+const (
+	// SeverityError is the error Severity.
+	//
+	// This is the default Severity of FileAnnotations.
+	SeverityError Severity = iota + 1
+	// SeverityWarning is the warning Severity.
+	SeverityWarning
+	// SeverityInfo is the info Severity.
+	SeverityInfo
+)
+
+var (
+	// AllSeverityStrings is all severity strings.
+	//
+	// Sorted in the order we want to display them.
+	AllSeverityStrings = []string{
+		"error",
+		"warning",
+		"info",
+	}
+
+	stringToSeverity = map[string]Severity{
+		"error":   SeverityError,
+		"warning": SeverityWarning,
+		"info":    SeverityInfo,
+	}
+	severityToString = map[Severity]string{
+		SeverityError:   "error",
+		SeverityWarning: "warning",
+		SeverityInfo:    "info",
+	}
+)
+
+// Severity is the severity of a FileAnnotation.
+type Severity int
+
+// String implements fmt.Stringer.
+func (s Severity) String() string {
+	str, ok := severityToString[s]
+	if !ok {
+		return strconv.Itoa(int(s))
+	}
+	return str
+}
+
+// ParseSeverity parses the Severity.
+func ParseSeverity(s string) (Severity, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	severity, ok := stringToSeverity[s]
+	if ok {
+		return severity, nil
+	}
+	return 0, fmt.Errorf("unknown severity: %q", s)
+}
+
 // FileInfo is a minimal FileInfo interface.
 type FileInfo interface {
 	Path() string
 	ExternalPath() string
 }
 
+// RuleInfo is a minimal rule interface.
+//
+// This is used to attach rule metadata to formats that support it, such as FormatSARIF.
+type RuleInfo interface {
+	// ID is the ID of the rule.
+	//
+	// This matches the Type of FileAnnotations produced by the rule.
+	ID() string
+	// Categories are the categories of the rule.
+	Categories() []string
+	// Purpose is the purpose of the rule.
+	Purpose() string
+}
+
 // FileAnnotation is a file annotation.
 type FileAnnotation interface {
 	// Stringer returns the string representation of this annotation.
@@ -146,6 +223,21 @@ type FileAnnotation interface {
 	Type() string
 	// Message is the message of the annotation.
 	Message() string
+	// ElementPath is the path of the element the annotation is for, typically the
+	// fully-qualified name of a Protobuf element such as foo.v1.Bar.baz.
+	//
+	// If the element is not known, or the annotation is for a file as a whole, this will be empty.
+	ElementPath() string
+	// Severity is the severity of the annotation.
+	//
+	// Only annotations with SeverityError should result in a failure.
+	// This defaults to SeverityError.
+	Severity() Severity
+	// Commit is the hash of the commit that introduced the annotation, when
+	// checking a range of commits.
+	//
+	// If the commit is not known, this will be empty.
+	Commit() string
 }
 
 // NewFileAnnotation returns a new FileAnnotation.
@@ -157,8 +249,9 @@ func NewFileAnnotation(
 	endColumn int,
 	typeString string,
 	message string,
+	options ...FileAnnotationOption,
 ) FileAnnotation {
-	return newFileAnnotation(
+	fileAnnotation := newFileAnnotation(
 		fileInfo,
 		startLine,
 		startColumn,
@@ -167,6 +260,52 @@ func NewFileAnnotation(
 		typeString,
 		message,
 	)
+	for _, option := range options {
+		option(fileAnnotation)
+	}
+	return fileAnnotation
+}
+
+// FileAnnotationOption is an option for a new FileAnnotation.
+type FileAnnotationOption func(*fileAnnotation)
+
+// FileAnnotationWithElementPath returns a new FileAnnotationOption that sets
+// the path of the element the FileAnnotation is for.
+func FileAnnotationWithElementPath(elementPath string) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.elementPath = elementPath
+	}
+}
+
+// FileAnnotationWithSeverity returns a new FileAnnotationOption that sets
+// the Severity of the FileAnnotation.
+//
+// The default is SeverityError.
+func FileAnnotationWithSeverity(severity Severity) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.severity = severity
+	}
+}
+
+// FileAnnotationWithCommit returns a new FileAnnotationOption that sets
+// the hash of the commit that introduced the FileAnnotation.
+//
+// The commit is printed by the formats that have a field for it, currently
+// FormatJSON and FormatSARIF.
+func FileAnnotationWithCommit(commit string) FileAnnotationOption {
+	return func(fileAnnotation *fileAnnotation) {
+		fileAnnotation.commit = commit
+	}
+}
+
+// FileAnnotationsContainError returns true if any of the FileAnnotations has SeverityError.
+func FileAnnotationsContainError(fileAnnotations []FileAnnotation) bool {
+	for _, fileAnnotation := range fileAnnotations {
+		if fileAnnotation.Severity() == SeverityError {
+			return true
+		}
+	}
+	return false
 }
 
 // SortFileAnnotations sorts the FileAnnotations.
@@ -202,11 +341,20 @@ func DeduplicateAndSortFileAnnotations(fileAnnotations []FileAnnotation) []FileA
 }
 
 // PrintFileAnnotations prints the file annotations separated by newlines.
-func PrintFileAnnotations(writer io.Writer, fileAnnotations []FileAnnotation, formatString string) error {
+func PrintFileAnnotations(
+	writer io.Writer,
+	fileAnnotations []FileAnnotation,
+	formatString string,
+	options ...PrintFileAnnotationsOption,
+) error {
 	format, err := ParseFormat(formatString)
 	if err != nil {
 		return err
 	}
+	printFileAnnotationsOptions := newPrintFileAnnotationsOptions()
+	for _, option := range options {
+		option(printFileAnnotationsOptions)
+	}
 
 	switch format {
 	case FormatText:
@@ -219,11 +367,35 @@ func PrintFileAnnotations(writer io.Writer, fileAnnotations []FileAnnotation, fo
 		return printAsJUnit(writer, fileAnnotations)
 	case FormatGithubActions:
 		return printAsGithubActions(writer, fileAnnotations)
+	case FormatSARIF:
+		return printAsSARIF(writer, fileAnnotations, printFileAnnotationsOptions.rules)
 	default:
 		return fmt.Errorf("unknown FileAnnotation Format: %v", format)
 	}
 }
 
+// PrintFileAnnotationsOption is an option for PrintFileAnnotations.
+type PrintFileAnnotationsOption func(*printFileAnnotationsOptions)
+
+// PrintFileAnnotationsWithRules returns a new PrintFileAnnotationsOption that
+// attaches the metadata of the given rules to the printed FileAnnotations.
+//
+// Rules are matched to FileAnnotations by ID and Type. This is only used by
+// formats that can carry rule metadata, currently FormatSARIF.
+func PrintFileAnnotationsWithRules(rules ...RuleInfo) PrintFileAnnotationsOption {
+	return func(printFileAnnotationsOptions *printFileAnnotationsOptions) {
+		printFileAnnotationsOptions.rules = append(printFileAnnotationsOptions.rules, rules...)
+	}
+}
+
+type printFileAnnotationsOptions struct {
+	rules []RuleInfo
+}
+
+func newPrintFileAnnotationsOptions() *printFileAnnotationsOptions {
+	return &printFileAnnotationsOptions{}
+}
+
 // hash returns a hash value that uniquely identifies the given FileAnnotation.
 func hash(fileAnnotation FileAnnotation) string {
 	path := ""
@@ -238,6 +410,7 @@ func hash(fileAnnotation FileAnnotation) string {
 	_, _ = hash.Write([]byte(strconv.Itoa(fileAnnotation.EndColumn())))
 	_, _ = hash.Write([]byte(fileAnnotation.Type()))
 	_, _ = hash.Write([]byte(fileAnnotation.Message()))
+	_, _ = hash.Write([]byte(fileAnnotation.Commit()))
 	return string(hash.Sum(nil))
 }
 
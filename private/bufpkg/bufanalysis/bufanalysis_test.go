@@ -0,0 +1,449 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufanalysis
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCommit = "0123456789abcdef0123456789abcdef01234567"
+
+func TestPrintFileAnnotationsSeverity(t *testing.T) {
+	t.Parallel()
+	fileAnnotations := []FileAnnotation{
+		newTestFileAnnotationWithSeverity(1, SeverityError),
+		newTestFileAnnotationWithSeverity(2, SeverityWarning),
+		newTestFileAnnotationWithSeverity(3, SeverityInfo),
+	}
+	assert.True(t, FileAnnotationsContainError(fileAnnotations))
+	assert.False(t, FileAnnotationsContainError(fileAnnotations[1:]))
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"text",
+		`path/to/file.proto:1:1:Hello.
+path/to/file.proto:2:1:warning: Hello.
+path/to/file.proto:3:1:info: Hello.
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"json",
+		`{"path":"path/to/file.proto","start_line":1,"start_column":1,"end_line":1,"end_column":1,"type":"FOO","message":"Hello."}
+{"path":"path/to/file.proto","start_line":2,"start_column":1,"end_line":2,"end_column":1,"type":"FOO","message":"Hello.","severity":"warning"}
+{"path":"path/to/file.proto","start_line":3,"start_column":1,"end_line":3,"end_column":1,"type":"FOO","message":"Hello.","severity":"info"}
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"msvs",
+		`path/to/file.proto(1,1) : error FOO : Hello.
+path/to/file.proto(2,1) : warning FOO : Hello.
+path/to/file.proto(3,1) : warning FOO : Hello.
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"junit",
+		`<testsuites>
+  <testsuite name="path/to/file" tests="3" failures="1" errors="0">
+    <testcase name="FOO_1_1">
+      <failure message="path/to/file.proto:1:1:Hello." type="FOO"></failure>
+    </testcase>
+    <testcase name="FOO_2_1">
+      <system-out>path/to/file.proto:2:1:warning: Hello.</system-out>
+    </testcase>
+    <testcase name="FOO_3_1">
+      <system-out>path/to/file.proto:3:1:info: Hello.</system-out>
+    </testcase>
+  </testsuite>
+</testsuites>
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"github-actions",
+		`::error file=path/to/file.proto,line=1,col=1,endLine=1,endColumn=1::Hello.
+::warning file=path/to/file.proto,line=2,col=1,endLine=2,endColumn=1::Hello.
+::notice file=path/to/file.proto,line=3,col=1,endLine=3,endColumn=1::Hello.
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"sarif",
+		`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "buf",
+          "informationUri": "https://github.com/bufbuild/buf",
+          "rules": [
+            {
+              "id": "FOO"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "FOO",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "Hello."
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "path/to/file.proto"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1,
+                  "endLine": 1,
+                  "endColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "FOO",
+          "ruleIndex": 0,
+          "level": "warning",
+          "message": {
+            "text": "Hello."
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "path/to/file.proto"
+                },
+                "region": {
+                  "startLine": 2,
+                  "startColumn": 1,
+                  "endLine": 2,
+                  "endColumn": 1
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "FOO",
+          "ruleIndex": 0,
+          "level": "note",
+          "message": {
+            "text": "Hello."
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "path/to/file.proto"
+                },
+                "region": {
+                  "startLine": 3,
+                  "startColumn": 1,
+                  "endLine": 3,
+                  "endColumn": 1
+                }
+              }
+            }
+          ]
+        }
+      ]
+    }
+  ]
+}
+`,
+	)
+}
+
+func TestPrintFileAnnotationsSARIF(t *testing.T) {
+	t.Parallel()
+	testPrintFileAnnotations(
+		t,
+		[]FileAnnotation{
+			NewFileAnnotation(
+				newTestFileInfo("path/to/file.proto"),
+				2,
+				1,
+				2,
+				8,
+				"FOO",
+				"Hello.",
+			),
+			NewFileAnnotation(
+				nil,
+				0,
+				0,
+				0,
+				0,
+				"BAR",
+				"Goodbye.",
+			),
+		},
+		"sarif",
+		`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "buf",
+          "informationUri": "https://github.com/bufbuild/buf",
+          "rules": [
+            {
+              "id": "FOO",
+              "shortDescription": {
+                "text": "Checks foo."
+              },
+              "properties": {
+                "tags": [
+                  "BASIC",
+                  "DEFAULT"
+                ]
+              }
+            },
+            {
+              "id": "BAR"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "FOO",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "Hello."
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "path/to/file.proto"
+                },
+                "region": {
+                  "startLine": 2,
+                  "startColumn": 1,
+                  "endLine": 2,
+                  "endColumn": 8
+                }
+              }
+            }
+          ]
+        },
+        {
+          "ruleId": "BAR",
+          "ruleIndex": 1,
+          "level": "error",
+          "message": {
+            "text": "Goodbye."
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+		PrintFileAnnotationsWithRules(
+			newTestRuleInfo("FOO", "Checks foo.", "BASIC", "DEFAULT"),
+			newTestRuleInfo("FOO", "Checks foo.", "BASIC", "DEFAULT"),
+		),
+	)
+}
+
+func TestPrintFileAnnotationsCommit(t *testing.T) {
+	t.Parallel()
+	fileAnnotations := []FileAnnotation{
+		NewFileAnnotation(
+			newTestFileInfo("path/to/file.proto"),
+			1,
+			1,
+			1,
+			1,
+			"FOO",
+			"Hello.",
+			FileAnnotationWithCommit(testCommit),
+		),
+	}
+	// The commit does not change the message.
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"text",
+		`path/to/file.proto:1:1:Hello.
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"json",
+		`{"path":"path/to/file.proto","start_line":1,"start_column":1,"end_line":1,"end_column":1,"type":"FOO","message":"Hello.","commit":"0123456789abcdef0123456789abcdef01234567"}
+`,
+	)
+	testPrintFileAnnotations(
+		t,
+		fileAnnotations,
+		"sarif",
+		`{
+  "$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+  "version": "2.1.0",
+  "runs": [
+    {
+      "tool": {
+        "driver": {
+          "name": "buf",
+          "informationUri": "https://github.com/bufbuild/buf",
+          "rules": [
+            {
+              "id": "FOO"
+            }
+          ]
+        }
+      },
+      "results": [
+        {
+          "ruleId": "FOO",
+          "ruleIndex": 0,
+          "level": "error",
+          "message": {
+            "text": "Hello."
+          },
+          "locations": [
+            {
+              "physicalLocation": {
+                "artifactLocation": {
+                  "uri": "path/to/file.proto"
+                },
+                "region": {
+                  "startLine": 1,
+                  "startColumn": 1,
+                  "endLine": 1,
+                  "endColumn": 1
+                }
+              }
+            }
+          ],
+          "properties": {
+            "commit": "0123456789abcdef0123456789abcdef01234567"
+          }
+        }
+      ]
+    }
+  ]
+}
+`,
+	)
+}
+
+func TestParseSeverity(t *testing.T) {
+	t.Parallel()
+	for _, severityString := range AllSeverityStrings {
+		severity, err := ParseSeverity(severityString)
+		require.NoError(t, err)
+		assert.Equal(t, severityString, severity.String())
+	}
+	_, err := ParseSeverity("fatal")
+	require.Error(t, err)
+}
+
+func testPrintFileAnnotations(
+	t *testing.T,
+	fileAnnotations []FileAnnotation,
+	formatString string,
+	expected string,
+	options ...PrintFileAnnotationsOption,
+) {
+	sb := &strings.Builder{}
+	err := PrintFileAnnotations(sb, fileAnnotations, formatString, options...)
+	require.NoError(t, err)
+	assert.Equal(t, expected, sb.String(), formatString)
+}
+
+func newTestFileAnnotationWithSeverity(line int, severity Severity) FileAnnotation {
+	return NewFileAnnotation(
+		newTestFileInfo("path/to/file.proto"),
+		line,
+		1,
+		line,
+		1,
+		"FOO",
+		"Hello.",
+		FileAnnotationWithSeverity(severity),
+	)
+}
+
+type testFileInfo struct {
+	path string
+}
+
+func newTestFileInfo(path string) *testFileInfo {
+	return &testFileInfo{
+		path: path,
+	}
+}
+
+func (f *testFileInfo) Path() string {
+	return f.path
+}
+
+func (f *testFileInfo) ExternalPath() string {
+	return f.path
+}
+
+type testRuleInfo struct {
+	id         string
+	purpose    string
+	categories []string
+}
+
+func newTestRuleInfo(id string, purpose string, categories ...string) *testRuleInfo {
+	return &testRuleInfo{
+		id:         id,
+		purpose:    purpose,
+		categories: categories,
+	}
+}
+
+func (r *testRuleInfo) ID() string {
+	return r.id
+}
+
+func (r *testRuleInfo) Categories() []string {
+	return r.categories
+}
+
+func (r *testRuleInfo) Purpose() string {
+	return r.purpose
+}
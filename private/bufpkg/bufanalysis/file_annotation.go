@@ -27,6 +27,9 @@ type fileAnnotation struct {
 	endColumn   int
 	typeString  string
 	message     string
+	elementPath string
+	severity    Severity
+	commit      string
 }
 
 func newFileAnnotation(
@@ -46,6 +49,7 @@ func newFileAnnotation(
 		endColumn:   endColumn,
 		typeString:  typeString,
 		message:     message,
+		severity:    SeverityError,
 	}
 }
 
@@ -77,6 +81,18 @@ func (f *fileAnnotation) Message() string {
 	return f.message
 }
 
+func (f *fileAnnotation) ElementPath() string {
+	return f.elementPath
+}
+
+func (f *fileAnnotation) Severity() Severity {
+	return f.severity
+}
+
+func (f *fileAnnotation) Commit() string {
+	return f.commit
+}
+
 func (f *fileAnnotation) String() string {
 	if f == nil {
 		return ""
@@ -102,6 +118,12 @@ func (f *fileAnnotation) String() string {
 	_, _ = buffer.WriteRune(':')
 	_, _ = buffer.WriteString(strconv.Itoa(column))
 	_, _ = buffer.WriteRune(':')
+	// We only print the severity if it is not the default, to keep
+	// the output the same as it was before severities were added.
+	if f.severity != SeverityError {
+		_, _ = buffer.WriteString(f.severity.String())
+		_, _ = buffer.WriteString(": ")
+	}
 	_, _ = buffer.WriteString(message)
 	return buffer.String()
 }
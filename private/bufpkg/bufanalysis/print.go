@@ -20,10 +20,19 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 )
 
+const (
+	sarifSchema         = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion        = "2.1.0"
+	sarifToolName       = "buf"
+	sarifInformationURI = "https://github.com/bufbuild/buf"
+)
+
 func printAsText(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	return printEachAnnotationOnNewLine(
 		writer,
@@ -76,7 +85,7 @@ func printAsJUnit(writer io.Writer, fileAnnotations []FileAnnotation) error {
 			Attr: []xml.Attr{
 				{Name: xml.Name{Local: "name"}, Value: path},
 				{Name: xml.Name{Local: "tests"}, Value: strconv.Itoa(len(annotations))},
-				{Name: xml.Name{Local: "failures"}, Value: strconv.Itoa(len(annotations))},
+				{Name: xml.Name{Local: "failures"}, Value: strconv.Itoa(numErrors(annotations))},
 				{Name: xml.Name{Local: "errors"}, Value: "0"},
 			},
 		}
@@ -104,6 +113,62 @@ func printAsJUnit(writer io.Writer, fileAnnotations []FileAnnotation) error {
 	return nil
 }
 
+func printAsSARIF(writer io.Writer, fileAnnotations []FileAnnotation, rules []RuleInfo) error {
+	sarifRules := make([]*sarifReportingDescriptor, 0, len(rules))
+	ruleIDToIndex := make(map[string]int, len(rules))
+	for _, rule := range sarifSortedUniqueRules(rules) {
+		ruleIDToIndex[rule.ID()] = len(sarifRules)
+		sarifRules = append(sarifRules, newSARIFReportingDescriptor(rule))
+	}
+	sarifResults := make([]*sarifResult, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		if fileAnnotation == nil {
+			continue
+		}
+		typeString := fileAnnotation.Type()
+		if typeString == "" {
+			// should never happen but just in case
+			typeString = "FAILURE"
+		}
+		ruleIndex, ok := ruleIDToIndex[typeString]
+		if !ok {
+			// The type is not a known rule, for example a compilation failure.
+			// We still add a descriptor so that every result references a rule.
+			ruleIndex = len(sarifRules)
+			ruleIDToIndex[typeString] = ruleIndex
+			sarifRules = append(sarifRules, &sarifReportingDescriptor{ID: typeString})
+		}
+		sarifResults = append(sarifResults, newSARIFResult(fileAnnotation, typeString, ruleIndex))
+	}
+	data, err := json.MarshalIndent(
+		&sarifLog{
+			Schema:  sarifSchema,
+			Version: sarifVersion,
+			Runs: []*sarifRun{
+				{
+					Tool: &sarifTool{
+						Driver: &sarifToolComponent{
+							Name:           sarifToolName,
+							InformationURI: sarifInformationURI,
+							Rules:          sarifRules,
+						},
+					},
+					Results: sarifResults,
+				},
+			},
+		},
+		"",
+		"  ",
+	)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
 func printFileAnnotationAsJUnit(encoder *xml.Encoder, annotation FileAnnotation) error {
 	testcase := xml.StartElement{Name: xml.Name{Local: "testcase"}}
 	name := annotation.Type()
@@ -116,18 +181,26 @@ func printFileAnnotationAsJUnit(encoder *xml.Encoder, annotation FileAnnotation)
 	if err := encoder.EncodeToken(testcase); err != nil {
 		return err
 	}
-	failure := xml.StartElement{
-		Name: xml.Name{Local: "failure"},
-		Attr: []xml.Attr{
-			{Name: xml.Name{Local: "message"}, Value: annotation.String()},
-			{Name: xml.Name{Local: "type"}, Value: annotation.Type()},
-		},
-	}
-	if err := encoder.EncodeToken(failure); err != nil {
-		return err
-	}
-	if err := encoder.EncodeToken(xml.EndElement{Name: failure.Name}); err != nil {
-		return err
+	if annotation.Severity() == SeverityError {
+		failure := xml.StartElement{
+			Name: xml.Name{Local: "failure"},
+			Attr: []xml.Attr{
+				{Name: xml.Name{Local: "message"}, Value: annotation.String()},
+				{Name: xml.Name{Local: "type"}, Value: annotation.Type()},
+			},
+		}
+		if err := encoder.EncodeToken(failure); err != nil {
+			return err
+		}
+		if err := encoder.EncodeToken(xml.EndElement{Name: failure.Name}); err != nil {
+			return err
+		}
+	} else {
+		// JUnit has no notion of warnings, so annotations that are not errors
+		// are passing test cases with the annotation written to system-out.
+		if err := encoder.EncodeElement(annotation.String(), xml.StartElement{Name: xml.Name{Local: "system-out"}}); err != nil {
+			return err
+		}
 	}
 	if err := encoder.EncodeToken(xml.EndElement{Name: testcase.Name}); err != nil {
 		return err
@@ -135,6 +208,16 @@ func printFileAnnotationAsJUnit(encoder *xml.Encoder, annotation FileAnnotation)
 	return nil
 }
 
+func numErrors(annotations []FileAnnotation) int {
+	var count int
+	for _, annotation := range annotations {
+		if annotation.Severity() == SeverityError {
+			count++
+		}
+	}
+	return count
+}
+
 func groupAnnotationsByPath(annotations []FileAnnotation) [][]FileAnnotation {
 	pathToIndex := make(map[string]int)
 	annotationsByPath := make([][]FileAnnotation, 0)
@@ -190,7 +273,13 @@ func printFileAnnotationAsMSVS(buffer *bytes.Buffer, f FileAnnotation) error {
 		_, _ = buffer.WriteRune(',')
 		_, _ = buffer.WriteString(strconv.Itoa(column))
 	}
-	_, _ = buffer.WriteString(") : error ")
+	_, _ = buffer.WriteString(") : ")
+	// MSVS only has the error and warning categories.
+	if f.Severity() == SeverityError {
+		_, _ = buffer.WriteString("error ")
+	} else {
+		_, _ = buffer.WriteString("warning ")
+	}
 	_, _ = buffer.WriteString(typeString)
 	_, _ = buffer.WriteString(" : ")
 	_, _ = buffer.WriteString(message)
@@ -210,7 +299,14 @@ func printFileAnnotationAsGithubActions(buffer *bytes.Buffer, f FileAnnotation)
 	if f == nil {
 		return nil
 	}
-	_, _ = buffer.WriteString("::error ")
+	switch f.Severity() {
+	case SeverityWarning:
+		_, _ = buffer.WriteString("::warning ")
+	case SeverityInfo:
+		_, _ = buffer.WriteString("::notice ")
+	default:
+		_, _ = buffer.WriteString("::error ")
+	}
 
 	// file= is required for GitHub Actions, however it is possible to not have
 	// a path for a FileAnnotation. We still print something, however we need
@@ -257,6 +353,9 @@ type externalFileAnnotation struct {
 	EndColumn   int    `json:"end_column,omitempty" yaml:"end_column,omitempty"`
 	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
 	Message     string `json:"message,omitempty" yaml:"message,omitempty"`
+	// Severity is omitted for errors, which are the default.
+	Severity string `json:"severity,omitempty" yaml:"severity,omitempty"`
+	Commit   string `json:"commit,omitempty" yaml:"commit,omitempty"`
 }
 
 func newExternalFileAnnotation(f FileAnnotation) externalFileAnnotation {
@@ -264,6 +363,10 @@ func newExternalFileAnnotation(f FileAnnotation) externalFileAnnotation {
 	if f.FileInfo() != nil {
 		path = f.FileInfo().ExternalPath()
 	}
+	var severity string
+	if f.Severity() != SeverityError {
+		severity = f.Severity().String()
+	}
 	return externalFileAnnotation{
 		Path:        path,
 		StartLine:   atLeast1(f.StartLine()),
@@ -272,9 +375,178 @@ func newExternalFileAnnotation(f FileAnnotation) externalFileAnnotation {
 		EndColumn:   atLeast1(f.EndColumn()),
 		Type:        f.Type(),
 		Message:     f.Message(),
+		Severity:    severity,
+		Commit:      f.Commit(),
 	}
 }
 
+// sarifLog is the top-level SARIF object.
+//
+// We only implement the subset of SARIF 2.1.0 that is needed to represent FileAnnotations.
+type sarifLog struct {
+	Schema  string      `json:"$schema"`
+	Version string      `json:"version"`
+	Runs    []*sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    *sarifTool     `json:"tool"`
+	Results []*sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver *sarifToolComponent `json:"driver"`
+}
+
+type sarifToolComponent struct {
+	Name           string                      `json:"name"`
+	InformationURI string                      `json:"informationUri,omitempty"`
+	Rules          []*sarifReportingDescriptor `json:"rules"`
+}
+
+type sarifReportingDescriptor struct {
+	ID               string                   `json:"id"`
+	ShortDescription *sarifMessage            `json:"shortDescription,omitempty"`
+	Properties       *sarifDescriptorProperty `json:"properties,omitempty"`
+}
+
+type sarifDescriptorProperty struct {
+	Tags []string `json:"tags,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string               `json:"ruleId"`
+	RuleIndex  int                  `json:"ruleIndex"`
+	Level      string               `json:"level"`
+	Message    *sarifMessage        `json:"message"`
+	Locations  []*sarifLocation     `json:"locations,omitempty"`
+	Properties *sarifResultProperty `json:"properties,omitempty"`
+}
+
+// sarifResultProperty is the property bag of a result, which carries the
+// fields of FileAnnotations that SARIF has no field for.
+type sarifResultProperty struct {
+	Commit string `json:"commit,omitempty"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation *sarifArtifactLocation `json:"artifactLocation"`
+	Region           *sarifRegion           `json:"region,omitempty"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine,omitempty"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+	EndColumn   int `json:"endColumn,omitempty"`
+}
+
+func newSARIFReportingDescriptor(rule RuleInfo) *sarifReportingDescriptor {
+	reportingDescriptor := &sarifReportingDescriptor{
+		ID: rule.ID(),
+	}
+	if purpose := rule.Purpose(); purpose != "" {
+		reportingDescriptor.ShortDescription = &sarifMessage{
+			Text: purpose,
+		}
+	}
+	if categories := rule.Categories(); len(categories) > 0 {
+		reportingDescriptor.Properties = &sarifDescriptorProperty{
+			Tags: categories,
+		}
+	}
+	return reportingDescriptor
+}
+
+func sarifLevel(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityInfo:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func newSARIFResult(f FileAnnotation, typeString string, ruleIndex int) *sarifResult {
+	message := f.Message()
+	if message == "" {
+		// should never happen but just in case
+		message = typeString
+	}
+	result := &sarifResult{
+		RuleID:    typeString,
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(f.Severity()),
+		Message: &sarifMessage{
+			Text: message,
+		},
+	}
+	if commit := f.Commit(); commit != "" {
+		result.Properties = &sarifResultProperty{
+			Commit: commit,
+		}
+	}
+	// A location without an artifact is not useful to SARIF consumers,
+	// so we only add a location if we have a path.
+	if f.FileInfo() == nil {
+		return result
+	}
+	physicalLocation := &sarifPhysicalLocation{
+		ArtifactLocation: &sarifArtifactLocation{
+			URI: filepath.ToSlash(f.FileInfo().ExternalPath()),
+		},
+	}
+	// We only do any region information if we have starting line information.
+	if startLine := f.StartLine(); startLine > 0 {
+		physicalLocation.Region = &sarifRegion{
+			StartLine:   startLine,
+			StartColumn: f.StartColumn(),
+			EndLine:     f.EndLine(),
+			EndColumn:   f.EndColumn(),
+		}
+	}
+	result.Locations = []*sarifLocation{
+		{
+			PhysicalLocation: physicalLocation,
+		},
+	}
+	return result
+}
+
+// sarifSortedUniqueRules returns the rules deduplicated by ID and sorted by ID.
+func sarifSortedUniqueRules(rules []RuleInfo) []RuleInfo {
+	uniqueRules := make([]RuleInfo, 0, len(rules))
+	seen := make(map[string]struct{}, len(rules))
+	for _, rule := range rules {
+		if _, ok := seen[rule.ID()]; ok {
+			continue
+		}
+		seen[rule.ID()] = struct{}{}
+		uniqueRules = append(uniqueRules, rule)
+	}
+	sort.Slice(
+		uniqueRules,
+		func(i int, j int) bool {
+			return uniqueRules[i].ID() < uniqueRules[j].ID()
+		},
+	)
+	return uniqueRules
+}
+
 func printEachAnnotationOnNewLine(
 	writer io.Writer,
 	fileAnnotations []FileAnnotation,
@@ -25,9 +25,11 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/internal/bufbreakingv1"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/internal/bufbreakingv1beta1"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
@@ -45,6 +47,14 @@ type Handler interface {
 		previousImage bufimage.Image,
 		image bufimage.Image,
 	) ([]bufanalysis.FileAnnotation, error)
+	// Rules returns the rules for the config.
+	//
+	// The plugins of the config are run to list their rules. Plugins are kept by the
+	// Handler, so that they only list their rules and compile once across calls to
+	// Rules and Check.
+	Rules(ctx context.Context, config *bufbreakingconfig.Config) ([]bufcheck.Rule, error)
+	// Close closes the plugins kept by the Handler.
+	Close() error
 }
 
 // NewHandler returns a new Handler.
@@ -54,13 +64,15 @@ func NewHandler(logger *zap.Logger) Handler {
 
 // RulesForConfig returns the rules for a given config.
 //
+// This includes the rules of the plugins of the config, which are run to list their rules.
+//
 // Should only be used for printing.
-func RulesForConfig(config *bufbreakingconfig.Config) ([]bufcheck.Rule, error) {
-	internalConfig, err := internalConfigForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return rulesForInternalRules(internalConfig.Rules), nil
+func RulesForConfig(ctx context.Context, config *bufbreakingconfig.Config) (_ []bufcheck.Rule, retErr error) {
+	handler := newHandler(zap.NewNop())
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
+	return handler.Rules(ctx, config)
 }
 
 // GetAllRulesV1Beta1 gets all known rules.
@@ -68,10 +80,14 @@ func RulesForConfig(config *bufbreakingconfig.Config) ([]bufcheck.Rule, error) {
 // Should only be used for printing.
 func GetAllRulesV1Beta1() ([]bufcheck.Rule, error) {
 	internalConfig, err := internalConfigForConfig(
+		context.Background(),
+		nil,
 		&bufbreakingconfig.Config{
 			Use:     internal.AllIDsForVersionSpec(bufbreakingv1beta1.VersionSpec),
 			Version: bufconfig.V1Beta1Version,
 		},
+		nil,
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -84,10 +100,14 @@ func GetAllRulesV1Beta1() ([]bufcheck.Rule, error) {
 // Should only be used for printing.
 func GetAllRulesV1() ([]bufcheck.Rule, error) {
 	internalConfig, err := internalConfigForConfig(
+		context.Background(),
+		nil,
 		&bufbreakingconfig.Config{
 			Use:     internal.AllIDsForVersionSpec(bufbreakingv1.VersionSpec),
 			Version: bufconfig.V1Version,
 		},
+		nil,
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -109,7 +129,18 @@ func GetAllRulesAndCategoriesV1() []string {
 	return internal.AllCategoriesAndIDsForVersionSpec(bufbreakingv1.VersionSpec)
 }
 
-func internalConfigForConfig(config *bufbreakingconfig.Config) (*internal.Config, error) {
+// internalConfigForConfig returns the internal Config for the Config.
+//
+// The plugins of the config are returned by getPlugin, and are run to list their rules.
+// If image and previousImage are non-nil, the plugin rules will check the image against
+// previousImage when run, otherwise the config should only be used for printing.
+func internalConfigForConfig(
+	ctx context.Context,
+	getPlugin func(*bufbreakingconfig.PluginConfig) (bufcheckplugin.Plugin, error),
+	config *bufbreakingconfig.Config,
+	previousImage bufimage.Image,
+	image bufimage.Image,
+) (*internal.Config, error) {
 	var versionSpec *internal.VersionSpec
 	switch config.Version {
 	case bufconfig.V1Beta1Version:
@@ -117,15 +148,54 @@ func internalConfigForConfig(config *bufbreakingconfig.Config) (*internal.Config
 	case bufconfig.V1Version:
 		versionSpec = bufbreakingv1.VersionSpec
 	}
-	return internal.ConfigBuilder{
+	var pluginRuleBuilders []*internal.RuleBuilder
+	pluginIDToCategories := make(map[string][]string)
+	idToPluginChecker := make(map[string]*internal.PluginChecker)
+	for _, pluginConfig := range config.Plugins {
+		plugin, err := getPlugin(pluginConfig)
+		if err != nil {
+			return nil, err
+		}
+		pluginRules, err := plugin.Rules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pluginChecker := internal.NewPluginChecker(plugin, image, previousImage)
+		for _, pluginRule := range pluginRules {
+			pluginRuleBuilders = append(
+				pluginRuleBuilders,
+				internal.NewPluginRuleBuilder(
+					pluginRule.ID,
+					pluginRule.Purpose,
+					pluginChecker.FileAnnotations,
+				),
+			)
+			pluginIDToCategories[pluginRule.ID] = pluginRule.Categories
+			idToPluginChecker[pluginRule.ID] = pluginChecker
+		}
+	}
+	internalConfig, err := internal.ConfigBuilder{
 		Use:                           config.Use,
 		Except:                        config.Except,
 		IgnoreRootPaths:               config.IgnoreRootPaths,
 		IgnoreIDOrCategoryToRootPaths: config.IgnoreIDOrCategoryToRootPaths,
 		IgnoreUnstablePackages:        config.IgnoreUnstablePackages,
+		PluginRuleBuilders:            pluginRuleBuilders,
+		PluginIDToCategories:          pluginIDToCategories,
+		IDOrCategoryToSeverity:        config.IDOrCategoryToSeverity,
 	}.NewConfig(
 		versionSpec,
 	)
+	if err != nil {
+		return nil, err
+	}
+	// Plugins only run the rules that were selected by the config.
+	for _, rule := range internalConfig.Rules {
+		if pluginChecker, ok := idToPluginChecker[rule.ID()]; ok {
+			pluginChecker.AddRuleID(rule.ID())
+		}
+	}
+	return internalConfig, nil
 }
 
 func rulesForInternalRules(rules []*internal.Rule) []bufcheck.Rule {
@@ -48,6 +48,29 @@ type Config struct {
 	IgnoreUnstablePackages bool
 	// Version represents the version of the breaking change rule and category IDs that should be used with this config.
 	Version string
+	// Plugins are the plugins that provide additional breaking change rules.
+	//
+	// The rules of plugins can be selected with Use and Except like built-in rules. Plugins
+	// refer to local executables, so they are not part of the proto representation of the Config.
+	Plugins []*PluginConfig
+	// IDOrCategoryToSeverity is a map of rule and/or category IDs to the severity of their failures.
+	//
+	// Valid severities are error, warning, and info. Only failures with the error severity
+	// fail the breaking change check. Rules without a severity default to error.
+	//
+	// Severities only affect how failures are reported, so they are not part of the proto
+	// representation of the Config.
+	IDOrCategoryToSeverity map[string]string
+}
+
+// PluginConfig is the config for a breaking plugin.
+type PluginConfig struct {
+	// Plugin is the name of the plugin on the PATH, or the path to the plugin.
+	//
+	// Plugins with a path ending in .wasm are run as WASM modules.
+	Plugin string
+	// Options are passed to the plugin.
+	Options map[string]string
 }
 
 // NewConfigV1Beta1 returns a new Config.
@@ -71,6 +94,8 @@ func NewConfigV1(externalConfig ExternalConfigV1) *Config {
 		IgnoreIDOrCategoryToRootPaths: externalConfig.IgnoreOnly,
 		IgnoreUnstablePackages:        externalConfig.IgnoreUnstablePackages,
 		Version:                       v1Version,
+		Plugins:                       pluginConfigsForExternal(externalConfig.Plugins),
+		IDOrCategoryToSeverity:        externalConfig.Severity,
 	}
 }
 
@@ -116,8 +141,17 @@ type ExternalConfigV1 struct {
 	// IgnoreRootPaths
 	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
 	// IgnoreIDOrCategoryToRootPaths
-	IgnoreOnly             map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
-	IgnoreUnstablePackages bool                `json:"ignore_unstable_packages,omitempty" yaml:"ignore_unstable_packages,omitempty"`
+	IgnoreOnly             map[string][]string      `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
+	IgnoreUnstablePackages bool                     `json:"ignore_unstable_packages,omitempty" yaml:"ignore_unstable_packages,omitempty"`
+	Plugins                []ExternalPluginConfigV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// IDOrCategoryToSeverity
+	Severity map[string]string `json:"severity,omitempty" yaml:"severity,omitempty"`
+}
+
+// ExternalPluginConfigV1 is an external plugin config.
+type ExternalPluginConfigV1 struct {
+	Plugin  string            `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
 }
 
 // ExternalConfigV1Beta1ForConfig takes a *Config and returns the v1beta1 external config representation.
@@ -139,6 +173,20 @@ func ExternalConfigV1ForConfig(config *Config) ExternalConfigV1 {
 		Ignore:                 config.IgnoreRootPaths,
 		IgnoreOnly:             config.IgnoreIDOrCategoryToRootPaths,
 		IgnoreUnstablePackages: config.IgnoreUnstablePackages,
+		Plugins:                externalPluginConfigsForPluginConfigs(config.Plugins),
+		Severity:               config.IDOrCategoryToSeverity,
+	}
+}
+
+// ExternalConfigV1ForExternalConfigV1Beta1 takes a v1beta1 external config and returns
+// the equivalent v1 external config.
+func ExternalConfigV1ForExternalConfigV1Beta1(externalConfig ExternalConfigV1Beta1) ExternalConfigV1 {
+	return ExternalConfigV1{
+		Use:                    externalConfig.Use,
+		Except:                 externalConfig.Except,
+		Ignore:                 externalConfig.Ignore,
+		IgnoreOnly:             externalConfig.IgnoreOnly,
+		IgnoreUnstablePackages: externalConfig.IgnoreUnstablePackages,
 	}
 }
 
@@ -199,6 +247,34 @@ func configToJSON(config *Config) *configJSON {
 	}
 }
 
+func pluginConfigsForExternal(externalPluginConfigs []ExternalPluginConfigV1) []*PluginConfig {
+	if len(externalPluginConfigs) == 0 {
+		return nil
+	}
+	pluginConfigs := make([]*PluginConfig, len(externalPluginConfigs))
+	for i, externalPluginConfig := range externalPluginConfigs {
+		pluginConfigs[i] = &PluginConfig{
+			Plugin:  externalPluginConfig.Plugin,
+			Options: externalPluginConfig.Options,
+		}
+	}
+	return pluginConfigs
+}
+
+func externalPluginConfigsForPluginConfigs(pluginConfigs []*PluginConfig) []ExternalPluginConfigV1 {
+	if len(pluginConfigs) == 0 {
+		return nil
+	}
+	externalPluginConfigs := make([]ExternalPluginConfigV1, len(pluginConfigs))
+	for i, pluginConfig := range pluginConfigs {
+		externalPluginConfigs[i] = ExternalPluginConfigV1{
+			Plugin:  pluginConfig.Plugin,
+			Options: pluginConfig.Options,
+		}
+	}
+	return externalPluginConfigs
+}
+
 func ignoreIDOrCategoryToRootPathsForProto(protoIgnoreIDPaths []*breakingv1.IDPaths) map[string][]string {
 	if protoIgnoreIDPaths == nil {
 		return nil
@@ -16,19 +16,31 @@ package bufbreaking
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/protosource"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
 type handler struct {
 	logger *zap.Logger
 	runner *internal.Runner
+	// commandRunner runs breaking plugins.
+	commandRunner command.Runner
+
+	pluginsLock sync.Mutex
+	// keyToPlugin are the plugins run by the handler, by their path and options.
+	keyToPlugin map[string]bufcheckplugin.Plugin
 }
 
 func newHandler(
@@ -38,7 +50,9 @@ func newHandler(
 		logger: logger,
 		// comment ignores are not allowed for breaking changes
 		// so do not set the ignore prefix per the RunnerWithIgnorePrefix comments
-		runner: internal.NewRunner(logger),
+		runner:        internal.NewRunner(logger),
+		commandRunner: command.NewRunner(),
+		keyToPlugin:   make(map[string]bufcheckplugin.Plugin),
 	}
 }
 
@@ -56,9 +70,51 @@ func (h *handler) Check(
 	if err != nil {
 		return nil, err
 	}
-	internalConfig, err := internalConfigForConfig(config)
+	internalConfig, err := internalConfigForConfig(ctx, h.getPlugin, config, previousImage, image)
 	if err != nil {
 		return nil, err
 	}
 	return h.runner.Check(ctx, internalConfig, previousFiles, files)
 }
+
+func (h *handler) Rules(ctx context.Context, config *bufbreakingconfig.Config) ([]bufcheck.Rule, error) {
+	internalConfig, err := internalConfigForConfig(ctx, h.getPlugin, config, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rulesForInternalRules(internalConfig.Rules), nil
+}
+
+func (h *handler) Close() error {
+	h.pluginsLock.Lock()
+	defer h.pluginsLock.Unlock()
+	var err error
+	for key, plugin := range h.keyToPlugin {
+		err = multierr.Append(err, plugin.Close())
+		delete(h.keyToPlugin, key)
+	}
+	return err
+}
+
+// getPlugin returns the plugin for the config, creating it on first use.
+func (h *handler) getPlugin(pluginConfig *bufbreakingconfig.PluginConfig) (bufcheckplugin.Plugin, error) {
+	// The options are a map, which encoding/json marshals with sorted keys.
+	keyData, err := json.Marshal(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	key := string(keyData)
+	h.pluginsLock.Lock()
+	defer h.pluginsLock.Unlock()
+	plugin, ok := h.keyToPlugin[key]
+	if !ok {
+		plugin = bufcheckplugin.NewPlugin(
+			h.commandRunner,
+			bufcheckplugin.CheckTypeBreaking,
+			pluginConfig.Plugin,
+			pluginConfig.Options,
+		)
+		h.keyToPlugin[key] = plugin
+	}
+	return plugin, nil
+}
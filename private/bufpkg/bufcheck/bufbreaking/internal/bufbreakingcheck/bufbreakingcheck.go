@@ -23,6 +23,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/internal/bufpair"
 	"github.com/bufbuild/buf/private/pkg/protodescriptor"
 	"github.com/bufbuild/buf/private/pkg/protosource"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
@@ -76,16 +77,19 @@ func checkEnumValueNoDeleteUnlessNameReserved(add addFunc, corpus *corpus, previ
 }
 
 func checkEnumValueNoDeleteWithRules(add addFunc, previousEnum protosource.Enum, enum protosource.Enum, allowIfNumberReserved bool, allowIfNameReserved bool) error {
-	previousNumberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(previousEnum)
-	if err != nil {
-		return err
-	}
-	numberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(enum)
-	if err != nil {
-		return err
-	}
-	for previousNumber, previousNameToEnumValue := range previousNumberToNameToEnumValue {
-		if _, ok := numberToNameToEnumValue[previousNumber]; !ok {
+	return bufpair.EnumValuesByNumber(
+		previousEnum,
+		enum,
+		func(previousNameToEnumValue map[string]protosource.EnumValue, nameToEnumValue map[string]protosource.EnumValue) error {
+			if previousNameToEnumValue == nil || nameToEnumValue != nil {
+				return nil
+			}
+			// All enum values in previousNameToEnumValue share the same number.
+			var previousNumber int
+			for _, previousEnumValue := range previousNameToEnumValue {
+				previousNumber = previousEnumValue.Number()
+				break
+			}
 			if !isDeletedEnumValueAllowedWithRules(previousNumber, previousNameToEnumValue, enum, allowIfNumberReserved, allowIfNameReserved) {
 				suffix := ""
 				if allowIfNumberReserved && allowIfNameReserved {
@@ -103,9 +107,9 @@ func checkEnumValueNoDeleteWithRules(add addFunc, previousEnum protosource.Enum,
 				}
 				add(enum, nil, enum.Location(), `Previously present enum value "%d" on enum %q was deleted%s.`, previousNumber, enum.Name(), suffix)
 			}
-		}
-	}
-	return nil
+			return nil
+		},
+	)
 }
 
 func isDeletedEnumValueAllowedWithRules(previousNumber int, previousNameToEnumValue map[string]protosource.EnumValue, enum protosource.Enum, allowIfNumberReserved bool, allowIfNameReserved bool) bool {
@@ -184,19 +188,16 @@ func checkFieldNoDeleteUnlessNameReserved(add addFunc, corpus *corpus, previousM
 }
 
 func checkFieldNoDeleteWithRules(add addFunc, previousMessage protosource.Message, message protosource.Message, allowIfNumberReserved bool, allowIfNameReserved bool) error {
-	previousNumberToField, err := protosource.NumberToMessageField(previousMessage)
-	if err != nil {
-		return err
-	}
-	numberToField, err := protosource.NumberToMessageField(message)
-	if err != nil {
-		return err
-	}
-	for previousNumber, previousField := range previousNumberToField {
-		if _, ok := numberToField[previousNumber]; !ok {
+	return bufpair.MessageFields(
+		previousMessage,
+		message,
+		func(previousField protosource.Field, field protosource.Field) error {
+			if previousField == nil || field != nil {
+				return nil
+			}
 			if !isDeletedFieldAllowedWithRules(previousField, message, allowIfNumberReserved, allowIfNameReserved) {
 				// otherwise prints as hex
-				previousNumberString := strconv.FormatInt(int64(previousNumber), 10)
+				previousNumberString := strconv.FormatInt(int64(previousField.Number()), 10)
 				suffix := ""
 				if allowIfNumberReserved && allowIfNameReserved {
 					return errors.New("both allowIfNumberReserved and allowIfNameReserved set")
@@ -209,9 +210,9 @@ func checkFieldNoDeleteWithRules(add addFunc, previousMessage protosource.Messag
 				}
 				add(message, nil, message.Location(), `Previously present field %q with name %q on message %q was deleted%s.`, previousNumberString, previousField.Name(), message.Name(), suffix)
 			}
-		}
-	}
-	return nil
+			return nil
+		},
+	)
 }
 
 func isDeletedFieldAllowedWithRules(previousField protosource.Field, message protosource.Message, allowIfNumberReserved bool, allowIfNameReserved bool) bool {
@@ -519,23 +520,19 @@ func addEnumGroupMessageFieldChangedTypeName(add addFunc, previousField protosou
 var CheckFileNoDelete = newFilesCheckFunc(checkFileNoDelete)
 
 func checkFileNoDelete(add addFunc, corpus *corpus) error {
-	previousFilePathToFile, err := protosource.FilePathToFile(corpus.previousFiles...)
-	if err != nil {
-		return err
-	}
-	filePathToFile, err := protosource.FilePathToFile(corpus.files...)
-	if err != nil {
-		return err
-	}
-	for previousFilePath, previousFile := range previousFilePathToFile {
-		if _, ok := filePathToFile[previousFilePath]; !ok {
-			// Add previous descriptor to check for ignores. This will mean that if
-			// we have ignore_unstable_packages set, this file will cause the ignore
-			// to happen.
-			add(nil, []protosource.Descriptor{previousFile}, nil, `Previously present file %q was deleted.`, previousFilePath)
-		}
-	}
-	return nil
+	return bufpair.Files(
+		corpus.previousFiles,
+		corpus.files,
+		func(previousFile protosource.File, file protosource.File) error {
+			if previousFile != nil && file == nil {
+				// Add previous descriptor to check for ignores. This will mean that if
+				// we have ignore_unstable_packages set, this file will cause the ignore
+				// to happen.
+				add(nil, []protosource.Descriptor{previousFile}, nil, `Previously present file %q was deleted.`, previousFile.Path())
+			}
+			return nil
+		},
+	)
 }
 
 // CheckFileSameCsharpNamespace is a check function.
@@ -775,20 +772,16 @@ func checkMessageSameRequiredFields(add addFunc, corpus *corpus, previousMessage
 var CheckOneofNoDelete = newMessagePairCheckFunc(checkOneofNoDelete)
 
 func checkOneofNoDelete(add addFunc, corpus *corpus, previousMessage protosource.Message, message protosource.Message) error {
-	previousNameToOneof, err := protosource.NameToMessageOneof(previousMessage)
-	if err != nil {
-		return err
-	}
-	nameToOneof, err := protosource.NameToMessageOneof(message)
-	if err != nil {
-		return err
-	}
-	for previousName := range previousNameToOneof {
-		if _, ok := nameToOneof[previousName]; !ok {
-			add(message, nil, message.Location(), `Previously present oneof %q on message %q was deleted.`, previousName, message.Name())
-		}
-	}
-	return nil
+	return bufpair.MessageOneofs(
+		previousMessage,
+		message,
+		func(previousOneof protosource.Oneof, oneof protosource.Oneof) error {
+			if previousOneof != nil && oneof == nil {
+				add(message, nil, message.Location(), `Previously present oneof %q on message %q was deleted.`, previousOneof.Name(), message.Name())
+			}
+			return nil
+		},
+	)
 }
 
 // CheckPackageEnumNoDelete is a check function.
@@ -1004,20 +997,16 @@ func checkReservedMessageNoDelete(add addFunc, corpus *corpus, previousMessage p
 var CheckRPCNoDelete = newServicePairCheckFunc(checkRPCNoDelete)
 
 func checkRPCNoDelete(add addFunc, corpus *corpus, previousService protosource.Service, service protosource.Service) error {
-	previousNameToMethod, err := protosource.NameToMethod(previousService)
-	if err != nil {
-		return err
-	}
-	nameToMethod, err := protosource.NameToMethod(service)
-	if err != nil {
-		return err
-	}
-	for previousName := range previousNameToMethod {
-		if _, ok := nameToMethod[previousName]; !ok {
-			add(service, nil, service.Location(), `Previously present RPC %q on service %q was deleted.`, previousName, service.Name())
-		}
-	}
-	return nil
+	return bufpair.Methods(
+		previousService,
+		service,
+		func(previousMethod protosource.Method, method protosource.Method) error {
+			if previousMethod != nil && method == nil {
+				add(service, nil, service.Location(), `Previously present RPC %q on service %q was deleted.`, previousMethod.Name(), service.Name())
+			}
+			return nil
+		},
+	)
 }
 
 // CheckRPCSameClientStreaming is a check function.
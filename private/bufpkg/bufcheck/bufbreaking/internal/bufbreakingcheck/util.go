@@ -21,6 +21,7 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/internal"
+	"github.com/bufbuild/buf/private/bufpkg/internal/bufpair"
 	"github.com/bufbuild/buf/private/pkg/protosource"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -124,22 +125,16 @@ func newFilePairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
-			previousFilePathToFile, err := protosource.FilePathToFile(corpus.previousFiles...)
-			if err != nil {
-				return err
-			}
-			filePathToFile, err := protosource.FilePathToFile(corpus.files...)
-			if err != nil {
-				return err
-			}
-			for previousFilePath, previousFile := range previousFilePathToFile {
-				if file, ok := filePathToFile[previousFilePath]; ok {
-					if err := f(add, corpus, previousFile, file); err != nil {
-						return err
+			return bufpair.Files(
+				corpus.previousFiles,
+				corpus.files,
+				func(previousFile protosource.File, file protosource.File) error {
+					if previousFile == nil || file == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousFile, file)
+				},
+			)
 		},
 	)
 }
@@ -149,22 +144,16 @@ func newEnumPairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
-			previousFullNameToEnum, err := protosource.FullNameToEnum(corpus.previousFiles...)
-			if err != nil {
-				return err
-			}
-			fullNameToEnum, err := protosource.FullNameToEnum(corpus.files...)
-			if err != nil {
-				return err
-			}
-			for previousFullName, previousEnum := range previousFullNameToEnum {
-				if enum, ok := fullNameToEnum[previousFullName]; ok {
-					if err := f(add, corpus, previousEnum, enum); err != nil {
-						return err
+			return bufpair.Enums(
+				corpus.previousFiles,
+				corpus.files,
+				func(previousEnum protosource.Enum, enum protosource.Enum) error {
+					if previousEnum == nil || enum == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousEnum, enum)
+				},
+			)
 		},
 	)
 }
@@ -176,22 +165,16 @@ func newEnumValuePairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newEnumPairCheckFunc(
 		func(add addFunc, corpus *corpus, previousEnum protosource.Enum, enum protosource.Enum) error {
-			previousNumberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(previousEnum)
-			if err != nil {
-				return err
-			}
-			numberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(enum)
-			if err != nil {
-				return err
-			}
-			for previousNumber, previousNameToEnumValue := range previousNumberToNameToEnumValue {
-				if nameToEnumValue, ok := numberToNameToEnumValue[previousNumber]; ok {
-					if err := f(add, corpus, previousNameToEnumValue, nameToEnumValue); err != nil {
-						return err
+			return bufpair.EnumValuesByNumber(
+				previousEnum,
+				enum,
+				func(previousNameToEnumValue map[string]protosource.EnumValue, nameToEnumValue map[string]protosource.EnumValue) error {
+					if previousNameToEnumValue == nil || nameToEnumValue == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousNameToEnumValue, nameToEnumValue)
+				},
+			)
 		},
 	)
 }
@@ -201,22 +184,16 @@ func newMessagePairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
-			previousFullNameToMessage, err := protosource.FullNameToMessage(corpus.previousFiles...)
-			if err != nil {
-				return err
-			}
-			fullNameToMessage, err := protosource.FullNameToMessage(corpus.files...)
-			if err != nil {
-				return err
-			}
-			for previousFullName, previousMessage := range previousFullNameToMessage {
-				if message, ok := fullNameToMessage[previousFullName]; ok {
-					if err := f(add, corpus, previousMessage, message); err != nil {
-						return err
+			return bufpair.Messages(
+				corpus.previousFiles,
+				corpus.files,
+				func(previousMessage protosource.Message, message protosource.Message) error {
+					if previousMessage == nil || message == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousMessage, message)
+				},
+			)
 		},
 	)
 }
@@ -226,22 +203,16 @@ func newFieldPairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newMessagePairCheckFunc(
 		func(add addFunc, corpus *corpus, previousMessage protosource.Message, message protosource.Message) error {
-			previousNumberToField, err := protosource.NumberToMessageField(previousMessage)
-			if err != nil {
-				return err
-			}
-			numberToField, err := protosource.NumberToMessageField(message)
-			if err != nil {
-				return err
-			}
-			for previousNumber, previousField := range previousNumberToField {
-				if field, ok := numberToField[previousNumber]; ok {
-					if err := f(add, corpus, previousField, field); err != nil {
-						return err
+			return bufpair.MessageFields(
+				previousMessage,
+				message,
+				func(previousField protosource.Field, field protosource.Field) error {
+					if previousField == nil || field == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousField, field)
+				},
+			)
 		},
 	)
 }
@@ -251,22 +222,16 @@ func newServicePairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newFilesCheckFunc(
 		func(add addFunc, corpus *corpus) error {
-			previousFullNameToService, err := protosource.FullNameToService(corpus.previousFiles...)
-			if err != nil {
-				return err
-			}
-			fullNameToService, err := protosource.FullNameToService(corpus.files...)
-			if err != nil {
-				return err
-			}
-			for previousFullName, previousService := range previousFullNameToService {
-				if service, ok := fullNameToService[previousFullName]; ok {
-					if err := f(add, corpus, previousService, service); err != nil {
-						return err
+			return bufpair.Services(
+				corpus.previousFiles,
+				corpus.files,
+				func(previousService protosource.Service, service protosource.Service) error {
+					if previousService == nil || service == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousService, service)
+				},
+			)
 		},
 	)
 }
@@ -276,22 +241,16 @@ func newMethodPairCheckFunc(
 ) func(string, internal.IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error) {
 	return newServicePairCheckFunc(
 		func(add addFunc, corpus *corpus, previousService protosource.Service, service protosource.Service) error {
-			previousNameToMethod, err := protosource.NameToMethod(previousService)
-			if err != nil {
-				return err
-			}
-			nameToMethod, err := protosource.NameToMethod(service)
-			if err != nil {
-				return err
-			}
-			for previousName, previousMethod := range previousNameToMethod {
-				if method, ok := nameToMethod[previousName]; ok {
-					if err := f(add, corpus, previousMethod, method); err != nil {
-						return err
+			return bufpair.Methods(
+				previousService,
+				service,
+				func(previousMethod protosource.Method, method protosource.Method) error {
+					if previousMethod == nil || method == nil {
+						return nil
 					}
-				}
-			}
-			return nil
+					return f(add, corpus, previousMethod, method)
+				},
+			)
 		},
 	)
 }
@@ -26,6 +26,7 @@ import (
 	"strings"
 	"text/tabwriter"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"go.uber.org/multierr"
 )
 
@@ -55,6 +56,20 @@ type Rule interface {
 	Purpose() string
 }
 
+// RulesToRuleInfos converts the Rules to bufanalysis.RuleInfos.
+//
+// This is used to attach rule metadata when printing FileAnnotations.
+func RulesToRuleInfos(rules []Rule) []bufanalysis.RuleInfo {
+	if rules == nil {
+		return nil
+	}
+	ruleInfos := make([]bufanalysis.RuleInfo, len(rules))
+	for i, rule := range rules {
+		ruleInfos[i] = rule
+	}
+	return ruleInfos
+}
+
 // PrintRules prints the rules to the writer.
 //
 // The empty string defaults to text.
@@ -0,0 +1,159 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufcheckplugin runs plugins that provide additional lint or breaking change rules.
+//
+// A plugin is either an executable, or a WASM module if the plugin path ends
+// in .wasm. The plugin reads a single JSON request from stdin and writes a
+// single JSON response to stdout. A plugin is invoked in two ways, and every
+// request has a "check_type" of "lint" or "breaking", depending on the section
+// of the buf.yaml that the plugin is configured in.
+//
+// To list its rules, the plugin receives:
+//
+//	{"version":"v1","check_type":"lint","list_rules":true,"options":{"key":"value"}}
+//
+// And responds with its rules:
+//
+//	{"rules":[{"id":"RPC_AUTH_POLICY","categories":["ACME"],"purpose":"RPCs set the (acme.auth.policy) option"}]}
+//
+// Rule IDs and categories are UPPER_SNAKE_CASE. The purpose completes the sentence
+// "Checks that ...", in the same way as the purposes of the built-in rules.
+//
+// To check an image, the plugin receives:
+//
+//	{"version":"v1","check_type":"lint","rule_ids":["RPC_AUTH_POLICY"],"options":{"key":"value"},"image":"<base64>"}
+//
+// The image is a serialized buf.alpha.image.v1.Image, which is wire compatible with
+// google.protobuf.FileDescriptorSet, and includes source code info. For lint, the image
+// is self-contained, so that custom options defined in imports can be resolved. Imports
+// have buf_extension.is_import set, and are not checked: failures for imports are
+// ignored.
+//
+// For breaking changes, the request also has an "against_image" to compare the image
+// against, which does not need to have source code info. Both images include their
+// imports unless --exclude-imports is set, and imports are checked like other files.
+//
+// The plugin responds with its failures, using the same fields as --error-format=json:
+//
+//	{"file_annotations":[{"path":"acme/v1/acme.proto","start_line":5,"start_column":3,"end_line":5,"end_column":30,"type":"RPC_AUTH_POLICY","message":"..."}]}
+//
+// Each file annotation should also set "element" to the fully-qualified name of the
+// element it is for, such as acme.v1.AcmeService.Get, so that comment ignores and
+// lint baselines work for plugin rules. Breaking change failures for files that were
+// deleted may use the path of a file of the against image.
+package bufcheckplugin
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufwasm"
+	"github.com/bufbuild/buf/private/pkg/command"
+)
+
+// Plugin is a plugin that provides check rules.
+type Plugin interface {
+	// Name returns the name or path of the plugin.
+	Name() string
+	// Rules returns the rules provided by the plugin.
+	//
+	// The plugin is only run to list its rules once, and the rules are cached for
+	// the life of the Plugin.
+	Rules(ctx context.Context) ([]*Rule, error)
+	// Check runs the rules with the given IDs on the image, and returns the FileAnnotations.
+	//
+	// Breaking plugins must be given the image to check against with CheckWithAgainstImage.
+	//
+	// All returned FileAnnotations have one of the ruleIDs as their Type.
+	Check(
+		ctx context.Context,
+		image bufimage.Image,
+		ruleIDs []string,
+		options ...CheckOption,
+	) ([]bufanalysis.FileAnnotation, error)
+	// Close releases the compiled WASM module of the plugin, if any.
+	Close() error
+}
+
+// CheckType is the type of the checks that a plugin provides rules for.
+type CheckType int
+
+const (
+	// CheckTypeLint is the type of plugins configured in the lint section of buf.yaml.
+	CheckTypeLint CheckType = iota + 1
+	// CheckTypeBreaking is the type of plugins configured in the breaking section of buf.yaml.
+	CheckTypeBreaking
+)
+
+// NewPlugin returns a new Plugin of the check type for the given name or path.
+//
+// The options are passed to the plugin on every invocation. WASM plugins are compiled
+// once, on their first invocation.
+//
+// The Plugin should be closed when it is no longer used.
+func NewPlugin(
+	runner command.Runner,
+	checkType CheckType,
+	name string,
+	options map[string]string,
+	pluginOptions ...PluginOption,
+) Plugin {
+	return newPlugin(runner, checkType, name, options, pluginOptions...)
+}
+
+// ResolvePluginPath returns the path to run the plugin with the given name or path
+// from a configuration in the directory dirPath.
+//
+// Relative paths are resolved against dirPath. Names without a path separator that
+// do not end in .wasm are looked up on the PATH when the plugin is run, and are
+// returned unchanged.
+func ResolvePluginPath(name string, dirPath string) (string, error) {
+	return resolvePluginPath(name, dirPath)
+}
+
+// PluginOption is an option for a new Plugin.
+type PluginOption func(*plugin)
+
+// PluginWithWASMPluginExecutor returns a new PluginOption that uses the given
+// executor to run WASM plugins.
+//
+// The default is to use an executor without a compilation cache.
+func PluginWithWASMPluginExecutor(wasmPluginExecutor bufwasm.PluginExecutor) PluginOption {
+	return func(plugin *plugin) {
+		plugin.wasmPluginExecutor = wasmPluginExecutor
+	}
+}
+
+// CheckOption is an option for Check.
+type CheckOption func(*checkOptions)
+
+// CheckWithAgainstImage returns a new CheckOption that gives the image to check
+// against to a breaking plugin.
+func CheckWithAgainstImage(againstImage bufimage.Image) CheckOption {
+	return func(checkOptions *checkOptions) {
+		checkOptions.againstImage = againstImage
+	}
+}
+
+// Rule is a rule provided by a Plugin.
+type Rule struct {
+	// ID is the ID of the rule.
+	ID string
+	// Categories are the categories of the rule.
+	Categories []string
+	// Purpose is the purpose of the rule, completing the sentence "Checks that ...".
+	Purpose string
+}
@@ -0,0 +1,345 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcheckplugin
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagetesting"
+	imagev1 "github.com/bufbuild/buf/private/gen/proto/go/buf/alpha/image/v1"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestRules(t *testing.T) {
+	t.Parallel()
+	plugin := newTestPlugin(
+		t,
+		func(request *externalRequest) *externalResponse {
+			assert.True(t, request.ListRules)
+			assert.Equal(t, map[string]string{"option": "acme.auth.policy"}, request.Options)
+			return &externalResponse{
+				Rules: []externalRule{
+					{
+						ID:         "RPC_AUTH_POLICY",
+						Categories: []string{"ACME"},
+						Purpose:    "RPCs set the (acme.auth.policy) option",
+					},
+				},
+			}
+		},
+	)
+	rules, err := plugin.Rules(context.Background())
+	require.NoError(t, err)
+	assert.Equal(
+		t,
+		[]*Rule{
+			{
+				ID:         "RPC_AUTH_POLICY",
+				Categories: []string{"ACME"},
+				Purpose:    "RPCs set the (acme.auth.policy) option",
+			},
+		},
+		rules,
+	)
+}
+
+func TestRulesCached(t *testing.T) {
+	t.Parallel()
+	var calls int
+	plugin := newTestPlugin(
+		t,
+		func(request *externalRequest) *externalResponse {
+			calls++
+			return &externalResponse{}
+		},
+	)
+	for i := 0; i < 2; i++ {
+		rules, err := plugin.Rules(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, rules)
+	}
+	assert.Equal(t, 1, calls)
+}
+
+func TestRulesInvalid(t *testing.T) {
+	t.Parallel()
+	testRulesInvalid(t, externalRule{ID: "rpc_auth_policy", Purpose: "purpose"})
+	testRulesInvalid(t, externalRule{ID: "RPC_AUTH_POLICY", Categories: []string{"acme"}, Purpose: "purpose"})
+	testRulesInvalid(t, externalRule{ID: "RPC_AUTH_POLICY"})
+	testRulesInvalid(
+		t,
+		externalRule{ID: "RPC_AUTH_POLICY", Purpose: "purpose"},
+		externalRule{ID: "RPC_AUTH_POLICY", Purpose: "purpose"},
+	)
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+	image := newTestImage(t)
+	plugin := newTestPlugin(
+		t,
+		func(request *externalRequest) *externalResponse {
+			assert.False(t, request.ListRules)
+			assert.Equal(t, []string{"RPC_AUTH_POLICY"}, request.RuleIDs)
+			assert.NotEmpty(t, request.Image)
+			return &externalResponse{
+				FileAnnotations: []externalFileAnnotation{
+					{
+						Path:        "a/v1/a.proto",
+						StartLine:   5,
+						StartColumn: 3,
+						Type:        "RPC_AUTH_POLICY",
+						Message:     `RPC "Get" must set (acme.auth.policy).`,
+						Element:     "a.v1.AService.Get",
+					},
+				},
+			}
+		},
+	)
+	fileAnnotations, err := plugin.Check(context.Background(), image, []string{"RPC_AUTH_POLICY"})
+	require.NoError(t, err)
+	require.Len(t, fileAnnotations, 1)
+	fileAnnotation := fileAnnotations[0]
+	require.NotNil(t, fileAnnotation.FileInfo())
+	assert.Equal(t, "a/v1/a.proto", fileAnnotation.FileInfo().Path())
+	assert.Equal(t, 5, fileAnnotation.StartLine())
+	assert.Equal(t, 3, fileAnnotation.StartColumn())
+	// End defaults to start.
+	assert.Equal(t, 5, fileAnnotation.EndLine())
+	assert.Equal(t, 3, fileAnnotation.EndColumn())
+	assert.Equal(t, "RPC_AUTH_POLICY", fileAnnotation.Type())
+	assert.Equal(t, "a.v1.AService.Get", fileAnnotation.ElementPath())
+}
+
+func TestCheckImport(t *testing.T) {
+	t.Parallel()
+	image := newTestImage(t)
+	plugin := newTestPlugin(
+		t,
+		func(request *externalRequest) *externalResponse {
+			// The image is self-contained, and marks the imports.
+			protoImage := &imagev1.Image{}
+			require.NoError(t, protoencoding.NewWireUnmarshaler(nil).Unmarshal(request.Image, protoImage))
+			require.Len(t, protoImage.File, 2)
+			assert.Equal(t, "acme/auth/v1/auth.proto", protoImage.File[0].GetName())
+			assert.True(t, protoImage.File[0].GetBufExtension().GetIsImport())
+			assert.False(t, protoImage.File[1].GetBufExtension().GetIsImport())
+			return &externalResponse{
+				FileAnnotations: []externalFileAnnotation{
+					{
+						Path:    "acme/auth/v1/auth.proto",
+						Type:    "RPC_AUTH_POLICY",
+						Message: "Imports are not checked.",
+					},
+				},
+			}
+		},
+	)
+	fileAnnotations, err := plugin.Check(context.Background(), image, []string{"RPC_AUTH_POLICY"})
+	require.NoError(t, err)
+	assert.Empty(t, fileAnnotations)
+}
+
+func TestCheckBreaking(t *testing.T) {
+	t.Parallel()
+	image := newTestImage(t)
+	againstImage := newTestAgainstImage(t)
+	plugin := newTestPluginForCheckType(
+		t,
+		CheckTypeBreaking,
+		func(request *externalRequest) *externalResponse {
+			assert.NotEmpty(t, request.Image)
+			assert.NotEmpty(t, request.AgainstImage)
+			return &externalResponse{
+				FileAnnotations: []externalFileAnnotation{
+					{
+						// Failures for imports are kept for breaking plugins.
+						Path:    "acme/auth/v1/auth.proto",
+						Type:    "ACME_POLICY_NO_DELETE",
+						Message: `Previously present policy "admin" was deleted.`,
+					},
+					{
+						// Failures for deleted files use the path in the against image.
+						Path:    "b/v1/b.proto",
+						Type:    "ACME_POLICY_NO_DELETE",
+						Message: `Previously present file "b/v1/b.proto" was deleted.`,
+					},
+				},
+			}
+		},
+	)
+	fileAnnotations, err := plugin.Check(
+		context.Background(),
+		image,
+		[]string{"ACME_POLICY_NO_DELETE"},
+		CheckWithAgainstImage(againstImage),
+	)
+	require.NoError(t, err)
+	require.Len(t, fileAnnotations, 2)
+	assert.Equal(t, "acme/auth/v1/auth.proto", fileAnnotations[0].FileInfo().Path())
+	assert.Equal(t, "b/v1/b.proto", fileAnnotations[1].FileInfo().Path())
+	// The image to check against is required.
+	_, err = plugin.Check(context.Background(), image, []string{"ACME_POLICY_NO_DELETE"})
+	assert.Error(t, err)
+}
+
+func TestCheckInvalid(t *testing.T) {
+	t.Parallel()
+	image := newTestImage(t)
+	// Rule that was not requested.
+	testCheckInvalid(t, image, externalFileAnnotation{Path: "a/v1/a.proto", Type: "OTHER_RULE"})
+	// File that is not in the image.
+	testCheckInvalid(t, image, externalFileAnnotation{Path: "b/v1/b.proto", Type: "RPC_AUTH_POLICY"})
+}
+
+func TestResolvePluginPath(t *testing.T) {
+	t.Parallel()
+	absDirPath, err := filepath.Abs("proto")
+	require.NoError(t, err)
+	testResolvePluginPath(t, "buf-plugin-acme", "proto", "buf-plugin-acme")
+	testResolvePluginPath(t, "./buf-plugin-acme", "proto", filepath.Join(absDirPath, "buf-plugin-acme"))
+	testResolvePluginPath(t, "bin/buf-plugin-acme", "proto", filepath.Join(absDirPath, "bin", "buf-plugin-acme"))
+	testResolvePluginPath(t, "buf-plugin-acme.wasm", "proto", filepath.Join(absDirPath, "buf-plugin-acme.wasm"))
+	absPluginPath, err := filepath.Abs(filepath.Join("bin", "buf-plugin-acme"))
+	require.NoError(t, err)
+	testResolvePluginPath(t, absPluginPath, "proto", absPluginPath)
+}
+
+func testResolvePluginPath(t *testing.T, name string, dirPath string, expectedPath string) {
+	path, err := ResolvePluginPath(name, dirPath)
+	require.NoError(t, err)
+	assert.Equal(t, expectedPath, path)
+}
+
+func testRulesInvalid(t *testing.T, externalRules ...externalRule) {
+	plugin := newTestPlugin(
+		t,
+		func(*externalRequest) *externalResponse {
+			return &externalResponse{
+				Rules: externalRules,
+			}
+		},
+	)
+	_, err := plugin.Rules(context.Background())
+	assert.Error(t, err)
+}
+
+func testCheckInvalid(t *testing.T, image bufimage.Image, externalFileAnnotations ...externalFileAnnotation) {
+	plugin := newTestPlugin(
+		t,
+		func(*externalRequest) *externalResponse {
+			return &externalResponse{
+				FileAnnotations: externalFileAnnotations,
+			}
+		},
+	)
+	_, err := plugin.Check(context.Background(), image, []string{"RPC_AUTH_POLICY"})
+	assert.Error(t, err)
+}
+
+func newTestPlugin(t *testing.T, handle func(*externalRequest) *externalResponse) *plugin {
+	return newTestPluginForCheckType(t, CheckTypeLint, handle)
+}
+
+func newTestPluginForCheckType(
+	t *testing.T,
+	checkType CheckType,
+	handle func(*externalRequest) *externalResponse,
+) *plugin {
+	plugin := newPlugin(
+		command.NewRunner(),
+		checkType,
+		"buf-plugin-test",
+		map[string]string{"option": "acme.auth.policy"},
+	)
+	plugin.invoke = func(_ context.Context, requestData []byte) ([]byte, error) {
+		request := &externalRequest{}
+		if err := json.Unmarshal(requestData, request); err != nil {
+			return nil, err
+		}
+		assert.Equal(t, v1Version, request.Version)
+		if checkType == CheckTypeBreaking {
+			assert.Equal(t, breakingCheckType, request.CheckType)
+		} else {
+			assert.Equal(t, lintCheckType, request.CheckType)
+		}
+		return json.Marshal(handle(request))
+	}
+	return plugin
+}
+
+func newTestAgainstImage(t *testing.T) bufimage.Image {
+	imageFile := bufimagetesting.NewImageFile(
+		t,
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("b/v1/b.proto"),
+			Package: proto.String("b.v1"),
+			Syntax:  proto.String("proto3"),
+		},
+		nil,
+		"",
+		"b/v1/b.proto",
+		false,
+		false,
+		nil,
+	)
+	image, err := bufimage.NewImage([]bufimage.ImageFile{imageFile})
+	require.NoError(t, err)
+	return image
+}
+
+func newTestImage(t *testing.T) bufimage.Image {
+	importImageFile := bufimagetesting.NewImageFile(
+		t,
+		&descriptorpb.FileDescriptorProto{
+			Name:    proto.String("acme/auth/v1/auth.proto"),
+			Package: proto.String("acme.auth.v1"),
+			Syntax:  proto.String("proto3"),
+		},
+		nil,
+		"",
+		"acme/auth/v1/auth.proto",
+		true,
+		false,
+		nil,
+	)
+	imageFile := bufimagetesting.NewImageFile(
+		t,
+		&descriptorpb.FileDescriptorProto{
+			Name:       proto.String("a/v1/a.proto"),
+			Package:    proto.String("a.v1"),
+			Dependency: []string{"acme/auth/v1/auth.proto"},
+			Syntax:     proto.String("proto3"),
+		},
+		nil,
+		"",
+		"a/v1/a.proto",
+		false,
+		false,
+		nil,
+	)
+	image, err := bufimage.NewImage([]bufimage.ImageFile{importImageFile, imageFile})
+	require.NoError(t, err)
+	return image
+}
@@ -0,0 +1,403 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufcheckplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufwasm"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+)
+
+const (
+	v1Version = "v1"
+
+	lintCheckType     = "lint"
+	breakingCheckType = "breaking"
+)
+
+type plugin struct {
+	runner             command.Runner
+	checkType          CheckType
+	name               string
+	options            map[string]string
+	wasmPluginExecutor bufwasm.PluginExecutor
+	// invoke sends the request data to the plugin and returns the response data.
+	//
+	// Set to the binary or WASM invocation in newPlugin, and replaced in tests.
+	invoke func(ctx context.Context, requestData []byte) ([]byte, error)
+
+	rulesLock sync.Mutex
+	// rules are cached after the plugin is first run to list its rules.
+	rules []*Rule
+
+	compiledPluginLock sync.Mutex
+	// compiledPlugin is cached after the first invocation of a WASM plugin.
+	compiledPlugin *bufwasm.CompiledPlugin
+}
+
+func newPlugin(
+	runner command.Runner,
+	checkType CheckType,
+	name string,
+	options map[string]string,
+	pluginOptions ...PluginOption,
+) *plugin {
+	plugin := &plugin{
+		runner:    runner,
+		checkType: checkType,
+		name:      name,
+		options:   options,
+	}
+	for _, pluginOption := range pluginOptions {
+		pluginOption(plugin)
+	}
+	if strings.HasSuffix(name, ".wasm") {
+		plugin.invoke = plugin.invokeWASM
+	} else {
+		plugin.invoke = plugin.invokeBinary
+	}
+	return plugin
+}
+
+func (p *plugin) Name() string {
+	return p.name
+}
+
+func (p *plugin) Rules(ctx context.Context) ([]*Rule, error) {
+	p.rulesLock.Lock()
+	defer p.rulesLock.Unlock()
+	if p.rules != nil {
+		return p.rules, nil
+	}
+	rules, err := p.listRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	p.rules = rules
+	return rules, nil
+}
+
+func (p *plugin) listRules(ctx context.Context) ([]*Rule, error) {
+	response, err := p.call(
+		ctx,
+		&externalRequest{
+			Version:   v1Version,
+			CheckType: p.externalCheckType(),
+			ListRules: true,
+			Options:   p.options,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]*Rule, 0, len(response.Rules))
+	seenIDs := make(map[string]struct{}, len(response.Rules))
+	for _, externalRule := range response.Rules {
+		if !isUpperSnakeCase(externalRule.ID) {
+			return nil, fmt.Errorf("plugin %q returned rule ID %q that is not UPPER_SNAKE_CASE", p.name, externalRule.ID)
+		}
+		if _, ok := seenIDs[externalRule.ID]; ok {
+			return nil, fmt.Errorf("plugin %q returned duplicate rule ID %q", p.name, externalRule.ID)
+		}
+		seenIDs[externalRule.ID] = struct{}{}
+		for _, category := range externalRule.Categories {
+			if !isUpperSnakeCase(category) {
+				return nil, fmt.Errorf("plugin %q returned category %q for rule %q that is not UPPER_SNAKE_CASE", p.name, category, externalRule.ID)
+			}
+		}
+		if externalRule.Purpose == "" {
+			return nil, fmt.Errorf("plugin %q returned rule %q without a purpose", p.name, externalRule.ID)
+		}
+		rules = append(
+			rules,
+			&Rule{
+				ID:         externalRule.ID,
+				Categories: externalRule.Categories,
+				Purpose:    externalRule.Purpose,
+			},
+		)
+	}
+	return rules, nil
+}
+
+func (p *plugin) Check(
+	ctx context.Context,
+	image bufimage.Image,
+	ruleIDs []string,
+	options ...CheckOption,
+) ([]bufanalysis.FileAnnotation, error) {
+	checkOptions := newCheckOptions()
+	for _, option := range options {
+		option(checkOptions)
+	}
+	if len(ruleIDs) == 0 {
+		return nil, nil
+	}
+	if p.checkType == CheckTypeBreaking && checkOptions.againstImage == nil {
+		// This is a system error.
+		return nil, fmt.Errorf("no image to check against for breaking plugin %q", p.name)
+	}
+	imageData, err := protoencoding.NewWireMarshaler().Marshal(bufimage.ImageToProtoImage(image))
+	if err != nil {
+		return nil, err
+	}
+	var againstImageData []byte
+	if checkOptions.againstImage != nil {
+		againstImageData, err = protoencoding.NewWireMarshaler().Marshal(bufimage.ImageToProtoImage(checkOptions.againstImage))
+		if err != nil {
+			return nil, err
+		}
+	}
+	response, err := p.call(
+		ctx,
+		&externalRequest{
+			Version:      v1Version,
+			CheckType:    p.externalCheckType(),
+			RuleIDs:      ruleIDs,
+			Options:      p.options,
+			Image:        imageData,
+			AgainstImage: againstImageData,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	ruleIDMap := make(map[string]struct{}, len(ruleIDs))
+	for _, ruleID := range ruleIDs {
+		ruleIDMap[ruleID] = struct{}{}
+	}
+	fileAnnotations := make([]bufanalysis.FileAnnotation, 0, len(response.FileAnnotations))
+	for _, externalFileAnnotation := range response.FileAnnotations {
+		if _, ok := ruleIDMap[externalFileAnnotation.Type]; !ok {
+			return nil, fmt.Errorf("plugin %q returned a failure for rule %q which was not requested", p.name, externalFileAnnotation.Type)
+		}
+		var fileInfo bufanalysis.FileInfo
+		if externalFileAnnotation.Path != "" {
+			imageFile := image.GetFile(externalFileAnnotation.Path)
+			if imageFile == nil && checkOptions.againstImage != nil {
+				// The file was deleted.
+				imageFile = checkOptions.againstImage.GetFile(externalFileAnnotation.Path)
+			}
+			if imageFile == nil {
+				return nil, fmt.Errorf("plugin %q returned a failure for file %q which is not in the image", p.name, externalFileAnnotation.Path)
+			}
+			if p.checkType == CheckTypeLint && imageFile.IsImport() {
+				// Imports are only given to lint plugins to resolve references.
+				continue
+			}
+			fileInfo = imageFile
+		}
+		endLine := externalFileAnnotation.EndLine
+		if endLine == 0 {
+			endLine = externalFileAnnotation.StartLine
+		}
+		endColumn := externalFileAnnotation.EndColumn
+		if endColumn == 0 {
+			endColumn = externalFileAnnotation.StartColumn
+		}
+		fileAnnotations = append(
+			fileAnnotations,
+			bufanalysis.NewFileAnnotation(
+				fileInfo,
+				externalFileAnnotation.StartLine,
+				externalFileAnnotation.StartColumn,
+				endLine,
+				endColumn,
+				externalFileAnnotation.Type,
+				externalFileAnnotation.Message,
+				bufanalysis.FileAnnotationWithElementPath(externalFileAnnotation.Element),
+			),
+		)
+	}
+	return fileAnnotations, nil
+}
+
+func (p *plugin) externalCheckType() string {
+	if p.checkType == CheckTypeBreaking {
+		return breakingCheckType
+	}
+	return lintCheckType
+}
+
+func (p *plugin) Close() error {
+	p.compiledPluginLock.Lock()
+	defer p.compiledPluginLock.Unlock()
+	if p.compiledPlugin == nil {
+		return nil
+	}
+	err := p.compiledPlugin.Close()
+	p.compiledPlugin = nil
+	return err
+}
+
+func (p *plugin) call(ctx context.Context, request *externalRequest) (*externalResponse, error) {
+	requestData, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	responseData, err := p.invoke(ctx, requestData)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %q: %w", p.name, err)
+	}
+	response := &externalResponse{}
+	if err := encoding.UnmarshalJSONNonStrict(responseData, response); err != nil {
+		return nil, fmt.Errorf("plugin %q returned an invalid response: %w", p.name, err)
+	}
+	return response, nil
+}
+
+func (p *plugin) invokeBinary(ctx context.Context, requestData []byte) ([]byte, error) {
+	responseBuffer := bytes.NewBuffer(nil)
+	stderrBuffer := bytes.NewBuffer(nil)
+	if err := p.runner.Run(
+		ctx,
+		p.name,
+		command.RunWithStdin(bytes.NewReader(requestData)),
+		command.RunWithStdout(responseBuffer),
+		command.RunWithStderr(stderrBuffer),
+	); err != nil {
+		if stderr := strings.TrimSpace(stderrBuffer.String()); stderr != "" {
+			return nil, fmt.Errorf("%w: %s", err, stderr)
+		}
+		return nil, err
+	}
+	return responseBuffer.Bytes(), nil
+}
+
+func (p *plugin) invokeWASM(ctx context.Context, requestData []byte) ([]byte, error) {
+	compiledPlugin, err := p.getCompiledPlugin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	responseBuffer := bytes.NewBuffer(nil)
+	if err := p.wasmPluginExecutor.Run(
+		ctx,
+		compiledPlugin,
+		bytes.NewReader(requestData),
+		responseBuffer,
+	); err != nil {
+		if pluginErr := new(bufwasm.PluginExecutionError); errors.As(err, &pluginErr) {
+			if stderr := strings.TrimSpace(pluginErr.Stderr); stderr != "" {
+				return nil, fmt.Errorf("%w: %s", err, stderr)
+			}
+		}
+		return nil, err
+	}
+	return responseBuffer.Bytes(), nil
+}
+
+// getCompiledPlugin compiles the WASM plugin on the first call, and returns the
+// cached module afterwards.
+func (p *plugin) getCompiledPlugin(ctx context.Context) (*bufwasm.CompiledPlugin, error) {
+	p.compiledPluginLock.Lock()
+	defer p.compiledPluginLock.Unlock()
+	if p.compiledPlugin != nil {
+		return p.compiledPlugin, nil
+	}
+	if p.wasmPluginExecutor == nil {
+		wasmPluginExecutor, err := bufwasm.NewPluginExecutor("")
+		if err != nil {
+			return nil, err
+		}
+		p.wasmPluginExecutor = wasmPluginExecutor
+	}
+	pluginData, err := os.ReadFile(p.name)
+	if err != nil {
+		return nil, err
+	}
+	compiledPlugin, err := p.wasmPluginExecutor.CompilePlugin(ctx, pluginData)
+	if err != nil {
+		return nil, err
+	}
+	p.compiledPlugin = compiledPlugin
+	return compiledPlugin, nil
+}
+
+func resolvePluginPath(name string, dirPath string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+	if !strings.ContainsAny(name, "/"+string(filepath.Separator)) && !strings.HasSuffix(name, ".wasm") {
+		return name, nil
+	}
+	// The path is made absolute so that a plugin in dirPath is not looked up on the PATH.
+	return filepath.Abs(filepath.Join(dirPath, name))
+}
+
+func isUpperSnakeCase(s string) bool {
+	if s == "" || s[0] < 'A' || s[0] > 'Z' {
+		return false
+	}
+	for _, r := range s {
+		if !(r >= 'A' && r <= 'Z') && !(r >= '0' && r <= '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+type checkOptions struct {
+	againstImage bufimage.Image
+}
+
+func newCheckOptions() *checkOptions {
+	return &checkOptions{}
+}
+
+type externalRequest struct {
+	Version   string            `json:"version,omitempty"`
+	CheckType string            `json:"check_type,omitempty"`
+	ListRules bool              `json:"list_rules,omitempty"`
+	RuleIDs   []string          `json:"rule_ids,omitempty"`
+	Options   map[string]string `json:"options,omitempty"`
+	// Image and AgainstImage are base64-encoded by encoding/json.
+	Image        []byte `json:"image,omitempty"`
+	AgainstImage []byte `json:"against_image,omitempty"`
+}
+
+type externalResponse struct {
+	Rules           []externalRule           `json:"rules,omitempty"`
+	FileAnnotations []externalFileAnnotation `json:"file_annotations,omitempty"`
+}
+
+type externalRule struct {
+	ID         string   `json:"id,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Purpose    string   `json:"purpose,omitempty"`
+}
+
+type externalFileAnnotation struct {
+	Path        string `json:"path,omitempty"`
+	StartLine   int    `json:"start_line,omitempty"`
+	StartColumn int    `json:"start_column,omitempty"`
+	EndLine     int    `json:"end_line,omitempty"`
+	EndColumn   int    `json:"end_column,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Element     string `json:"element,omitempty"`
+}
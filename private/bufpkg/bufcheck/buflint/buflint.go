@@ -22,12 +22,14 @@ import (
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/internal/buflintv1"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/internal/buflintv1beta1"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
@@ -43,12 +45,21 @@ type Handler interface {
 	//
 	// The image should have source code info for this to work properly.
 	//
-	// Images should be filtered with regards to imports before passing to this function.
+	// Only the files of the image that are not imports are checked. Plugins are given
+	// the full image, so that they can resolve custom options defined in imports.
 	Check(
 		ctx context.Context,
 		config *buflintconfig.Config,
 		image bufimage.Image,
 	) ([]bufanalysis.FileAnnotation, error)
+	// Rules returns the rules for the config.
+	//
+	// The plugins of the config are run to list their rules. Plugins are kept by the
+	// Handler, so that they only list their rules and compile once across calls to
+	// Rules and Check.
+	Rules(ctx context.Context, config *buflintconfig.Config) ([]bufcheck.Rule, error)
+	// Close closes the plugins kept by the Handler.
+	Close() error
 }
 
 // NewHandler returns a new Handler.
@@ -58,13 +69,15 @@ func NewHandler(logger *zap.Logger) Handler {
 
 // RulesForConfig returns the rules for a given config.
 //
+// This includes the rules of the plugins of the config, which are run to list their rules.
+//
 // Should only be used for printing.
-func RulesForConfig(config *buflintconfig.Config) ([]bufcheck.Rule, error) {
-	internalConfig, err := internalConfigForConfig(config)
-	if err != nil {
-		return nil, err
-	}
-	return rulesForInternalRules(internalConfig.Rules), nil
+func RulesForConfig(ctx context.Context, config *buflintconfig.Config) (_ []bufcheck.Rule, retErr error) {
+	handler := newHandler(zap.NewNop())
+	defer func() {
+		retErr = multierr.Append(retErr, handler.Close())
+	}()
+	return handler.Rules(ctx, config)
 }
 
 // GetAllRulesV1Beta1 gets all known rules.
@@ -72,10 +85,13 @@ func RulesForConfig(config *buflintconfig.Config) ([]bufcheck.Rule, error) {
 // Should only be used for printing.
 func GetAllRulesV1Beta1() ([]bufcheck.Rule, error) {
 	internalConfig, err := internalConfigForConfig(
+		context.Background(),
+		nil,
 		&buflintconfig.Config{
 			Use:     internal.AllIDsForVersionSpec(buflintv1beta1.VersionSpec),
 			Version: bufconfig.V1Beta1Version,
 		},
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -88,10 +104,13 @@ func GetAllRulesV1Beta1() ([]bufcheck.Rule, error) {
 // Should only be used for printing.
 func GetAllRulesV1() ([]bufcheck.Rule, error) {
 	internalConfig, err := internalConfigForConfig(
+		context.Background(),
+		nil,
 		&buflintconfig.Config{
 			Use:     internal.AllIDsForVersionSpec(buflintv1.VersionSpec),
 			Version: bufconfig.V1Version,
 		},
+		nil,
 	)
 	if err != nil {
 		return nil, err
@@ -113,7 +132,17 @@ func GetAllRulesAndCategoriesV1() []string {
 	return internal.AllCategoriesAndIDsForVersionSpec(buflintv1.VersionSpec)
 }
 
-func internalConfigForConfig(config *buflintconfig.Config) (*internal.Config, error) {
+// internalConfigForConfig returns the internal Config for the Config.
+//
+// The plugins of the config are returned by getPlugin, and are run to list their rules.
+// If image is non-nil, the plugin rules will check the image when run, otherwise the
+// config should only be used for printing.
+func internalConfigForConfig(
+	ctx context.Context,
+	getPlugin func(*buflintconfig.PluginConfig) (bufcheckplugin.Plugin, error),
+	config *buflintconfig.Config,
+	image bufimage.Image,
+) (*internal.Config, error) {
 	var versionSpec *internal.VersionSpec
 	switch config.Version {
 	case bufconfig.V1Beta1Version:
@@ -121,7 +150,33 @@ func internalConfigForConfig(config *buflintconfig.Config) (*internal.Config, er
 	case bufconfig.V1Version:
 		versionSpec = buflintv1.VersionSpec
 	}
-	return internal.ConfigBuilder{
+	var pluginRuleBuilders []*internal.RuleBuilder
+	pluginIDToCategories := make(map[string][]string)
+	idToPluginChecker := make(map[string]*internal.PluginChecker)
+	for _, pluginConfig := range config.Plugins {
+		plugin, err := getPlugin(pluginConfig)
+		if err != nil {
+			return nil, err
+		}
+		pluginRules, err := plugin.Rules(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pluginChecker := internal.NewPluginChecker(plugin, image, nil)
+		for _, pluginRule := range pluginRules {
+			pluginRuleBuilders = append(
+				pluginRuleBuilders,
+				internal.NewPluginRuleBuilder(
+					pluginRule.ID,
+					pluginRule.Purpose,
+					pluginChecker.FileAnnotations,
+				),
+			)
+			pluginIDToCategories[pluginRule.ID] = pluginRule.Categories
+			idToPluginChecker[pluginRule.ID] = pluginChecker
+		}
+	}
+	internalConfig, err := internal.ConfigBuilder{
 		Use:                                  config.Use,
 		Except:                               config.Except,
 		IgnoreRootPaths:                      config.IgnoreRootPaths,
@@ -132,9 +187,22 @@ func internalConfigForConfig(config *buflintconfig.Config) (*internal.Config, er
 		RPCAllowGoogleProtobufEmptyRequests:  config.RPCAllowGoogleProtobufEmptyRequests,
 		RPCAllowGoogleProtobufEmptyResponses: config.RPCAllowGoogleProtobufEmptyResponses,
 		ServiceSuffix:                        config.ServiceSuffix,
+		PluginRuleBuilders:                   pluginRuleBuilders,
+		PluginIDToCategories:                 pluginIDToCategories,
+		IDOrCategoryToSeverity:               config.IDOrCategoryToSeverity,
 	}.NewConfig(
 		versionSpec,
 	)
+	if err != nil {
+		return nil, err
+	}
+	// Plugins only run the rules that were selected by the config.
+	for _, rule := range internalConfig.Rules {
+		if pluginChecker, ok := idToPluginChecker[rule.ID()]; ok {
+			pluginChecker.AddRuleID(rule.ID())
+		}
+	}
+	return internalConfig, nil
 }
 
 func rulesForInternalRules(rules []*internal.Rule) []bufcheck.Rule {
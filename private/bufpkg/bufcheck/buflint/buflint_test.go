@@ -23,6 +23,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis/bufanalysistesting"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
@@ -934,6 +935,45 @@ func TestCommentIgnoresCascadeOn(t *testing.T) {
 	)
 }
 
+func TestRunSeverity(t *testing.T) {
+	t.Parallel()
+	testLint(
+		t,
+		"severity",
+		bufanalysistesting.NewFileAnnotation(t, "a.proto", 3, 1, 3, 11, "PACKAGE_LOWER_SNAKE_CASE"),
+		withSeverity(
+			bufanalysistesting.NewFileAnnotation(t, "a.proto", 5, 6, 5, 9, "ENUM_PASCAL_CASE"),
+			bufanalysis.SeverityWarning,
+		),
+		withSeverity(
+			bufanalysistesting.NewFileAnnotation(t, "a.proto", 9, 9, 9, 12, "SERVICE_SUFFIX"),
+			bufanalysis.SeverityInfo,
+		),
+	)
+}
+
+func TestRunSeverityInvalid(t *testing.T) {
+	t.Parallel()
+	_, err := buflint.RulesForConfig(
+		context.Background(),
+		&buflintconfig.Config{
+			Use:                    []string{"SERVICE_SUFFIX"},
+			IDOrCategoryToSeverity: map[string]string{"SERVICE_SUFFIX": "fatal"},
+			Version:                "v1",
+		},
+	)
+	assert.Error(t, err)
+	_, err = buflint.RulesForConfig(
+		context.Background(),
+		&buflintconfig.Config{
+			Use:                    []string{"SERVICE_SUFFIX"},
+			IDOrCategoryToSeverity: map[string]string{"NOT_A_RULE": "warning"},
+			Version:                "v1",
+		},
+	)
+	assert.Error(t, err)
+}
+
 func testLint(
 	t *testing.T,
 	relDirPath string,
@@ -1002,6 +1042,19 @@ func testLintConfigModifier(
 	)
 }
 
+func withSeverity(fileAnnotation bufanalysis.FileAnnotation, severity bufanalysis.Severity) bufanalysis.FileAnnotation {
+	return bufanalysis.NewFileAnnotation(
+		fileAnnotation.FileInfo(),
+		fileAnnotation.StartLine(),
+		fileAnnotation.StartColumn(),
+		fileAnnotation.EndLine(),
+		fileAnnotation.EndColumn(),
+		fileAnnotation.Type(),
+		fileAnnotation.Message(),
+		bufanalysis.FileAnnotationWithSeverity(severity),
+	)
+}
+
 func testGetConfig(
 	t *testing.T,
 	readBucket storage.ReadBucket,
@@ -0,0 +1,83 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflintbaseline
+
+import (
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+)
+
+type fingerprint struct {
+	Rule    string
+	Path    string
+	Element string
+	Message string
+}
+
+func newFingerprint(fileAnnotation bufanalysis.FileAnnotation) fingerprint {
+	var path string
+	// We use the root relative path and not the external path, so that the
+	// baseline does not depend on where the input is located.
+	if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+		path = fileInfo.Path()
+	}
+	return fingerprint{
+		Rule:    fileAnnotation.Type(),
+		Path:    path,
+		Element: fileAnnotation.ElementPath(),
+		Message: fileAnnotation.Message(),
+	}
+}
+
+type baseline struct {
+	fingerprintToCount map[fingerprint]int
+}
+
+func newBaseline(fileAnnotations []bufanalysis.FileAnnotation) *baseline {
+	fingerprintToCount := make(map[fingerprint]int, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		fingerprintToCount[newFingerprint(fileAnnotation)]++
+	}
+	return &baseline{
+		fingerprintToCount: fingerprintToCount,
+	}
+}
+
+func newBaselineForExternal(externalBaseline externalBaselineV1) *baseline {
+	fingerprintToCount := make(map[fingerprint]int, len(externalBaseline.Failures))
+	for _, externalFailure := range externalBaseline.Failures {
+		fingerprintToCount[fingerprint(externalFailure)]++
+	}
+	return &baseline{
+		fingerprintToCount: fingerprintToCount,
+	}
+}
+
+func (b *baseline) Filter(fileAnnotations []bufanalysis.FileAnnotation) []bufanalysis.FileAnnotation {
+	// Copy so that Filter can be called multiple times.
+	fingerprintToRemaining := make(map[fingerprint]int, len(b.fingerprintToCount))
+	for fingerprint, count := range b.fingerprintToCount {
+		fingerprintToRemaining[fingerprint] = count
+	}
+	var filtered []bufanalysis.FileAnnotation
+	for _, fileAnnotation := range fileAnnotations {
+		fingerprint := newFingerprint(fileAnnotation)
+		if fingerprintToRemaining[fingerprint] > 0 {
+			fingerprintToRemaining[fingerprint]--
+			continue
+		}
+		filtered = append(filtered, fileAnnotation)
+	}
+	return filtered
+}
@@ -0,0 +1,112 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package buflintbaseline implements lint baseline files.
+//
+// A baseline file records the lint failures that are known to exist, so that
+// only new failures are reported. This allows new rules to be adopted incrementally.
+//
+// Failures are identified by their fingerprint, which is the rule ID, the file path,
+// the element path, and the message. Line and column information is not part of the
+// fingerprint, so failures stay matched when unrelated edits move them around a file.
+package buflintbaseline
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/encoding"
+	"go.uber.org/multierr"
+)
+
+const v1Version = "v1"
+
+// Baseline is a set of known lint failures.
+type Baseline interface {
+	// Filter returns the FileAnnotations that are not in the Baseline.
+	//
+	// Each failure recorded in the Baseline matches at most one FileAnnotation,
+	// so if a failure is recorded once and now occurs twice, one is returned.
+	Filter(fileAnnotations []bufanalysis.FileAnnotation) []bufanalysis.FileAnnotation
+}
+
+// NewBaseline returns a new Baseline that contains the FileAnnotations.
+func NewBaseline(fileAnnotations []bufanalysis.FileAnnotation) Baseline {
+	return newBaseline(fileAnnotations)
+}
+
+// ReadBaseline reads a Baseline from the reader.
+func ReadBaseline(reader io.Reader) (Baseline, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	var externalBaseline externalBaselineV1
+	if err := encoding.UnmarshalYAMLStrict(data, &externalBaseline); err != nil {
+		return nil, fmt.Errorf("could not read lint baseline: %w", err)
+	}
+	if externalBaseline.Version != v1Version {
+		return nil, fmt.Errorf("lint baseline has unknown version %q, expected %q", externalBaseline.Version, v1Version)
+	}
+	return newBaselineForExternal(externalBaseline), nil
+}
+
+// WriteBaseline writes a Baseline that contains the FileAnnotations to the writer.
+func WriteBaseline(writer io.Writer, fileAnnotations []bufanalysis.FileAnnotation) (retErr error) {
+	externalFailures := make([]externalFailureV1, 0, len(fileAnnotations))
+	for _, fileAnnotation := range fileAnnotations {
+		externalFailures = append(externalFailures, externalFailureV1(newFingerprint(fileAnnotation)))
+	}
+	sort.Slice(
+		externalFailures,
+		func(i int, j int) bool {
+			one := externalFailures[i]
+			two := externalFailures[j]
+			if one.Path != two.Path {
+				return one.Path < two.Path
+			}
+			if one.Rule != two.Rule {
+				return one.Rule < two.Rule
+			}
+			if one.Element != two.Element {
+				return one.Element < two.Element
+			}
+			return one.Message < two.Message
+		},
+	)
+	yamlEncoder := encoding.NewYAMLEncoder(writer)
+	defer func() {
+		retErr = multierr.Append(retErr, yamlEncoder.Close())
+	}()
+	return yamlEncoder.Encode(
+		externalBaselineV1{
+			Version:  v1Version,
+			Failures: externalFailures,
+		},
+	)
+}
+
+type externalBaselineV1 struct {
+	Version  string              `json:"version,omitempty" yaml:"version,omitempty"`
+	Failures []externalFailureV1 `json:"failures,omitempty" yaml:"failures,omitempty"`
+}
+
+type externalFailureV1 struct {
+	Rule    string `json:"rule,omitempty" yaml:"rule,omitempty"`
+	Path    string `json:"path,omitempty" yaml:"path,omitempty"`
+	Element string `json:"element,omitempty" yaml:"element,omitempty"`
+	Message string `json:"message,omitempty" yaml:"message,omitempty"`
+}
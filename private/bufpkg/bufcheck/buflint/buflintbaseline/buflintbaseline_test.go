@@ -0,0 +1,109 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buflintbaseline
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoundTrip(t *testing.T) {
+	t.Parallel()
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(
+		t,
+		WriteBaseline(
+			buffer,
+			[]bufanalysis.FileAnnotation{
+				newFileAnnotation("b.proto", 3, "FIELD_LOWER_SNAKE_CASE", "a.Foo.barBaz", `Field name "barBaz" should be lower_snake_case, such as "bar_baz".`),
+				newFileAnnotation("a.proto", 1, "PACKAGE_VERSION_SUFFIX", "", `Package name "a" should be suffixed with a correctly formed version, such as "a.v1".`),
+			},
+		),
+	)
+	assert.Equal(
+		t,
+		`version: v1
+failures:
+  - rule: PACKAGE_VERSION_SUFFIX
+    path: a.proto
+    message: Package name "a" should be suffixed with a correctly formed version, such as "a.v1".
+  - rule: FIELD_LOWER_SNAKE_CASE
+    path: b.proto
+    element: a.Foo.barBaz
+    message: Field name "barBaz" should be lower_snake_case, such as "bar_baz".
+`,
+		buffer.String(),
+	)
+	baseline, err := ReadBaseline(buffer)
+	require.NoError(t, err)
+	// Line numbers are not part of the fingerprint.
+	newFailure := newFileAnnotation("b.proto", 20, "FIELD_LOWER_SNAKE_CASE", "a.Foo.quxQuux", `Field name "quxQuux" should be lower_snake_case, such as "qux_quux".`)
+	assert.Equal(
+		t,
+		[]bufanalysis.FileAnnotation{newFailure},
+		baseline.Filter(
+			[]bufanalysis.FileAnnotation{
+				newFileAnnotation("a.proto", 5, "PACKAGE_VERSION_SUFFIX", "", `Package name "a" should be suffixed with a correctly formed version, such as "a.v1".`),
+				newFileAnnotation("b.proto", 10, "FIELD_LOWER_SNAKE_CASE", "a.Foo.barBaz", `Field name "barBaz" should be lower_snake_case, such as "bar_baz".`),
+				newFailure,
+			},
+		),
+	)
+}
+
+func TestFilterCounts(t *testing.T) {
+	t.Parallel()
+	fileAnnotation := newFileAnnotation("a.proto", 1, "COMMENT_FIELD", "a.Foo.bar", `Field "bar" should have a non-empty comment for documentation.`)
+	baseline := NewBaseline([]bufanalysis.FileAnnotation{fileAnnotation})
+	assert.Empty(t, baseline.Filter([]bufanalysis.FileAnnotation{fileAnnotation}))
+	// Each recorded failure only matches once.
+	assert.Len(t, baseline.Filter([]bufanalysis.FileAnnotation{fileAnnotation, fileAnnotation}), 1)
+}
+
+func TestReadBaselineUnknownVersion(t *testing.T) {
+	t.Parallel()
+	_, err := ReadBaseline(strings.NewReader("version: v2\n"))
+	require.Error(t, err)
+}
+
+func newFileAnnotation(path string, line int, typeString string, elementPath string, message string) bufanalysis.FileAnnotation {
+	return bufanalysis.NewFileAnnotation(
+		&fileInfo{path: path},
+		line,
+		1,
+		line,
+		10,
+		typeString,
+		message,
+		bufanalysis.FileAnnotationWithElementPath(elementPath),
+	)
+}
+
+type fileInfo struct {
+	path string
+}
+
+func (f *fileInfo) Path() string {
+	return f.path
+}
+
+func (f *fileInfo) ExternalPath() string {
+	return "external/" + f.path
+}
@@ -61,6 +61,26 @@ type Config struct {
 	AllowCommentIgnores bool
 	// Version represents the version of the lint rule and category IDs that should be used with this config.
 	Version string
+	// Plugins are the plugins that provide additional lint rules.
+	//
+	// The rules of plugins can be selected with Use and Except like built-in rules. Plugins
+	// refer to local executables, so they are not part of the proto representation of the Config.
+	Plugins []*PluginConfig
+	// IDOrCategoryToSeverity is a map of rule and/or category IDs to the severity of their failures.
+	//
+	// Valid severities are error, warning, and info. Only failures with the error severity
+	// fail the lint check. Rules without a severity default to error.
+	IDOrCategoryToSeverity map[string]string
+}
+
+// PluginConfig is the config for a lint plugin.
+type PluginConfig struct {
+	// Plugin is the name of the plugin on the PATH, or the path to the plugin.
+	//
+	// Plugins with a path ending in .wasm are run as WASM modules.
+	Plugin string
+	// Options are passed to the plugin.
+	Options map[string]string
 }
 
 // NewConfigV1Beta1 returns a new Config.
@@ -94,6 +114,8 @@ func NewConfigV1(externalConfig ExternalConfigV1) *Config {
 		ServiceSuffix:                        externalConfig.ServiceSuffix,
 		AllowCommentIgnores:                  externalConfig.AllowCommentIgnores,
 		Version:                              v1Version,
+		Plugins:                              pluginConfigsForExternal(externalConfig.Plugins),
+		IDOrCategoryToSeverity:               externalConfig.Severity,
 	}
 }
 
@@ -147,6 +169,12 @@ type ExternalConfigV1Beta1 struct {
 	AllowCommentIgnores                  bool                `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
 }
 
+// ExternalPluginConfigV1 is an external plugin config.
+type ExternalPluginConfigV1 struct {
+	Plugin  string            `json:"plugin,omitempty" yaml:"plugin,omitempty"`
+	Options map[string]string `json:"options,omitempty" yaml:"options,omitempty"`
+}
+
 // ExternalConfigV1 is an external config.
 type ExternalConfigV1 struct {
 	Use    []string `json:"use,omitempty" yaml:"use,omitempty"`
@@ -154,13 +182,16 @@ type ExternalConfigV1 struct {
 	// IgnoreRootPaths
 	Ignore []string `json:"ignore,omitempty" yaml:"ignore,omitempty"`
 	// IgnoreIDOrCategoryToRootPaths
-	IgnoreOnly                           map[string][]string `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
-	EnumZeroValueSuffix                  string              `json:"enum_zero_value_suffix,omitempty" yaml:"enum_zero_value_suffix,omitempty"`
-	RPCAllowSameRequestResponse          bool                `json:"rpc_allow_same_request_response,omitempty" yaml:"rpc_allow_same_request_response,omitempty"`
-	RPCAllowGoogleProtobufEmptyRequests  bool                `json:"rpc_allow_google_protobuf_empty_requests,omitempty" yaml:"rpc_allow_google_protobuf_empty_requests,omitempty"`
-	RPCAllowGoogleProtobufEmptyResponses bool                `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
-	ServiceSuffix                        string              `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
-	AllowCommentIgnores                  bool                `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
+	IgnoreOnly                           map[string][]string      `json:"ignore_only,omitempty" yaml:"ignore_only,omitempty"`
+	EnumZeroValueSuffix                  string                   `json:"enum_zero_value_suffix,omitempty" yaml:"enum_zero_value_suffix,omitempty"`
+	RPCAllowSameRequestResponse          bool                     `json:"rpc_allow_same_request_response,omitempty" yaml:"rpc_allow_same_request_response,omitempty"`
+	RPCAllowGoogleProtobufEmptyRequests  bool                     `json:"rpc_allow_google_protobuf_empty_requests,omitempty" yaml:"rpc_allow_google_protobuf_empty_requests,omitempty"`
+	RPCAllowGoogleProtobufEmptyResponses bool                     `json:"rpc_allow_google_protobuf_empty_responses,omitempty" yaml:"rpc_allow_google_protobuf_empty_responses,omitempty"`
+	ServiceSuffix                        string                   `json:"service_suffix,omitempty" yaml:"service_suffix,omitempty"`
+	AllowCommentIgnores                  bool                     `json:"allow_comment_ignores,omitempty" yaml:"allow_comment_ignores,omitempty"`
+	Plugins                              []ExternalPluginConfigV1 `json:"plugins,omitempty" yaml:"plugins,omitempty"`
+	// IDOrCategoryToSeverity
+	Severity map[string]string `json:"severity,omitempty" yaml:"severity,omitempty"`
 }
 
 // ExternalConfigV1Beta1ForConfig takes a *Config and returns the v1beta1 externalconfig representation.
@@ -192,6 +223,25 @@ func ExternalConfigV1ForConfig(config *Config) ExternalConfigV1 {
 		RPCAllowGoogleProtobufEmptyResponses: config.RPCAllowGoogleProtobufEmptyResponses,
 		ServiceSuffix:                        config.ServiceSuffix,
 		AllowCommentIgnores:                  config.AllowCommentIgnores,
+		Plugins:                              externalPluginConfigsForPluginConfigs(config.Plugins),
+		Severity:                             config.IDOrCategoryToSeverity,
+	}
+}
+
+// ExternalConfigV1ForExternalConfigV1Beta1 takes a v1beta1 externalconfig and returns
+// the equivalent v1 externalconfig.
+func ExternalConfigV1ForExternalConfigV1Beta1(externalConfig ExternalConfigV1Beta1) ExternalConfigV1 {
+	return ExternalConfigV1{
+		Use:                                  externalConfig.Use,
+		Except:                               externalConfig.Except,
+		Ignore:                               externalConfig.Ignore,
+		IgnoreOnly:                           externalConfig.IgnoreOnly,
+		EnumZeroValueSuffix:                  externalConfig.EnumZeroValueSuffix,
+		RPCAllowSameRequestResponse:          externalConfig.RPCAllowSameRequestResponse,
+		RPCAllowGoogleProtobufEmptyRequests:  externalConfig.RPCAllowGoogleProtobufEmptyRequests,
+		RPCAllowGoogleProtobufEmptyResponses: externalConfig.RPCAllowGoogleProtobufEmptyResponses,
+		ServiceSuffix:                        externalConfig.ServiceSuffix,
+		AllowCommentIgnores:                  externalConfig.AllowCommentIgnores,
 	}
 }
 
@@ -269,12 +319,13 @@ func PrintFileAnnotations(
 	writer io.Writer,
 	fileAnnotations []bufanalysis.FileAnnotation,
 	formatString string,
+	options ...bufanalysis.PrintFileAnnotationsOption,
 ) error {
 	switch s := strings.ToLower(strings.TrimSpace(formatString)); s {
 	case "config-ignore-yaml":
 		return printFileAnnotationsConfigIgnoreYAML(writer, fileAnnotations)
 	default:
-		return bufanalysis.PrintFileAnnotations(writer, fileAnnotations, s)
+		return bufanalysis.PrintFileAnnotations(writer, fileAnnotations, s, options...)
 	}
 }
 
@@ -334,6 +385,34 @@ lint:
 	return err
 }
 
+func pluginConfigsForExternal(externalPluginConfigs []ExternalPluginConfigV1) []*PluginConfig {
+	if len(externalPluginConfigs) == 0 {
+		return nil
+	}
+	pluginConfigs := make([]*PluginConfig, len(externalPluginConfigs))
+	for i, externalPluginConfig := range externalPluginConfigs {
+		pluginConfigs[i] = &PluginConfig{
+			Plugin:  externalPluginConfig.Plugin,
+			Options: externalPluginConfig.Options,
+		}
+	}
+	return pluginConfigs
+}
+
+func externalPluginConfigsForPluginConfigs(pluginConfigs []*PluginConfig) []ExternalPluginConfigV1 {
+	if len(pluginConfigs) == 0 {
+		return nil
+	}
+	externalPluginConfigs := make([]ExternalPluginConfigV1, len(pluginConfigs))
+	for i, pluginConfig := range pluginConfigs {
+		externalPluginConfigs[i] = ExternalPluginConfigV1{
+			Plugin:  pluginConfig.Plugin,
+			Options: pluginConfig.Options,
+		}
+	}
+	return externalPluginConfigs
+}
+
 func ignoreIDOrCategoryToRootPathsForProto(protoIgnoreIDPaths []*lintv1.IDPaths) map[string][]string {
 	if protoIgnoreIDPaths == nil {
 		return nil
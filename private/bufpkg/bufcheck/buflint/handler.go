@@ -16,20 +16,32 @@ package buflint
 
 import (
 	"context"
+	"encoding/json"
+	"sync"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/buflintconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/buflint/internal/buflintcheck"
 	"github.com/bufbuild/buf/private/bufpkg/bufcheck/internal"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage"
 	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/protosource"
+	"go.uber.org/multierr"
 	"go.uber.org/zap"
 )
 
 type handler struct {
 	logger *zap.Logger
 	runner *internal.Runner
+	// commandRunner runs lint plugins.
+	commandRunner command.Runner
+
+	pluginsLock sync.Mutex
+	// keyToPlugin are the plugins run by the handler, by their path and options.
+	keyToPlugin map[string]bufcheckplugin.Plugin
 }
 
 func newHandler(logger *zap.Logger) *handler {
@@ -43,6 +55,8 @@ func newHandler(logger *zap.Logger) *handler {
 			logger,
 			internal.RunnerWithIgnorePrefix(buflintcheck.CommentIgnorePrefix),
 		),
+		commandRunner: command.NewRunner(),
+		keyToPlugin:   make(map[string]bufcheckplugin.Plugin),
 	}
 }
 
@@ -51,13 +65,58 @@ func (h *handler) Check(
 	config *buflintconfig.Config,
 	image bufimage.Image,
 ) ([]bufanalysis.FileAnnotation, error) {
-	files, err := protosource.NewFilesUnstable(ctx, bufimageutil.NewInputFiles(image.Files())...)
+	files, err := protosource.NewFilesUnstable(
+		ctx,
+		bufimageutil.NewInputFiles(bufimage.ImageWithoutImports(image).Files())...,
+	)
 	if err != nil {
 		return nil, err
 	}
-	internalConfig, err := internalConfigForConfig(config)
+	internalConfig, err := internalConfigForConfig(ctx, h.getPlugin, config, image)
 	if err != nil {
 		return nil, err
 	}
 	return h.runner.Check(ctx, internalConfig, nil, files)
 }
+
+func (h *handler) Rules(ctx context.Context, config *buflintconfig.Config) ([]bufcheck.Rule, error) {
+	internalConfig, err := internalConfigForConfig(ctx, h.getPlugin, config, nil)
+	if err != nil {
+		return nil, err
+	}
+	return rulesForInternalRules(internalConfig.Rules), nil
+}
+
+func (h *handler) Close() error {
+	h.pluginsLock.Lock()
+	defer h.pluginsLock.Unlock()
+	var err error
+	for key, plugin := range h.keyToPlugin {
+		err = multierr.Append(err, plugin.Close())
+		delete(h.keyToPlugin, key)
+	}
+	return err
+}
+
+// getPlugin returns the plugin for the config, creating it on first use.
+func (h *handler) getPlugin(pluginConfig *buflintconfig.PluginConfig) (bufcheckplugin.Plugin, error) {
+	// The options are a map, which encoding/json marshals with sorted keys.
+	keyData, err := json.Marshal(pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	key := string(keyData)
+	h.pluginsLock.Lock()
+	defer h.pluginsLock.Unlock()
+	plugin, ok := h.keyToPlugin[key]
+	if !ok {
+		plugin = bufcheckplugin.NewPlugin(
+			h.commandRunner,
+			bufcheckplugin.CheckTypeLint,
+			pluginConfig.Plugin,
+			pluginConfig.Options,
+		)
+		h.keyToPlugin[key] = plugin
+	}
+	return plugin, nil
+}
@@ -19,6 +19,7 @@ import (
 	"sort"
 	"strings"
 
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/stringutil"
 )
@@ -51,6 +52,11 @@ type ConfigBuilder struct {
 	IgnoreRootPaths               []string
 	IgnoreIDOrCategoryToRootPaths map[string][]string
 
+	// IDOrCategoryToSeverity maps rule and/or category IDs to severity strings.
+	//
+	// Rules without a severity have bufanalysis.SeverityError.
+	IDOrCategoryToSeverity map[string]string
+
 	AllowCommentIgnores    bool
 	IgnoreUnstablePackages bool
 
@@ -59,6 +65,12 @@ type ConfigBuilder struct {
 	RPCAllowGoogleProtobufEmptyRequests  bool
 	RPCAllowGoogleProtobufEmptyResponses bool
 	ServiceSuffix                        string
+
+	// PluginRuleBuilders are the RuleBuilders for rules provided by plugins,
+	// in addition to the RuleBuilders of the VersionSpec.
+	PluginRuleBuilders []*RuleBuilder
+	// PluginIDToCategories maps the IDs of the PluginRuleBuilders to their categories.
+	PluginIDToCategories map[string][]string
 }
 
 // NewConfig returns a new Config.
@@ -79,13 +91,60 @@ func newConfig(configBuilder ConfigBuilder, versionSpec *VersionSpec) (*Config,
 	if configBuilder.ServiceSuffix == "" {
 		configBuilder.ServiceSuffix = defaultServiceSuffix
 	}
+	ruleBuilders, idToCategories, err := mergePluginRuleBuilders(
+		versionSpec,
+		configBuilder.PluginRuleBuilders,
+		configBuilder.PluginIDToCategories,
+	)
+	if err != nil {
+		return nil, err
+	}
 	return newConfigForRuleBuilders(
 		configBuilder,
-		versionSpec.RuleBuilders,
-		versionSpec.IDToCategories,
+		ruleBuilders,
+		idToCategories,
 	)
 }
 
+func mergePluginRuleBuilders(
+	versionSpec *VersionSpec,
+	pluginRuleBuilders []*RuleBuilder,
+	pluginIDToCategories map[string][]string,
+) ([]*RuleBuilder, map[string][]string, error) {
+	if len(pluginRuleBuilders) == 0 {
+		return versionSpec.RuleBuilders, versionSpec.IDToCategories, nil
+	}
+	builtinCategories := make(map[string]struct{})
+	for _, category := range AllCategoriesForVersionSpec(versionSpec) {
+		builtinCategories[category] = struct{}{}
+	}
+	ruleBuilders := make([]*RuleBuilder, 0, len(versionSpec.RuleBuilders)+len(pluginRuleBuilders))
+	ruleBuilders = append(ruleBuilders, versionSpec.RuleBuilders...)
+	idToCategories := make(map[string][]string, len(versionSpec.IDToCategories)+len(pluginIDToCategories))
+	for id, categories := range versionSpec.IDToCategories {
+		idToCategories[id] = categories
+	}
+	for _, pluginRuleBuilder := range pluginRuleBuilders {
+		id := pluginRuleBuilder.ID()
+		if _, ok := idToCategories[id]; ok {
+			return nil, nil, fmt.Errorf("plugin rule %q has the same ID as another rule", id)
+		}
+		if _, ok := builtinCategories[id]; ok {
+			return nil, nil, fmt.Errorf("plugin rule %q has the same ID as a built-in category", id)
+		}
+		categories := pluginIDToCategories[id]
+		for _, category := range categories {
+			if _, ok := builtinCategories[category]; ok {
+				return nil, nil, fmt.Errorf("plugin rule %q cannot be added to the built-in category %q", id, category)
+			}
+		}
+		ruleBuilders = append(ruleBuilders, pluginRuleBuilder)
+		// Make sure there is an entry even if there are no categories.
+		idToCategories[id] = append([]string{}, categories...)
+	}
+	return ruleBuilders, idToCategories, nil
+}
+
 func newConfigForRuleBuilders(
 	configBuilder ConfigBuilder,
 	ruleBuilders []*RuleBuilder,
@@ -129,6 +188,10 @@ func newConfigForRuleBuilders(
 	for _, ruleBuilder := range resultIDToRuleBuilder {
 		resultRuleBuilders = append(resultRuleBuilders, ruleBuilder)
 	}
+	idToSeverity, err := transformToIDToSeverity(configBuilder.IDOrCategoryToSeverity, idToCategories, categoryToIDs)
+	if err != nil {
+		return nil, err
+	}
 	resultRules := make([]*Rule, 0, len(resultRuleBuilders))
 	for _, ruleBuilder := range resultRuleBuilders {
 		categories, err := getRuleBuilderCategories(ruleBuilder, idToCategories)
@@ -139,6 +202,9 @@ func newConfigForRuleBuilders(
 		if err != nil {
 			return nil, err
 		}
+		if severity, ok := idToSeverity[rule.ID()]; ok {
+			rule.severity = severity
+		}
 		resultRules = append(resultRules, rule)
 	}
 	sortRules(resultRules)
@@ -249,6 +315,52 @@ func transformToIDToListMap(idOrCategoryToList map[string][]string, idToCategori
 	return idToListMap, nil
 }
 
+// transformToIDToSeverity resolves the severities of rule and/or category IDs
+// to the severities of rule IDs.
+//
+// A severity set for a rule ID takes precedence over a severity set for any of
+// its categories. If the categories of a rule have different severities, the most
+// severe is used.
+func transformToIDToSeverity(
+	idOrCategoryToSeverity map[string]string,
+	idToCategories map[string][]string,
+	categoryToIDs map[string][]string,
+) (map[string]bufanalysis.Severity, error) {
+	if len(idOrCategoryToSeverity) == 0 {
+		return nil, nil
+	}
+	idToSeverity := make(map[string]bufanalysis.Severity)
+	idToCategorySeverity := make(map[string]bufanalysis.Severity)
+	for idOrCategory, severityString := range idOrCategoryToSeverity {
+		if idOrCategory == "" {
+			continue
+		}
+		severity, err := bufanalysis.ParseSeverity(severityString)
+		if err != nil {
+			return nil, fmt.Errorf("invalid severity for %q, must be one of %s: %w", idOrCategory, stringutil.SliceToString(bufanalysis.AllSeverityStrings), err)
+		}
+		if _, ok := idToCategories[idOrCategory]; ok {
+			id := idOrCategory
+			idToSeverity[id] = severity
+		} else if ids, ok := categoryToIDs[idOrCategory]; ok {
+			for _, id := range ids {
+				// Severities are ordered from most severe to least severe.
+				if existingSeverity, ok := idToCategorySeverity[id]; !ok || severity < existingSeverity {
+					idToCategorySeverity[id] = severity
+				}
+			}
+		} else {
+			return nil, fmt.Errorf("%q is not a known id or category", idOrCategory)
+		}
+	}
+	for id, severity := range idToCategorySeverity {
+		if _, ok := idToSeverity[id]; !ok {
+			idToSeverity[id] = severity
+		}
+	}
+	return idToSeverity, nil
+}
+
 func getCategoryToIDs(idToCategories map[string][]string) map[string][]string {
 	categoryToIDs := make(map[string][]string)
 	for id, categories := range idToCategories {
@@ -159,6 +159,10 @@ func newFileAnnotationf(
 	if descriptor != nil {
 		fileInfo = descriptor.File()
 	}
+	var elementPath string
+	if namedDescriptor, ok := descriptor.(protosource.NamedDescriptor); ok {
+		elementPath = namedDescriptor.FullName()
+	}
 	return bufanalysis.NewFileAnnotation(
 		fileInfo,
 		startLine,
@@ -167,5 +171,6 @@ func newFileAnnotationf(
 		endColumn,
 		id,
 		fmt.Sprintf(format, args...),
+		bufanalysis.FileAnnotationWithElementPath(elementPath),
 	)
 }
@@ -0,0 +1,132 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+)
+
+// NewPluginRuleBuilder returns a new RuleBuilder for a rule provided by a plugin.
+//
+// getFileAnnotations returns the FileAnnotations produced by the plugin for all of
+// its rules. It is called once for every rule of the plugin that is run, so it should
+// cache its result. Only the FileAnnotations with the rule id as the Type are used.
+//
+// Ignores are applied to the FileAnnotations in the same way as for built-in rules,
+// using the file path and, if set, the element path of each FileAnnotation.
+func NewPluginRuleBuilder(
+	id string,
+	purpose string,
+	getFileAnnotations func(ctx context.Context) ([]bufanalysis.FileAnnotation, error),
+) *RuleBuilder {
+	return &RuleBuilder{
+		id:         id,
+		newPurpose: newNopPurpose(purpose),
+		newCheck: func(ConfigBuilder) (ContextCheckFunc, error) {
+			return func(ctx context.Context, id string, ignoreFunc IgnoreFunc, _ []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+				pluginFileAnnotations, err := getFileAnnotations(ctx)
+				if err != nil {
+					return nil, err
+				}
+				filePathToFile, err := protosource.FilePathToFile(files...)
+				if err != nil {
+					return nil, err
+				}
+				filePathToFullNameToDescriptor := make(map[string]map[string]protosource.LocationDescriptor)
+				var fileAnnotations []bufanalysis.FileAnnotation
+				for _, fileAnnotation := range pluginFileAnnotations {
+					if fileAnnotation.Type() != id {
+						continue
+					}
+					var descriptor protosource.Descriptor
+					var location protosource.Location
+					if fileInfo := fileAnnotation.FileInfo(); fileInfo != nil {
+						if file, ok := filePathToFile[fileInfo.Path()]; ok {
+							descriptor = file
+							if elementPath := fileAnnotation.ElementPath(); elementPath != "" {
+								fullNameToDescriptor, ok := filePathToFullNameToDescriptor[file.Path()]
+								if !ok {
+									fullNameToDescriptor = getFullNameToDescriptor(file)
+									filePathToFullNameToDescriptor[file.Path()] = fullNameToDescriptor
+								}
+								if elementDescriptor, ok := fullNameToDescriptor[elementPath]; ok {
+									descriptor = elementDescriptor
+									location = elementDescriptor.Location()
+								}
+							}
+						}
+					}
+					if ignoreFunc != nil && ignoreFunc(
+						id,
+						[]protosource.Descriptor{descriptor},
+						[]protosource.Location{location},
+					) {
+						continue
+					}
+					fileAnnotations = append(fileAnnotations, fileAnnotation)
+				}
+				return fileAnnotations, nil
+			}, nil
+		},
+	}
+}
+
+// getFullNameToDescriptor returns a map from fully-qualified name to descriptor
+// for all the named descriptors within the File.
+func getFullNameToDescriptor(file protosource.File) map[string]protosource.LocationDescriptor {
+	fullNameToDescriptor := make(map[string]protosource.LocationDescriptor)
+	add := func(namedDescriptor protosource.NamedDescriptor) {
+		fullNameToDescriptor[namedDescriptor.FullName()] = namedDescriptor
+	}
+	_ = protosource.ForEachMessage(
+		func(message protosource.Message) error {
+			add(message)
+			for _, field := range message.Fields() {
+				add(field)
+			}
+			for _, extension := range message.Extensions() {
+				add(extension)
+			}
+			for _, oneof := range message.Oneofs() {
+				add(oneof)
+			}
+			return nil
+		},
+		file,
+	)
+	_ = protosource.ForEachEnum(
+		func(enum protosource.Enum) error {
+			add(enum)
+			for _, enumValue := range enum.Values() {
+				add(enumValue)
+			}
+			return nil
+		},
+		file,
+	)
+	for _, service := range file.Services() {
+		add(service)
+		for _, method := range service.Methods() {
+			add(method)
+		}
+	}
+	for _, extension := range file.Extensions() {
+		add(extension)
+	}
+	return fullNameToDescriptor
+}
@@ -0,0 +1,82 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufcheckplugin"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// PluginChecker runs a plugin once for all of its selected rules.
+type PluginChecker struct {
+	plugin       bufcheckplugin.Plugin
+	image        bufimage.Image
+	againstImage bufimage.Image
+	// ruleIDs are the IDs of the selected rules of the plugin.
+	//
+	// Added after the internal config is built.
+	ruleIDs []string
+
+	once            sync.Once
+	fileAnnotations []bufanalysis.FileAnnotation
+	err             error
+}
+
+// NewPluginChecker returns a new PluginChecker.
+//
+// If image is nil, the PluginChecker returns an error when run, as configs without
+// images are only used for printing. againstImage is only set for breaking plugins.
+func NewPluginChecker(
+	plugin bufcheckplugin.Plugin,
+	image bufimage.Image,
+	againstImage bufimage.Image,
+) *PluginChecker {
+	return &PluginChecker{
+		plugin:       plugin,
+		image:        image,
+		againstImage: againstImage,
+	}
+}
+
+// AddRuleID adds the ID of a selected rule of the plugin.
+//
+// Must be called before FileAnnotations.
+func (p *PluginChecker) AddRuleID(ruleID string) {
+	p.ruleIDs = append(p.ruleIDs, ruleID)
+}
+
+// FileAnnotations returns the FileAnnotations of the plugin for all of the selected rules.
+//
+// The plugin is only run on the first call, the context of later calls is not used.
+func (p *PluginChecker) FileAnnotations(ctx context.Context) ([]bufanalysis.FileAnnotation, error) {
+	p.once.Do(func() {
+		if p.image == nil {
+			// This is a system error, configs without images are only used for printing.
+			p.err = fmt.Errorf("no image to check with plugin %q", p.plugin.Name())
+			return
+		}
+		var checkOptions []bufcheckplugin.CheckOption
+		if p.againstImage != nil {
+			checkOptions = append(checkOptions, bufcheckplugin.CheckWithAgainstImage(p.againstImage))
+		}
+		p.fileAnnotations, p.err = p.plugin.Check(ctx, p.image, p.ruleIDs, checkOptions...)
+	})
+	return p.fileAnnotations, p.err
+}
@@ -15,6 +15,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"sort"
 
@@ -38,12 +39,19 @@ type IgnoreFunc func(id string, descriptors []protosource.Descriptor, locations
 // CheckFunc is a check function.
 type CheckFunc func(id string, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error)
 
+// ContextCheckFunc is a check function that takes the context of the check.
+//
+// This is used by rules that do more than inspect the files, such as the rules of plugins.
+type ContextCheckFunc func(ctx context.Context, id string, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error)
+
 // Rule provides a base embeddable rule.
 type Rule struct {
 	id         string
 	categories []string
 	purpose    string
-	checkFunc  CheckFunc
+	checkFunc  ContextCheckFunc
+	// severity is set by the Config.
+	severity bufanalysis.Severity
 }
 
 // newRule returns a new Rule.
@@ -54,7 +62,7 @@ func newRule(
 	id string,
 	categories []string,
 	purpose string,
-	checkFunc CheckFunc,
+	checkFunc ContextCheckFunc,
 ) *Rule {
 	c := make([]string, len(categories))
 	copy(c, categories)
@@ -69,6 +77,7 @@ func newRule(
 		categories: c,
 		purpose:    "Checks that " + purpose + ".",
 		checkFunc:  checkFunc,
+		severity:   bufanalysis.SeverityError,
 	}
 }
 
@@ -87,13 +96,39 @@ func (c *Rule) Purpose() string {
 	return c.purpose
 }
 
+// Severity returns the severity of the FileAnnotations produced by the Rule.
+func (c *Rule) Severity() bufanalysis.Severity {
+	return c.severity
+}
+
 // MarshalJSON implements Rule.
 func (c *Rule) MarshalJSON() ([]byte, error) {
 	return json.Marshal(ruleJSON{ID: c.id, Categories: c.categories, Purpose: c.purpose})
 }
 
-func (c *Rule) check(ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
-	return c.checkFunc(c.ID(), ignoreFunc, previousFiles, files)
+func (c *Rule) check(ctx context.Context, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+	fileAnnotations, err := c.checkFunc(ctx, c.ID(), ignoreFunc, previousFiles, files)
+	if err != nil {
+		return nil, err
+	}
+	if c.severity == bufanalysis.SeverityError {
+		return fileAnnotations, nil
+	}
+	fileAnnotationsWithSeverity := make([]bufanalysis.FileAnnotation, len(fileAnnotations))
+	for i, fileAnnotation := range fileAnnotations {
+		fileAnnotationsWithSeverity[i] = bufanalysis.NewFileAnnotation(
+			fileAnnotation.FileInfo(),
+			fileAnnotation.StartLine(),
+			fileAnnotation.StartColumn(),
+			fileAnnotation.EndLine(),
+			fileAnnotation.EndColumn(),
+			fileAnnotation.Type(),
+			fileAnnotation.Message(),
+			bufanalysis.FileAnnotationWithElementPath(fileAnnotation.ElementPath()),
+			bufanalysis.FileAnnotationWithSeverity(c.severity),
+		)
+	}
+	return fileAnnotationsWithSeverity, nil
 }
 
 type ruleJSON struct {
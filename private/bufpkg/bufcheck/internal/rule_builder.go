@@ -15,6 +15,8 @@
 package internal
 
 import (
+	"context"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
 	"github.com/bufbuild/buf/private/pkg/protosource"
 )
@@ -23,7 +25,7 @@ import (
 type RuleBuilder struct {
 	id         string
 	newPurpose func(ConfigBuilder) (string, error)
-	newCheck   func(ConfigBuilder) (CheckFunc, error)
+	newCheck   func(ConfigBuilder) (ContextCheckFunc, error)
 }
 
 // NewRuleBuilder returns a new RuleBuilder.
@@ -35,7 +37,13 @@ func NewRuleBuilder(
 	return &RuleBuilder{
 		id:         id,
 		newPurpose: newPurpose,
-		newCheck:   newCheck,
+		newCheck: func(configBuilder ConfigBuilder) (ContextCheckFunc, error) {
+			check, err := newCheck(configBuilder)
+			if err != nil {
+				return nil, err
+			}
+			return newContextCheckFunc(check), nil
+		},
 	}
 }
 
@@ -87,6 +95,13 @@ func newNopPurpose(purpose string) func(ConfigBuilder) (string, error) {
 	}
 }
 
+// newContextCheckFunc returns a ContextCheckFunc that ignores the context.
+func newContextCheckFunc(f CheckFunc) ContextCheckFunc {
+	return func(_ context.Context, id string, ignoreFunc IgnoreFunc, previousFiles []protosource.File, files []protosource.File) ([]bufanalysis.FileAnnotation, error) {
+		return f(id, ignoreFunc, previousFiles, files)
+	}
+}
+
 func newNopCheckFunc(
 	f func(string, IgnoreFunc, []protosource.File, []protosource.File) ([]bufanalysis.FileAnnotation, error),
 ) func(ConfigBuilder) (CheckFunc, error) {
@@ -87,7 +87,7 @@ func (r *Runner) Check(ctx context.Context, config *Config, previousFiles []prot
 	for _, rule := range rules {
 		rule := rule
 		go func() {
-			iFileAnnotations, iErr := rule.check(ignoreFunc, previousFiles, files)
+			iFileAnnotations, iErr := rule.check(ctx, ignoreFunc, previousFiles, files)
 			resultC <- newResult(iFileAnnotations, iErr)
 		}()
 	}
@@ -0,0 +1,220 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufdiff computes the semantic changes between two images.
+//
+// Elements are paired in the same way as by the breaking change rules: files by path,
+// messages, enums, services, and extensions by fully-qualified name, fields by number,
+// and enum values, oneofs, and methods by name.
+package bufdiff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+)
+
+const (
+	// ChangeTypeAdded says that the element was added.
+	ChangeTypeAdded ChangeType = iota + 1
+	// ChangeTypeRemoved says that the element was removed.
+	ChangeTypeRemoved
+	// ChangeTypeModified says that the element is present on both sides, but was modified.
+	ChangeTypeModified
+)
+
+const (
+	// ElementTypeFile is a file.
+	ElementTypeFile ElementType = iota + 1
+	// ElementTypeMessage is a message.
+	ElementTypeMessage
+	// ElementTypeField is a message field.
+	ElementTypeField
+	// ElementTypeOneof is a oneof.
+	ElementTypeOneof
+	// ElementTypeEnum is an enum.
+	ElementTypeEnum
+	// ElementTypeEnumValue is an enum value.
+	ElementTypeEnumValue
+	// ElementTypeService is a service.
+	ElementTypeService
+	// ElementTypeMethod is a method.
+	ElementTypeMethod
+	// ElementTypeExtension is an extension.
+	ElementTypeExtension
+)
+
+const (
+	// FormatText is the text format for Changes.
+	FormatText Format = iota + 1
+	// FormatJSON is the JSON format for Changes.
+	FormatJSON
+	// FormatMarkdown is the Markdown format for Changes.
+	FormatMarkdown
+)
+
+var (
+	// AllFormatStrings is all format strings.
+	//
+	// Sorted in the order we want to display them.
+	AllFormatStrings = []string{
+		"text",
+		"json",
+		"markdown",
+	}
+
+	stringToFormat = map[string]Format{
+		"text":     FormatText,
+		"json":     FormatJSON,
+		"markdown": FormatMarkdown,
+	}
+	formatToString = map[Format]string{
+		FormatText:     "text",
+		FormatJSON:     "json",
+		FormatMarkdown: "markdown",
+	}
+	changeTypeToString = map[ChangeType]string{
+		ChangeTypeAdded:    "added",
+		ChangeTypeRemoved:  "removed",
+		ChangeTypeModified: "modified",
+	}
+	elementTypeToString = map[ElementType]string{
+		ElementTypeFile:      "file",
+		ElementTypeMessage:   "message",
+		ElementTypeField:     "field",
+		ElementTypeOneof:     "oneof",
+		ElementTypeEnum:      "enum",
+		ElementTypeEnumValue: "enum_value",
+		ElementTypeService:   "service",
+		ElementTypeMethod:    "method",
+		ElementTypeExtension: "extension",
+	}
+)
+
+// ChangeType is the type of a Change.
+type ChangeType int
+
+// String implements fmt.Stringer.
+func (c ChangeType) String() string {
+	s, ok := changeTypeToString[c]
+	if !ok {
+		return strconv.Itoa(int(c))
+	}
+	return s
+}
+
+// ElementType is the type of the element that a Change is for.
+type ElementType int
+
+// String implements fmt.Stringer.
+func (e ElementType) String() string {
+	s, ok := elementTypeToString[e]
+	if !ok {
+		return strconv.Itoa(int(e))
+	}
+	return s
+}
+
+// Format is a format for Changes.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	s, ok := formatToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFormat parses the Format.
+//
+// The empty strings defaults to FormatText.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return FormatText, nil
+	}
+	f, ok := stringToFormat[s]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown format: %q", s)
+}
+
+// Change is a change to an element.
+type Change interface {
+	// Type is the type of the change.
+	Type() ChangeType
+	// ElementType is the type of the changed element.
+	ElementType() ElementType
+	// Package is the package of the element.
+	//
+	// For removed elements, this is the package on the previous side.
+	// Empty for elements without a package.
+	Package() string
+	// Name is the fully-qualified name of the element, or the path for files.
+	Name() string
+	// Path is the path of the file that contains the element.
+	//
+	// For removed elements, this is the path on the previous side.
+	Path() string
+	// Details describes the modifications of the element, such as `type changed from "string" to "bytes"`.
+	//
+	// Only set for ChangeTypeModified.
+	Details() []string
+}
+
+// Diff returns the Changes between the previous Image and the Image.
+//
+// Changes are sorted by package, type, name, and element type. Only the elements
+// themselves are reported as added or removed, for example the fields of an added
+// message are not reported separately.
+func Diff(ctx context.Context, previousImage bufimage.Image, image bufimage.Image) ([]Change, error) {
+	previousFiles, err := protosource.NewFilesUnstable(ctx, bufimageutil.NewInputFiles(previousImage.Files())...)
+	if err != nil {
+		return nil, err
+	}
+	files, err := protosource.NewFilesUnstable(ctx, bufimageutil.NewInputFiles(image.Files())...)
+	if err != nil {
+		return nil, err
+	}
+	return diff(previousFiles, files)
+}
+
+// IsCommentOrOptionChange returns true if the Change is a modification of only
+// the comments or options of the element.
+func IsCommentOrOptionChange(change Change) bool {
+	return isCommentOrOptionChange(change)
+}
+
+// SortChanges sorts the Changes by package, type, name, and element type.
+//
+// This should be used when combining the Changes of multiple calls to Diff.
+func SortChanges(changes []Change) {
+	sortChanges(changes)
+}
+
+// PrintChanges prints the Changes to the Writer.
+//
+// Changes are grouped by package, and must be sorted.
+func PrintChanges(writer io.Writer, changes []Change, format Format) error {
+	return printChanges(writer, changes, format)
+}
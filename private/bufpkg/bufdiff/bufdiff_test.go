@@ -0,0 +1,254 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const (
+	testPreviousProto = `syntax = "proto3";
+package acme.v1;
+// A Foo.
+message Foo {
+  string one = 1;
+  string two = 2;
+  map<string, string> labels = 3;
+}
+message Bar {}
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+}
+service FooService {
+  rpc GetFoo(Foo) returns (Foo);
+}
+`
+	testProto = `syntax = "proto3";
+package acme.v1;
+// A Foo with more comments.
+message Foo {
+  bytes one = 1;
+  map<string, string> labels = 3;
+  optional string three = 4;
+}
+message Baz {}
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 2;
+  COLOR_BLUE = 3;
+}
+service FooService {
+  rpc GetFoo(Foo) returns (stream Foo) {
+    option deprecated = true;
+  }
+}
+`
+)
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	changes := testDiff(
+		t,
+		map[string]string{
+			"acme/v1/a.proto": testPreviousProto,
+			"other.proto":     `syntax = "proto3"; message Other {}`,
+		},
+		map[string]string{
+			"acme/v1/a.proto": testProto,
+		},
+	)
+	type testChange struct {
+		changeType  ChangeType
+		elementType ElementType
+		name        string
+		details     []string
+	}
+	testChanges := make([]testChange, len(changes))
+	for i, change := range changes {
+		testChanges[i] = testChange{
+			changeType:  change.Type(),
+			elementType: change.ElementType(),
+			name:        change.Name(),
+			details:     change.Details(),
+		}
+	}
+	assert.Equal(
+		t,
+		[]testChange{
+			{
+				changeType:  ChangeTypeRemoved,
+				elementType: ElementTypeMessage,
+				name:        "Other",
+			},
+			{
+				changeType:  ChangeTypeRemoved,
+				elementType: ElementTypeFile,
+				name:        "other.proto",
+			},
+			{
+				changeType:  ChangeTypeAdded,
+				elementType: ElementTypeMessage,
+				name:        "acme.v1.Baz",
+			},
+			{
+				changeType:  ChangeTypeAdded,
+				elementType: ElementTypeEnumValue,
+				name:        "acme.v1.Color.COLOR_BLUE",
+			},
+			{
+				changeType:  ChangeTypeAdded,
+				elementType: ElementTypeField,
+				name:        "acme.v1.Foo.three",
+			},
+			{
+				changeType:  ChangeTypeRemoved,
+				elementType: ElementTypeMessage,
+				name:        "acme.v1.Bar",
+			},
+			{
+				changeType:  ChangeTypeRemoved,
+				elementType: ElementTypeField,
+				name:        "acme.v1.Foo.two",
+			},
+			{
+				changeType:  ChangeTypeModified,
+				elementType: ElementTypeEnumValue,
+				name:        "acme.v1.Color.COLOR_RED",
+				details:     []string{`number changed from "1" to "2"`},
+			},
+			{
+				changeType:  ChangeTypeModified,
+				elementType: ElementTypeMessage,
+				name:        "acme.v1.Foo",
+				details:     []string{"comments changed"},
+			},
+			{
+				changeType:  ChangeTypeModified,
+				elementType: ElementTypeField,
+				name:        "acme.v1.Foo.one",
+				details:     []string{`type changed from "string" to "bytes"`},
+			},
+			{
+				changeType:  ChangeTypeModified,
+				elementType: ElementTypeMethod,
+				name:        "acme.v1.FooService.GetFoo",
+				details:     []string{`server streaming changed from "false" to "true"`, "options changed"},
+			},
+		},
+		testChanges,
+	)
+}
+
+func TestDiffNoChanges(t *testing.T) {
+	t.Parallel()
+	changes := testDiff(
+		t,
+		map[string]string{
+			"acme/v1/a.proto": testPreviousProto,
+		},
+		map[string]string{
+			"acme/v1/a.proto": testPreviousProto,
+		},
+	)
+	assert.Empty(t, changes)
+}
+
+func TestPrintChanges(t *testing.T) {
+	t.Parallel()
+	changes := []Change{
+		newChange(ChangeTypeRemoved, ElementTypeMessage, "", "Other", "other.proto", nil),
+		newChange(ChangeTypeAdded, ElementTypeMessage, "acme.v1", "acme.v1.Baz", "acme/v1/a.proto", nil),
+		newChange(ChangeTypeModified, ElementTypeField, "acme.v1", "acme.v1.Foo.one", "acme/v1/a.proto", []string{"one", "two"}),
+	}
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintChanges(buffer, changes, FormatText))
+	assert.Equal(
+		t,
+		`(no package)
+  removed message Other
+
+acme.v1
+  added message acme.v1.Baz
+  modified field acme.v1.Foo.one: one, two
+`,
+		buffer.String(),
+	)
+	buffer.Reset()
+	require.NoError(t, PrintChanges(buffer, changes, FormatMarkdown))
+	assert.Equal(
+		t,
+		"## (no package)\n\n### Removed\n\n- message `Other`\n\n"+
+			"## acme.v1\n\n### Added\n\n- message `acme.v1.Baz`\n\n### Modified\n\n- field `acme.v1.Foo.one`: one, two\n",
+		buffer.String(),
+	)
+	buffer.Reset()
+	require.NoError(t, PrintChanges(buffer, changes, FormatJSON))
+	var external externalChanges
+	require.NoError(t, json.Unmarshal(buffer.Bytes(), &external))
+	require.Len(t, external.Packages, 2)
+	assert.Equal(t, "", external.Packages[0].Name)
+	assert.Len(t, external.Packages[0].Removed, 1)
+	assert.Equal(t, "acme.v1", external.Packages[1].Name)
+	assert.Len(t, external.Packages[1].Added, 1)
+	require.Len(t, external.Packages[1].Modified, 1)
+	assert.Equal(t, []string{"one", "two"}, external.Packages[1].Modified[0].Details)
+	buffer.Reset()
+	require.NoError(t, PrintChanges(buffer, nil, FormatJSON))
+	assert.Equal(t, `{"packages":[]}`+"\n", buffer.String())
+}
+
+func testDiff(t *testing.T, previousPathToData map[string]string, pathToData map[string]string) []Change {
+	changes, err := Diff(
+		context.Background(),
+		testBuildImage(t, previousPathToData),
+		testBuildImage(t, pathToData),
+	)
+	require.NoError(t, err)
+	return changes
+}
+
+func testBuildImage(t *testing.T, pathToData map[string]string) bufimage.Image {
+	ctx := context.Background()
+	pathToBytes := make(map[string][]byte, len(pathToData))
+	for path, data := range pathToData {
+		pathToBytes[path] = []byte(data)
+	}
+	bucket, err := storagemem.NewReadBucket(pathToBytes)
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
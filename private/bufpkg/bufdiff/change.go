@@ -0,0 +1,66 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+type change struct {
+	changeType  ChangeType
+	elementType ElementType
+	pkg         string
+	name        string
+	path        string
+	details     []string
+}
+
+func newChange(
+	changeType ChangeType,
+	elementType ElementType,
+	pkg string,
+	name string,
+	path string,
+	details []string,
+) *change {
+	return &change{
+		changeType:  changeType,
+		elementType: elementType,
+		pkg:         pkg,
+		name:        name,
+		path:        path,
+		details:     details,
+	}
+}
+
+func (c *change) Type() ChangeType {
+	return c.changeType
+}
+
+func (c *change) ElementType() ElementType {
+	return c.elementType
+}
+
+func (c *change) Package() string {
+	return c.pkg
+}
+
+func (c *change) Name() string {
+	return c.name
+}
+
+func (c *change) Path() string {
+	return c.path
+}
+
+func (c *change) Details() []string {
+	return c.details
+}
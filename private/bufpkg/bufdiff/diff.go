@@ -0,0 +1,508 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/internal/bufpair"
+	"github.com/bufbuild/buf/private/pkg/protosource"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	optionsChangedDetail  = "options changed"
+	commentsChangedDetail = "comments changed"
+)
+
+func isCommentOrOptionChange(change Change) bool {
+	if change.Type() != ChangeTypeModified {
+		return false
+	}
+	for _, detail := range change.Details() {
+		if detail != optionsChangedDetail && detail != commentsChangedDetail {
+			return false
+		}
+	}
+	return true
+}
+
+func sortChanges(changes []Change) {
+	sort.SliceStable(
+		changes,
+		func(i int, j int) bool {
+			one := changes[i]
+			two := changes[j]
+			if one.Package() != two.Package() {
+				return one.Package() < two.Package()
+			}
+			if one.Type() != two.Type() {
+				return one.Type() < two.Type()
+			}
+			if one.Name() != two.Name() {
+				return one.Name() < two.Name()
+			}
+			return one.ElementType() < two.ElementType()
+		},
+	)
+}
+
+// differ accumulates the Changes between two sets of files.
+type differ struct {
+	changes []Change
+}
+
+func diff(previousFiles []protosource.File, files []protosource.File) ([]Change, error) {
+	differ := &differ{}
+	for _, f := range []func([]protosource.File, []protosource.File) error{
+		differ.diffFiles,
+		differ.diffMessages,
+		differ.diffEnums,
+		differ.diffServices,
+		differ.diffExtensions,
+	} {
+		if err := f(previousFiles, files); err != nil {
+			return nil, err
+		}
+	}
+	sortChanges(differ.changes)
+	return differ.changes, nil
+}
+
+func (d *differ) diffFiles(previousFiles []protosource.File, files []protosource.File) error {
+	return bufpair.Files(
+		previousFiles,
+		files,
+		func(previousFile protosource.File, file protosource.File) error {
+			switch {
+			case previousFile == nil:
+				d.addFileChange(ChangeTypeAdded, file)
+			case file == nil:
+				d.addFileChange(ChangeTypeRemoved, previousFile)
+			default:
+				var details []string
+				details = appendIfChanged(details, "package", previousFile.Package(), file.Package())
+				details = appendIfChanged(details, "syntax", previousFile.Syntax().String(), file.Syntax().String())
+				previousImports := getImports(previousFile)
+				imports := getImports(file)
+				for _, previousImport := range stringutil.MapToSortedSlice(previousImports) {
+					if _, ok := imports[previousImport]; !ok {
+						details = append(details, fmt.Sprintf("import %q removed", previousImport))
+					}
+				}
+				for _, fileImport := range stringutil.MapToSortedSlice(imports) {
+					if _, ok := previousImports[fileImport]; !ok {
+						details = append(details, fmt.Sprintf("import %q added", fileImport))
+					}
+				}
+				details = appendOptionsIfChanged(details, previousFile, file)
+				d.addFileChange(ChangeTypeModified, file, details...)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffMessages(previousFiles []protosource.File, files []protosource.File) error {
+	return bufpair.Messages(
+		previousFiles,
+		files,
+		func(previousMessage protosource.Message, message protosource.Message) error {
+			// Map entries are reported as part of the type of their map field.
+			if previousMessage != nil && previousMessage.IsMapEntry() {
+				previousMessage = nil
+			}
+			if message != nil && message.IsMapEntry() {
+				message = nil
+			}
+			switch {
+			case previousMessage == nil && message == nil:
+			case previousMessage == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeMessage, message)
+			case message == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeMessage, previousMessage)
+			default:
+				var details []string
+				details = appendIfChanged(details, "file", previousMessage.File().Path(), message.File().Path())
+				details = appendIfChanged(details, "reserved ranges", getTagRangesString(previousMessage.ReservedTagRanges()), getTagRangesString(message.ReservedTagRanges()))
+				details = appendIfChanged(details, "reserved names", getReservedNamesString(previousMessage.ReservedNames()), getReservedNamesString(message.ReservedNames()))
+				details = appendIfChanged(details, "extension ranges", getExtensionRangesString(previousMessage.ExtensionRanges()), getExtensionRangesString(message.ExtensionRanges()))
+				details = appendOptionsIfChanged(details, previousMessage, message)
+				details = appendCommentsIfChanged(details, previousMessage, message)
+				d.addChange(ChangeTypeModified, ElementTypeMessage, message, details...)
+				if err := d.diffMessageFields(previousMessage, message); err != nil {
+					return err
+				}
+				return d.diffMessageOneofs(previousMessage, message)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffMessageFields(previousMessage protosource.Message, message protosource.Message) error {
+	return bufpair.MessageFields(
+		previousMessage,
+		message,
+		func(previousField protosource.Field, field protosource.Field) error {
+			// Extensions are paired separately by full name.
+			if previousField != nil && previousField.Extendee() != "" {
+				previousField = nil
+			}
+			if field != nil && field.Extendee() != "" {
+				field = nil
+			}
+			switch {
+			case previousField == nil && field == nil:
+			case previousField == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeField, field)
+			case field == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeField, previousField)
+			default:
+				d.addChange(ChangeTypeModified, ElementTypeField, field, getFieldDetails(previousField, field)...)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffMessageOneofs(previousMessage protosource.Message, message protosource.Message) error {
+	return bufpair.MessageOneofs(
+		previousMessage,
+		message,
+		func(previousOneof protosource.Oneof, oneof protosource.Oneof) error {
+			// Synthetic oneofs of proto3 optional fields are reported as the label of the field.
+			if previousOneof != nil && isSyntheticOneof(previousOneof) {
+				previousOneof = nil
+			}
+			if oneof != nil && isSyntheticOneof(oneof) {
+				oneof = nil
+			}
+			switch {
+			case previousOneof == nil && oneof == nil:
+			case previousOneof == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeOneof, oneof)
+			case oneof == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeOneof, previousOneof)
+			default:
+				var details []string
+				details = appendOptionsIfChanged(details, previousOneof, oneof)
+				details = appendCommentsIfChanged(details, previousOneof, oneof)
+				d.addChange(ChangeTypeModified, ElementTypeOneof, oneof, details...)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffEnums(previousFiles []protosource.File, files []protosource.File) error {
+	return bufpair.Enums(
+		previousFiles,
+		files,
+		func(previousEnum protosource.Enum, enum protosource.Enum) error {
+			switch {
+			case previousEnum == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeEnum, enum)
+			case enum == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeEnum, previousEnum)
+			default:
+				var details []string
+				details = appendIfChanged(details, "file", previousEnum.File().Path(), enum.File().Path())
+				details = appendIfChanged(details, "reserved ranges", getTagRangesString(previousEnum.ReservedTagRanges()), getTagRangesString(enum.ReservedTagRanges()))
+				details = appendIfChanged(details, "reserved names", getReservedNamesString(previousEnum.ReservedNames()), getReservedNamesString(enum.ReservedNames()))
+				details = appendOptionsIfChanged(details, previousEnum, enum)
+				details = appendCommentsIfChanged(details, previousEnum, enum)
+				d.addChange(ChangeTypeModified, ElementTypeEnum, enum, details...)
+				return d.diffEnumValues(previousEnum, enum)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffEnumValues(previousEnum protosource.Enum, enum protosource.Enum) error {
+	return bufpair.EnumValuesByName(
+		previousEnum,
+		enum,
+		func(previousEnumValue protosource.EnumValue, enumValue protosource.EnumValue) error {
+			switch {
+			case previousEnumValue == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeEnumValue, enumValue)
+			case enumValue == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeEnumValue, previousEnumValue)
+			default:
+				var details []string
+				details = appendIfChanged(details, "number", strconv.Itoa(previousEnumValue.Number()), strconv.Itoa(enumValue.Number()))
+				details = appendOptionsIfChanged(details, previousEnumValue, enumValue)
+				details = appendCommentsIfChanged(details, previousEnumValue, enumValue)
+				d.addChange(ChangeTypeModified, ElementTypeEnumValue, enumValue, details...)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffServices(previousFiles []protosource.File, files []protosource.File) error {
+	return bufpair.Services(
+		previousFiles,
+		files,
+		func(previousService protosource.Service, service protosource.Service) error {
+			switch {
+			case previousService == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeService, service)
+			case service == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeService, previousService)
+			default:
+				var details []string
+				details = appendIfChanged(details, "file", previousService.File().Path(), service.File().Path())
+				details = appendOptionsIfChanged(details, previousService, service)
+				details = appendCommentsIfChanged(details, previousService, service)
+				d.addChange(ChangeTypeModified, ElementTypeService, service, details...)
+				return d.diffMethods(previousService, service)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffMethods(previousService protosource.Service, service protosource.Service) error {
+	return bufpair.Methods(
+		previousService,
+		service,
+		func(previousMethod protosource.Method, method protosource.Method) error {
+			switch {
+			case previousMethod == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeMethod, method)
+			case method == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeMethod, previousMethod)
+			default:
+				var details []string
+				details = appendIfChanged(details, "input type", previousMethod.InputTypeName(), method.InputTypeName())
+				details = appendIfChanged(details, "output type", previousMethod.OutputTypeName(), method.OutputTypeName())
+				details = appendIfChanged(details, "client streaming", strconv.FormatBool(previousMethod.ClientStreaming()), strconv.FormatBool(method.ClientStreaming()))
+				details = appendIfChanged(details, "server streaming", strconv.FormatBool(previousMethod.ServerStreaming()), strconv.FormatBool(method.ServerStreaming()))
+				details = appendOptionsIfChanged(details, previousMethod, method)
+				details = appendCommentsIfChanged(details, previousMethod, method)
+				d.addChange(ChangeTypeModified, ElementTypeMethod, method, details...)
+			}
+			return nil
+		},
+	)
+}
+
+func (d *differ) diffExtensions(previousFiles []protosource.File, files []protosource.File) error {
+	return bufpair.Extensions(
+		previousFiles,
+		files,
+		func(previousExtension protosource.Field, extension protosource.Field) error {
+			switch {
+			case previousExtension == nil:
+				d.addChange(ChangeTypeAdded, ElementTypeExtension, extension)
+			case extension == nil:
+				d.addChange(ChangeTypeRemoved, ElementTypeExtension, previousExtension)
+			default:
+				var details []string
+				details = appendIfChanged(details, "file", previousExtension.File().Path(), extension.File().Path())
+				details = appendIfChanged(details, "extendee", previousExtension.Extendee(), extension.Extendee())
+				details = appendIfChanged(details, "number", strconv.Itoa(previousExtension.Number()), strconv.Itoa(extension.Number()))
+				details = append(details, getFieldDetails(previousExtension, extension)...)
+				d.addChange(ChangeTypeModified, ElementTypeExtension, extension, details...)
+			}
+			return nil
+		},
+	)
+}
+
+// addFileChange adds a Change for the file.
+//
+// Modified changes without details are not added.
+func (d *differ) addFileChange(changeType ChangeType, file protosource.File, details ...string) {
+	if changeType == ChangeTypeModified && len(details) == 0 {
+		return
+	}
+	d.changes = append(
+		d.changes,
+		newChange(changeType, ElementTypeFile, file.Package(), file.Path(), file.Path(), details),
+	)
+}
+
+// addChange adds a Change for the named descriptor.
+//
+// Modified changes without details are not added.
+func (d *differ) addChange(
+	changeType ChangeType,
+	elementType ElementType,
+	namedDescriptor protosource.NamedDescriptor,
+	details ...string,
+) {
+	if changeType == ChangeTypeModified && len(details) == 0 {
+		return
+	}
+	d.changes = append(
+		d.changes,
+		newChange(
+			changeType,
+			elementType,
+			namedDescriptor.File().Package(),
+			namedDescriptor.FullName(),
+			namedDescriptor.File().Path(),
+			details,
+		),
+	)
+}
+
+func getFieldDetails(previousField protosource.Field, field protosource.Field) []string {
+	var details []string
+	details = appendIfChanged(details, "name", previousField.Name(), field.Name())
+	details = appendIfChanged(details, "type", getFieldTypeString(previousField), getFieldTypeString(field))
+	details = appendIfChanged(details, "label", getFieldLabelString(previousField), getFieldLabelString(field))
+	details = appendIfChanged(details, "JSON name", previousField.JSONName(), field.JSONName())
+	details = appendIfChanged(details, "oneof", getFieldOneofString(previousField), getFieldOneofString(field))
+	details = appendOptionsIfChanged(details, previousField, field)
+	details = appendCommentsIfChanged(details, previousField, field)
+	return details
+}
+
+func isSyntheticOneof(oneof protosource.Oneof) bool {
+	for _, field := range oneof.Fields() {
+		if field.Proto3Optional() {
+			return true
+		}
+	}
+	return false
+}
+
+func getImports(file protosource.File) map[string]struct{} {
+	imports := make(map[string]struct{}, len(file.FileImports()))
+	for _, fileImport := range file.FileImports() {
+		imports[fileImport.Import()] = struct{}{}
+	}
+	return imports
+}
+
+func getFieldTypeString(field protosource.Field) string {
+	switch field.Type() {
+	case descriptorpb.FieldDescriptorProto_TYPE_MESSAGE,
+		descriptorpb.FieldDescriptorProto_TYPE_ENUM,
+		descriptorpb.FieldDescriptorProto_TYPE_GROUP:
+		return strings.TrimPrefix(field.TypeName(), ".")
+	default:
+		return strings.ToLower(strings.TrimPrefix(field.Type().String(), "TYPE_"))
+	}
+}
+
+func getFieldLabelString(field protosource.Field) string {
+	switch field.Label() {
+	case descriptorpb.FieldDescriptorProto_LABEL_REPEATED:
+		return "repeated"
+	case descriptorpb.FieldDescriptorProto_LABEL_REQUIRED:
+		return "required"
+	}
+	if syntax := field.File().Syntax(); field.Proto3Optional() || syntax == protosource.SyntaxProto2 || syntax == protosource.SyntaxUnspecified {
+		return "optional"
+	}
+	return "implicit"
+}
+
+func getFieldOneofString(field protosource.Field) string {
+	if oneof := field.Oneof(); oneof != nil && !field.Proto3Optional() {
+		return oneof.Name()
+	}
+	return "none"
+}
+
+func getTagRangesString(tagRanges []protosource.TagRange) string {
+	values := make([]string, len(tagRanges))
+	for i, tagRange := range tagRanges {
+		values[i] = protosource.TagRangeString(tagRange)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
+func getExtensionRangesString(extensionRanges []protosource.ExtensionRange) string {
+	tagRanges := make([]protosource.TagRange, len(extensionRanges))
+	for i, extensionRange := range extensionRanges {
+		tagRanges[i] = extensionRange
+	}
+	return getTagRangesString(tagRanges)
+}
+
+func getReservedNamesString(reservedNames []protosource.ReservedName) string {
+	values := make([]string, len(reservedNames))
+	for i, reservedName := range reservedNames {
+		values[i] = reservedName.Value()
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
+
+func appendIfChanged(details []string, name string, previousValue string, value string) []string {
+	if previousValue == value {
+		return details
+	}
+	return append(details, fmt.Sprintf("%s changed from %q to %q", name, previousValue, value))
+}
+
+func appendOptionsIfChanged(
+	details []string,
+	previousDescriptor protosource.OptionExtensionDescriptor,
+	descriptor protosource.OptionExtensionDescriptor,
+) []string {
+	// Options that are not known to buf are unknown fields, so we compare the
+	// serialized options instead of using proto.Equal.
+	if bytes.Equal(getOptionsData(previousDescriptor), getOptionsData(descriptor)) {
+		return details
+	}
+	return append(details, optionsChangedDetail)
+}
+
+func appendCommentsIfChanged(
+	details []string,
+	previousDescriptor protosource.LocationDescriptor,
+	descriptor protosource.LocationDescriptor,
+) []string {
+	if getComments(previousDescriptor) == getComments(descriptor) {
+		return details
+	}
+	return append(details, commentsChangedDetail)
+}
+
+func getOptionsData(descriptor protosource.OptionExtensionDescriptor) []byte {
+	options := descriptor.Options()
+	if options == nil || !options.ProtoReflect().IsValid() {
+		return nil
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(options)
+	if err != nil {
+		// Options were unmarshaled from an image, so this should never happen.
+		return nil
+	}
+	return data
+}
+
+func getComments(descriptor protosource.LocationDescriptor) string {
+	location := descriptor.Location()
+	if location == nil {
+		return ""
+	}
+	return strings.TrimSpace(location.LeadingComments()) + "\n" + strings.TrimSpace(location.TrailingComments())
+}
@@ -0,0 +1,163 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const noPackageName = "(no package)"
+
+var changeTypeToMarkdownHeading = map[ChangeType]string{
+	ChangeTypeAdded:    "Added",
+	ChangeTypeRemoved:  "Removed",
+	ChangeTypeModified: "Modified",
+}
+
+type externalChanges struct {
+	Packages []*externalPackageChanges `json:"packages"`
+}
+
+type externalPackageChanges struct {
+	Name     string            `json:"name"`
+	Added    []*externalChange `json:"added,omitempty"`
+	Removed  []*externalChange `json:"removed,omitempty"`
+	Modified []*externalChange `json:"modified,omitempty"`
+}
+
+type externalChange struct {
+	ElementType string   `json:"element_type"`
+	Name        string   `json:"name"`
+	Path        string   `json:"path"`
+	Details     []string `json:"details,omitempty"`
+}
+
+func printChanges(writer io.Writer, changes []Change, format Format) error {
+	switch format {
+	case FormatText:
+		return printChangesText(writer, changes)
+	case FormatJSON:
+		return printChangesJSON(writer, changes)
+	case FormatMarkdown:
+		return printChangesMarkdown(writer, changes)
+	default:
+		return fmt.Errorf("unknown Format: %v", format)
+	}
+}
+
+func printChangesText(writer io.Writer, changes []Change) error {
+	buffer := bytes.NewBuffer(nil)
+	for i, packageChanges := range groupChangesByPackage(changes) {
+		if i > 0 {
+			_, _ = buffer.WriteString("\n")
+		}
+		_, _ = buffer.WriteString(getPackageDisplayName(packageChanges[0].Package()) + "\n")
+		for _, change := range packageChanges {
+			_, _ = fmt.Fprintf(buffer, "  %s %s %s", change.Type(), change.ElementType(), change.Name())
+			if details := change.Details(); len(details) > 0 {
+				_, _ = buffer.WriteString(": " + strings.Join(details, ", "))
+			}
+			_, _ = buffer.WriteString("\n")
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+func printChangesJSON(writer io.Writer, changes []Change) error {
+	// Always print a document, even with no changes, so that the output can be parsed.
+	external := &externalChanges{
+		Packages: []*externalPackageChanges{},
+	}
+	for _, packageChanges := range groupChangesByPackage(changes) {
+		externalPackage := &externalPackageChanges{
+			Name: packageChanges[0].Package(),
+		}
+		for _, change := range packageChanges {
+			externalChange := &externalChange{
+				ElementType: change.ElementType().String(),
+				Name:        change.Name(),
+				Path:        change.Path(),
+				Details:     change.Details(),
+			}
+			switch change.Type() {
+			case ChangeTypeAdded:
+				externalPackage.Added = append(externalPackage.Added, externalChange)
+			case ChangeTypeRemoved:
+				externalPackage.Removed = append(externalPackage.Removed, externalChange)
+			case ChangeTypeModified:
+				externalPackage.Modified = append(externalPackage.Modified, externalChange)
+			default:
+				return fmt.Errorf("unknown ChangeType: %v", change.Type())
+			}
+		}
+		external.Packages = append(external.Packages, externalPackage)
+	}
+	data, err := json.Marshal(external)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+func printChangesMarkdown(writer io.Writer, changes []Change) error {
+	buffer := bytes.NewBuffer(nil)
+	for i, packageChanges := range groupChangesByPackage(changes) {
+		if i > 0 {
+			_, _ = buffer.WriteString("\n")
+		}
+		_, _ = fmt.Fprintf(buffer, "## %s\n", getPackageDisplayName(packageChanges[0].Package()))
+		var lastChangeType ChangeType
+		for _, change := range packageChanges {
+			if change.Type() != lastChangeType {
+				lastChangeType = change.Type()
+				_, _ = fmt.Fprintf(buffer, "\n### %s\n\n", changeTypeToMarkdownHeading[change.Type()])
+			}
+			_, _ = fmt.Fprintf(buffer, "- %s `%s`", change.ElementType(), change.Name())
+			if details := change.Details(); len(details) > 0 {
+				_, _ = buffer.WriteString(": " + strings.Join(details, ", "))
+			}
+			_, _ = buffer.WriteString("\n")
+		}
+	}
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// groupChangesByPackage groups the sorted Changes by package.
+//
+// Each group is non-empty.
+func groupChangesByPackage(changes []Change) [][]Change {
+	var groups [][]Change
+	for i, change := range changes {
+		if i == 0 || change.Package() != changes[i-1].Package() {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], change)
+	}
+	return groups
+}
+
+func getPackageDisplayName(pkg string) string {
+	if pkg == "" {
+		return noPackageName
+	}
+	return pkg
+}
@@ -18,12 +18,18 @@ import (
 	"context"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/bufbuild/buf/private/buf/bufwork"
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/manifest"
 	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -74,6 +80,89 @@ func TestBasic(t *testing.T) {
 	)
 }
 
+func TestVendor(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	dependencyBucket, err := storageos.NewProvider().NewReadWriteBucket(filepath.Join("testdata", "basic", "test-d"))
+	require.NoError(t, err)
+	dependencyManifest, dependencyBlobSet, err := manifest.NewFromBucket(ctx, dependencyBucket)
+	require.NoError(t, err)
+	dependencyModule, err := bufmodule.NewModuleForManifestAndBlobSet(ctx, dependencyManifest, dependencyBlobSet)
+	require.NoError(t, err)
+	dependencyManifestBlob, err := dependencyManifest.Blob()
+	require.NoError(t, err)
+	dependencyModulePin, err := bufmoduleref.NewModulePin(
+		"bsr.internal",
+		"foo",
+		"test-d",
+		"",
+		"0123456789abcdef0123456789abcdef",
+		dependencyManifestBlob.Digest().String(),
+		time.Now(),
+	)
+	require.NoError(t, err)
+
+	moduleBucket := storagemem.NewReadWriteBucket()
+	require.NoError(t, storage.PutPath(ctx, moduleBucket, "a/v1/a.proto", []byte(`syntax = "proto3";
+
+package a.v1;
+
+import "d/v1/d.proto";
+
+message A {
+  d.v1.D d = 1;
+}
+`)))
+	require.NoError(t, bufmoduleref.PutDependencyModulePinsToBucket(ctx, moduleBucket, []bufmoduleref.ModulePin{dependencyModulePin}))
+	require.NoError(t, bufmodulecache.PutVendorModule(
+		ctx,
+		zap.NewNop(),
+		storage.MapReadWriteBucket(moduleBucket, storage.MapOnPrefix(bufmodule.VendorDirPath)),
+		dependencyModulePin,
+		dependencyModule,
+	))
+	moduleIdentity, err := bufmoduleref.NewModuleIdentity("bsr.internal", "foo", "test-a")
+	require.NoError(t, err)
+	moduleConfig, err := bufmoduleconfig.NewConfigV1(bufmoduleconfig.ExternalConfigV1{})
+	require.NoError(t, err)
+	module, err := bufmodulebuild.NewModuleBucketBuilder().BuildForBucket(
+		ctx,
+		moduleBucket,
+		moduleConfig,
+		bufmodulebuild.WithModuleIdentity(moduleIdentity),
+	)
+	require.NoError(t, err)
+
+	// The nop ModuleResolver and ModuleReader fail for every dependency,
+	// so the dependency can only be read from the vendor directory.
+	builder := NewBuilder(
+		zap.NewNop(),
+		bufmodule.NewNopModuleResolver(),
+		bufmodule.NewNopModuleReader(),
+	)
+	graph, fileAnnotations, err := builder.Build(
+		ctx,
+		[]bufmodule.Module{module},
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	dotString, err := graph.DOTString(func(key Node) string { return key.String() })
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		`digraph {
+
+  1 [label="bsr.internal/foo/test-a"]
+  2 [label="bsr.internal/foo/test-d:0123456789abcdef0123456789abcdef"]
+
+  1 -> 2
+
+}`,
+		dotString,
+	)
+}
+
 // TODO: This entire function is all you should need to do to build workspaces, and even
 // this is overly complicated because of the wonkiness of bufmodulebuild and NewWorkspace.
 // We should have this in a common place for at least testing.
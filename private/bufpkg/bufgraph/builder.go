@@ -24,6 +24,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/pkg/dag"
+	"github.com/bufbuild/buf/private/pkg/storage"
 	"go.uber.org/zap"
 )
 
@@ -74,7 +75,18 @@ func (b *builder) build(
 	graph := dag.NewGraph[Node]()
 	alreadyProcessedNodes := make(map[Node]struct{})
 	for _, module := range modules {
-		fileAnnotations, err := b.buildForModule(
+		moduleBuilder := b
+		if vendorModuleReader := module.VendorModuleReader(); vendorModuleReader != nil {
+			// The vendor directory contains all of the dependencies pinned in the buf.lock
+			// of the Module, so we resolve and read every dependency below the Module from
+			// the vendor directory instead of the network.
+			moduleBuilder = newBuilder(
+				b.logger,
+				newPinnedModuleResolver(module.DependencyModulePins()),
+				vendorModuleReader,
+			)
+		}
+		fileAnnotations, err := moduleBuilder.buildForModule(
 			ctx,
 			module,
 			newNodeForModule(module),
@@ -217,6 +229,29 @@ func newNodeForModule(module bufmodule.Module) Node {
 	return node
 }
 
+// pinnedModuleResolver resolves ModuleReferences to commits against a fixed set of ModulePins.
+type pinnedModuleResolver struct {
+	modulePins []bufmoduleref.ModulePin
+}
+
+func newPinnedModuleResolver(modulePins []bufmoduleref.ModulePin) *pinnedModuleResolver {
+	return &pinnedModuleResolver{
+		modulePins: modulePins,
+	}
+}
+
+func (r *pinnedModuleResolver) GetModulePin(
+	_ context.Context,
+	moduleReference bufmoduleref.ModuleReference,
+) (bufmoduleref.ModulePin, error) {
+	for _, modulePin := range r.modulePins {
+		if modulePin.IdentityString() == moduleReference.IdentityString() && modulePin.Commit() == moduleReference.Reference() {
+			return modulePin, nil
+		}
+	}
+	return nil, storage.NewErrNotExist(moduleReference.String())
+}
+
 type buildOptions struct {
 	workspace bufmodule.Workspace
 }
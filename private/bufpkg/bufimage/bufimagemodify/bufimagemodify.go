@@ -211,6 +211,30 @@ func OptimizeFor(
 	return optimizeFor(logger, sweeper, defaultOptimizeFor, except, moduleOverrides, validatedOverrides), nil
 }
 
+// JsType returns a Modifier that sets the jstype field option of all 64-bit
+// integer fields according to the given defaultJsType, exceptions, and overrides.
+// Files for which no value is configured are left unchanged, which is the case for
+// all files that are not overridden if defaultJsType is nil.
+//
+// The packageOverrides apply to the files of the package and of its sub-packages,
+// with the most specific package taking precedence. They take precedence over the
+// moduleOverrides, and the overrides of file paths take precedence over both.
+func JsType(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	defaultJsType *descriptorpb.FieldOptions_JSType,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType,
+	packageOverrides map[string]descriptorpb.FieldOptions_JSType,
+	overrides map[string]string,
+) (Modifier, error) {
+	validatedOverrides, err := stringOverridesToJsTypeOverrides(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("invalid override for %s: %w", JsTypeID, err)
+	}
+	return jsType(logger, sweeper, defaultJsType, except, moduleOverrides, packageOverrides, validatedOverrides), nil
+}
+
 // GoPackageImportPathForFile returns the go_package import path for the given
 // ImageFile. If the package contains a version suffix, and if there are more
 // than two components, concatenate the final two components. Otherwise, we
@@ -248,6 +272,20 @@ func ObjcClassPrefix(
 	return objcClassPrefix(logger, sweeper, defaultPrefix, except, moduleOverride, overrides)
 }
 
+// SwiftPrefix returns a Modifier that sets the swift_prefix file option
+// according to the given defaultPrefix, exceptions, and overrides. Files for which
+// no value is configured are left unchanged.
+func SwiftPrefix(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	defaultPrefix string,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]string,
+	overrides map[string]string,
+) Modifier {
+	return swiftPrefix(logger, sweeper, defaultPrefix, except, moduleOverrides, overrides)
+}
+
 // CsharpNamespace returns a Modifier that sets the csharp_namespace file option
 // according to the package name. It is set to the package name with each package sub-name capitalized.
 func CsharpNamespace(
@@ -287,6 +325,20 @@ func PhpMetadataNamespace(
 	return phpMetadataNamespace(logger, sweeper, overrides)
 }
 
+// PhpClassPrefix returns a Modifier that sets the php_class_prefix file option
+// according to the given defaultPrefix, exceptions, and overrides. Files for which
+// no value is configured are left unchanged.
+func PhpClassPrefix(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	defaultPrefix string,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]string,
+	overrides map[string]string,
+) Modifier {
+	return phpClassPrefix(logger, sweeper, defaultPrefix, except, moduleOverrides, overrides)
+}
+
 // RubyPackage returns a Modifier that sets the ruby_package file option
 // according to the given packagePrefix. It is set to the package name with each package sub-name capitalized
 // and each "." replaced with "::".
@@ -347,3 +399,15 @@ func stringOverridesToOptimizeModeOverrides(stringOverrides map[string]string) (
 	}
 	return validatedOverrides, nil
 }
+
+func stringOverridesToJsTypeOverrides(stringOverrides map[string]string) (map[string]descriptorpb.FieldOptions_JSType, error) {
+	validatedOverrides := make(map[string]descriptorpb.FieldOptions_JSType, len(stringOverrides))
+	for fileImportPath, stringOverride := range stringOverrides {
+		jsType, ok := descriptorpb.FieldOptions_JSType_value[stringOverride]
+		if !ok {
+			return nil, fmt.Errorf("invalid jstype %s set for file %s", stringOverride, fileImportPath)
+		}
+		validatedOverrides[fileImportPath] = descriptorpb.FieldOptions_JSType(jsType)
+	}
+	return validatedOverrides, nil
+}
@@ -41,7 +41,7 @@ func newFileOptionSweeper() *fileOptionSweeper {
 
 // mark is used to mark the given SourceCodeInfo_Location indices for
 // deletion. This method should be called in each of the file option
-// modifiers.
+// and field option modifiers.
 func (s *fileOptionSweeper) mark(imageFilePath string, path []int32) {
 	paths, ok := s.sourceCodeInfoPaths[imageFilePath]
 	if !ok {
@@ -72,6 +72,12 @@ func (s *fileOptionSweeper) Sweep(ctx context.Context, image bufimage.Image) err
 			if _, ok := paths[getPathKey(location.Path)]; !ok {
 				continue
 			}
+			if !isFileOptionPath(location.Path) {
+				// Field options are declared together within a single location,
+				// so only the location of the target option is deleted.
+				indices[i] = struct{}{}
+				continue
+			}
 			if i == 0 {
 				return fmt.Errorf("path %v must have a preceding parent path", location.Path)
 			}
@@ -100,6 +106,11 @@ func (s *fileOptionSweeper) Sweep(ctx context.Context, image bufimage.Image) err
 	return nil
 }
 
+// isFileOptionPath returns true if the given path is the path of a file option.
+func isFileOptionPath(path []int32) bool {
+	return len(path) == len(fileOptionPath)+1 && int32SliceIsEqual(path[:len(fileOptionPath)], fileOptionPath)
+}
+
 // getPathKey returns a unique key for the given path.
 func getPathKey(path []int32) string {
 	key := make([]byte, len(path)*4)
@@ -0,0 +1,232 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// JsTypeID is the ID of the jstype modifier.
+const JsTypeID = "JSTYPE"
+
+const (
+	// The field numbers of the descriptors that contain fields, used to build
+	// the SourceCodeInfo paths of the jstype option of each field.
+	fileMessageTypeTag    = 4
+	fileExtensionTag      = 7
+	messageFieldTag       = 2
+	messageNestedTypeTag  = 3
+	messageExtensionTag   = 6
+	fieldOptionsTag       = 8
+	fieldOptionsJsTypeTag = 6
+)
+
+// jsTypePath is the SourceCodeInfo path for the jstype option, relative to its field.
+// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L565
+var jsTypePath = []int32{fieldOptionsTag, fieldOptionsJsTypeTag}
+
+func jsType(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	defaultJsType *descriptorpb.FieldOptions_JSType,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType,
+	packageOverrides map[string]descriptorpb.FieldOptions_JSType,
+	overrides map[string]descriptorpb.FieldOptions_JSType,
+) Modifier {
+	// Convert the bufmoduleref.ModuleIdentity types into
+	// strings so that they're comparable.
+	exceptModuleIdentityStrings := make(map[string]struct{}, len(except))
+	for _, moduleIdentity := range except {
+		exceptModuleIdentityStrings[moduleIdentity.IdentityString()] = struct{}{}
+	}
+	overrideModuleIdentityStrings := make(
+		map[string]descriptorpb.FieldOptions_JSType,
+		len(moduleOverrides),
+	)
+	for moduleIdentity, jsType := range moduleOverrides {
+		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = jsType
+	}
+	return ModifierFunc(
+		func(ctx context.Context, image bufimage.Image) error {
+			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
+			seenOverridePackages := make(map[string]struct{}, len(packageOverrides))
+			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			for _, imageFile := range image.Files() {
+				modifierValue := defaultJsType
+				if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+					moduleIdentityString := moduleIdentity.IdentityString()
+					if jsTypeOverride, ok := overrideModuleIdentityStrings[moduleIdentityString]; ok {
+						modifierValue = &jsTypeOverride
+						seenModuleIdentityStrings[moduleIdentityString] = struct{}{}
+					}
+				}
+				if overridePackage, ok := getOverridePackage(imageFile.Proto().GetPackage(), packageOverrides); ok {
+					packageOverrideValue := packageOverrides[overridePackage]
+					modifierValue = &packageOverrideValue
+					seenOverridePackages[overridePackage] = struct{}{}
+				}
+				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+					modifierValue = &overrideValue
+					seenOverrideFiles[imageFile.Path()] = struct{}{}
+				}
+				if modifierValue == nil {
+					// There is no jstype value configured for this file.
+					continue
+				}
+				if err := jsTypeForFile(
+					ctx,
+					sweeper,
+					imageFile,
+					*modifierValue,
+					exceptModuleIdentityStrings,
+				); err != nil {
+					return err
+				}
+			}
+			for moduleIdentityString := range overrideModuleIdentityStrings {
+				if _, ok := seenModuleIdentityStrings[moduleIdentityString]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", JsTypeID, moduleIdentityString)
+				}
+			}
+			for overridePackage := range packageOverrides {
+				if _, ok := seenOverridePackages[overridePackage]; !ok {
+					logger.Sugar().Warnf("%s package override for %q was unused", JsTypeID, overridePackage)
+				}
+			}
+			for overrideFile := range overrides {
+				if _, ok := seenOverrideFiles[overrideFile]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", JsTypeID, overrideFile)
+				}
+			}
+			return nil
+		},
+	)
+}
+
+// getOverridePackage returns the most specific package of the packageOverrides that
+// is either the package or a parent package of the package.
+func getOverridePackage(
+	packageName string,
+	packageOverrides map[string]descriptorpb.FieldOptions_JSType,
+) (string, bool) {
+	for packageName != "" {
+		if _, ok := packageOverrides[packageName]; ok {
+			return packageName, true
+		}
+		lastDotIndex := strings.LastIndex(packageName, ".")
+		if lastDotIndex < 0 {
+			break
+		}
+		packageName = packageName[:lastDotIndex]
+	}
+	return "", false
+}
+
+func jsTypeForFile(
+	ctx context.Context,
+	sweeper Sweeper,
+	imageFile bufimage.ImageFile,
+	value descriptorpb.FieldOptions_JSType,
+	exceptModuleIdentityStrings map[string]struct{},
+) error {
+	if isWellKnownType(ctx, imageFile) {
+		// The file is a well-known type, don't do anything.
+		return nil
+	}
+	if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+		if _, ok := exceptModuleIdentityStrings[moduleIdentity.IdentityString()]; ok {
+			return nil
+		}
+	}
+	descriptor := imageFile.Proto()
+	for i, field := range descriptor.GetExtension() {
+		jsTypeForField(sweeper, imageFile, field, []int32{fileExtensionTag, int32(i)}, value)
+	}
+	for i, message := range descriptor.GetMessageType() {
+		jsTypeForMessage(sweeper, imageFile, message, []int32{fileMessageTypeTag, int32(i)}, value)
+	}
+	return nil
+}
+
+// jsTypeForMessage sets the jstype option of the fields and extensions of the message
+// and its nested messages.
+func jsTypeForMessage(
+	sweeper Sweeper,
+	imageFile bufimage.ImageFile,
+	message *descriptorpb.DescriptorProto,
+	messagePath []int32,
+	value descriptorpb.FieldOptions_JSType,
+) {
+	for i, field := range message.GetField() {
+		jsTypeForField(sweeper, imageFile, field, appendPath(messagePath, messageFieldTag, int32(i)), value)
+	}
+	for i, field := range message.GetExtension() {
+		jsTypeForField(sweeper, imageFile, field, appendPath(messagePath, messageExtensionTag, int32(i)), value)
+	}
+	for i, nestedMessage := range message.GetNestedType() {
+		jsTypeForMessage(sweeper, imageFile, nestedMessage, appendPath(messagePath, messageNestedTypeTag, int32(i)), value)
+	}
+}
+
+// jsTypeForField sets the jstype option of the field if it is a 64-bit integer field,
+// which is the only kind of field that jstype applies to.
+func jsTypeForField(
+	sweeper Sweeper,
+	imageFile bufimage.ImageFile,
+	field *descriptorpb.FieldDescriptorProto,
+	fieldPath []int32,
+	value descriptorpb.FieldOptions_JSType,
+) {
+	switch field.GetType() {
+	case descriptorpb.FieldDescriptorProto_TYPE_INT64,
+		descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+		descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+		descriptorpb.FieldDescriptorProto_TYPE_SFIXED64:
+	default:
+		return
+	}
+	options := field.GetOptions()
+	switch {
+	case options != nil && options.GetJstype() == value:
+		// The option is already set to the same value, don't do anything.
+		return
+	case options == nil && descriptorpb.Default_FieldOptions_Jstype == value:
+		// The option is not set, but the value we want to set is the
+		// same as the default, don't do anything.
+		return
+	}
+	if options == nil {
+		field.Options = &descriptorpb.FieldOptions{}
+	}
+	field.Options.Jstype = &value
+	if sweeper != nil {
+		sweeper.mark(imageFile.Path(), appendPath(fieldPath, jsTypePath...))
+	}
+}
+
+// appendPath returns a new path with the elements appended to the path.
+func appendPath(path []int32, elems ...int32) []int32 {
+	newPath := make([]int32, 0, len(path)+len(elems))
+	newPath = append(newPath, path...)
+	return append(newPath, elems...)
+}
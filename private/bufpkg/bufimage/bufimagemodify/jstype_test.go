@@ -0,0 +1,349 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestJsTypeDefault(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "jstypeoptions")
+	t.Run("with SourceCodeInfo", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+		assert.Equal(t, 2, testCountJsTypeSourceCodeInfoLocations(t, image))
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(zap.NewNop(), sweeper, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), nil, nil, nil, nil)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			map[string]*descriptorpb.FieldOptions_JSType{
+				"int64_field":        jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+				"uint64_field":       jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+				"int32_field":        nil,
+				"string_field":       nil,
+				"fixed64_field":      jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+				"sint64_extension":   jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+				"sfixed64_extension": jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+			},
+			testGetJsTypes(t, image),
+		)
+		assert.Equal(t, 0, testCountJsTypeSourceCodeInfoLocations(t, image))
+		// Other field options are kept.
+		assert.True(t, image.Files()[0].Proto().GetMessageType()[0].GetNestedType()[0].GetField()[0].GetOptions().GetDeprecated())
+	})
+
+	t.Run("without SourceCodeInfo", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, false)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(zap.NewNop(), sweeper, jsTypePtr(descriptorpb.FieldOptions_JS_NORMAL), nil, nil, nil, nil)
+		require.NoError(t, err)
+		err = jsTypeModifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		assert.Equal(
+			t,
+			map[string]*descriptorpb.FieldOptions_JSType{
+				// The default value is not set on fields without options.
+				"int64_field":        nil,
+				"uint64_field":       jsTypePtr(descriptorpb.FieldOptions_JS_NORMAL),
+				"int32_field":        nil,
+				"string_field":       nil,
+				"fixed64_field":      jsTypePtr(descriptorpb.FieldOptions_JS_NORMAL),
+				"sint64_extension":   nil,
+				"sfixed64_extension": nil,
+			},
+			testGetJsTypes(t, image),
+		)
+	})
+
+	t.Run("without a default", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(zap.NewNop(), sweeper, nil, nil, nil, nil, nil)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, testGetImage(t, dirPath, true), image)
+	})
+}
+
+func TestJsTypeWithExcept(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "jstypeoptions")
+	testModuleIdentity, err := bufmoduleref.NewModuleIdentity(
+		testRemote,
+		testRepositoryOwner,
+		testRepositoryName,
+	)
+	require.NoError(t, err)
+	image := testGetImage(t, dirPath, true)
+
+	sweeper := NewFileOptionSweeper()
+	jsTypeModifier, err := JsType(
+		zap.NewNop(),
+		sweeper,
+		jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+		[]bufmoduleref.ModuleIdentity{testModuleIdentity},
+		nil,
+		nil,
+		map[string]string{"a.proto": "JS_STRING"},
+	)
+	require.NoError(t, err)
+	modifier := NewMultiModifier(
+		jsTypeModifier,
+		ModifierFunc(sweeper.Sweep),
+	)
+	err = modifier.Modify(
+		context.Background(),
+		image,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, testGetImage(t, dirPath, true), image)
+}
+
+func TestJsTypeWithOverride(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "jstypeoptions")
+	testModuleIdentity, err := bufmoduleref.NewModuleIdentity(
+		testRemote,
+		testRepositoryOwner,
+		testRepositoryName,
+	)
+	require.NoError(t, err)
+	t.Run("with module override", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(
+			zap.NewNop(),
+			sweeper,
+			nil,
+			nil,
+			map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType{
+				testModuleIdentity: descriptorpb.FieldOptions_JS_STRING,
+			},
+			nil,
+			nil,
+		)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		jsTypes := testGetJsTypes(t, image)
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["int64_field"])
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["fixed64_field"])
+	})
+
+	t.Run("with per-file override", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(
+			zap.NewNop(),
+			sweeper,
+			jsTypePtr(descriptorpb.FieldOptions_JS_STRING),
+			nil,
+			nil,
+			nil,
+			map[string]string{"a.proto": "JS_NUMBER"},
+		)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		jsTypes := testGetJsTypes(t, image)
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_NUMBER), jsTypes["int64_field"])
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_NUMBER), jsTypes["sfixed64_extension"])
+		assert.Nil(t, jsTypes["int32_field"])
+	})
+
+	t.Run("with package override", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(
+			zap.NewNop(),
+			sweeper,
+			nil,
+			nil,
+			map[bufmoduleref.ModuleIdentity]descriptorpb.FieldOptions_JSType{
+				testModuleIdentity: descriptorpb.FieldOptions_JS_NUMBER,
+			},
+			map[string]descriptorpb.FieldOptions_JSType{
+				// The most specific package takes precedence, and package overrides
+				// take precedence over module overrides.
+				"acme":    descriptorpb.FieldOptions_JS_NORMAL,
+				"acme.v1": descriptorpb.FieldOptions_JS_STRING,
+				"acme.v2": descriptorpb.FieldOptions_JS_NORMAL,
+			},
+			nil,
+		)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		jsTypes := testGetJsTypes(t, image)
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["int64_field"])
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["uint64_field"])
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["sfixed64_extension"])
+		assert.Nil(t, jsTypes["int32_field"])
+	})
+
+	t.Run("with package override of a parent package", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		jsTypeModifier, err := JsType(
+			zap.NewNop(),
+			sweeper,
+			nil,
+			nil,
+			nil,
+			map[string]descriptorpb.FieldOptions_JSType{
+				"acme": descriptorpb.FieldOptions_JS_STRING,
+				"acm":  descriptorpb.FieldOptions_JS_NUMBER,
+			},
+			nil,
+		)
+		require.NoError(t, err)
+		modifier := NewMultiModifier(
+			jsTypeModifier,
+			ModifierFunc(sweeper.Sweep),
+		)
+		err = modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		jsTypes := testGetJsTypes(t, image)
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["int64_field"])
+		assert.Equal(t, jsTypePtr(descriptorpb.FieldOptions_JS_STRING), jsTypes["fixed64_field"])
+	})
+
+	t.Run("with invalid per-file override", func(t *testing.T) {
+		t.Parallel()
+		_, err := JsType(
+			zap.NewNop(),
+			NewFileOptionSweeper(),
+			nil,
+			nil,
+			nil,
+			nil,
+			map[string]string{"a.proto": "JS_INVALID"},
+		)
+		require.Error(t, err)
+	})
+}
+
+func jsTypePtr(jsType descriptorpb.FieldOptions_JSType) *descriptorpb.FieldOptions_JSType {
+	return &jsType
+}
+
+// testGetJsTypes returns the jstype option of all fields in the image by field name.
+func testGetJsTypes(t *testing.T, image bufimage.Image) map[string]*descriptorpb.FieldOptions_JSType {
+	jsTypes := make(map[string]*descriptorpb.FieldOptions_JSType)
+	addFields := func(fields []*descriptorpb.FieldDescriptorProto) {
+		for _, field := range fields {
+			jsTypes[field.GetName()] = nil
+			if options := field.GetOptions(); options != nil {
+				jsTypes[field.GetName()] = options.Jstype
+			}
+		}
+	}
+	var addMessages func([]*descriptorpb.DescriptorProto)
+	addMessages = func(messages []*descriptorpb.DescriptorProto) {
+		for _, message := range messages {
+			addFields(message.GetField())
+			addFields(message.GetExtension())
+			addMessages(message.GetNestedType())
+		}
+	}
+	for _, imageFile := range image.Files() {
+		addFields(imageFile.Proto().GetExtension())
+		addMessages(imageFile.Proto().GetMessageType())
+	}
+	return jsTypes
+}
+
+// testCountJsTypeSourceCodeInfoLocations returns the number of SourceCodeInfo locations
+// of jstype options in the image.
+func testCountJsTypeSourceCodeInfoLocations(t *testing.T, image bufimage.Image) int {
+	var count int
+	for _, imageFile := range image.Files() {
+		for _, location := range imageFile.Proto().GetSourceCodeInfo().GetLocation() {
+			path := location.GetPath()
+			if len(path) > len(jsTypePath) && int32SliceIsEqual(path[len(path)-len(jsTypePath):], jsTypePath) {
+				count++
+			}
+		}
+	}
+	return count
+}
@@ -0,0 +1,125 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// prefix returns a Modifier that sets a prefix file option, such as swift_prefix,
+// to the value configured for each file. Files for which no value is configured
+// are left unchanged.
+//
+// The modifierID is used in log messages, the optionPath is the SourceCodeInfo path
+// of the option that is swept, and setOption sets the option.
+func prefix(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	modifierID string,
+	optionPath []int32,
+	setOption func(*descriptorpb.FileOptions, string),
+	defaultPrefix string,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]string,
+	overrides map[string]string,
+) Modifier {
+	// Convert the bufmoduleref.ModuleIdentity types into
+	// strings so that they're comparable.
+	exceptModuleIdentityStrings := make(map[string]struct{}, len(except))
+	for _, moduleIdentity := range except {
+		exceptModuleIdentityStrings[moduleIdentity.IdentityString()] = struct{}{}
+	}
+	overrideModuleIdentityStrings := make(map[string]string, len(moduleOverrides))
+	for moduleIdentity, modulePrefixOverride := range moduleOverrides {
+		overrideModuleIdentityStrings[moduleIdentity.IdentityString()] = modulePrefixOverride
+	}
+	return ModifierFunc(
+		func(ctx context.Context, image bufimage.Image) error {
+			seenModuleIdentityStrings := make(map[string]struct{}, len(overrideModuleIdentityStrings))
+			seenOverrideFiles := make(map[string]struct{}, len(overrides))
+			for _, imageFile := range image.Files() {
+				prefixValue := defaultPrefix
+				if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+					moduleIdentityString := moduleIdentity.IdentityString()
+					if modulePrefixOverride, ok := overrideModuleIdentityStrings[moduleIdentityString]; ok {
+						prefixValue = modulePrefixOverride
+						seenModuleIdentityStrings[moduleIdentityString] = struct{}{}
+					}
+				}
+				if overrideValue, ok := overrides[imageFile.Path()]; ok {
+					prefixValue = overrideValue
+					seenOverrideFiles[imageFile.Path()] = struct{}{}
+				}
+				if err := prefixForFile(
+					ctx,
+					sweeper,
+					imageFile,
+					optionPath,
+					setOption,
+					prefixValue,
+					exceptModuleIdentityStrings,
+				); err != nil {
+					return err
+				}
+			}
+			for moduleIdentityString := range overrideModuleIdentityStrings {
+				if _, ok := seenModuleIdentityStrings[moduleIdentityString]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", modifierID, moduleIdentityString)
+				}
+			}
+			for overrideFile := range overrides {
+				if _, ok := seenOverrideFiles[overrideFile]; !ok {
+					logger.Sugar().Warnf("%s override for %q was unused", modifierID, overrideFile)
+				}
+			}
+			return nil
+		},
+	)
+}
+
+func prefixForFile(
+	ctx context.Context,
+	sweeper Sweeper,
+	imageFile bufimage.ImageFile,
+	optionPath []int32,
+	setOption func(*descriptorpb.FileOptions, string),
+	prefixValue string,
+	exceptModuleIdentityStrings map[string]struct{},
+) error {
+	descriptor := imageFile.Proto()
+	if isWellKnownType(ctx, imageFile) || prefixValue == "" {
+		// This is a well-known type or there is no prefix value
+		// configured for this file, so this is a no-op.
+		return nil
+	}
+	if moduleIdentity := imageFile.ModuleIdentity(); moduleIdentity != nil {
+		if _, ok := exceptModuleIdentityStrings[moduleIdentity.IdentityString()]; ok {
+			return nil
+		}
+	}
+	if descriptor.Options == nil {
+		descriptor.Options = &descriptorpb.FileOptions{}
+	}
+	setOption(descriptor.Options, prefixValue)
+	if sweeper != nil {
+		sweeper.mark(imageFile.Path(), optionPath)
+	}
+	return nil
+}
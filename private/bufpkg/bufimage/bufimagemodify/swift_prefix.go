@@ -0,0 +1,52 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// SwiftPrefixID is the ID of the swift_prefix modifier.
+const SwiftPrefixID = "SWIFT_PREFIX"
+
+// swiftPrefixPath is the SourceCodeInfo path for the swift_prefix option.
+// https://github.com/protocolbuffers/protobuf/blob/61689226c0e3ec88287eaed66164614d9c4f2bf7/src/google/protobuf/descriptor.proto#L434
+var swiftPrefixPath = []int32{8, 39}
+
+func swiftPrefix(
+	logger *zap.Logger,
+	sweeper Sweeper,
+	defaultPrefix string,
+	except []bufmoduleref.ModuleIdentity,
+	moduleOverrides map[bufmoduleref.ModuleIdentity]string,
+	overrides map[string]string,
+) Modifier {
+	return prefix(
+		logger,
+		sweeper,
+		SwiftPrefixID,
+		swiftPrefixPath,
+		func(options *descriptorpb.FileOptions, value string) {
+			options.SwiftPrefix = proto.String(value)
+		},
+		defaultPrefix,
+		except,
+		moduleOverrides,
+		overrides,
+	)
+}
@@ -0,0 +1,228 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufimagemodify
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestSwiftPrefixEmptyOptions(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "emptyoptions")
+	t.Run("with SourceCodeInfo", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+		assertFileOptionSourceCodeInfoEmpty(t, image, swiftPrefixPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(zap.NewNop(), sweeper, "DEFAULT", nil, nil, nil),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		for _, imageFile := range image.Files() {
+			descriptor := imageFile.Proto()
+			assert.Equal(t, "DEFAULT", descriptor.GetOptions().GetSwiftPrefix())
+		}
+		assertFileOptionSourceCodeInfoEmpty(t, image, swiftPrefixPath, true)
+	})
+
+	t.Run("without SourceCodeInfo", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, false)
+		assertFileOptionSourceCodeInfoEmpty(t, image, swiftPrefixPath, false)
+
+		sweeper := NewFileOptionSweeper()
+		err := SwiftPrefix(zap.NewNop(), sweeper, "DEFAULT", nil, nil, nil).Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		for _, imageFile := range image.Files() {
+			descriptor := imageFile.Proto()
+			assert.Equal(t, "DEFAULT", descriptor.GetOptions().GetSwiftPrefix())
+		}
+		assertFileOptionSourceCodeInfoEmpty(t, image, swiftPrefixPath, false)
+	})
+
+	t.Run("without a default", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(zap.NewNop(), sweeper, "", nil, nil, nil),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, testGetImage(t, dirPath, true), image)
+	})
+}
+
+func TestSwiftPrefixAllOptions(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "alloptions")
+	t.Run("with SourceCodeInfo", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+		assertFileOptionSourceCodeInfoNotEmpty(t, image, swiftPrefixPath)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(zap.NewNop(), sweeper, "DEFAULT", nil, nil, nil),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		for _, imageFile := range image.Files() {
+			descriptor := imageFile.Proto()
+			assert.Equal(t, "DEFAULT", descriptor.GetOptions().GetSwiftPrefix())
+		}
+		assertFileOptionSourceCodeInfoEmpty(t, image, swiftPrefixPath, true)
+	})
+
+	t.Run("without a default", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(zap.NewNop(), sweeper, "", nil, nil, nil),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		assert.Equal(t, testGetImage(t, dirPath, true), image)
+		assertFileOptionSourceCodeInfoNotEmpty(t, image, swiftPrefixPath)
+	})
+}
+
+func TestSwiftPrefixWithExcept(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "alloptions")
+	testModuleIdentity, err := bufmoduleref.NewModuleIdentity(
+		testRemote,
+		testRepositoryOwner,
+		testRepositoryName,
+	)
+	require.NoError(t, err)
+	image := testGetImage(t, dirPath, true)
+
+	sweeper := NewFileOptionSweeper()
+	modifier := NewMultiModifier(
+		SwiftPrefix(
+			zap.NewNop(),
+			sweeper,
+			"DEFAULT",
+			[]bufmoduleref.ModuleIdentity{testModuleIdentity},
+			nil,
+			map[string]string{"a.proto": "OVERRIDE"},
+		),
+		ModifierFunc(sweeper.Sweep),
+	)
+	err = modifier.Modify(
+		context.Background(),
+		image,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, testGetImage(t, dirPath, true), image)
+	// Should still be non-empty because the module is skipped.
+	assertFileOptionSourceCodeInfoNotEmpty(t, image, swiftPrefixPath)
+}
+
+func TestSwiftPrefixWithOverride(t *testing.T) {
+	t.Parallel()
+	dirPath := filepath.Join("testdata", "emptyoptions")
+	testModuleIdentity, err := bufmoduleref.NewModuleIdentity(
+		testRemote,
+		testRepositoryOwner,
+		testRepositoryName,
+	)
+	require.NoError(t, err)
+	t.Run("with module override", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(
+				zap.NewNop(),
+				sweeper,
+				"",
+				nil,
+				map[bufmoduleref.ModuleIdentity]string{testModuleIdentity: "MODULE_OVERRIDE"},
+				nil,
+			),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		for _, imageFile := range image.Files() {
+			descriptor := imageFile.Proto()
+			assert.Equal(t, "MODULE_OVERRIDE", descriptor.GetOptions().GetSwiftPrefix())
+		}
+	})
+
+	t.Run("with per-file override", func(t *testing.T) {
+		t.Parallel()
+		image := testGetImage(t, dirPath, true)
+
+		sweeper := NewFileOptionSweeper()
+		modifier := NewMultiModifier(
+			SwiftPrefix(
+				zap.NewNop(),
+				sweeper,
+				"DEFAULT",
+				nil,
+				map[bufmoduleref.ModuleIdentity]string{testModuleIdentity: "MODULE_OVERRIDE"},
+				map[string]string{"a.proto": "FILE_OVERRIDE"},
+			),
+			ModifierFunc(sweeper.Sweep),
+		)
+		err := modifier.Modify(
+			context.Background(),
+			image,
+		)
+		require.NoError(t, err)
+		for _, imageFile := range image.Files() {
+			descriptor := imageFile.Proto()
+			assert.Equal(t, "FILE_OVERRIDE", descriptor.GetOptions().GetSwiftPrefix())
+		}
+	})
+}
@@ -0,0 +1,74 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufjsonschema generates JSON Schema for the messages in images.
+//
+// Schemas accept all values that protojson accepts, not only the values that it outputs:
+//
+//   - Fields are named by their JSON name, or by their field name, but not both at once.
+//     Unknown fields are not allowed.
+//   - Any field may be null, which leaves the field unset.
+//   - Integers and floating point numbers are numbers or strings, and floating point numbers
+//     may be "NaN", "Infinity", or "-Infinity". protojson outputs 64-bit integers as strings.
+//   - Bytes are base64-encoded strings.
+//   - Enums are the names or numbers of their values. protojson outputs names.
+//   - At most one field of each oneof may be set.
+//   - Well-known types use their special JSON representation, for example google.protobuf.Timestamp
+//     is an RFC 3339 string.
+//
+// Extensions are not included.
+package bufjsonschema
+
+import (
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+)
+
+// SchemaURI is the URI of the JSON Schema dialect of generated documents.
+const SchemaURI = "https://json-schema.org/draft/2020-12/schema"
+
+// Generate generates a JSON Schema document for the messages in the Image.
+//
+// Every message and enum of the non-import files of the Image is defined in
+// "$defs" by its fully-qualified name, along with the messages and enums that they
+// reference. Use bufimageutil.ImageFilteredByTypes to limit the messages.
+//
+// The document is indented JSON.
+func Generate(image bufimage.Image, options ...GenerateOption) ([]byte, error) {
+	generateOptions := newGenerateOptions()
+	for _, option := range options {
+		option(generateOptions)
+	}
+	return generate(image, generateOptions)
+}
+
+// GenerateOption is an option for Generate.
+type GenerateOption func(*generateOptions)
+
+// WithRootMessage returns a new GenerateOption that makes the document itself
+// describe the message with the fully-qualified name, by referencing its definition.
+//
+// The message must be defined in the document.
+func WithRootMessage(fullName string) GenerateOption {
+	return func(generateOptions *generateOptions) {
+		generateOptions.rootMessage = fullName
+	}
+}
+
+type generateOptions struct {
+	rootMessage string
+}
+
+func newGenerateOptions() *generateOptions {
+	return &generateOptions{}
+}
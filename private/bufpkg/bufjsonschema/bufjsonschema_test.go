@@ -0,0 +1,248 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimageutil"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const testProto = `syntax = "proto3";
+package a.v1;
+import "google/protobuf/struct.proto";
+import "google/protobuf/timestamp.proto";
+// A Foo.
+message Foo {
+  // The ID.
+  int64 id = 1;
+  string display_name = 2;
+  repeated Color colors = 3;
+  map<string, Foo> children = 4;
+  google.protobuf.Timestamp create_time = 5;
+  oneof value {
+    bytes data = 6;
+    double number = 7;
+  }
+  optional uint32 count = 8;
+  google.protobuf.Value any = 9;
+}
+enum Color {
+  COLOR_UNSPECIFIED = 0;
+  COLOR_RED = 1;
+}
+message Bar {}
+`
+
+func TestGenerate(t *testing.T) {
+	t.Parallel()
+	document := testGenerate(t, testBuildImage(t))
+	assert.Equal(t, SchemaURI, document["$schema"])
+	assert.Nil(t, document["$ref"])
+	defs := document["$defs"].(map[string]interface{})
+	assert.Len(t, defs, 3)
+	assert.Contains(t, defs, "a.v1.Bar")
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{
+					"type": "string",
+					"enum": []interface{}{"COLOR_UNSPECIFIED", "COLOR_RED"},
+				},
+				map[string]interface{}{
+					"type":    "integer",
+					"minimum": float64(math.MinInt32),
+					"maximum": float64(math.MaxInt32),
+				},
+			},
+		},
+		defs["a.v1.Color"],
+	)
+	foo := defs["a.v1.Foo"].(map[string]interface{})
+	assert.Equal(t, "A Foo.", foo["description"])
+	assert.Equal(t, false, foo["additionalProperties"])
+	properties := foo["properties"].(map[string]interface{})
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{
+					// protojson accepts numbers for 64-bit integers, but outputs strings.
+					"anyOf": []interface{}{
+						map[string]interface{}{"type": "integer"},
+						map[string]interface{}{"type": "string", "pattern": `^-?[0-9]+$`},
+					},
+				},
+				testNullSchema,
+			},
+			"description": "The ID.",
+		},
+		properties["id"],
+	)
+	assert.Contains(t, properties, "displayName")
+	// Fields may also be set by their field name, but not by both names.
+	assert.Equal(t, properties["displayName"], properties["display_name"])
+	assert.Equal(t, properties["createTime"], properties["create_time"])
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"display_name": map[string]interface{}{
+				"not": map[string]interface{}{"required": []interface{}{"displayName"}},
+			},
+			"create_time": map[string]interface{}{
+				"not": map[string]interface{}{"required": []interface{}{"createTime"}},
+			},
+		},
+		foo["dependentSchemas"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"type":  "array",
+			"items": map[string]interface{}{"$ref": "#/$defs/a.v1.Color"},
+		}),
+		properties["colors"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"$ref": "#/$defs/a.v1.Foo"},
+		}),
+		properties["children"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"type":   "string",
+			"format": "date-time",
+		}),
+		properties["createTime"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"type":            "string",
+			"contentEncoding": "base64",
+		}),
+		properties["data"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "number"},
+				map[string]interface{}{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`},
+				map[string]interface{}{"type": "string", "enum": []interface{}{"NaN", "Infinity", "-Infinity"}},
+			},
+		}),
+		properties["number"],
+	)
+	assert.Equal(
+		t,
+		testNullable(map[string]interface{}{
+			"anyOf": []interface{}{
+				map[string]interface{}{"type": "integer", "minimum": float64(0), "maximum": float64(math.MaxUint32)},
+				map[string]interface{}{"type": "string", "pattern": `^[0-9]+$`},
+			},
+		}),
+		properties["count"],
+	)
+	// google.protobuf.Value already accepts null.
+	assert.Equal(t, map[string]interface{}{}, properties["any"])
+	// The synthetic oneof of count is not included.
+	assert.Equal(
+		t,
+		[]interface{}{
+			map[string]interface{}{
+				"oneOf": []interface{}{
+					map[string]interface{}{"required": []interface{}{"data"}},
+					map[string]interface{}{"required": []interface{}{"number"}},
+					map[string]interface{}{
+						"not": map[string]interface{}{
+							"anyOf": []interface{}{
+								map[string]interface{}{"required": []interface{}{"data"}},
+								map[string]interface{}{"required": []interface{}{"number"}},
+							},
+						},
+					},
+				},
+			},
+		},
+		foo["allOf"],
+	)
+}
+
+func TestGenerateFilteredWithRootMessage(t *testing.T) {
+	t.Parallel()
+	image, err := bufimageutil.ImageFilteredByTypes(testBuildImage(t), "a.v1.Foo")
+	require.NoError(t, err)
+	document := testGenerate(t, image, WithRootMessage("a.v1.Foo"))
+	assert.Equal(t, "#/$defs/a.v1.Foo", document["$ref"])
+	defs := document["$defs"].(map[string]interface{})
+	assert.Len(t, defs, 2)
+	assert.Contains(t, defs, "a.v1.Foo")
+	assert.Contains(t, defs, "a.v1.Color")
+	_, err = Generate(image, WithRootMessage("a.v1.Bar"))
+	assert.Error(t, err)
+	_, err = Generate(image, WithRootMessage("a.v1.Color"))
+	assert.Error(t, err)
+}
+
+var testNullSchema = map[string]interface{}{"type": "null"}
+
+// testNullable returns the schema of a field with the given schema, as any field may be null.
+func testNullable(fieldSchema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"anyOf": []interface{}{fieldSchema, testNullSchema},
+	}
+}
+
+func testGenerate(t *testing.T, image bufimage.Image, options ...GenerateOption) map[string]interface{} {
+	data, err := Generate(image, options...)
+	require.NoError(t, err)
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &document))
+	return document
+}
+
+func testBuildImage(t *testing.T) bufimage.Image {
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(map[string][]byte{"a/v1/a.proto": []byte(testProto)})
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
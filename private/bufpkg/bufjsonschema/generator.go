@@ -0,0 +1,387 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufjsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// schema is a JSON Schema.
+type schema map[string]interface{}
+
+var (
+	// Integers and floating point numbers are accepted as numbers or strings, and
+	// 64-bit integers are strings in the output of protojson.
+	signedInt64Schema = schema{
+		"anyOf": []interface{}{
+			schema{"type": "integer"},
+			schema{"type": "string", "pattern": `^-?[0-9]+$`},
+		},
+	}
+	unsignedInt64Schema = schema{
+		"anyOf": []interface{}{
+			schema{"type": "integer", "minimum": 0},
+			schema{"type": "string", "pattern": `^[0-9]+$`},
+		},
+	}
+	signedInt32Schema = schema{
+		"anyOf": []interface{}{
+			schema{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32},
+			schema{"type": "string", "pattern": `^-?[0-9]+$`},
+		},
+	}
+	unsignedInt32Schema = schema{
+		"anyOf": []interface{}{
+			schema{"type": "integer", "minimum": 0, "maximum": math.MaxUint32},
+			schema{"type": "string", "pattern": `^[0-9]+$`},
+		},
+	}
+	floatSchema = schema{
+		"anyOf": []interface{}{
+			schema{"type": "number"},
+			schema{"type": "string", "pattern": `^-?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`},
+			schema{"type": "string", "enum": []string{"NaN", "Infinity", "-Infinity"}},
+		},
+	}
+	nullSchema   = schema{"type": "null"}
+	boolSchema   = schema{"type": "boolean"}
+	stringSchema = schema{"type": "string"}
+	bytesSchema  = schema{
+		"type":            "string",
+		"contentEncoding": "base64",
+	}
+
+	// wellKnownTypeToSchema are the schemas of the well-known types with a special JSON
+	// representation.
+	//
+	// Other well-known types, such as google.protobuf.Empty, are regular messages.
+	wellKnownTypeToSchema = map[protoreflect.FullName]schema{
+		"google.protobuf.Any": {
+			"type": "object",
+			"properties": schema{
+				"@type": stringSchema,
+			},
+			"required": []string{"@type"},
+		},
+		"google.protobuf.Timestamp": {
+			"type":   "string",
+			"format": "date-time",
+		},
+		"google.protobuf.Duration": {
+			"type":    "string",
+			"pattern": `^-?[0-9]+(\.[0-9]{1,9})?s$`,
+		},
+		"google.protobuf.FieldMask": stringSchema,
+		"google.protobuf.Struct": {
+			"type": "object",
+		},
+		"google.protobuf.Value": {},
+		"google.protobuf.ListValue": {
+			"type": "array",
+		},
+		"google.protobuf.DoubleValue": floatSchema,
+		"google.protobuf.FloatValue":  floatSchema,
+		"google.protobuf.Int64Value":  signedInt64Schema,
+		"google.protobuf.UInt64Value": unsignedInt64Schema,
+		"google.protobuf.Int32Value":  signedInt32Schema,
+		"google.protobuf.UInt32Value": unsignedInt32Schema,
+		"google.protobuf.BoolValue":   boolSchema,
+		"google.protobuf.StringValue": stringSchema,
+		"google.protobuf.BytesValue":  bytesSchema,
+	}
+	valueFullName     protoreflect.FullName = "google.protobuf.Value"
+	nullValueFullName protoreflect.FullName = "google.protobuf.NullValue"
+)
+
+type generator struct {
+	defs schema
+	// messageFullNames are the full names of the messages in defs.
+	messageFullNames map[string]struct{}
+}
+
+func generate(image bufimage.Image, generateOptions *generateOptions) ([]byte, error) {
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	generator := &generator{
+		defs:             make(schema),
+		messageFullNames: make(map[string]struct{}),
+	}
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		generator.addMessages(fileDescriptor.Messages())
+		generator.addEnums(fileDescriptor.Enums())
+	}
+	document := schema{
+		"$schema": SchemaURI,
+		"$defs":   generator.defs,
+	}
+	if generateOptions.rootMessage != "" {
+		if _, ok := generator.messageFullNames[generateOptions.rootMessage]; !ok {
+			return nil, fmt.Errorf("message %q is not in the image", generateOptions.rootMessage)
+		}
+		document["$ref"] = getDefRef(protoreflect.FullName(generateOptions.rootMessage))
+	}
+	return json.MarshalIndent(document, "", "  ")
+}
+
+func (g *generator) addMessages(messageDescriptors protoreflect.MessageDescriptors) {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		if messageDescriptor.IsMapEntry() {
+			continue
+		}
+		g.addMessage(messageDescriptor)
+		g.addMessages(messageDescriptor.Messages())
+		g.addEnums(messageDescriptor.Enums())
+	}
+}
+
+func (g *generator) addEnums(enumDescriptors protoreflect.EnumDescriptors) {
+	for i := 0; i < enumDescriptors.Len(); i++ {
+		g.addEnum(enumDescriptors.Get(i))
+	}
+}
+
+// addMessage adds the definition of the message and of the messages and enums that it
+// references, if not already added.
+func (g *generator) addMessage(messageDescriptor protoreflect.MessageDescriptor) {
+	fullName := string(messageDescriptor.FullName())
+	if _, ok := g.defs[fullName]; ok {
+		return
+	}
+	g.messageFullNames[fullName] = struct{}{}
+	if wellKnownTypeSchema, ok := wellKnownTypeToSchema[messageDescriptor.FullName()]; ok {
+		g.defs[fullName] = wellKnownTypeSchema
+		return
+	}
+	def := schema{
+		"type":                 "object",
+		"additionalProperties": false,
+	}
+	// Add the definition before the fields so that recursive messages terminate.
+	g.defs[fullName] = def
+	properties := make(schema)
+	dependentSchemas := make(schema)
+	var required []string
+	var allOfSchemas []interface{}
+	fields := messageDescriptor.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fieldSchema := g.getFieldSchema(field)
+		properties[field.JSONName()] = fieldSchema
+		// Like protojson, also accept the field name, but not together with the JSON name.
+		if name := string(field.Name()); name != field.JSONName() {
+			properties[name] = fieldSchema
+			dependentSchemas[name] = schema{"not": schema{"required": []string{field.JSONName()}}}
+		}
+		if field.Cardinality() == protoreflect.Required {
+			if string(field.Name()) == field.JSONName() {
+				required = append(required, field.JSONName())
+			} else {
+				allOfSchemas = append(allOfSchemas, getFieldPresentSchema(field))
+			}
+		}
+	}
+	def["properties"] = properties
+	if len(dependentSchemas) > 0 {
+		def["dependentSchemas"] = dependentSchemas
+	}
+	if len(required) > 0 {
+		def["required"] = required
+	}
+	oneofs := messageDescriptor.Oneofs()
+	for i := 0; i < oneofs.Len(); i++ {
+		if oneof := oneofs.Get(i); !oneof.IsSynthetic() {
+			allOfSchemas = append(allOfSchemas, getOneofSchema(oneof))
+		}
+	}
+	if len(allOfSchemas) > 0 {
+		def["allOf"] = allOfSchemas
+	}
+	addDescription(def, messageDescriptor)
+}
+
+// addEnum adds the definition of the enum, if not already added.
+func (g *generator) addEnum(enumDescriptor protoreflect.EnumDescriptor) {
+	fullName := string(enumDescriptor.FullName())
+	if _, ok := g.defs[fullName]; ok {
+		return
+	}
+	values := enumDescriptor.Values()
+	names := make([]string, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		names[i] = string(values.Get(i).Name())
+	}
+	// Enums are also accepted as the numbers of their values, and
+	// open enums accept any number.
+	def := schema{
+		"anyOf": []interface{}{
+			schema{"type": "string", "enum": names},
+			schema{"type": "integer", "minimum": math.MinInt32, "maximum": math.MaxInt32},
+		},
+	}
+	addDescription(def, enumDescriptor)
+	g.defs[fullName] = def
+}
+
+func (g *generator) getFieldSchema(field protoreflect.FieldDescriptor) schema {
+	var fieldSchema schema
+	switch {
+	case field.IsMap():
+		// Map keys are always strings in JSON.
+		fieldSchema = schema{
+			"type":                 "object",
+			"additionalProperties": g.getSingularFieldSchema(field.MapValue()),
+		}
+	case field.IsList():
+		fieldSchema = schema{
+			"type":  "array",
+			"items": g.getSingularFieldSchema(field),
+		}
+	default:
+		fieldSchema = g.getSingularFieldSchema(field)
+	}
+	if acceptsNull(field) {
+		// The description is added to a copy so that shared schemas are not modified.
+		described := make(schema, len(fieldSchema)+1)
+		for key, value := range fieldSchema {
+			described[key] = value
+		}
+		fieldSchema = described
+	} else {
+		// Like protojson, accept null for any field, which leaves the field unset.
+		fieldSchema = schema{
+			"anyOf": []interface{}{fieldSchema, nullSchema},
+		}
+	}
+	addDescription(fieldSchema, field)
+	return fieldSchema
+}
+
+func (g *generator) getSingularFieldSchema(field protoreflect.FieldDescriptor) schema {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		messageDescriptor := field.Message()
+		if wellKnownTypeSchema, ok := wellKnownTypeToSchema[messageDescriptor.FullName()]; ok {
+			return wellKnownTypeSchema
+		}
+		g.addMessage(messageDescriptor)
+		return schema{"$ref": getDefRef(messageDescriptor.FullName())}
+	case protoreflect.EnumKind:
+		enumDescriptor := field.Enum()
+		if enumDescriptor.FullName() == nullValueFullName {
+			return nullSchema
+		}
+		g.addEnum(enumDescriptor)
+		return schema{"$ref": getDefRef(enumDescriptor.FullName())}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return signedInt64Schema
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return unsignedInt64Schema
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return signedInt32Schema
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return unsignedInt32Schema
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return floatSchema
+	case protoreflect.BoolKind:
+		return boolSchema
+	case protoreflect.StringKind:
+		return stringSchema
+	case protoreflect.BytesKind:
+		return bytesSchema
+	default:
+		// All kinds are handled above, so allow any value for unknown kinds.
+		return schema{}
+	}
+}
+
+// acceptsNull returns true if the schema of the singular field already accepts null.
+func acceptsNull(field protoreflect.FieldDescriptor) bool {
+	if field.IsList() || field.IsMap() {
+		return false
+	}
+	switch field.Kind() {
+	case protoreflect.MessageKind:
+		return field.Message().FullName() == valueFullName
+	case protoreflect.EnumKind:
+		return field.Enum().FullName() == nullValueFullName
+	default:
+		return false
+	}
+}
+
+// getOneofSchema returns a schema that allows at most one field of the oneof to be set.
+func getOneofSchema(oneof protoreflect.OneofDescriptor) schema {
+	fields := oneof.Fields()
+	fieldSchemas := make([]interface{}, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		fieldSchemas[i] = getFieldPresentSchema(fields.Get(i))
+	}
+	return schema{
+		"oneOf": append(
+			fieldSchemas,
+			schema{"not": schema{"anyOf": fieldSchemas}},
+		),
+	}
+}
+
+// getFieldPresentSchema returns the schema that requires the field to be present, by either
+// its JSON name or its field name.
+func getFieldPresentSchema(field protoreflect.FieldDescriptor) schema {
+	if name := string(field.Name()); name != field.JSONName() {
+		return schema{
+			"anyOf": []interface{}{
+				schema{"required": []string{field.JSONName()}},
+				schema{"required": []string{name}},
+			},
+		}
+	}
+	return schema{"required": []string{field.JSONName()}}
+}
+
+func addDescription(s schema, descriptor protoreflect.Descriptor) {
+	if description := getDescription(descriptor); description != "" {
+		s["description"] = description
+	}
+}
+
+func getDescription(descriptor protoreflect.Descriptor) string {
+	location := descriptor.ParentFile().SourceLocations().ByDescriptor(descriptor)
+	lines := strings.Split(strings.TrimSpace(location.LeadingComments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func getDefRef(fullName protoreflect.FullName) string {
+	return "#/$defs/" + string(fullName)
+}
@@ -0,0 +1,87 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufmock serves mock implementations of the services in images.
+//
+// Every method of every service is served over the Connect, gRPC, and gRPC-Web
+// protocols, using dynamic messages. Responses come from fixtures, and calls that
+// do not match any fixture get a response with all fields set to their default values.
+//
+// Fixtures are read from a bucket, in which each fixture is at the path
+// "<service>/<method>/<name>.<ext>", where service is the fully-qualified name of the
+// service, method is the name of the method, and ext is "json" or "txtpb". For example,
+// "acme.user.v1.UserService/GetUser/alice.json". The file contains the response of the
+// fixture in the JSON or text format. The JSON format may contain a stream of multiple
+// responses for server and bidirectional streaming methods.
+//
+// A fixture may restrict the requests that it matches with a request file at the path
+// "<service>/<method>/<name>.request.<ext>". A request matches the fixture if all of the
+// fields set in the request file are equal to the fields of the request. Message fields
+// are matched recursively, so only the set fields of nested messages are compared.
+// Fixtures with request files are matched first, then fixtures without request files,
+// each in the order of their names.
+//
+// Unary and server streaming methods match the request of the call. Client streaming
+// methods match the last request of the stream. Bidirectional streaming methods match
+// each request of the stream, and send the responses of the matched fixture for each.
+package bufmock
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// NewHandler returns a new http.Handler that serves the services of the non-import
+// files of the image.
+//
+// The handler should be served with HTTP/2 for the gRPC protocol and bidirectional
+// streaming methods.
+func NewHandler(
+	ctx context.Context,
+	logger *zap.Logger,
+	image bufimage.Image,
+	options ...HandlerOption,
+) (http.Handler, error) {
+	handlerOptions := newHandlerOptions()
+	for _, option := range options {
+		option(handlerOptions)
+	}
+	return newHandler(ctx, logger, image, handlerOptions)
+}
+
+// HandlerOption is an option for a new Handler.
+type HandlerOption func(*handlerOptions)
+
+// HandlerWithFixtureBucket returns a new HandlerOption that reads the fixtures
+// from the bucket.
+//
+// Fixtures must be for methods of services in the image.
+// The default is to use no fixtures.
+func HandlerWithFixtureBucket(readBucket storage.ReadBucket) HandlerOption {
+	return func(handlerOptions *handlerOptions) {
+		handlerOptions.fixtureBucket = readBucket
+	}
+}
+
+type handlerOptions struct {
+	fixtureBucket storage.ReadBucket
+}
+
+func newHandlerOptions() *handlerOptions {
+	return &handlerOptions{}
+}
@@ -0,0 +1,199 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	connect "github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const testProto = `syntax = "proto3";
+package a.v1;
+message GetUserRequest {
+  string id = 1;
+  Filter filter = 2;
+}
+message Filter {
+  bool deleted = 1;
+  string region = 2;
+}
+message GetUserResponse {
+  string name = 1;
+  int32 age = 2;
+}
+service UserService {
+  rpc GetUser(GetUserRequest) returns (GetUserResponse);
+  rpc ListUsers(GetUserRequest) returns (stream GetUserResponse);
+}
+`
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	image := testBuildImage(t)
+	fixtureBucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a.v1.UserService/GetUser/alice.json":           []byte(`{"name": "Alice", "age": 30}`),
+			"a.v1.UserService/GetUser/alice.request.json":   []byte(`{"id": "1"}`),
+			"a.v1.UserService/GetUser/bob.txtpb":            []byte(`name: "Bob"`),
+			"a.v1.UserService/GetUser/bob.request.txtpb":    []byte(`filter: { deleted: true }`),
+			"a.v1.UserService/ListUsers/all.json":           []byte(`{"name": "Alice"} {"name": "Bob"}`),
+			"a.v1.UserService/ListUsers/README.md":          []byte(`ignored`),
+			"a.v1.UserService/ListUsers/empty.request.json": []byte(`{"id": "none"}`),
+			"a.v1.UserService/ListUsers/empty.json":         []byte(``),
+		},
+	)
+	require.NoError(t, err)
+	handler, err := NewHandler(ctx, zap.NewNop(), image, HandlerWithFixtureBucket(fixtureBucket))
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// Connect with JSON.
+	response, err := server.Client().Post(
+		server.URL+"/a.v1.UserService/GetUser",
+		"application/json",
+		bytes.NewBufferString(`{"id": "1", "filter": {"region": "us"}}`),
+	)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"name": "Alice", "age": 30}`, string(body))
+
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	require.NoError(t, err)
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptors(image)...)
+	require.NoError(t, err)
+	descriptor, err := files.FindDescriptorByName("a.v1.UserService")
+	require.NoError(t, err)
+	methods := descriptor.(protoreflect.ServiceDescriptor).Methods()
+	getUser := methods.ByName("GetUser")
+	listUsers := methods.ByName("ListUsers")
+	newRequest := func(json string) *message {
+		request := dynamicpb.NewMessage(getUser.Input())
+		require.NoError(t, protoencoding.NewJSONUnmarshaler(resolver).Unmarshal([]byte(json), request))
+		return &message{request}
+	}
+	getResponseJSON := func(response *message) string {
+		data, err := protoencoding.NewJSONMarshaler(resolver).Marshal(response.message)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	for _, protocolOption := range []connect.ClientOption{connect.WithGRPC(), connect.WithGRPCWeb()} {
+		getUserClient := connect.NewClient[message, message](
+			server.Client(),
+			server.URL+"/a.v1.UserService/GetUser",
+			protocolOption,
+			connect.WithCodec(newProtoCodec(getUser.Output(), resolver)),
+		)
+		getUserResponse, err := getUserClient.CallUnary(ctx, connect.NewRequest(newRequest(`{"filter": {"deleted": true, "region": "us"}}`)))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name": "Bob"}`, getResponseJSON(getUserResponse.Msg))
+		// Requests that do not match any fixture get default values.
+		getUserResponse, err = getUserClient.CallUnary(ctx, connect.NewRequest(newRequest(`{"id": "2"}`)))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{}`, getResponseJSON(getUserResponse.Msg))
+
+		listUsersClient := connect.NewClient[message, message](
+			server.Client(),
+			server.URL+"/a.v1.UserService/ListUsers",
+			protocolOption,
+			connect.WithCodec(newProtoCodec(listUsers.Output(), resolver)),
+		)
+		assert.Equal(
+			t,
+			[]string{`{"name":"Alice"}`, `{"name":"Bob"}`},
+			testReceiveAll(t, ctx, listUsersClient, newRequest(`{}`), getResponseJSON),
+		)
+		assert.Empty(t, testReceiveAll(t, ctx, listUsersClient, newRequest(`{"id": "none"}`), getResponseJSON))
+	}
+}
+
+func TestHandlerInvalidFixtures(t *testing.T) {
+	t.Parallel()
+	image := testBuildImage(t)
+	for _, fixtures := range []map[string][]byte{
+		{"a.v1.UserService/GetUser.json": []byte(`{}`)},
+		{"a.v1.OtherService/GetUser/a.json": []byte(`{}`)},
+		{"a.v1.UserService/DeleteUser/a.json": []byte(`{}`)},
+		{"a.v1.UserService/GetUser/a.json": []byte(`{"name": 1}`)},
+		{"a.v1.UserService/GetUser/a.json": []byte(`{} {}`)},
+		{"a.v1.UserService/GetUser/a.request.json": []byte(`{}`)},
+	} {
+		fixtureBucket, err := storagemem.NewReadBucket(fixtures)
+		require.NoError(t, err)
+		_, err = NewHandler(context.Background(), zap.NewNop(), image, HandlerWithFixtureBucket(fixtureBucket))
+		assert.Error(t, err, fixtures)
+	}
+}
+
+func testReceiveAll(
+	t *testing.T,
+	ctx context.Context,
+	client *connect.Client[message, message],
+	request *message,
+	getResponseJSON func(*message) string,
+) []string {
+	stream, err := client.CallServerStream(ctx, connect.NewRequest(request))
+	require.NoError(t, err)
+	var responses []string
+	for stream.Receive() {
+		responses = append(responses, getResponseJSON(stream.Msg()))
+	}
+	require.NoError(t, stream.Err())
+	require.NoError(t, stream.Close())
+	return responses
+}
+
+func testBuildImage(t *testing.T) bufimage.Image {
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(map[string][]byte{"a/v1/a.proto": []byte(testProto)})
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
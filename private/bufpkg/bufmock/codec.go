@@ -0,0 +1,95 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	connect "github.com/bufbuild/connect-go"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// message is the type of the requests and responses of all methods.
+//
+// The generic handlers of connect create requests with new(message), so the
+// codecs of each method set the dynamic message of the input type of the method
+// when unmarshaling.
+type message struct {
+	message proto.Message
+}
+
+// codec is a connect.Codec for the requests and responses of a single method.
+type codec struct {
+	name                   string
+	inputMessageDescriptor protoreflect.MessageDescriptor
+	marshaler              protoencoding.Marshaler
+	unmarshaler            protoencoding.Unmarshaler
+}
+
+var _ connect.Codec = (*codec)(nil)
+
+func newProtoCodec(inputMessageDescriptor protoreflect.MessageDescriptor, resolver protoencoding.Resolver) *codec {
+	return &codec{
+		name:                   "proto",
+		inputMessageDescriptor: inputMessageDescriptor,
+		marshaler:              protoencoding.NewWireMarshaler(),
+		unmarshaler:            protoencoding.NewWireUnmarshaler(resolver),
+	}
+}
+
+func newJSONCodec(inputMessageDescriptor protoreflect.MessageDescriptor, resolver protoencoding.Resolver) *codec {
+	return &codec{
+		name:                   "json",
+		inputMessageDescriptor: inputMessageDescriptor,
+		marshaler:              protoencoding.NewJSONMarshaler(resolver),
+		unmarshaler:            protoencoding.NewJSONUnmarshaler(resolver),
+	}
+}
+
+func (c *codec) Name() string {
+	return c.name
+}
+
+func (c *codec) Marshal(src any) ([]byte, error) {
+	switch typedSrc := src.(type) {
+	case *message:
+		return c.marshaler.Marshal(typedSrc.message)
+	case proto.Message:
+		// When the codec is named "proto", connect also uses it to marshal
+		// the details of errors for the gRPC protocols.
+		return c.marshaler.Marshal(typedSrc)
+	default:
+		return nil, fmt.Errorf("marshal unexpected type %T", src)
+	}
+}
+
+func (c *codec) Unmarshal(src []byte, dst any) error {
+	switch destination := dst.(type) {
+	case *message:
+		inputMessage := dynamicpb.NewMessage(c.inputMessageDescriptor)
+		if err := c.unmarshaler.Unmarshal(src, inputMessage); err != nil {
+			return err
+		}
+		destination.message = inputMessage
+		return nil
+	case proto.Message:
+		return c.unmarshaler.Unmarshal(src, destination)
+	default:
+		return fmt.Errorf("unmarshal unexpected type %T", dst)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+const (
+	jsonExt          = ".json"
+	txtpbExt         = ".txtpb"
+	requestExtPrefix = ".request"
+)
+
+// fixture is a set of responses to a method, optionally restricted to requests
+// with the fields of a request.
+type fixture struct {
+	// name is the path of the response file without the extension.
+	name      string
+	request   proto.Message
+	responses []proto.Message
+}
+
+// matches returns true if the fixture matches the request.
+func (f *fixture) matches(request proto.Message) bool {
+	if f.request == nil {
+		return true
+	}
+	return messageMatches(f.request.ProtoReflect(), request.ProtoReflect())
+}
+
+// fixtureFile is a request or response file of a fixture.
+type fixtureFile struct {
+	path string
+	ext  string
+	data []byte
+}
+
+// readFixtures reads the fixtures in the bucket, and returns them by the full
+// name of their method, in the order that they should be matched.
+func readFixtures(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	files *protoregistry.Files,
+	resolver protoencoding.Resolver,
+) (map[protoreflect.FullName][]*fixture, error) {
+	nameToRequestFile := make(map[string]*fixtureFile)
+	nameToResponseFile := make(map[string]*fixtureFile)
+	if err := storage.WalkReadObjects(
+		ctx,
+		readBucket,
+		"",
+		func(readObject storage.ReadObject) error {
+			path := readObject.Path()
+			ext := normalpath.Ext(path)
+			if ext != jsonExt && ext != txtpbExt {
+				return nil
+			}
+			data, err := io.ReadAll(readObject)
+			if err != nil {
+				return err
+			}
+			name := strings.TrimSuffix(path, ext)
+			fixtureFile := &fixtureFile{
+				path: path,
+				ext:  ext,
+				data: data,
+			}
+			nameToFixtureFile := nameToResponseFile
+			if strings.HasSuffix(name, requestExtPrefix) {
+				name = strings.TrimSuffix(name, requestExtPrefix)
+				nameToFixtureFile = nameToRequestFile
+			}
+			if existingFixtureFile, ok := nameToFixtureFile[name]; ok {
+				return fmt.Errorf("fixture files %q and %q have the same name", existingFixtureFile.path, path)
+			}
+			nameToFixtureFile[name] = fixtureFile
+			return nil
+		},
+	); err != nil {
+		return nil, err
+	}
+	for name, requestFile := range nameToRequestFile {
+		if _, ok := nameToResponseFile[name]; !ok {
+			return nil, fmt.Errorf("fixture request file %q has no response file", requestFile.path)
+		}
+	}
+	methodFullNameToFixtures := make(map[protoreflect.FullName][]*fixture)
+	for name, responseFile := range nameToResponseFile {
+		methodDescriptor, err := getFixtureMethodDescriptor(files, responseFile.path)
+		if err != nil {
+			return nil, err
+		}
+		responses, err := unmarshalFixtureMessages(resolver, responseFile, methodDescriptor.Output())
+		if err != nil {
+			return nil, err
+		}
+		if len(responses) != 1 && !methodDescriptor.IsStreamingServer() {
+			return nil, fmt.Errorf("fixture %q must contain a single response, as %q is not a server or bidirectional streaming method", responseFile.path, methodDescriptor.FullName())
+		}
+		fixture := &fixture{
+			name:      name,
+			responses: responses,
+		}
+		if requestFile, ok := nameToRequestFile[name]; ok {
+			requests, err := unmarshalFixtureMessages(resolver, requestFile, methodDescriptor.Input())
+			if err != nil {
+				return nil, err
+			}
+			if len(requests) != 1 {
+				return nil, fmt.Errorf("fixture %q must contain a single request", requestFile.path)
+			}
+			fixture.request = requests[0]
+		}
+		methodFullNameToFixtures[methodDescriptor.FullName()] = append(
+			methodFullNameToFixtures[methodDescriptor.FullName()],
+			fixture,
+		)
+	}
+	for _, fixtures := range methodFullNameToFixtures {
+		sort.Slice(
+			fixtures,
+			func(i int, j int) bool {
+				if (fixtures[i].request != nil) != (fixtures[j].request != nil) {
+					return fixtures[i].request != nil
+				}
+				return fixtures[i].name < fixtures[j].name
+			},
+		)
+	}
+	return methodFullNameToFixtures, nil
+}
+
+// getFixtureMethodDescriptor returns the descriptor of the method of the fixture file
+// at the path "<service>/<method>/<name>.<ext>".
+func getFixtureMethodDescriptor(files *protoregistry.Files, path string) (protoreflect.MethodDescriptor, error) {
+	components := normalpath.Components(normalpath.Dir(path))
+	if len(components) != 2 {
+		return nil, fmt.Errorf("fixture %q must be in a directory named by the fully-qualified service name and method name, such as \"acme.v1.UserService/GetUser\"", path)
+	}
+	descriptor, err := files.FindDescriptorByName(protoreflect.FullName(components[0]))
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, fmt.Errorf("fixture %q: service %q is not in the image", path, components[0])
+		}
+		return nil, err
+	}
+	serviceDescriptor, ok := descriptor.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, fmt.Errorf("fixture %q: %q is not a service", path, components[0])
+	}
+	methodDescriptor := serviceDescriptor.Methods().ByName(protoreflect.Name(components[1]))
+	if methodDescriptor == nil {
+		return nil, fmt.Errorf("fixture %q: service %q has no method %q", path, components[0], components[1])
+	}
+	return methodDescriptor, nil
+}
+
+// unmarshalFixtureMessages unmarshals the messages in the fixture file.
+//
+// JSON files may contain a stream of messages, and text files contain a single message.
+func unmarshalFixtureMessages(
+	resolver protoencoding.Resolver,
+	fixtureFile *fixtureFile,
+	messageDescriptor protoreflect.MessageDescriptor,
+) ([]proto.Message, error) {
+	if fixtureFile.ext == txtpbExt {
+		message := dynamicpb.NewMessage(messageDescriptor)
+		if err := protoencoding.NewTxtpbUnmarshaler(resolver).Unmarshal(fixtureFile.data, message); err != nil {
+			return nil, fmt.Errorf("fixture %q: %w", fixtureFile.path, err)
+		}
+		return []proto.Message{message}, nil
+	}
+	var messages []proto.Message
+	decoder := json.NewDecoder(bytes.NewReader(fixtureFile.data))
+	for {
+		var rawMessage json.RawMessage
+		if err := decoder.Decode(&rawMessage); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("fixture %q: %w", fixtureFile.path, err)
+		}
+		message := dynamicpb.NewMessage(messageDescriptor)
+		if err := protoencoding.NewJSONUnmarshaler(resolver).Unmarshal(rawMessage, message); err != nil {
+			return nil, fmt.Errorf("fixture %q: %w", fixtureFile.path, err)
+		}
+		messages = append(messages, message)
+	}
+	return messages, nil
+}
+
+// messageMatches returns true if all of the fields set in expected are equal to the
+// fields of actual. Singular message fields are matched recursively.
+func messageMatches(expected protoreflect.Message, actual protoreflect.Message) bool {
+	matches := true
+	expected.Range(
+		func(fieldDescriptor protoreflect.FieldDescriptor, expectedValue protoreflect.Value) bool {
+			if !actual.Has(fieldDescriptor) {
+				matches = false
+				return false
+			}
+			actualValue := actual.Get(fieldDescriptor)
+			if fieldDescriptor.Message() != nil && !fieldDescriptor.IsList() && !fieldDescriptor.IsMap() {
+				matches = messageMatches(expectedValue.Message(), actualValue.Message())
+			} else {
+				matches = expectedValue.Equal(actualValue)
+			}
+			return matches
+		},
+	)
+	return matches
+}
@@ -0,0 +1,199 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	connect "github.com/bufbuild/connect-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// defaultFixtureName is logged for calls that do not match any fixture.
+const defaultFixtureName = "<default>"
+
+func newHandler(
+	ctx context.Context,
+	logger *zap.Logger,
+	image bufimage.Image,
+	handlerOptions *handlerOptions,
+) (http.Handler, error) {
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	resolver, err := protoencoding.NewResolver(bufimage.ImageToFileDescriptors(image)...)
+	if err != nil {
+		return nil, err
+	}
+	var methodFullNameToFixtures map[protoreflect.FullName][]*fixture
+	if handlerOptions.fixtureBucket != nil {
+		methodFullNameToFixtures, err = readFixtures(ctx, handlerOptions.fixtureBucket, files, resolver)
+		if err != nil {
+			return nil, err
+		}
+	}
+	mux := http.NewServeMux()
+	for _, imageFile := range image.Files() {
+		if imageFile.IsImport() {
+			continue
+		}
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				methodDescriptor := methods.Get(j)
+				procedure, handler := newMethodHandler(
+					&methodMocker{
+						logger:           logger,
+						methodDescriptor: methodDescriptor,
+						fixtures:         methodFullNameToFixtures[methodDescriptor.FullName()],
+					},
+					resolver,
+				)
+				logger.Debug("mock_method", zap.String("procedure", procedure))
+				mux.Handle(procedure, handler)
+			}
+		}
+	}
+	return mux, nil
+}
+
+// newMethodHandler returns the procedure and handler of the method.
+func newMethodHandler(methodMocker *methodMocker, resolver protoencoding.Resolver) (string, http.Handler) {
+	methodDescriptor := methodMocker.methodDescriptor
+	procedure := fmt.Sprintf("/%s/%s", methodDescriptor.Parent().FullName(), methodDescriptor.Name())
+	options := []connect.HandlerOption{
+		connect.WithCodec(newProtoCodec(methodDescriptor.Input(), resolver)),
+		connect.WithCodec(newJSONCodec(methodDescriptor.Input(), resolver)),
+	}
+	switch {
+	case methodDescriptor.IsStreamingClient() && methodDescriptor.IsStreamingServer():
+		return procedure, connect.NewBidiStreamHandler(procedure, methodMocker.handleBidiStream, options...)
+	case methodDescriptor.IsStreamingClient():
+		return procedure, connect.NewClientStreamHandler(procedure, methodMocker.handleClientStream, options...)
+	case methodDescriptor.IsStreamingServer():
+		return procedure, connect.NewServerStreamHandler(procedure, methodMocker.handleServerStream, options...)
+	default:
+		return procedure, connect.NewUnaryHandler(procedure, methodMocker.handleUnary, options...)
+	}
+}
+
+// methodMocker mocks a single method.
+type methodMocker struct {
+	logger           *zap.Logger
+	methodDescriptor protoreflect.MethodDescriptor
+	fixtures         []*fixture
+}
+
+func (m *methodMocker) handleUnary(
+	ctx context.Context,
+	request *connect.Request[message],
+) (*connect.Response[message], error) {
+	return connect.NewResponse(&message{m.getResponses(m.getRequest(request.Msg))[0]}), nil
+}
+
+func (m *methodMocker) handleClientStream(
+	ctx context.Context,
+	stream *connect.ClientStream[message],
+) (*connect.Response[message], error) {
+	// If the stream is empty, the responses for an empty request are used.
+	var lastRequest proto.Message = dynamicpb.NewMessage(m.methodDescriptor.Input())
+	for stream.Receive() {
+		lastRequest = m.getRequest(stream.Msg())
+	}
+	if err := stream.Err(); err != nil {
+		return nil, err
+	}
+	return connect.NewResponse(&message{m.getResponses(lastRequest)[0]}), nil
+}
+
+func (m *methodMocker) handleServerStream(
+	ctx context.Context,
+	request *connect.Request[message],
+	stream *connect.ServerStream[message],
+) error {
+	for _, response := range m.getResponses(m.getRequest(request.Msg)) {
+		if err := stream.Send(&message{response}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *methodMocker) handleBidiStream(
+	ctx context.Context,
+	stream *connect.BidiStream[message, message],
+) error {
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		for _, response := range m.getResponses(m.getRequest(request)) {
+			if err := stream.Send(&message{response}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// getRequest returns the request of the message.
+//
+// Codecs are not invoked for empty messages, in which case the request is empty.
+func (m *methodMocker) getRequest(request *message) proto.Message {
+	if request.message == nil {
+		return dynamicpb.NewMessage(m.methodDescriptor.Input())
+	}
+	return request.message
+}
+
+// getResponses returns the responses of the first fixture that matches the request,
+// or a single response with default values if no fixture matches.
+func (m *methodMocker) getResponses(request proto.Message) []proto.Message {
+	for _, fixture := range m.fixtures {
+		if fixture.matches(request) {
+			m.logger.Info(
+				"mock_call",
+				zap.String("method", string(m.methodDescriptor.FullName())),
+				zap.String("fixture", fixture.name),
+			)
+			return fixture.responses
+		}
+	}
+	m.logger.Info(
+		"mock_call",
+		zap.String("method", string(m.methodDescriptor.FullName())),
+		zap.String("fixture", defaultFixtureName),
+	)
+	return []proto.Message{dynamicpb.NewMessage(m.methodDescriptor.Output())}
+}
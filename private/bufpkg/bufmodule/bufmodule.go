@@ -38,6 +38,11 @@ const (
 	DefaultDocumentationPath = "buf.md"
 	// LicenseFilePath defines the path to the license file, relative to the root of the module.
 	LicenseFilePath = "LICENSE"
+	// VendorDirPath defines the path to the directory of vendored dependencies, relative to
+	// the root of the module.
+	//
+	// This is written by "buf mod vendor", and contains no .proto files.
+	VendorDirPath = "buf_vendor"
 
 	// b3DigestPrefix is the digest prefix for the third version of the digest function.
 	//
@@ -165,6 +170,12 @@ type Module interface {
 	// even if ModuleIdentity is set, that is commit is optional information
 	// even if we know what module this file came from.
 	Commit() string
+	// VendorModuleReader returns the ModuleReader for the vendored dependencies of the Module,
+	// if it was provided at construction time via ModuleWithVendorModuleReader.
+	//
+	// If this is set, dependencies must be read from it instead of any other ModuleReader.
+	// This can be nil.
+	VendorModuleReader() ModuleReader
 	isModule()
 }
 
@@ -189,6 +200,14 @@ func ModuleWithModuleIdentityAndCommit(moduleIdentity bufmoduleref.ModuleIdentit
 	}
 }
 
+// ModuleWithVendorModuleReader is used to construct a Module with a ModuleReader for its
+// vendored dependencies.
+func ModuleWithVendorModuleReader(vendorModuleReader ModuleReader) ModuleOption {
+	return func(module *module) {
+		module.vendorModuleReader = vendorModuleReader
+	}
+}
+
 // NewModuleForBucket returns a new Module. It attempts to read dependencies
 // from a lock file in the read bucket.
 func NewModuleForBucket(
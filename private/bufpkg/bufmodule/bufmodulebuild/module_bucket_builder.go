@@ -20,6 +20,7 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
 	"github.com/bufbuild/buf/private/bufpkg/buflock"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmodulecache"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
@@ -91,6 +92,8 @@ func (b *moduleBucketBuilder) buildForBucket(
 			// need to do match extension here
 			// https://github.com/bufbuild/buf/issues/113
 			storage.MatchPathExt(".proto"),
+			// never include vendored dependencies in the module itself
+			storage.MatchNot(storage.MatchPathContained(bufmodule.VendorDirPath)),
 			storage.MapOnPrefix(root),
 		}
 		if len(excludes) != 0 {
@@ -119,12 +122,37 @@ func (b *moduleBucketBuilder) buildForBucket(
 		)
 	}
 	bucket := storage.MultiReadBucket(rootBuckets...)
-	module, err := bufmodule.NewModuleForBucket(
-		ctx,
-		bucket,
+	moduleOptions := []bufmodule.ModuleOption{
 		bufmodule.ModuleWithModuleIdentity(
 			buildOptions.moduleIdentity, // This may be nil
 		),
+	}
+	// if dependencies were vendored, they must be read from the vendor directory
+	isVendorEmpty, err := storage.IsEmpty(ctx, readBucket, bufmodule.VendorDirPath)
+	if err != nil {
+		if !storage.IsNotExist(err) {
+			return nil, err
+		}
+		// some buckets, such as git buckets, return an error for a directory that does not exist
+		isVendorEmpty = true
+	}
+	if !isVendorEmpty {
+		moduleOptions = append(
+			moduleOptions,
+			bufmodule.ModuleWithVendorModuleReader(
+				bufmodulecache.NewVendorModuleReader(
+					storage.MapReadBucket(
+						readBucket,
+						storage.MapOnPrefix(bufmodule.VendorDirPath),
+					),
+				),
+			),
+		)
+	}
+	module, err := bufmodule.NewModuleForBucket(
+		ctx,
+		bucket,
+		moduleOptions...,
 	)
 	if err != nil {
 		return nil, err
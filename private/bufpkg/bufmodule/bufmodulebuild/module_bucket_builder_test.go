@@ -27,8 +27,10 @@ import (
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleconfig"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduletesting"
+	"github.com/bufbuild/buf/private/pkg/git/gittest"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagegit"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"github.com/stretchr/testify/assert"
@@ -335,6 +337,61 @@ func TestLicense(t *testing.T) {
 	)
 }
 
+func TestVendor(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config, err := bufmoduleconfig.NewConfigV1(
+		bufmoduleconfig.ExternalConfigV1{},
+	)
+	require.NoError(t, err)
+	bucket, err := memBucket(ctx,
+		"a/1.proto", "",
+	)
+	require.NoError(t, err)
+	module, err := NewModuleBucketBuilder().BuildForBucket(ctx, bucket, config)
+	require.NoError(t, err)
+	assert.Nil(t, module.VendorModuleReader())
+
+	bucket, err = memBucket(ctx,
+		"a/1.proto", "",
+		normalpath.Join(bufmodule.VendorDirPath, "buf.build/foo/bar/commits/1234"), "",
+		normalpath.Join(bufmodule.VendorDirPath, "buf.build/foo/bar/blobs/12/34.proto"), "",
+	)
+	require.NoError(t, err)
+	module, err = NewModuleBucketBuilder().BuildForBucket(ctx, bucket, config)
+	require.NoError(t, err)
+	assert.NotNil(t, module.VendorModuleReader())
+	fileInfos, err := module.TargetFileInfos(ctx)
+	require.NoError(t, err)
+	require.Len(t, fileInfos, 1)
+	assert.Equal(t, "a/1.proto", fileInfos[0].Path())
+}
+
+func TestVendorGitBucket(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	config, err := bufmoduleconfig.NewConfigV1(
+		bufmoduleconfig.ExternalConfigV1{},
+	)
+	require.NoError(t, err)
+	repo := gittest.ScaffoldGitRepository(t)
+	headCommit, err := repo.HEADCommit(repo.DefaultBranch())
+	require.NoError(t, err)
+	bucket, err := storagegit.NewProvider(repo.Objects()).NewReadBucket(headCommit.Tree())
+	require.NoError(t, err)
+	// git buckets return an error when walking a directory that does not exist
+	module, err := NewModuleBucketBuilder().BuildForBucket(
+		ctx,
+		storage.MapReadBucket(bucket, storage.MapOnPrefix("proto/acme")),
+		config,
+	)
+	require.NoError(t, err)
+	assert.Nil(t, module.VendorModuleReader())
+	fileInfos, err := module.TargetFileInfos(ctx)
+	require.NoError(t, err)
+	assert.Len(t, fileInfos, 8)
+}
+
 func TestConfigInclusion(t *testing.T) {
 	t.Parallel()
 	t.Run("buf.yaml", func(t *testing.T) {
@@ -136,7 +136,13 @@ func (m *moduleFileSetBuilder) build(
 				continue
 			}
 		}
-		dependencyModule, err := m.moduleReader.GetModule(ctx, dependencyModulePin)
+		moduleReader := m.moduleReader
+		if vendorModuleReader := module.VendorModuleReader(); vendorModuleReader != nil {
+			// Vendored dependencies take precedence, and a dependency that is not
+			// vendored is an error, so that builds never fall back to the network.
+			moduleReader = vendorModuleReader
+		}
+		dependencyModule, err := moduleReader.GetModule(ctx, dependencyModulePin)
 		if err != nil {
 			return nil, err
 		}
@@ -15,9 +15,12 @@
 package bufmodulecache
 
 import (
+	"time"
+
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
 	"github.com/bufbuild/buf/private/pkg/connectclient"
+	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/verbose"
 	"go.uber.org/zap"
@@ -38,6 +41,7 @@ func NewModuleReader(
 	bucket storage.ReadWriteBucket,
 	delegate bufmodule.ModuleReader,
 	repositoryClientFactory RepositoryServiceClientFactory,
+	options ...ModuleReaderOption,
 ) bufmodule.ModuleReader {
 	return newCASModuleReader(
 		bucket,
@@ -45,5 +49,35 @@ func NewModuleReader(
 		repositoryClientFactory,
 		logger,
 		verbosePrinter,
+		options...,
 	)
 }
+
+// ModuleReaderOption is an option for a new ModuleReader.
+type ModuleReaderOption func(*moduleReaderOptions)
+
+// ModuleReaderWithFileLocker returns a new ModuleReaderOption that locks the cache
+// with the given Locker, so that it can be safely garbage collected while it is read
+// by other processes.
+//
+// The default is to not lock the cache.
+func ModuleReaderWithFileLocker(fileLocker filelock.Locker) ModuleReaderOption {
+	return func(moduleReaderOptions *moduleReaderOptions) {
+		moduleReaderOptions.fileLocker = fileLocker
+	}
+}
+
+// ModuleReaderWithAutoGC returns a new ModuleReaderOption that garbage collects the
+// cache with the given options after a module is written to it, at most once per interval.
+//
+// Garbage collection is skipped if the cache is being read by another process.
+// The default is to never garbage collect the cache automatically.
+func ModuleReaderWithAutoGC(interval time.Duration, options ...GCOption) ModuleReaderOption {
+	return func(moduleReaderOptions *moduleReaderOptions) {
+		moduleReaderOptions.autoGCInterval = interval
+		moduleReaderOptions.autoGCOptions = newGCOptions()
+		for _, option := range options {
+			option(moduleReaderOptions.autoGCOptions)
+		}
+	}
+}
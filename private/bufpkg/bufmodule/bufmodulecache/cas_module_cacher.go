@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/manifest"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
@@ -34,17 +36,73 @@ import (
 const (
 	blobsDir   = "blobs"
 	commitsDir = "commits"
+	// accessDir contains the last access time of each manifest, which is
+	// used to evict the least recently used modules from the cache.
+	accessDir = "access"
+)
+
+const (
+	// lockPath is the path of the file lock of the cache within the root
+	// directory of the fileLocker.
+	//
+	// Reads and writes of the cache hold a shared lock, and garbage
+	// collection holds an exclusive lock.
+	lockPath = "cas.lock"
+	// lockTimeout is the timeout to acquire the file lock of the cache.
+	//
+	// This is longer than filelock.DefaultLockTimeout, as garbage collection
+	// of a large cache can take a while.
+	lockTimeout = time.Minute
+	// accessTimeResolution is the resolution of the recorded access times.
+	//
+	// The access time of a manifest is only written if the recorded access time
+	// is older than this, to avoid a write for every read from the cache.
+	accessTimeResolution = time.Hour
 )
 
 type casModuleCacher struct {
-	logger *zap.Logger
-	bucket storage.ReadWriteBucket
+	logger     *zap.Logger
+	bucket     storage.ReadWriteBucket
+	fileLocker filelock.Locker
+	now        func() time.Time
+	// skipAccessTimes disables recording access times, for caches that are
+	// never garbage collected such as vendor directories.
+	skipAccessTimes bool
 }
 
 func (c *casModuleCacher) GetModule(
 	ctx context.Context,
 	modulePin bufmoduleref.ModulePin,
 ) (_ bufmodule.Module, retErr error) {
+	unlocker, err := c.fileLocker.RLock(ctx, lockPath, filelock.LockWithTimeout(lockTimeout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	return c.getModule(ctx, modulePin)
+}
+
+func (c *casModuleCacher) PutModule(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+	module bufmodule.Module,
+) (retErr error) {
+	unlocker, err := c.fileLocker.RLock(ctx, lockPath, filelock.LockWithTimeout(lockTimeout))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	return c.putModule(ctx, modulePin, module)
+}
+
+func (c *casModuleCacher) getModule(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+) (bufmodule.Module, error) {
 	moduleBasedir := normalpath.Join(modulePin.Remote(), modulePin.Owner(), modulePin.Repository())
 	manifestDigestStr := modulePin.Digest()
 	if manifestDigestStr == "" {
@@ -60,39 +118,22 @@ func (c *casModuleCacher) GetModule(
 	if err != nil {
 		return nil, err
 	}
-	manifestFromCache, err := c.readManifest(ctx, moduleBasedir, *manifestDigest)
+	module, err := readModule(ctx, c.bucket, moduleBasedir, *manifestDigest, modulePin)
 	if err != nil {
 		return nil, err
 	}
-	digests := manifestFromCache.Digests()
-	blobs := make([]manifest.Blob, len(digests))
-	for i, digest := range digests {
-		blob, err := c.readBlob(ctx, moduleBasedir, digest)
-		if err != nil {
-			return nil, err
-		}
-		blobs[i] = blob
-	}
-	blobSet, err := manifest.NewBlobSet(ctx, blobs)
-	if err != nil {
-		return nil, err
+	if err := c.markAccessed(ctx, moduleBasedir, *manifestDigest); err != nil {
+		// The module is still usable, it just may be evicted earlier than it should be.
+		c.logger.Debug("could not record module cache access", zap.Error(err))
 	}
-	return bufmodule.NewModuleForManifestAndBlobSet(
-		ctx,
-		manifestFromCache,
-		blobSet,
-		bufmodule.ModuleWithModuleIdentityAndCommit(
-			modulePin,
-			modulePin.Commit(),
-		),
-	)
+	return module, nil
 }
 
-func (c *casModuleCacher) PutModule(
+func (c *casModuleCacher) putModule(
 	ctx context.Context,
 	modulePin bufmoduleref.ModulePin,
 	module bufmodule.Module,
-) (retErr error) {
+) error {
 	moduleManifest := module.Manifest()
 	if moduleManifest == nil {
 		return fmt.Errorf("manifest must be non-nil")
@@ -136,17 +177,73 @@ func (c *casModuleCacher) PutModule(
 	if err := c.atomicWrite(ctx, strings.NewReader(manifestBlob.Digest().String()), commitPath); err != nil {
 		return err
 	}
-	return nil
+	return c.markAccessed(ctx, moduleBasedir, *manifestDigest)
+}
+
+// markAccessed records that the module with the given manifest digest was accessed.
+//
+// A blob is considered to be accessed whenever a manifest that references it is accessed.
+func (c *casModuleCacher) markAccessed(
+	ctx context.Context,
+	moduleBasedir string,
+	manifestDigest manifest.Digest,
+) error {
+	if c.skipAccessTimes {
+		return nil
+	}
+	accessPath := accessPathForDigestHex(moduleBasedir, manifestDigest.Hex())
+	now := c.now()
+	if accessTime, err := readAccessTime(ctx, c.bucket, accessPath); err == nil && now.Sub(accessTime) < accessTimeResolution {
+		return nil
+	}
+	return writeTime(ctx, c.bucket, accessPath, now)
 }
 
-func (c *casModuleCacher) readBlob(
+// readModule reads the module with the given manifest digest, validating the digests
+// of the manifest and all blobs.
+func readModule(
 	ctx context.Context,
+	bucket storage.ReadBucket,
+	moduleBasedir string,
+	manifestDigest manifest.Digest,
+	modulePin bufmoduleref.ModulePin,
+) (bufmodule.Module, error) {
+	moduleManifest, err := readManifest(ctx, bucket, moduleBasedir, manifestDigest)
+	if err != nil {
+		return nil, err
+	}
+	digests := moduleManifest.Digests()
+	blobs := make([]manifest.Blob, len(digests))
+	for i, digest := range digests {
+		blob, err := readBlob(ctx, bucket, moduleBasedir, digest)
+		if err != nil {
+			return nil, err
+		}
+		blobs[i] = blob
+	}
+	blobSet, err := manifest.NewBlobSet(ctx, blobs)
+	if err != nil {
+		return nil, err
+	}
+	return bufmodule.NewModuleForManifestAndBlobSet(
+		ctx,
+		moduleManifest,
+		blobSet,
+		bufmodule.ModuleWithModuleIdentityAndCommit(
+			modulePin,
+			modulePin.Commit(),
+		),
+	)
+}
+
+func readBlob(
+	ctx context.Context,
+	bucket storage.ReadBucket,
 	moduleBasedir string,
 	digest manifest.Digest,
-) (_ manifest.Blob, retErr error) {
-	hexDigest := digest.Hex()
-	blobPath := normalpath.Join(moduleBasedir, blobsDir, hexDigest[:2], hexDigest[2:])
-	contents, err := c.loadPath(ctx, blobPath)
+) (manifest.Blob, error) {
+	blobPath := blobPathForDigestHex(moduleBasedir, digest.Hex())
+	contents, err := loadPath(ctx, bucket, blobPath)
 	if err != nil {
 		return nil, err
 	}
@@ -162,8 +259,7 @@ func (c *casModuleCacher) validateBlob(
 	moduleBasedir string,
 	digest *manifest.Digest,
 ) (bool, error) {
-	hexDigest := digest.Hex()
-	blobPath := normalpath.Join(moduleBasedir, blobsDir, hexDigest[:2], hexDigest[2:])
+	blobPath := blobPathForDigestHex(moduleBasedir, digest.Hex())
 	f, err := c.bucket.Get(ctx, blobPath)
 	if err != nil {
 		return false, err
@@ -184,12 +280,13 @@ func (c *casModuleCacher) validateBlob(
 	return digest.Equal(*cacheDigest), nil
 }
 
-func (c *casModuleCacher) readManifest(
+func readManifest(
 	ctx context.Context,
+	bucket storage.ReadBucket,
 	moduleBasedir string,
 	manifestDigest manifest.Digest,
 ) (_ *manifest.Manifest, retErr error) {
-	blob, err := c.readBlob(ctx, moduleBasedir, manifestDigest)
+	blob, err := readBlob(ctx, bucket, moduleBasedir, manifestDigest)
 	if err != nil {
 		return nil, err
 	}
@@ -231,8 +328,7 @@ func (c *casModuleCacher) writeBlob(
 	defer func() {
 		retErr = multierr.Append(retErr, contents.Close())
 	}()
-	hexDigest := blob.Digest().Hex()
-	blobPath := normalpath.Join(moduleBasedir, blobsDir, hexDigest[:2], hexDigest[2:])
+	blobPath := blobPathForDigestHex(moduleBasedir, blob.Digest().Hex())
 	return c.atomicWrite(ctx, contents, blobPath)
 }
 
@@ -253,8 +349,16 @@ func (c *casModuleCacher) atomicWrite(ctx context.Context, contents io.Reader, p
 func (c *casModuleCacher) loadPath(
 	ctx context.Context,
 	path string,
+) ([]byte, error) {
+	return loadPath(ctx, c.bucket, path)
+}
+
+func loadPath(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	path string,
 ) (_ []byte, retErr error) {
-	f, err := c.bucket.Get(ctx, path)
+	f, err := bucket.Get(ctx, path)
 	if err != nil {
 		return nil, err
 	}
@@ -263,3 +367,40 @@ func (c *casModuleCacher) loadPath(
 	}()
 	return io.ReadAll(f)
 }
+
+func readAccessTime(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	accessPath string,
+) (time.Time, error) {
+	data, err := loadPath(ctx, bucket, accessPath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, strings.TrimSpace(string(data)))
+}
+
+func writeTime(
+	ctx context.Context,
+	bucket storage.WriteBucket,
+	path string,
+	t time.Time,
+) (retErr error) {
+	writeObjectCloser, err := bucket.Put(ctx, path, storage.PutWithAtomic())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, writeObjectCloser.Close())
+	}()
+	_, err = writeObjectCloser.Write([]byte(t.UTC().Format(time.RFC3339Nano)))
+	return err
+}
+
+func blobPathForDigestHex(moduleBasedir string, hexDigest string) string {
+	return normalpath.Join(moduleBasedir, blobsDir, hexDigest[:2], hexDigest[2:])
+}
+
+func accessPathForDigestHex(moduleBasedir string, hexDigest string) string {
+	return normalpath.Join(moduleBasedir, accessDir, hexDigest[:2], hexDigest[2:])
+}
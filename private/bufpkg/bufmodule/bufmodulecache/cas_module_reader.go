@@ -17,9 +17,11 @@ package bufmodulecache
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
 	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/filelock"
 	"github.com/bufbuild/buf/private/pkg/manifest"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/verbose"
@@ -32,6 +34,9 @@ type casModuleReader struct {
 	repositoryClientFactory RepositoryServiceClientFactory
 	logger                  *zap.Logger
 	verbosePrinter          verbose.Printer
+	// optional parameters
+	autoGCInterval time.Duration
+	autoGCOptions  *gcOptions
 	// initialized in newCASModuleReader
 	cache *casModuleCacher
 	stats *cacheStats
@@ -45,15 +50,24 @@ func newCASModuleReader(
 	repositoryClientFactory RepositoryServiceClientFactory,
 	logger *zap.Logger,
 	verbosePrinter verbose.Printer,
+	options ...ModuleReaderOption,
 ) *casModuleReader {
+	moduleReaderOptions := newModuleReaderOptions()
+	for _, option := range options {
+		option(moduleReaderOptions)
+	}
 	return &casModuleReader{
 		delegate:                delegate,
 		repositoryClientFactory: repositoryClientFactory,
 		logger:                  logger,
 		verbosePrinter:          verbosePrinter,
+		autoGCInterval:          moduleReaderOptions.autoGCInterval,
+		autoGCOptions:           moduleReaderOptions.autoGCOptions,
 		cache: &casModuleCacher{
-			logger: logger,
-			bucket: bucket,
+			logger:     logger,
+			bucket:     bucket,
+			fileLocker: moduleReaderOptions.fileLocker,
+			now:        time.Now,
 		},
 		stats: &cacheStats{},
 	}
@@ -101,8 +115,52 @@ func (c *casModuleReader) GetModule(
 	if err := c.cache.PutModule(ctx, modulePin, remoteModule); err != nil {
 		return nil, err
 	}
+	if c.autoGCOptions != nil {
+		c.autoGC(ctx)
+	}
 	if err := warnIfDeprecated(ctx, c.repositoryClientFactory, modulePin, c.logger); err != nil {
 		return nil, err
 	}
 	return remoteModule, nil
 }
+
+// autoGC garbage collects the cache if it has not been garbage collected within
+// the auto GC interval.
+//
+// Errors are logged instead of returned, as they do not affect the module that was read.
+func (c *casModuleReader) autoGC(ctx context.Context) {
+	lastGCTime, err := readLastGCTime(ctx, c.cache.bucket)
+	if err != nil {
+		c.logger.Debug("could not read last module cache garbage collection time", zap.Error(err))
+	}
+	now := c.cache.now()
+	if now.Sub(lastGCTime) < c.autoGCInterval {
+		return
+	}
+	// Use the default timeout instead of lockTimeout, as other processes reading the
+	// cache should not delay this process for long.
+	result, err := gc(ctx, c.logger, c.cache.bucket, c.cache.fileLocker, now, filelock.DefaultLockTimeout, c.autoGCOptions)
+	if err != nil {
+		c.logger.Debug("module cache garbage collection skipped", zap.Error(err))
+		return
+	}
+	c.logger.Debug(
+		"module cache garbage collected",
+		zap.Int("modules_evicted", result.ModulesEvicted),
+		zap.Int64("bytes_freed", result.BytesFreed),
+		zap.Int("modules_retained", result.ModulesRetained),
+		zap.Int64("bytes_retained", result.BytesRetained),
+	)
+}
+
+type moduleReaderOptions struct {
+	fileLocker     filelock.Locker
+	autoGCInterval time.Duration
+	autoGCOptions  *gcOptions
+}
+
+func newModuleReaderOptions() *moduleReaderOptions {
+	return &moduleReaderOptions{
+		fileLocker: filelock.NewNopLocker(),
+	}
+}
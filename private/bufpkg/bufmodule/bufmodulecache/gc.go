@@ -0,0 +1,467 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+	"go.uber.org/zap"
+)
+
+// lastGCPath is the path of the file that contains the time of the last garbage
+// collection, which is used to run automatic garbage collection at most once
+// per interval.
+//
+// Remotes are hostnames, which cannot start with a dot, so this never conflicts
+// with the directory of a module.
+const lastGCPath = ".last-gc"
+
+// GCResult is the result of a garbage collection of the module cache.
+type GCResult struct {
+	// ModulesEvicted is the number of modules that were evicted.
+	ModulesEvicted int
+	// BytesFreed is the number of bytes of blobs that were deleted.
+	BytesFreed int64
+	// ModulesRetained is the number of modules that remain in the cache.
+	ModulesRetained int
+	// BytesRetained is the number of bytes of blobs that remain in the cache.
+	BytesRetained int64
+}
+
+// GCOption is an option for GC.
+type GCOption func(*gcOptions)
+
+// GCWithMaxSize returns a new GCOption that evicts the least recently used modules
+// until the blobs in the cache take up at most maxSize bytes.
+//
+// The default is to not limit the size of the cache.
+func GCWithMaxSize(maxSize int64) GCOption {
+	return func(gcOptions *gcOptions) {
+		gcOptions.maxSize = maxSize
+	}
+}
+
+// GCWithMaxAge returns a new GCOption that evicts the modules that have not been
+// accessed within maxAge.
+//
+// The default is to not limit the age of the modules in the cache.
+func GCWithMaxAge(maxAge time.Duration) GCOption {
+	return func(gcOptions *gcOptions) {
+		gcOptions.maxAge = maxAge
+	}
+}
+
+// GC evicts the least recently used modules from the module cache in the bucket
+// according to the options.
+//
+// Blobs that are referenced by a retained manifest are never deleted, and blobs that
+// are not referenced by any manifest are always deleted. Modules that were written by
+// a version of buf that did not record access times are considered accessed now.
+//
+// GC holds an exclusive lock on the cache while it runs, so the fileLocker must
+// be the same as the one given to the ModuleReader that reads from the bucket.
+func GC(
+	ctx context.Context,
+	logger *zap.Logger,
+	bucket storage.ReadWriteBucket,
+	fileLocker filelock.Locker,
+	options ...GCOption,
+) (*GCResult, error) {
+	gcOptions := newGCOptions()
+	for _, option := range options {
+		option(gcOptions)
+	}
+	return gc(ctx, logger, bucket, fileLocker, time.Now(), lockTimeout, gcOptions)
+}
+
+func gc(
+	ctx context.Context,
+	logger *zap.Logger,
+	bucket storage.ReadWriteBucket,
+	fileLocker filelock.Locker,
+	now time.Time,
+	timeout time.Duration,
+	gcOptions *gcOptions,
+) (_ *GCResult, retErr error) {
+	if gcOptions.maxSize < 0 {
+		return nil, errors.New("max size must be non-negative")
+	}
+	if gcOptions.maxAge < 0 {
+		return nil, errors.New("max age must be non-negative")
+	}
+	unlocker, err := fileLocker.Lock(ctx, lockPath, filelock.LockWithTimeout(timeout))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	collector := newGCCollector(logger, bucket, now)
+	if err := collector.load(ctx); err != nil {
+		return nil, err
+	}
+	result, err := collector.collect(ctx, gcOptions)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeTime(ctx, bucket, lastGCPath, now); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// readLastGCTime returns the time of the last garbage collection, or the zero
+// time if the cache has never been garbage collected.
+func readLastGCTime(ctx context.Context, bucket storage.ReadBucket) (time.Time, error) {
+	lastGCTime, err := readAccessTime(ctx, bucket, lastGCPath)
+	if err != nil && storage.IsNotExist(err) {
+		return time.Time{}, nil
+	}
+	return lastGCTime, err
+}
+
+type gcOptions struct {
+	maxSize int64
+	maxAge  time.Duration
+}
+
+func newGCOptions() *gcOptions {
+	return &gcOptions{}
+}
+
+// gcRepository is the cache of a single repository, that is the contents of
+// {remote}/{owner}/{repository}.
+//
+// Blobs are only shared between modules of the same repository.
+type gcRepository struct {
+	// hexDigestToBlobSize contains the size of every blob, including manifests.
+	hexDigestToBlobSize map[string]int64
+	// hexDigestToRefCount contains the number of retained modules that reference
+	// each blob. A module references its manifest and the blobs in its manifest.
+	hexDigestToRefCount map[string]int
+	// commitToManifestDigest contains the manifest digest string of every commit.
+	commitToManifestDigest map[string]string
+	// hexDigestToAccessTime contains the last access time of every manifest.
+	hexDigestToAccessTime map[string]time.Time
+}
+
+func newGCRepository() *gcRepository {
+	return &gcRepository{
+		hexDigestToBlobSize:    make(map[string]int64),
+		hexDigestToRefCount:    make(map[string]int),
+		commitToManifestDigest: make(map[string]string),
+		hexDigestToAccessTime:  make(map[string]time.Time),
+	}
+}
+
+// gcModule is a module in the cache, identified by the digest of its manifest.
+type gcModule struct {
+	moduleBasedir     string
+	manifestHexDigest string
+	accessTime        time.Time
+	// blobHexDigests are the hex digests of the blobs referenced by the module,
+	// including the manifest itself.
+	blobHexDigests []string
+}
+
+type gcCollector struct {
+	logger                    *zap.Logger
+	bucket                    storage.ReadWriteBucket
+	now                       time.Time
+	moduleBasedirToRepository map[string]*gcRepository
+	modules                   []*gcModule
+	retainedBytes             int64
+	freedBytes                int64
+}
+
+func newGCCollector(logger *zap.Logger, bucket storage.ReadWriteBucket, now time.Time) *gcCollector {
+	return &gcCollector{
+		logger:                    logger,
+		bucket:                    bucket,
+		now:                       now,
+		moduleBasedirToRepository: make(map[string]*gcRepository),
+	}
+}
+
+// load reads the contents of the cache.
+func (c *gcCollector) load(ctx context.Context) error {
+	var objectInfos []storage.ObjectInfo
+	if err := c.bucket.Walk(ctx, "", func(objectInfo storage.ObjectInfo) error {
+		objectInfos = append(objectInfos, objectInfo)
+		return nil
+	}); err != nil {
+		return err
+	}
+	for _, objectInfo := range objectInfos {
+		if err := c.loadObject(ctx, objectInfo); err != nil {
+			return err
+		}
+	}
+	moduleBasedirs := make([]string, 0, len(c.moduleBasedirToRepository))
+	for moduleBasedir := range c.moduleBasedirToRepository {
+		moduleBasedirs = append(moduleBasedirs, moduleBasedir)
+	}
+	sort.Strings(moduleBasedirs)
+	for _, moduleBasedir := range moduleBasedirs {
+		if err := c.loadModules(ctx, moduleBasedir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadObject records an object of the cache in its gcRepository.
+//
+// Objects that do not match the layout of the cache are ignored.
+func (c *gcCollector) loadObject(ctx context.Context, objectInfo storage.ObjectInfo) error {
+	path := objectInfo.Path()
+	// {remote}/{owner}/{repository}/{blobs,commits,access}/...
+	components := normalpath.Components(path)
+	if len(components) < 5 {
+		return nil
+	}
+	moduleBasedir := normalpath.Join(components[:3]...)
+	repository, ok := c.moduleBasedirToRepository[moduleBasedir]
+	if !ok {
+		repository = newGCRepository()
+		c.moduleBasedirToRepository[moduleBasedir] = repository
+	}
+	switch rest := components[4:]; {
+	case components[3] == blobsDir && len(rest) == 2:
+		size, err := c.objectSize(ctx, objectInfo)
+		if err != nil {
+			return err
+		}
+		repository.hexDigestToBlobSize[rest[0]+rest[1]] = size
+	case components[3] == commitsDir && len(rest) == 1:
+		manifestDigest, err := loadPath(ctx, c.bucket, path)
+		if err != nil {
+			return err
+		}
+		repository.commitToManifestDigest[rest[0]] = string(manifestDigest)
+	case components[3] == accessDir && len(rest) == 2:
+		accessTime, err := readAccessTime(ctx, c.bucket, path)
+		if err != nil {
+			// A corrupt access time is rewritten when the module is loaded.
+			c.logger.Debug("invalid module cache access time", zap.String("path", path), zap.Error(err))
+			return nil
+		}
+		repository.hexDigestToAccessTime[rest[0]+rest[1]] = accessTime
+	}
+	return nil
+}
+
+// loadModules reads the manifests of a repository and records their references.
+//
+// A blob is a manifest if a commit or an access time refers to it.
+func (c *gcCollector) loadModules(ctx context.Context, moduleBasedir string) error {
+	repository := c.moduleBasedirToRepository[moduleBasedir]
+	manifestHexDigests := make(map[string]struct{})
+	for commit, manifestDigestString := range repository.commitToManifestDigest {
+		manifestDigest, err := manifest.NewDigestFromString(manifestDigestString)
+		if err != nil || !c.hasBlob(repository, manifestDigest.Hex()) {
+			// The commit refers to a manifest that is not in the cache.
+			if err := c.bucket.Delete(ctx, normalpath.Join(moduleBasedir, commitsDir, commit)); err != nil {
+				return err
+			}
+			delete(repository.commitToManifestDigest, commit)
+			continue
+		}
+		manifestHexDigests[manifestDigest.Hex()] = struct{}{}
+	}
+	for manifestHexDigest := range repository.hexDigestToAccessTime {
+		if !c.hasBlob(repository, manifestHexDigest) {
+			if err := c.deleteIfExists(ctx, accessPathForDigestHex(moduleBasedir, manifestHexDigest)); err != nil {
+				return err
+			}
+			continue
+		}
+		manifestHexDigests[manifestHexDigest] = struct{}{}
+	}
+	for manifestHexDigest := range manifestHexDigests {
+		data, err := loadPath(ctx, c.bucket, blobPathForDigestHex(moduleBasedir, manifestHexDigest))
+		if err != nil {
+			return err
+		}
+		moduleManifest, err := manifest.NewFromReader(bytes.NewReader(data))
+		if err != nil {
+			// The manifest is corrupt, so no blobs are referenced, and the manifest
+			// and its commits are deleted along with the unreferenced blobs.
+			c.logger.Debug("invalid module cache manifest", zap.String("digest", manifestHexDigest), zap.Error(err))
+			if err := c.deleteModule(ctx, moduleBasedir, manifestHexDigest); err != nil {
+				return err
+			}
+			continue
+		}
+		accessTime, ok := repository.hexDigestToAccessTime[manifestHexDigest]
+		if !ok {
+			// The module was written before access times were recorded.
+			accessTime = c.now
+			if err := writeTime(ctx, c.bucket, accessPathForDigestHex(moduleBasedir, manifestHexDigest), accessTime); err != nil {
+				return err
+			}
+		}
+		module := &gcModule{
+			moduleBasedir:     moduleBasedir,
+			manifestHexDigest: manifestHexDigest,
+			accessTime:        accessTime,
+			blobHexDigests:    []string{manifestHexDigest},
+		}
+		for _, digest := range moduleManifest.Digests() {
+			module.blobHexDigests = append(module.blobHexDigests, digest.Hex())
+		}
+		for _, blobHexDigest := range module.blobHexDigests {
+			repository.hexDigestToRefCount[blobHexDigest]++
+		}
+		c.modules = append(c.modules, module)
+	}
+	// Blobs that are not referenced by any module are deleted, and the remaining
+	// blobs make up the size of the cache.
+	for blobHexDigest, size := range repository.hexDigestToBlobSize {
+		if repository.hexDigestToRefCount[blobHexDigest] > 0 {
+			c.retainedBytes += size
+			continue
+		}
+		if err := c.deleteBlob(ctx, moduleBasedir, blobHexDigest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collect evicts the least recently used modules until the cache satisfies the options.
+func (c *gcCollector) collect(ctx context.Context, gcOptions *gcOptions) (*GCResult, error) {
+	sort.SliceStable(c.modules, func(i int, j int) bool {
+		if c.modules[i].accessTime.Equal(c.modules[j].accessTime) {
+			return c.modules[i].moduleBasedir+c.modules[i].manifestHexDigest < c.modules[j].moduleBasedir+c.modules[j].manifestHexDigest
+		}
+		return c.modules[i].accessTime.Before(c.modules[j].accessTime)
+	})
+	var modulesEvicted int
+	for _, module := range c.modules {
+		expired := gcOptions.maxAge > 0 && c.now.Sub(module.accessTime) > gcOptions.maxAge
+		oversized := gcOptions.maxSize > 0 && c.retainedBytes > gcOptions.maxSize
+		// Modules are sorted by access time and evicting a module never grows
+		// the cache, so no later module needs to be evicted either.
+		if !expired && !oversized {
+			break
+		}
+		if err := c.evictModule(ctx, module); err != nil {
+			return nil, err
+		}
+		modulesEvicted++
+	}
+	return &GCResult{
+		ModulesEvicted:  modulesEvicted,
+		BytesFreed:      c.freedBytes,
+		ModulesRetained: len(c.modules) - modulesEvicted,
+		BytesRetained:   c.retainedBytes,
+	}, nil
+}
+
+// evictModule deletes a module along with the blobs that no other retained module references.
+func (c *gcCollector) evictModule(ctx context.Context, module *gcModule) error {
+	repository := c.moduleBasedirToRepository[module.moduleBasedir]
+	if err := c.deleteModule(ctx, module.moduleBasedir, module.manifestHexDigest); err != nil {
+		return err
+	}
+	for _, blobHexDigest := range module.blobHexDigests {
+		repository.hexDigestToRefCount[blobHexDigest]--
+		if repository.hexDigestToRefCount[blobHexDigest] > 0 {
+			continue
+		}
+		c.retainedBytes -= repository.hexDigestToBlobSize[blobHexDigest]
+		if err := c.deleteBlob(ctx, module.moduleBasedir, blobHexDigest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteModule deletes the commits and the access time of a manifest.
+//
+// The manifest itself is deleted with the other blobs once it is no longer referenced.
+func (c *gcCollector) deleteModule(ctx context.Context, moduleBasedir string, manifestHexDigest string) error {
+	repository := c.moduleBasedirToRepository[moduleBasedir]
+	for commit, manifestDigestString := range repository.commitToManifestDigest {
+		manifestDigest, err := manifest.NewDigestFromString(manifestDigestString)
+		if err != nil || manifestDigest.Hex() != manifestHexDigest {
+			continue
+		}
+		if err := c.bucket.Delete(ctx, normalpath.Join(moduleBasedir, commitsDir, commit)); err != nil {
+			return err
+		}
+		delete(repository.commitToManifestDigest, commit)
+	}
+	return c.deleteIfExists(ctx, accessPathForDigestHex(moduleBasedir, manifestHexDigest))
+}
+
+func (c *gcCollector) deleteBlob(ctx context.Context, moduleBasedir string, blobHexDigest string) error {
+	repository := c.moduleBasedirToRepository[moduleBasedir]
+	size, ok := repository.hexDigestToBlobSize[blobHexDigest]
+	if !ok {
+		// The manifest references a blob that is not in the cache.
+		return nil
+	}
+	if err := c.bucket.Delete(ctx, blobPathForDigestHex(moduleBasedir, blobHexDigest)); err != nil {
+		return err
+	}
+	delete(repository.hexDigestToBlobSize, blobHexDigest)
+	c.freedBytes += size
+	return nil
+}
+
+func (c *gcCollector) deleteIfExists(ctx context.Context, path string) error {
+	if err := c.bucket.Delete(ctx, path); err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *gcCollector) hasBlob(repository *gcRepository, hexDigest string) bool {
+	_, ok := repository.hexDigestToBlobSize[hexDigest]
+	return ok
+}
+
+// objectSize returns the size of the object.
+//
+// Objects on disk are stat'ed, so that blobs are not read while the cache is
+// locked. Objects of other buckets, which have no file to stat, are read.
+func (c *gcCollector) objectSize(ctx context.Context, objectInfo storage.ObjectInfo) (_ int64, retErr error) {
+	if externalPath := objectInfo.ExternalPath(); externalPath != objectInfo.Path() {
+		if fileInfo, err := os.Stat(externalPath); err == nil {
+			return fileInfo.Size(), nil
+		}
+	}
+	readObjectCloser, err := c.bucket.Get(ctx, objectInfo.Path())
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, readObjectCloser.Close())
+	}()
+	return io.Copy(io.Discard, readObjectCloser)
+}
@@ -0,0 +1,301 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/gen/proto/connect/buf/alpha/registry/v1alpha1/registryv1alpha1connect"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const sharedProto = `syntax = "proto3";
+
+package shared.v1;
+
+message Shared {}
+`
+
+func TestGCMaxAge(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := storagemem.NewReadWriteBucket()
+	oldPin, oldModule := testPutModule(t, bucket, start, "ping", "old", "a.proto")
+	newPin, newModule := testPutModule(t, bucket, start.Add(48*time.Hour), "ping", "new", "b.proto")
+
+	result, err := gc(ctx, zaptest.NewLogger(t), bucket, filelock.NewNopLocker(), start.Add(72*time.Hour), lockTimeout, &gcOptions{maxAge: 36 * time.Hour})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ModulesEvicted)
+	assert.Equal(t, 1, result.ModulesRetained)
+	assert.Equal(t, testBlobSize(t, oldModule, "a.proto")+testManifestSize(t, oldModule), result.BytesFreed)
+	assert.Equal(t, testBucketSize(t, bucket, blobsDir), result.BytesRetained)
+
+	cacher := testNewCacher(t, bucket, start.Add(72*time.Hour))
+	_, err = cacher.GetModule(ctx, oldPin)
+	assert.True(t, storage.IsNotExist(err))
+	// The blob shared with the old module is retained, as the new module references it.
+	_, err = cacher.GetModule(ctx, newPin)
+	require.NoError(t, err)
+	verifyCache(t, bucket, newPin, newModule.Manifest(), newModule.BlobSet())
+	lastGCTime, err := readLastGCTime(ctx, bucket)
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(72*time.Hour), lastGCTime)
+}
+
+func TestGCMaxSize(t *testing.T) {
+	t.Parallel()
+	t.Run("mem", func(t *testing.T) {
+		t.Parallel()
+		testGCMaxSize(t, storagemem.NewReadWriteBucket())
+	})
+	t.Run("os", func(t *testing.T) {
+		t.Parallel()
+		// Blobs on disk are stat'ed instead of read.
+		bucket, err := storageos.NewProvider().NewReadWriteBucket(t.TempDir())
+		require.NoError(t, err)
+		testGCMaxSize(t, bucket)
+	})
+}
+
+func testGCMaxSize(t *testing.T, bucket storage.ReadWriteBucket) {
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	pin1, _ := testPutModule(t, bucket, start, "ping", "one", "a.proto")
+	pin2, _ := testPutModule(t, bucket, start.Add(2*time.Hour), "pong", "two", "b.proto")
+	pin3, _ := testPutModule(t, bucket, start.Add(4*time.Hour), "ping", "three", "c.proto")
+	// Reading the first module makes it the most recently used.
+	_, err := testNewCacher(t, bucket, start.Add(6*time.Hour)).GetModule(ctx, pin1)
+	require.NoError(t, err)
+	totalSize := testBucketSize(t, bucket, blobsDir)
+
+	result, err := gc(ctx, zaptest.NewLogger(t), bucket, filelock.NewNopLocker(), start.Add(8*time.Hour), lockTimeout, &gcOptions{maxSize: totalSize - 1})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.ModulesEvicted)
+	assert.Equal(t, 2, result.ModulesRetained)
+	assert.Equal(t, totalSize-result.BytesFreed, result.BytesRetained)
+	assert.Equal(t, result.BytesRetained, testBucketSize(t, bucket, blobsDir))
+	cacher := testNewCacher(t, bucket, start.Add(8*time.Hour))
+	_, err = cacher.GetModule(ctx, pin2)
+	assert.Error(t, err)
+	for _, pin := range []bufmoduleref.ModulePin{pin1, pin3} {
+		_, err = cacher.GetModule(ctx, pin)
+		assert.NoError(t, err)
+	}
+
+	result, err = gc(ctx, zaptest.NewLogger(t), bucket, filelock.NewNopLocker(), start.Add(8*time.Hour), lockTimeout, &gcOptions{maxSize: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.ModulesEvicted)
+	assert.Equal(t, 0, result.ModulesRetained)
+	assert.Equal(t, int64(0), result.BytesRetained)
+	assert.Equal(t, int64(0), testBucketSize(t, bucket, ""))
+}
+
+func TestGCUnreferencedAndLegacy(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := storagemem.NewReadWriteBucket()
+	pin, module := testPutModule(t, bucket, start, "ping", "legacy", "a.proto")
+	moduleBasedir := normalpath.Join(pin.Remote(), pin.Owner(), pin.Repository())
+	manifestBlob, err := module.Manifest().Blob()
+	require.NoError(t, err)
+	// Modules written before access times were recorded have no access time.
+	require.NoError(t, bucket.Delete(ctx, accessPathForDigestHex(moduleBasedir, manifestBlob.Digest().Hex())))
+	// Blobs without a manifest and commits without a manifest are deleted.
+	orphanBlob, err := manifest.NewMemoryBlobFromReader(strings.NewReader("orphan"))
+	require.NoError(t, err)
+	require.NoError(t, testNewCacher(t, bucket, start).writeBlob(ctx, moduleBasedir, orphanBlob))
+	require.NoError(t, writeTime(ctx, bucket, normalpath.Join(moduleBasedir, commitsDir, "dangling"), start))
+
+	result, err := gc(ctx, zaptest.NewLogger(t), bucket, filelock.NewNopLocker(), start.Add(time.Hour), lockTimeout, &gcOptions{maxAge: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.ModulesEvicted)
+	assert.Equal(t, 1, result.ModulesRetained)
+	assert.Equal(t, int64(len("orphan")), result.BytesFreed)
+	accessTime, err := readAccessTime(ctx, bucket, accessPathForDigestHex(moduleBasedir, manifestBlob.Digest().Hex()))
+	require.NoError(t, err)
+	assert.Equal(t, start.Add(time.Hour), accessTime)
+	_, err = bucket.Stat(ctx, normalpath.Join(moduleBasedir, commitsDir, "dangling"))
+	assert.True(t, storage.IsNotExist(err))
+	verifyCache(t, bucket, pin, module.Manifest(), module.BlobSet())
+}
+
+func TestGCLocked(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	fileLocker, err := filelock.NewLocker(t.TempDir())
+	require.NoError(t, err)
+	unlocker, err := fileLocker.RLock(ctx, lockPath)
+	require.NoError(t, err)
+	_, err = gc(ctx, zaptest.NewLogger(t), storagemem.NewReadWriteBucket(), fileLocker, time.Now(), 100*time.Millisecond, &gcOptions{maxSize: 1})
+	assert.Error(t, err)
+	require.NoError(t, unlocker.Unlock())
+	_, err = gc(ctx, zaptest.NewLogger(t), storagemem.NewReadWriteBucket(), fileLocker, time.Now(), 100*time.Millisecond, &gcOptions{maxSize: 1})
+	assert.NoError(t, err)
+}
+
+func TestCASModuleReaderAutoGC(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := storagemem.NewReadWriteBucket()
+	oldPin, _ := testPutModule(t, bucket, start, "ping", "old", "a.proto")
+	_, module := testNewModule(t, "b.proto")
+	moduleReader := newCASModuleReader(
+		bucket,
+		&testModuleReader{module: module},
+		func(_ string) registryv1alpha1connect.RepositoryServiceClient {
+			return &testRepositoryServiceClient{}
+		},
+		zaptest.NewLogger(t),
+		&testVerbosePrinter{t: t},
+		ModuleReaderWithAutoGC(time.Hour, GCWithMaxAge(time.Hour)),
+	)
+	now := start.Add(2 * time.Hour)
+	moduleReader.cache.now = func() time.Time { return now }
+	_, err := moduleReader.GetModule(ctx, testNewModulePin(t, "ping", "new", ""))
+	require.NoError(t, err)
+	_, err = moduleReader.cache.GetModule(ctx, oldPin)
+	assert.Error(t, err)
+
+	// Garbage collection runs at most once per interval.
+	oldPin, _ = testPutModule(t, bucket, start, "ping", "old", "a.proto")
+	now = now.Add(time.Minute)
+	_, err = moduleReader.GetModule(ctx, testNewModulePin(t, "ping", "newer", ""))
+	require.NoError(t, err)
+	_, err = moduleReader.cache.GetModule(ctx, oldPin)
+	assert.NoError(t, err)
+}
+
+// testPutModule puts a module with the given file and a file shared by all modules into
+// the cache at the given time.
+func testPutModule(
+	t *testing.T,
+	bucket storage.ReadWriteBucket,
+	now time.Time,
+	repository string,
+	commit string,
+	path string,
+) (bufmoduleref.ModulePin, bufmodule.Module) {
+	t.Helper()
+	digest, module := testNewModule(t, path)
+	pin := testNewModulePin(t, repository, commit, digest)
+	require.NoError(t, testNewCacher(t, bucket, now).PutModule(context.Background(), pin, module))
+	return pin, module
+}
+
+func testNewModule(t *testing.T, path string) (string, bufmodule.Module) {
+	t.Helper()
+	ctx := context.Background()
+	var moduleManifest manifest.Manifest
+	var blobs []manifest.Blob
+	for filePath, content := range map[string]string{
+		path:                     "// " + path + "\n" + pingProto,
+		"shared/v1/shared.proto": sharedProto,
+	} {
+		blob, err := manifest.NewMemoryBlobFromReader(strings.NewReader(content))
+		require.NoError(t, err)
+		require.NoError(t, moduleManifest.AddEntry(filePath, *blob.Digest()))
+		blobs = append(blobs, blob)
+	}
+	blobSet, err := manifest.NewBlobSet(ctx, blobs)
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForManifestAndBlobSet(ctx, &moduleManifest, blobSet)
+	require.NoError(t, err)
+	manifestBlob, err := moduleManifest.Blob()
+	require.NoError(t, err)
+	return manifestBlob.Digest().String(), module
+}
+
+func testNewModulePin(t *testing.T, repository string, commit string, digest string) bufmoduleref.ModulePin {
+	t.Helper()
+	pin, err := bufmoduleref.NewModulePin("buf.build", "test", repository, "", commit, digest, time.Now())
+	require.NoError(t, err)
+	return pin
+}
+
+func testNewCacher(t *testing.T, bucket storage.ReadWriteBucket, now time.Time) *casModuleCacher {
+	return &casModuleCacher{
+		logger:     zaptest.NewLogger(t),
+		bucket:     bucket,
+		fileLocker: filelock.NewNopLocker(),
+		now: func() time.Time {
+			return now
+		},
+	}
+}
+
+func testBlobSize(t *testing.T, module bufmodule.Module, path string) int64 {
+	t.Helper()
+	digest, ok := module.Manifest().DigestFor(path)
+	require.True(t, ok)
+	blob, ok := module.BlobSet().BlobFor(digest.String())
+	require.True(t, ok)
+	return testBlobBytesSize(t, blob)
+}
+
+func testManifestSize(t *testing.T, module bufmodule.Module) int64 {
+	t.Helper()
+	manifestBlob, err := module.Manifest().Blob()
+	require.NoError(t, err)
+	return testBlobBytesSize(t, manifestBlob)
+}
+
+func testBlobBytesSize(t *testing.T, blob manifest.Blob) int64 {
+	t.Helper()
+	readCloser, err := blob.Open(context.Background())
+	require.NoError(t, err)
+	defer readCloser.Close()
+	size, err := io.Copy(io.Discard, readCloser)
+	require.NoError(t, err)
+	return size
+}
+
+// testBucketSize returns the total size of the objects in the bucket whose path
+// contains the given directory, or all objects if dir is empty.
+func testBucketSize(t *testing.T, bucket storage.ReadBucket, dir string) int64 {
+	t.Helper()
+	var size int64
+	require.NoError(t, bucket.Walk(context.Background(), "", func(objectInfo storage.ObjectInfo) error {
+		if objectInfo.Path() == lastGCPath {
+			return nil
+		}
+		if dir != "" && !strings.Contains(objectInfo.Path(), "/"+dir+"/") {
+			return nil
+		}
+		data, err := loadPath(context.Background(), bucket, objectInfo.Path())
+		if err != nil {
+			return err
+		}
+		size += int64(len(data))
+		return nil
+	}))
+	return size
+}
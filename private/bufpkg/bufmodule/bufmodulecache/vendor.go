@@ -0,0 +1,118 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/zap"
+)
+
+// PutVendorModule writes the module for the pin to a vendor directory.
+//
+// Vendor directories have the same layout as the module cache, without access times,
+// so that they can be checked into a repository and read without network access.
+func PutVendorModule(
+	ctx context.Context,
+	logger *zap.Logger,
+	vendorBucket storage.ReadWriteBucket,
+	modulePin bufmoduleref.ModulePin,
+	module bufmodule.Module,
+) error {
+	cacher := &casModuleCacher{
+		logger:          logger,
+		bucket:          vendorBucket,
+		fileLocker:      filelock.NewNopLocker(),
+		skipAccessTimes: true,
+	}
+	return cacher.putModule(ctx, modulePin, module)
+}
+
+// NewVendorModuleReader returns a new ModuleReader that reads modules from a vendor
+// directory written by PutVendorModule.
+//
+// Unlike other ModuleReaders, the returned ModuleReader returns an error that does not
+// fulfill storage.IsNotExist if a module is not vendored, and an error if the vendored
+// module does not match the digest of the pin, so that callers never fall back to reading
+// the module from somewhere else.
+func NewVendorModuleReader(vendorBucket storage.ReadBucket) bufmodule.ModuleReader {
+	return newVendorModuleReader(vendorBucket)
+}
+
+// VerifyVendorModule verifies the vendored module for the pin in a vendor directory written
+// by PutVendorModule, in the same way that VerifyModule verifies a cached module.
+func VerifyVendorModule(
+	ctx context.Context,
+	vendorBucket storage.ReadBucket,
+	modulePin bufmoduleref.ModulePin,
+) error {
+	return VerifyModule(ctx, vendorBucket, filelock.NewNopLocker(), modulePin)
+}
+
+type vendorModuleReader struct {
+	bucket storage.ReadBucket
+}
+
+func newVendorModuleReader(bucket storage.ReadBucket) *vendorModuleReader {
+	return &vendorModuleReader{
+		bucket: bucket,
+	}
+}
+
+func (r *vendorModuleReader) GetModule(
+	ctx context.Context,
+	modulePin bufmoduleref.ModulePin,
+) (bufmodule.Module, error) {
+	moduleBasedir := normalpath.Join(modulePin.Remote(), modulePin.Owner(), modulePin.Repository())
+	vendoredManifestDigestBytes, err := loadPath(ctx, r.bucket, normalpath.Join(moduleBasedir, commitsDir, modulePin.Commit()))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, fmt.Errorf(
+				`dependency %q is not vendored, run "buf mod vendor" to update the %s directory`,
+				modulePin.String(),
+				bufmodule.VendorDirPath,
+			)
+		}
+		return nil, err
+	}
+	vendoredManifestDigestString := string(vendoredManifestDigestBytes)
+	if modulePinDigest := modulePin.Digest(); modulePinDigest != "" && modulePinDigest != vendoredManifestDigestString {
+		return nil, fmt.Errorf(
+			`vendored dependency %q has digest %q but the lock file has digest %q, run "buf mod vendor" to update the %s directory`,
+			modulePin.String(),
+			vendoredManifestDigestString,
+			modulePinDigest,
+			bufmodule.VendorDirPath,
+		)
+	}
+	manifestDigest, err := manifest.NewDigestFromString(vendoredManifestDigestString)
+	if err != nil {
+		return nil, fmt.Errorf("vendored dependency %q has a malformed digest %q: %w", modulePin.String(), vendoredManifestDigestString, err)
+	}
+	module, err := readModule(ctx, r.bucket, moduleBasedir, *manifestDigest, modulePin)
+	if err != nil {
+		// Any error here means the vendor directory was modified, so the error
+		// is not wrapped, as a missing blob must not fulfill storage.IsNotExist.
+		return nil, fmt.Errorf("vendored dependency %q does not match its digest: %v", modulePin.String(), err)
+	}
+	return module, nil
+}
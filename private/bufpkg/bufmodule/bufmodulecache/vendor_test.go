@@ -0,0 +1,94 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+func TestVendorModuleReader(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := storagemem.NewReadWriteBucket()
+	digest, module := testNewModule(t, "a.proto")
+	pin := testNewModulePin(t, "ping", "abcd", digest)
+	require.NoError(t, PutVendorModule(ctx, zaptest.NewLogger(t), bucket, pin, module))
+	isEmpty, err := storage.IsEmpty(ctx, bucket, accessDir)
+	require.NoError(t, err)
+	assert.True(t, isEmpty)
+
+	vendorModuleReader := NewVendorModuleReader(bucket)
+	vendoredModule, err := vendorModuleReader.GetModule(ctx, pin)
+	require.NoError(t, err)
+	expectedManifestText, err := module.Manifest().MarshalText()
+	require.NoError(t, err)
+	vendoredManifestText, err := vendoredModule.Manifest().MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, string(expectedManifestText), string(vendoredManifestText))
+
+	_, err = vendorModuleReader.GetModule(ctx, testNewModulePin(t, "ping", "efgh", digest))
+	require.Error(t, err)
+	assert.False(t, storage.IsNotExist(err))
+	assert.Contains(t, err.Error(), "is not vendored")
+
+	otherDigest, _ := testNewModule(t, "b.proto")
+	_, err = vendorModuleReader.GetModule(ctx, testNewModulePin(t, "ping", "abcd", otherDigest))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "but the lock file has digest")
+
+	blobDigest, ok := module.Manifest().DigestFor("a.proto")
+	require.True(t, ok)
+	blobPath := blobPathForDigestHex(normalpath.Join(pin.Remote(), pin.Owner(), pin.Repository()), blobDigest.Hex())
+	require.NoError(t, storage.PutPath(ctx, bucket, blobPath, []byte("tampered")))
+	_, err = vendorModuleReader.GetModule(ctx, pin)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "does not match its digest")
+
+	require.NoError(t, bucket.Delete(ctx, blobPath))
+	_, err = vendorModuleReader.GetModule(ctx, pin)
+	require.Error(t, err)
+	assert.False(t, storage.IsNotExist(err))
+}
+
+func TestVerifyVendorModule(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := storagemem.NewReadWriteBucket()
+	digest, module := testNewModule(t, "a.proto")
+	pin := testNewModulePin(t, "ping", "abcd", digest)
+	require.NoError(t, PutVendorModule(ctx, zaptest.NewLogger(t), bucket, pin, module))
+	require.NoError(t, VerifyVendorModule(ctx, bucket, pin))
+
+	err := VerifyVendorModule(ctx, bucket, testNewModulePin(t, "pong", "abcd", digest))
+	assert.ErrorIs(t, err, ErrModuleNotCached)
+	otherDigest, _ := testNewModule(t, "b.proto")
+	err = VerifyVendorModule(ctx, bucket, testNewModulePin(t, "ping", "abcd", otherDigest))
+	assert.ErrorIs(t, err, ErrDigestMismatch)
+
+	blobDigest, ok := module.Manifest().DigestFor("a.proto")
+	require.True(t, ok)
+	blobPath := blobPathForDigestHex(normalpath.Join(pin.Remote(), pin.Owner(), pin.Repository()), blobDigest.Hex())
+	require.NoError(t, storage.PutPath(ctx, bucket, blobPath, []byte("tampered")))
+	err = VerifyVendorModule(ctx, bucket, pin)
+	assert.ErrorIs(t, err, ErrModuleCorrupt)
+}
@@ -0,0 +1,132 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule/bufmoduleref"
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"go.uber.org/multierr"
+)
+
+var (
+	// ErrModuleNotCached is returned by VerifyModule if the module, or any of the blobs
+	// of the module, is not in the cache or vendor directory.
+	ErrModuleNotCached = errors.New("module is missing")
+	// ErrModuleCorrupt is returned by VerifyModule if the contents of a stored blob
+	// do not match its digest.
+	ErrModuleCorrupt = errors.New("module is corrupt")
+	// ErrDigestMismatch is returned by VerifyModule if the manifest digest recorded
+	// for the commit of the pin does not match the digest of the pin.
+	ErrDigestMismatch = errors.New("module digest does not match the recorded digest")
+)
+
+// VerifyModule verifies the cached module for the pin by recomputing the digests of its
+// manifest and blobs.
+//
+// If the pin has a digest, the cached manifest must have this digest. Otherwise, the
+// manifest that the cache recorded for the commit of the pin is verified.
+//
+// The returned error wraps ErrModuleNotCached, ErrModuleCorrupt, or ErrDigestMismatch
+// if the module failed verification.
+func VerifyModule(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	fileLocker filelock.Locker,
+	modulePin bufmoduleref.ModulePin,
+) (retErr error) {
+	unlocker, err := fileLocker.RLock(ctx, lockPath, filelock.LockWithTimeout(lockTimeout))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, unlocker.Unlock())
+	}()
+	moduleBasedir := normalpath.Join(modulePin.Remote(), modulePin.Owner(), modulePin.Repository())
+	commitPath := normalpath.Join(moduleBasedir, commitsDir, modulePin.Commit())
+	cachedManifestDigestBytes, err := loadPath(ctx, bucket, commitPath)
+	if err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+	cachedManifestDigestString := string(cachedManifestDigestBytes)
+	manifestDigestString := modulePin.Digest()
+	switch {
+	case manifestDigestString == "" && cachedManifestDigestString == "":
+		return fmt.Errorf("%w: no entry for commit %s", ErrModuleNotCached, modulePin.Commit())
+	case manifestDigestString == "":
+		manifestDigestString = cachedManifestDigestString
+	case cachedManifestDigestString != "" && cachedManifestDigestString != manifestDigestString:
+		return fmt.Errorf(
+			"%w: commit %s has digest %q, expected %q",
+			ErrDigestMismatch,
+			modulePin.Commit(),
+			cachedManifestDigestString,
+			manifestDigestString,
+		)
+	}
+	manifestDigest, err := manifest.NewDigestFromString(manifestDigestString)
+	if err != nil {
+		return fmt.Errorf("malformed module digest %q: %w", manifestDigestString, err)
+	}
+	manifestData, err := verifyBlob(ctx, bucket, moduleBasedir, *manifestDigest)
+	if err != nil {
+		return err
+	}
+	moduleManifest, err := manifest.NewFromReader(bytes.NewReader(manifestData))
+	if err != nil {
+		return fmt.Errorf("%w: invalid manifest %s: %v", ErrModuleCorrupt, manifestDigest.String(), err)
+	}
+	return moduleManifest.Range(func(path string, digest manifest.Digest) error {
+		if _, err := verifyBlob(ctx, bucket, moduleBasedir, digest); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+// verifyBlob returns the contents of the cached blob after verifying that they match the digest.
+func verifyBlob(
+	ctx context.Context,
+	bucket storage.ReadBucket,
+	moduleBasedir string,
+	digest manifest.Digest,
+) ([]byte, error) {
+	data, err := loadPath(ctx, bucket, blobPathForDigestHex(moduleBasedir, digest.Hex()))
+	if err != nil {
+		if storage.IsNotExist(err) {
+			return nil, fmt.Errorf("%w: no blob for digest %s", ErrModuleNotCached, digest.String())
+		}
+		return nil, err
+	}
+	digester, err := manifest.NewDigester(digest.Type())
+	if err != nil {
+		return nil, err
+	}
+	actualDigest, err := digester.Digest(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if !digest.Equal(*actualDigest) {
+		return nil, fmt.Errorf("%w: blob has digest %s, expected %s", ErrModuleCorrupt, actualDigest.String(), digest.String())
+	}
+	return data, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufmodulecache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bufbuild/buf/private/pkg/filelock"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
+	"github.com/bufbuild/buf/private/pkg/storage"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyModule(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bucket := storagemem.NewReadWriteBucket()
+	pin, module := testPutModule(t, bucket, time.Now(), "ping", "abcd", "a.proto")
+	require.NoError(t, VerifyModule(ctx, bucket, filelock.NewNopLocker(), pin))
+	// Without a digest, the manifest recorded for the commit is verified.
+	require.NoError(t, VerifyModule(ctx, bucket, filelock.NewNopLocker(), testNewModulePin(t, "ping", "abcd", "")))
+
+	err := VerifyModule(ctx, bucket, filelock.NewNopLocker(), testNewModulePin(t, "ping", "efgh", ""))
+	assert.ErrorIs(t, err, ErrModuleNotCached)
+	err = VerifyModule(ctx, bucket, filelock.NewNopLocker(), testNewModulePin(t, "pong", "abcd", pin.Digest()))
+	assert.ErrorIs(t, err, ErrModuleNotCached)
+	otherDigest, _ := testNewModule(t, "b.proto")
+	err = VerifyModule(ctx, bucket, filelock.NewNopLocker(), testNewModulePin(t, "ping", "abcd", otherDigest))
+	assert.ErrorIs(t, err, ErrDigestMismatch)
+
+	digest, ok := module.Manifest().DigestFor("a.proto")
+	require.True(t, ok)
+	blobPath := blobPathForDigestHex(normalpath.Join(pin.Remote(), pin.Owner(), pin.Repository()), digest.Hex())
+	require.NoError(t, storage.PutPath(ctx, bucket, blobPath, []byte("tampered")))
+	err = VerifyModule(ctx, bucket, filelock.NewNopLocker(), pin)
+	assert.ErrorIs(t, err, ErrModuleCorrupt)
+	assert.True(t, strings.HasPrefix(err.Error(), "a.proto: "))
+
+	require.NoError(t, bucket.Delete(ctx, blobPath))
+	err = VerifyModule(ctx, bucket, filelock.NewNopLocker(), pin)
+	assert.ErrorIs(t, err, ErrModuleNotCached)
+}
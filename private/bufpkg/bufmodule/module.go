@@ -45,6 +45,7 @@ type module struct {
 	lintConfig                 *buflintconfig.Config
 	manifest                   *manifest.Manifest
 	blobSet                    *manifest.BlobSet
+	vendorModuleReader         ModuleReader
 }
 
 func newModuleForProto(
@@ -364,6 +365,10 @@ func (m *module) Commit() string {
 	return m.commit
 }
 
+func (m *module) VendorModuleReader() ModuleReader {
+	return m.vendorModuleReader
+}
+
 func (m *module) getSourceReadBucket() storage.ReadBucket {
 	return m.sourceReadBucket
 }
@@ -185,6 +185,26 @@ func NewHandler(
 	)
 }
 
+// GetPluginDigest returns a digest that identifies the plugin that NewHandler would return a
+// Handler for, given the same plugin name and options.
+//
+// The digest is computed from the contents of the plugin binary or WASM module. For plugins
+// built in to protoc, the digest is computed from the contents of the protoc binary and the
+// plugin name. An error is returned for a plugin path with arguments, as the plugin binary
+// may then be a command such as go run that does not identify the plugin.
+//
+// This is used to cache the responses of plugins.
+func GetPluginDigest(
+	pluginName string,
+	options ...HandlerOption,
+) (string, error) {
+	handlerOptions := newHandlerOptions()
+	for _, option := range options {
+		option(handlerOptions)
+	}
+	return getPluginDigest(pluginName, handlerOptions)
+}
+
 // HandlerOption is an option for a new Handler.
 type HandlerOption func(*handlerOptions)
 
@@ -0,0 +1,84 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginexec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/multierr"
+)
+
+// getPluginDigest follows the same resolution order as NewHandler.
+func getPluginDigest(pluginName string, handlerOptions *handlerOptions) (string, error) {
+	if looksLikeWASM(pluginName) && handlerOptions.wasmEnabled {
+		pluginAbsPath, err := validateWASMFilePath(pluginName)
+		if err != nil {
+			return "", err
+		}
+		return getFileDigest("wasm", pluginAbsPath)
+	}
+	if len(handlerOptions.pluginPath) > 1 {
+		// The arguments may refer to anything, such as the source of a plugin run
+		// with go run, so the binary does not identify the plugin.
+		return "", fmt.Errorf("cannot compute a digest for plugin %s with arguments", pluginName)
+	}
+	if len(handlerOptions.pluginPath) > 0 {
+		pluginPath, err := unsafeLookPath(handlerOptions.pluginPath[0])
+		if err != nil {
+			return "", err
+		}
+		return getFileDigest("binary", pluginPath)
+	}
+	if pluginPath, err := unsafeLookPath("protoc-gen-" + pluginName); err == nil {
+		return getFileDigest("binary", pluginPath)
+	}
+	if _, ok := ProtocProxyPluginNames[pluginName]; ok {
+		protocPath := handlerOptions.protocPath
+		if protocPath == "" {
+			protocPath = "protoc"
+		}
+		protocPath, err := unsafeLookPath(protocPath)
+		if err != nil {
+			return "", err
+		}
+		return getFileDigest("protoc", protocPath, pluginName)
+	}
+	return "", fmt.Errorf("could not find protoc plugin for name %s", pluginName)
+}
+
+// getFileDigest returns the hex-encoded SHA256 digest of the kind, the contents of the file,
+// and the extra values.
+func getFileDigest(kind string, filePath string, extraValues ...string) (_ string, retErr error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		retErr = multierr.Append(retErr, file.Close())
+	}()
+	hash := sha256.New()
+	_, _ = hash.Write([]byte(kind + "\x00"))
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	for _, extraValue := range extraValues {
+		_, _ = hash.Write([]byte("\x00" + extraValue))
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
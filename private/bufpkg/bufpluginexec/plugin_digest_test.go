@@ -0,0 +1,44 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufpluginexec
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetPluginDigest(t *testing.T) {
+	t.Parallel()
+	pluginPath := filepath.Join(t.TempDir(), "protoc-gen-foo")
+	require.NoError(t, os.WriteFile(pluginPath, []byte("one"), 0700))
+	digest, err := GetPluginDigest("foo", HandlerWithPluginPath(pluginPath))
+	require.NoError(t, err)
+	otherPluginDigest, err := GetPluginDigest("bar", HandlerWithPluginPath(pluginPath))
+	require.NoError(t, err)
+	// The digest identifies the binary, not the name it is configured with.
+	assert.Equal(t, digest, otherPluginDigest)
+	require.NoError(t, os.WriteFile(pluginPath, []byte("two"), 0700))
+	otherBinaryDigest, err := GetPluginDigest("foo", HandlerWithPluginPath(pluginPath))
+	require.NoError(t, err)
+	assert.NotEqual(t, digest, otherBinaryDigest)
+	// With arguments, such as go run ./cmd/protoc-gen-foo, the binary does not
+	// identify the plugin, so no digest is computed.
+	_, err = GetPluginDigest("foo", HandlerWithPluginPath(pluginPath, "run", "./cmd/protoc-gen-foo"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufreflectionserver serves the gRPC server reflection protocol for images.
+package bufreflectionserver
+
+import (
+	"net/http"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"go.uber.org/zap"
+)
+
+const (
+	// V1Procedure is the procedure of the grpc.reflection.v1 ServerReflectionInfo method.
+	V1Procedure = "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo"
+	// V1AlphaProcedure is the procedure of the grpc.reflection.v1alpha ServerReflectionInfo method.
+	V1AlphaProcedure = "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo"
+)
+
+// NewHandler returns a new http.Handler that serves the grpc.reflection.v1 and
+// grpc.reflection.v1alpha ServerReflection services for the image.
+//
+// The services of the non-import files of the image are listed, and the descriptors of
+// all of the files of the image can be requested. The handler serves the Connect, gRPC, and
+// gRPC-Web protocols, and should be served with HTTP/2, as the reflection methods are
+// bidirectional streaming methods.
+func NewHandler(logger *zap.Logger, image bufimage.Image) (http.Handler, error) {
+	server, err := newServer(logger, image)
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(V1Procedure, server.newHandler(V1Procedure))
+	mux.Handle(V1AlphaProcedure, server.newHandler(V1AlphaProcedure))
+	return mux, nil
+}
@@ -0,0 +1,178 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufreflectionserver
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	connect "github.com/bufbuild/connect-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	testUserProto = `syntax = "proto3";
+package a.v1;
+import "a/v1/options.proto";
+import "google/protobuf/timestamp.proto";
+message GetUserRequest {
+  string id = 1;
+}
+message GetUserResponse {
+  google.protobuf.Timestamp create_time = 1;
+}
+service UserService {
+  option (a.v1.owner) = "users";
+  rpc GetUser(GetUserRequest) returns (GetUserResponse);
+}
+`
+	testOptionsProto = `syntax = "proto3";
+package a.v1;
+import "google/protobuf/descriptor.proto";
+extend google.protobuf.ServiceOptions {
+  string owner = 50000;
+}
+message Nested {
+  extend google.protobuf.ServiceOptions {
+    string team = 50001;
+  }
+}
+`
+)
+
+func TestHandler(t *testing.T) {
+	t.Parallel()
+	handler, err := NewHandler(zap.NewNop(), testBuildImage(t))
+	require.NoError(t, err)
+	server := httptest.NewUnstartedServer(handler)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+	for _, procedure := range []string{V1Procedure, V1AlphaProcedure} {
+		for _, protocolOption := range []connect.ClientOption{connect.WithGRPC(), connect.WithProtoJSON()} {
+			client := connect.NewClient[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse](
+				server.Client(),
+				server.URL+procedure,
+				protocolOption,
+			)
+			stream := client.CallBidiStream(context.Background())
+			send := func(request *reflectionv1.ServerReflectionRequest) *reflectionv1.ServerReflectionResponse {
+				require.NoError(t, stream.Send(request))
+				response, err := stream.Receive()
+				require.NoError(t, err)
+				return response
+			}
+
+			response := send(&reflectionv1.ServerReflectionRequest{
+				Host: "localhost",
+				MessageRequest: &reflectionv1.ServerReflectionRequest_ListServices{
+					ListServices: "*",
+				},
+			})
+			assert.Equal(t, "localhost", response.ValidHost)
+			serviceResponses := response.GetListServicesResponse().GetService()
+			require.Len(t, serviceResponses, 1)
+			assert.Equal(t, "a.v1.UserService", serviceResponses[0].GetName())
+
+			response = send(&reflectionv1.ServerReflectionRequest{
+				MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingSymbol{
+					FileContainingSymbol: "a.v1.UserService.GetUser",
+				},
+			})
+			assert.Equal(
+				t,
+				[]string{
+					"a/v1/user.proto",
+					"a/v1/options.proto",
+					"google/protobuf/descriptor.proto",
+					"google/protobuf/timestamp.proto",
+				},
+				testGetFilePaths(t, response),
+			)
+
+			// Files that were already sent are only sent again if requested.
+			response = send(&reflectionv1.ServerReflectionRequest{
+				MessageRequest: &reflectionv1.ServerReflectionRequest_FileContainingExtension{
+					FileContainingExtension: &reflectionv1.ExtensionRequest{
+						ContainingType:  "google.protobuf.ServiceOptions",
+						ExtensionNumber: 50001,
+					},
+				},
+			})
+			assert.Equal(t, []string{"a/v1/options.proto"}, testGetFilePaths(t, response))
+
+			response = send(&reflectionv1.ServerReflectionRequest{
+				MessageRequest: &reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType{
+					AllExtensionNumbersOfType: "google.protobuf.ServiceOptions",
+				},
+			})
+			assert.Equal(t, []int32{50000, 50001}, response.GetAllExtensionNumbersResponse().GetExtensionNumber())
+
+			response = send(&reflectionv1.ServerReflectionRequest{
+				MessageRequest: &reflectionv1.ServerReflectionRequest_FileByFilename{
+					FileByFilename: "a/v1/missing.proto",
+				},
+			})
+			assert.Equal(t, int32(connect.CodeNotFound), response.GetErrorResponse().GetErrorCode())
+
+			require.NoError(t, stream.CloseRequest())
+			require.NoError(t, stream.CloseResponse())
+		}
+	}
+}
+
+func testGetFilePaths(t *testing.T, response *reflectionv1.ServerReflectionResponse) []string {
+	var filePaths []string
+	for _, fileDescriptorProtoBytes := range response.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fileDescriptorProto := &descriptorpb.FileDescriptorProto{}
+		require.NoError(t, protoencoding.NewWireUnmarshaler(nil).Unmarshal(fileDescriptorProtoBytes, fileDescriptorProto))
+		filePaths = append(filePaths, fileDescriptorProto.GetName())
+	}
+	return filePaths
+}
+
+func testBuildImage(t *testing.T) bufimage.Image {
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(
+		map[string][]byte{
+			"a/v1/user.proto":    []byte(testUserProto),
+			"a/v1/options.proto": []byte(testOptionsProto),
+		},
+	)
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
@@ -0,0 +1,268 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufreflectionserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	reflectionv1 "github.com/bufbuild/buf/private/gen/proto/go/grpc/reflection/v1"
+	"github.com/bufbuild/buf/private/pkg/protoencoding"
+	connect "github.com/bufbuild/connect-go"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+type server struct {
+	logger *zap.Logger
+	files  *protoregistry.Files
+	// pathToFileDescriptorProtoBytes are the serialized FileDescriptorProtos of all files.
+	pathToFileDescriptorProtoBytes map[string][]byte
+	// serviceNames are the sorted names of the services of the non-import files.
+	serviceNames []string
+	// messageFullNameToExtensionNumberToFilePath are the paths of the files that
+	// define the extensions of each message.
+	messageFullNameToExtensionNumberToFilePath map[protoreflect.FullName]map[protoreflect.FieldNumber]string
+}
+
+func newServer(logger *zap.Logger, image bufimage.Image) (*server, error) {
+	files, err := protodesc.NewFiles(bufimage.ImageToFileDescriptorSet(image))
+	if err != nil {
+		return nil, err
+	}
+	server := &server{
+		logger:                         logger,
+		files:                          files,
+		pathToFileDescriptorProtoBytes: make(map[string][]byte),
+		messageFullNameToExtensionNumberToFilePath: make(map[protoreflect.FullName]map[protoreflect.FieldNumber]string),
+	}
+	for _, imageFile := range image.Files() {
+		fileDescriptorProtoBytes, err := protoencoding.NewWireMarshaler().Marshal(imageFile.Proto())
+		if err != nil {
+			return nil, err
+		}
+		server.pathToFileDescriptorProtoBytes[imageFile.Path()] = fileDescriptorProtoBytes
+		fileDescriptor, err := files.FindFileByPath(imageFile.Path())
+		if err != nil {
+			return nil, err
+		}
+		server.addExtensions(fileDescriptor.Extensions())
+		server.addMessageExtensions(fileDescriptor.Messages())
+		if imageFile.IsImport() {
+			continue
+		}
+		services := fileDescriptor.Services()
+		for i := 0; i < services.Len(); i++ {
+			server.serviceNames = append(server.serviceNames, string(services.Get(i).FullName()))
+		}
+	}
+	sort.Strings(server.serviceNames)
+	return server, nil
+}
+
+func (s *server) addMessageExtensions(messageDescriptors protoreflect.MessageDescriptors) {
+	for i := 0; i < messageDescriptors.Len(); i++ {
+		messageDescriptor := messageDescriptors.Get(i)
+		s.addExtensions(messageDescriptor.Extensions())
+		s.addMessageExtensions(messageDescriptor.Messages())
+	}
+}
+
+func (s *server) addExtensions(extensionDescriptors protoreflect.ExtensionDescriptors) {
+	for i := 0; i < extensionDescriptors.Len(); i++ {
+		extensionDescriptor := extensionDescriptors.Get(i)
+		messageFullName := extensionDescriptor.ContainingMessage().FullName()
+		extensionNumberToFilePath, ok := s.messageFullNameToExtensionNumberToFilePath[messageFullName]
+		if !ok {
+			extensionNumberToFilePath = make(map[protoreflect.FieldNumber]string)
+			s.messageFullNameToExtensionNumberToFilePath[messageFullName] = extensionNumberToFilePath
+		}
+		extensionNumberToFilePath[extensionDescriptor.Number()] = extensionDescriptor.ParentFile().Path()
+	}
+}
+
+func (s *server) newHandler(procedure string) http.Handler {
+	return connect.NewBidiStreamHandler(procedure, s.serverReflectionInfo)
+}
+
+func (s *server) serverReflectionInfo(
+	ctx context.Context,
+	stream *connect.BidiStream[reflectionv1.ServerReflectionRequest, reflectionv1.ServerReflectionResponse],
+) error {
+	// The files that were sent on the stream are not sent again as dependencies.
+	sentFilePaths := make(map[string]struct{})
+	for {
+		request, err := stream.Receive()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		response := &reflectionv1.ServerReflectionResponse{
+			ValidHost:       request.Host,
+			OriginalRequest: request,
+		}
+		if err := s.setMessageResponse(request, response, sentFilePaths); err != nil {
+			var connectErr *connect.Error
+			if !errors.As(err, &connectErr) {
+				return err
+			}
+			response.MessageResponse = &reflectionv1.ServerReflectionResponse_ErrorResponse{
+				ErrorResponse: &reflectionv1.ErrorResponse{
+					ErrorCode:    int32(connectErr.Code()),
+					ErrorMessage: connectErr.Message(),
+				},
+			}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+}
+
+// setMessageResponse sets the message response for the request.
+//
+// If a *connect.Error is returned, it is sent as an error response.
+func (s *server) setMessageResponse(
+	request *reflectionv1.ServerReflectionRequest,
+	response *reflectionv1.ServerReflectionResponse,
+	sentFilePaths map[string]struct{},
+) error {
+	switch messageRequest := request.MessageRequest.(type) {
+	case *reflectionv1.ServerReflectionRequest_FileByFilename:
+		s.logger.Debug("file_by_filename", zap.String("filename", messageRequest.FileByFilename))
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(messageRequest.FileByFilename, sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_FileContainingSymbol:
+		s.logger.Debug("file_containing_symbol", zap.String("symbol", messageRequest.FileContainingSymbol))
+		descriptor, err := s.files.FindDescriptorByName(protoreflect.FullName(messageRequest.FileContainingSymbol))
+		if err != nil {
+			if errors.Is(err, protoregistry.NotFound) {
+				return connect.NewError(connect.CodeNotFound, fmt.Errorf("symbol not found: %s", messageRequest.FileContainingSymbol))
+			}
+			return err
+		}
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(descriptor.ParentFile().Path(), sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_FileContainingExtension:
+		containingType := messageRequest.FileContainingExtension.GetContainingType()
+		extensionNumber := messageRequest.FileContainingExtension.GetExtensionNumber()
+		s.logger.Debug(
+			"file_containing_extension",
+			zap.String("containing_type", containingType),
+			zap.Int32("extension_number", extensionNumber),
+		)
+		filePath, ok := s.messageFullNameToExtensionNumberToFilePath[protoreflect.FullName(containingType)][protoreflect.FieldNumber(extensionNumber)]
+		if !ok {
+			return connect.NewError(connect.CodeNotFound, fmt.Errorf("extension not found: %s %d", containingType, extensionNumber))
+		}
+		fileDescriptorResponse, err := s.getFileDescriptorResponse(filePath, sentFilePaths)
+		if err != nil {
+			return err
+		}
+		response.MessageResponse = fileDescriptorResponse
+	case *reflectionv1.ServerReflectionRequest_AllExtensionNumbersOfType:
+		typeName := messageRequest.AllExtensionNumbersOfType
+		s.logger.Debug("all_extension_numbers_of_type", zap.String("type", typeName))
+		descriptor, err := s.files.FindDescriptorByName(protoreflect.FullName(typeName))
+		if err != nil {
+			if errors.Is(err, protoregistry.NotFound) {
+				return connect.NewError(connect.CodeNotFound, fmt.Errorf("type not found: %s", typeName))
+			}
+			return err
+		}
+		if _, ok := descriptor.(protoreflect.MessageDescriptor); !ok {
+			return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("not a message type: %s", typeName))
+		}
+		extensionNumberToFilePath := s.messageFullNameToExtensionNumberToFilePath[protoreflect.FullName(typeName)]
+		extensionNumbers := make([]int32, 0, len(extensionNumberToFilePath))
+		for extensionNumber := range extensionNumberToFilePath {
+			extensionNumbers = append(extensionNumbers, int32(extensionNumber))
+		}
+		sort.Slice(extensionNumbers, func(i int, j int) bool { return extensionNumbers[i] < extensionNumbers[j] })
+		response.MessageResponse = &reflectionv1.ServerReflectionResponse_AllExtensionNumbersResponse{
+			AllExtensionNumbersResponse: &reflectionv1.ExtensionNumberResponse{
+				BaseTypeName:    typeName,
+				ExtensionNumber: extensionNumbers,
+			},
+		}
+	case *reflectionv1.ServerReflectionRequest_ListServices:
+		s.logger.Debug("list_services")
+		serviceResponses := make([]*reflectionv1.ServiceResponse, len(s.serviceNames))
+		for i, serviceName := range s.serviceNames {
+			serviceResponses[i] = &reflectionv1.ServiceResponse{
+				Name: serviceName,
+			}
+		}
+		response.MessageResponse = &reflectionv1.ServerReflectionResponse_ListServicesResponse{
+			ListServicesResponse: &reflectionv1.ListServiceResponse{
+				Service: serviceResponses,
+			},
+		}
+	default:
+		return connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("invalid message request: %T", request.MessageRequest))
+	}
+	return nil
+}
+
+// getFileDescriptorResponse returns the response with the file at the path, followed by its
+// transitive dependencies that were not already sent on the stream.
+func (s *server) getFileDescriptorResponse(
+	path string,
+	sentFilePaths map[string]struct{},
+) (*reflectionv1.ServerReflectionResponse_FileDescriptorResponse, error) {
+	fileDescriptor, err := s.files.FindFileByPath(path)
+	if err != nil {
+		if errors.Is(err, protoregistry.NotFound) {
+			return nil, connect.NewError(connect.CodeNotFound, fmt.Errorf("file not found: %s", path))
+		}
+		return nil, err
+	}
+	// The requested file is always sent, even if it was sent before.
+	delete(sentFilePaths, path)
+	var fileDescriptorProtoBytes [][]byte
+	var addFile func(protoreflect.FileDescriptor)
+	addFile = func(fileDescriptor protoreflect.FileDescriptor) {
+		if _, ok := sentFilePaths[fileDescriptor.Path()]; ok {
+			return
+		}
+		sentFilePaths[fileDescriptor.Path()] = struct{}{}
+		fileDescriptorProtoBytes = append(fileDescriptorProtoBytes, s.pathToFileDescriptorProtoBytes[fileDescriptor.Path()])
+		imports := fileDescriptor.Imports()
+		for i := 0; i < imports.Len(); i++ {
+			addFile(imports.Get(i).FileDescriptor)
+		}
+	}
+	addFile(fileDescriptor)
+	return &reflectionv1.ServerReflectionResponse_FileDescriptorResponse{
+		FileDescriptorResponse: &reflectionv1.FileDescriptorResponse{
+			FileDescriptorProto: fileDescriptorProtoBytes,
+		},
+	}, nil
+}
@@ -0,0 +1,162 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufsemver suggests semantic version bumps for the changes between two images.
+package bufsemver
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking/bufbreakingconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufconfig"
+	"github.com/bufbuild/buf/private/bufpkg/bufdiff"
+)
+
+const (
+	// BumpNone says that there are no changes.
+	BumpNone Bump = iota + 1
+	// BumpPatch says that only comments or options changed.
+	BumpPatch
+	// BumpMinor says that there are changes that are not breaking, such as added elements.
+	BumpMinor
+	// BumpMajor says that there are breaking changes.
+	BumpMajor
+)
+
+const (
+	// FormatText is the text format for Suggestions.
+	FormatText Format = iota + 1
+	// FormatJSON is the JSON format for Suggestions.
+	FormatJSON
+)
+
+var (
+	// AllFormatStrings is all format strings.
+	//
+	// Sorted in the order we want to display them.
+	AllFormatStrings = []string{
+		"text",
+		"json",
+	}
+
+	stringToFormat = map[string]Format{
+		"text": FormatText,
+		"json": FormatJSON,
+	}
+	formatToString = map[Format]string{
+		FormatText: "text",
+		FormatJSON: "json",
+	}
+	bumpToString = map[Bump]string{
+		BumpNone:  "none",
+		BumpPatch: "patch",
+		BumpMinor: "minor",
+		BumpMajor: "major",
+	}
+)
+
+// Bump is a semantic version bump.
+//
+// Bumps are ordered, so a greater Bump is a larger bump.
+type Bump int
+
+// String implements fmt.Stringer.
+func (b Bump) String() string {
+	s, ok := bumpToString[b]
+	if !ok {
+		return strconv.Itoa(int(b))
+	}
+	return s
+}
+
+// Format is a format for Suggestions.
+type Format int
+
+// String implements fmt.Stringer.
+func (f Format) String() string {
+	s, ok := formatToString[f]
+	if !ok {
+		return strconv.Itoa(int(f))
+	}
+	return s
+}
+
+// ParseFormat parses the Format.
+//
+// The empty strings defaults to FormatText.
+func ParseFormat(s string) (Format, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return FormatText, nil
+	}
+	f, ok := stringToFormat[s]
+	if ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("unknown format: %q", s)
+}
+
+// Suggestion is a suggested semantic version bump.
+type Suggestion interface {
+	// Bump is the suggested Bump.
+	Bump() Bump
+	// Reasons are the changes that resulted in the Bump.
+	//
+	// For BumpMajor, these are the breaking changes. For BumpMinor and BumpPatch,
+	// these are the changes that resulted in the bump, and changes that would result
+	// in a smaller bump are not included. Empty for BumpNone.
+	Reasons() []string
+}
+
+// NewBreakingConfig returns the breaking Config to use to detect the changes that
+// require a major bump.
+//
+// The FILE category is used regardless of the rules selected in the given Config, as
+// it includes the same or stricter versions of all WIRE_JSON and WIRE rules. The ignored
+// paths and unstable packages of the given Config are kept. The given Config may be nil.
+func NewBreakingConfig(config *bufbreakingconfig.Config) *bufbreakingconfig.Config {
+	breakingConfig := &bufbreakingconfig.Config{
+		Use:     []string{"FILE"},
+		Version: bufconfig.V1Version,
+	}
+	if config != nil {
+		breakingConfig.IgnoreRootPaths = config.IgnoreRootPaths
+		breakingConfig.IgnoreUnstablePackages = config.IgnoreUnstablePackages
+	}
+	return breakingConfig
+}
+
+// Suggest suggests a semantic version bump.
+//
+// The FileAnnotations should be the result of a breaking change check with a Config
+// from NewBreakingConfig, and the Changes should be the result of bufdiff.Diff for the
+// same images.
+//
+// Any breaking change results in BumpMajor. Otherwise, any change other than a change to
+// only comments or options, such as an added element, results in BumpMinor. Changes to
+// only comments or options result in BumpPatch.
+func Suggest(breakingFileAnnotations []bufanalysis.FileAnnotation, changes []bufdiff.Change) Suggestion {
+	return suggest(breakingFileAnnotations, changes)
+}
+
+// PrintSuggestion prints the Suggestion to the Writer.
+//
+// The text format only prints the Bump, so that it can be used directly in scripts.
+func PrintSuggestion(writer io.Writer, suggestion Suggestion, format Format) error {
+	return printSuggestion(writer, suggestion, format)
+}
@@ -0,0 +1,148 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsemver
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufcheck/bufbreaking"
+	"github.com/bufbuild/buf/private/bufpkg/bufdiff"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage"
+	"github.com/bufbuild/buf/private/bufpkg/bufimage/bufimagebuild"
+	"github.com/bufbuild/buf/private/bufpkg/bufmodule"
+	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zaptest"
+)
+
+const testPreviousProto = `syntax = "proto3";
+package a.v1;
+// A Foo.
+message Foo {
+  string one = 1;
+  string two = 2;
+}
+`
+
+func TestSuggestMajor(t *testing.T) {
+	t.Parallel()
+	suggestion := testSuggest(
+		t,
+		`syntax = "proto3";
+package a.v1;
+// A Foo.
+message Foo {
+  string one = 1;
+  reserved 2;
+}
+message Bar {}
+`,
+	)
+	assert.Equal(t, BumpMajor, suggestion.Bump())
+	require.Len(t, suggestion.Reasons(), 1)
+	assert.Contains(t, suggestion.Reasons()[0], "(FIELD_NO_DELETE)")
+}
+
+func TestSuggestMinor(t *testing.T) {
+	t.Parallel()
+	suggestion := testSuggest(
+		t,
+		`syntax = "proto3";
+package a.v1;
+// A Foo with more comments.
+message Foo {
+  string one = 1;
+  string two = 2;
+}
+message Bar {}
+`,
+	)
+	assert.Equal(t, BumpMinor, suggestion.Bump())
+	assert.Equal(t, []string{"added message a.v1.Bar"}, suggestion.Reasons())
+}
+
+func TestSuggestPatch(t *testing.T) {
+	t.Parallel()
+	suggestion := testSuggest(
+		t,
+		`syntax = "proto3";
+package a.v1;
+// A Foo with more comments.
+message Foo {
+  string one = 1;
+  string two = 2 [deprecated = true];
+}
+`,
+	)
+	assert.Equal(t, BumpPatch, suggestion.Bump())
+	assert.Equal(
+		t,
+		[]string{
+			"modified message a.v1.Foo: comments changed",
+			"modified field a.v1.Foo.two: options changed",
+		},
+		suggestion.Reasons(),
+	)
+}
+
+func TestSuggestNone(t *testing.T) {
+	t.Parallel()
+	suggestion := testSuggest(t, testPreviousProto)
+	assert.Equal(t, BumpNone, suggestion.Bump())
+	assert.Empty(t, suggestion.Reasons())
+	buffer := bytes.NewBuffer(nil)
+	require.NoError(t, PrintSuggestion(buffer, suggestion, FormatJSON))
+	assert.Equal(t, `{"bump":"none","reasons":[]}`+"\n", buffer.String())
+	buffer.Reset()
+	require.NoError(t, PrintSuggestion(buffer, suggestion, FormatText))
+	assert.Equal(t, "none\n", buffer.String())
+}
+
+func testSuggest(t *testing.T, proto string) Suggestion {
+	ctx := context.Background()
+	previousImage := testBuildImage(t, testPreviousProto)
+	image := testBuildImage(t, proto)
+	fileAnnotations, err := bufbreaking.NewHandler(zaptest.NewLogger(t)).Check(
+		ctx,
+		NewBreakingConfig(nil),
+		previousImage,
+		image,
+	)
+	require.NoError(t, err)
+	changes, err := bufdiff.Diff(ctx, previousImage, image)
+	require.NoError(t, err)
+	return Suggest(fileAnnotations, changes)
+}
+
+func testBuildImage(t *testing.T, proto string) bufimage.Image {
+	ctx := context.Background()
+	bucket, err := storagemem.NewReadBucket(map[string][]byte{"a/v1/a.proto": []byte(proto)})
+	require.NoError(t, err)
+	module, err := bufmodule.NewModuleForBucket(ctx, bucket)
+	require.NoError(t, err)
+	image, fileAnnotations, err := bufimagebuild.NewBuilder(
+		zaptest.NewLogger(t),
+		bufmodule.NewNopModuleReader(),
+	).Build(
+		ctx,
+		module,
+	)
+	require.NoError(t, err)
+	require.Empty(t, fileAnnotations)
+	return image
+}
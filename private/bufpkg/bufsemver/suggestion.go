@@ -0,0 +1,112 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bufsemver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bufbuild/buf/private/bufpkg/bufanalysis"
+	"github.com/bufbuild/buf/private/bufpkg/bufdiff"
+)
+
+type suggestion struct {
+	bump    Bump
+	reasons []string
+}
+
+func newSuggestion(bump Bump, reasons []string) *suggestion {
+	return &suggestion{
+		bump:    bump,
+		reasons: reasons,
+	}
+}
+
+func (s *suggestion) Bump() Bump {
+	return s.bump
+}
+
+func (s *suggestion) Reasons() []string {
+	return s.reasons
+}
+
+type externalSuggestion struct {
+	Bump    string   `json:"bump"`
+	Reasons []string `json:"reasons"`
+}
+
+func suggest(breakingFileAnnotations []bufanalysis.FileAnnotation, changes []bufdiff.Change) *suggestion {
+	if len(breakingFileAnnotations) > 0 {
+		reasons := make([]string, 0, len(breakingFileAnnotations))
+		for _, fileAnnotation := range bufanalysis.DeduplicateAndSortFileAnnotations(breakingFileAnnotations) {
+			reasons = append(reasons, fmt.Sprintf("%s (%s)", fileAnnotation.String(), fileAnnotation.Type()))
+		}
+		return newSuggestion(BumpMajor, reasons)
+	}
+	var minorReasons []string
+	var patchReasons []string
+	for _, change := range changes {
+		if bufdiff.IsCommentOrOptionChange(change) {
+			patchReasons = append(patchReasons, getChangeReason(change))
+		} else {
+			minorReasons = append(minorReasons, getChangeReason(change))
+		}
+	}
+	switch {
+	case len(minorReasons) > 0:
+		return newSuggestion(BumpMinor, minorReasons)
+	case len(patchReasons) > 0:
+		return newSuggestion(BumpPatch, patchReasons)
+	default:
+		return newSuggestion(BumpNone, nil)
+	}
+}
+
+func printSuggestion(writer io.Writer, suggestion Suggestion, format Format) error {
+	switch format {
+	case FormatText:
+		_, err := writer.Write([]byte(suggestion.Bump().String() + "\n"))
+		return err
+	case FormatJSON:
+		reasons := suggestion.Reasons()
+		if reasons == nil {
+			// Always print an array so that the output is easy to consume.
+			reasons = []string{}
+		}
+		data, err := json.Marshal(
+			&externalSuggestion{
+				Bump:    suggestion.Bump().String(),
+				Reasons: reasons,
+			},
+		)
+		if err != nil {
+			return err
+		}
+		_, err = writer.Write(append(data, '\n'))
+		return err
+	default:
+		return fmt.Errorf("unknown Format: %v", format)
+	}
+}
+
+func getChangeReason(change bufdiff.Change) string {
+	reason := fmt.Sprintf("%s %s %s", change.Type(), change.ElementType(), change.Name())
+	if details := change.Details(); len(details) > 0 {
+		reason += ": " + strings.Join(details, ", ")
+	}
+	return reason
+}
@@ -0,0 +1,265 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bufpair pairs the elements of two versions of a set of files by identity.
+//
+// This is shared by the breaking change rules and by diffs, so that both agree on which
+// elements are the same element. Files are paired by path, messages, enums, services,
+// and extensions by fully-qualified name, fields by number, enum values by number or
+// name, and oneofs and methods by name.
+//
+// Each function calls f once for every identity, with the previous element and the
+// element with the same identity. If an element only exists in the previous version,
+// f is called with nil as the element, and if an element only exists in the current
+// version, f is called with nil as the previous element.
+package bufpair
+
+import (
+	"fmt"
+
+	"github.com/bufbuild/buf/private/pkg/protosource"
+)
+
+// Files pairs the files by path.
+func Files(
+	previousFiles []protosource.File,
+	files []protosource.File,
+	f func(protosource.File, protosource.File) error,
+) error {
+	previousFilePathToFile, err := protosource.FilePathToFile(previousFiles...)
+	if err != nil {
+		return err
+	}
+	filePathToFile, err := protosource.FilePathToFile(files...)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousFilePathToFile, filePathToFile, f)
+}
+
+// Enums pairs the enums of the files by fully-qualified name.
+func Enums(
+	previousFiles []protosource.File,
+	files []protosource.File,
+	f func(protosource.Enum, protosource.Enum) error,
+) error {
+	previousFullNameToEnum, err := protosource.FullNameToEnum(previousFiles...)
+	if err != nil {
+		return err
+	}
+	fullNameToEnum, err := protosource.FullNameToEnum(files...)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousFullNameToEnum, fullNameToEnum, f)
+}
+
+// EnumValuesByName pairs the values of the enums by name.
+func EnumValuesByName(
+	previousEnum protosource.Enum,
+	enum protosource.Enum,
+	f func(protosource.EnumValue, protosource.EnumValue) error,
+) error {
+	previousNameToEnumValue, err := protosource.NameToEnumValue(previousEnum)
+	if err != nil {
+		return err
+	}
+	nameToEnumValue, err := protosource.NameToEnumValue(enum)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousNameToEnumValue, nameToEnumValue, f)
+}
+
+// EnumValuesByNumber pairs the values of the enums by number.
+//
+// As enums may allow aliases, all values with the same number are paired at once,
+// as maps from name to value.
+func EnumValuesByNumber(
+	previousEnum protosource.Enum,
+	enum protosource.Enum,
+	f func(map[string]protosource.EnumValue, map[string]protosource.EnumValue) error,
+) error {
+	previousNumberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(previousEnum)
+	if err != nil {
+		return err
+	}
+	numberToNameToEnumValue, err := protosource.NumberToNameToEnumValue(enum)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousNumberToNameToEnumValue, numberToNameToEnumValue, f)
+}
+
+// Messages pairs the messages of the files, including nested messages and map entries,
+// by fully-qualified name.
+func Messages(
+	previousFiles []protosource.File,
+	files []protosource.File,
+	f func(protosource.Message, protosource.Message) error,
+) error {
+	previousFullNameToMessage, err := protosource.FullNameToMessage(previousFiles...)
+	if err != nil {
+		return err
+	}
+	fullNameToMessage, err := protosource.FullNameToMessage(files...)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousFullNameToMessage, fullNameToMessage, f)
+}
+
+// MessageFields pairs the fields of the messages by number.
+//
+// This includes the extensions of each message that are declared within the message.
+func MessageFields(
+	previousMessage protosource.Message,
+	message protosource.Message,
+	f func(protosource.Field, protosource.Field) error,
+) error {
+	previousNumberToField, err := protosource.NumberToMessageField(previousMessage)
+	if err != nil {
+		return err
+	}
+	numberToField, err := protosource.NumberToMessageField(message)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousNumberToField, numberToField, f)
+}
+
+// MessageOneofs pairs the oneofs of the messages by name.
+func MessageOneofs(
+	previousMessage protosource.Message,
+	message protosource.Message,
+	f func(protosource.Oneof, protosource.Oneof) error,
+) error {
+	previousNameToOneof, err := protosource.NameToMessageOneof(previousMessage)
+	if err != nil {
+		return err
+	}
+	nameToOneof, err := protosource.NameToMessageOneof(message)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousNameToOneof, nameToOneof, f)
+}
+
+// Services pairs the services of the files by fully-qualified name.
+func Services(
+	previousFiles []protosource.File,
+	files []protosource.File,
+	f func(protosource.Service, protosource.Service) error,
+) error {
+	previousFullNameToService, err := protosource.FullNameToService(previousFiles...)
+	if err != nil {
+		return err
+	}
+	fullNameToService, err := protosource.FullNameToService(files...)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousFullNameToService, fullNameToService, f)
+}
+
+// Methods pairs the methods of the services by name.
+func Methods(
+	previousService protosource.Service,
+	service protosource.Service,
+	f func(protosource.Method, protosource.Method) error,
+) error {
+	previousNameToMethod, err := protosource.NameToMethod(previousService)
+	if err != nil {
+		return err
+	}
+	nameToMethod, err := protosource.NameToMethod(service)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousNameToMethod, nameToMethod, f)
+}
+
+// Extensions pairs the extensions declared in the files, at the top level or within
+// messages, by fully-qualified name.
+func Extensions(
+	previousFiles []protosource.File,
+	files []protosource.File,
+	f func(protosource.Field, protosource.Field) error,
+) error {
+	previousFullNameToExtension, err := getFullNameToExtension(previousFiles)
+	if err != nil {
+		return err
+	}
+	fullNameToExtension, err := getFullNameToExtension(files)
+	if err != nil {
+		return err
+	}
+	return forEachPair(previousFullNameToExtension, fullNameToExtension, f)
+}
+
+// forEachPair calls f for every key of the maps, with the zero value for a
+// missing value.
+func forEachPair[K comparable, V any](
+	previousKeyToValue map[K]V,
+	keyToValue map[K]V,
+	f func(V, V) error,
+) error {
+	for key, previousValue := range previousKeyToValue {
+		// The value is the zero value if the key is not present.
+		if err := f(previousValue, keyToValue[key]); err != nil {
+			return err
+		}
+	}
+	var zero V
+	for key, value := range keyToValue {
+		if _, ok := previousKeyToValue[key]; !ok {
+			if err := f(zero, value); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func getFullNameToExtension(files []protosource.File) (map[string]protosource.Field, error) {
+	fullNameToExtension := make(map[string]protosource.Field)
+	add := func(extension protosource.Field) error {
+		if _, ok := fullNameToExtension[extension.FullName()]; ok {
+			return fmt.Errorf("duplicate extension: %q", extension.FullName())
+		}
+		fullNameToExtension[extension.FullName()] = extension
+		return nil
+	}
+	for _, file := range files {
+		for _, extension := range file.Extensions() {
+			if err := add(extension); err != nil {
+				return nil, err
+			}
+		}
+		if err := protosource.ForEachMessage(
+			func(message protosource.Message) error {
+				for _, extension := range message.Extensions() {
+					if err := add(extension); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+			file,
+		); err != nil {
+			return nil, err
+		}
+	}
+	return fullNameToExtension, nil
+}
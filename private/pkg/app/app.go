@@ -189,6 +189,18 @@ func NewContainerForArgs(container Container, newArgs ...string) Container {
 	)
 }
 
+// NewContainerWithEnvOverrides returns a new Container with the environment variables
+// of the input Container, overridden by the values in overrides.
+func NewContainerWithEnvOverrides(container Container, overrides map[string]string) Container {
+	return newContainer(
+		NewEnvContainerWithOverrides(container, overrides),
+		container,
+		container,
+		container,
+		container,
+	)
+}
+
 // StdioContainer is a stdio container.
 type StdioContainer interface {
 	StdinContainer
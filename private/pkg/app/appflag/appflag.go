@@ -58,6 +58,24 @@ func BuilderWithTimeout(defaultTimeout time.Duration) BuilderOption {
 	}
 }
 
+// BuilderWithEnvBoolFlag returns a new BuilderOption that adds a root bool flag
+// that is equivalent to setting the environment variable envKey to "true".
+//
+// If the flag is set, run functions see envKey set to "true" in their Container,
+// so that they only need to check the environment variable.
+func BuilderWithEnvBoolFlag(flagName string, envKey string, usage string) BuilderOption {
+	return func(builder *builder) {
+		builder.envBoolFlags = append(
+			builder.envBoolFlags,
+			&envBoolFlag{
+				flagName: flagName,
+				envKey:   envKey,
+				usage:    usage,
+			},
+		)
+	}
+}
+
 // BuilderWithTracing enables zap tracing for the builder.
 func BuilderWithTracing() BuilderOption {
 	return func(builder *builder) {
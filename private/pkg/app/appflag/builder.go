@@ -50,6 +50,15 @@ type builder struct {
 	defaultTimeout time.Duration
 
 	tracing bool
+
+	envBoolFlags []*envBoolFlag
+}
+
+type envBoolFlag struct {
+	flagName string
+	envKey   string
+	usage    string
+	value    bool
 }
 
 func newBuilder(appName string, options ...BuilderOption) *builder {
@@ -81,6 +90,10 @@ func (b *builder) BindRoot(flagSet *pflag.FlagSet) {
 	flagSet.BoolVar(&b.profileAllowError, "profile-allow-error", false, "Allow errors for profiled commands")
 	_ = flagSet.MarkHidden("profile-allow-error")
 
+	for _, envBoolFlag := range b.envBoolFlags {
+		flagSet.BoolVar(&envBoolFlag.value, envBoolFlag.flagName, false, envBoolFlag.usage)
+	}
+
 	// We do not officially support this flag, this is for testing, where we need warnings turned off.
 	flagSet.BoolVar(&b.noWarn, "no-warn", false, "Turn off warn logging")
 	_ = flagSet.MarkHidden("no-warn")
@@ -115,6 +128,15 @@ func (b *builder) run(
 	defer func() {
 		retErr = multierr.Append(retErr, logger.Sync())
 	}()
+	envOverrides := make(map[string]string)
+	for _, envBoolFlag := range b.envBoolFlags {
+		if envBoolFlag.value {
+			envOverrides[envBoolFlag.envKey] = "true"
+		}
+	}
+	if len(envOverrides) > 0 {
+		appContainer = app.NewContainerWithEnvOverrides(appContainer, envOverrides)
+	}
 	verbosePrinter := appverbose.NewVerbosePrinter(appContainer.Stderr(), b.appName, b.verbose)
 	container, err := newContainer(appContainer, b.appName, logger, verbosePrinter)
 	if err != nil {
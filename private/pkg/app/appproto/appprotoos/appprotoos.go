@@ -17,13 +17,19 @@ package appprotoos
 
 import (
 	"context"
+	"errors"
 	"io"
 
+	"github.com/bufbuild/buf/private/pkg/command"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/pluginpb"
 )
 
+// ErrOutOfDate is returned by ResponseWriter.Close in check mode if the responses
+// differ from the files on disk.
+var ErrOutOfDate = errors.New("generated files are out of date")
+
 // ResponseWriter writes CodeGeneratorResponses to the OS filesystem.
 type ResponseWriter interface {
 	// Close writes all of the responses to disk. No further calls can be
@@ -66,3 +72,31 @@ func ResponseWriterWithCreateOutDirIfNotExists() ResponseWriterOption {
 		responseWriterOptions.createOutDirIfNotExists = true
 	}
 }
+
+// ResponseWriterWithCheck returns a new ResponseWriterOption that compares the responses
+// with the files on disk on Close instead of writing them.
+//
+// A unified diff of the stale, missing, and extra files is written to diffWriter, and Close
+// returns ErrOutOfDate if there is any difference. Nothing is written to disk.
+//
+// Extra files are files in an output directory that are not generated, but are in a
+// directory that contains a generated file and have the file suffix of a generated file
+// in that directory, such as ".pb.go", or are recorded in the manifest set with
+// ResponseWriterWithCheckManifestFilePath. For .jar and .zip outputs, every entry is compared.
+func ResponseWriterWithCheck(runner command.Runner, diffWriter io.Writer) ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.checkRunner = runner
+		responseWriterOptions.checkDiffWriter = diffWriter
+	}
+}
+
+// ResponseWriterWithCheckManifestFilePath returns a new ResponseWriterOption that sets the
+// path of a manifest of previously generated files, relative to each output directory.
+//
+// Files recorded in the manifest that are not generated anymore are reported as extra
+// files by ResponseWriterWithCheck.
+func ResponseWriterWithCheckManifestFilePath(manifestFilePath string) ResponseWriterOption {
+	return func(responseWriterOptions *responseWriterOptions) {
+		responseWriterOptions.checkManifestFilePath = manifestFilePath
+	}
+}
@@ -15,18 +15,24 @@
 package appprotoos
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"sync"
 
 	"github.com/bufbuild/buf/private/pkg/app/appproto"
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/manifest"
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/storage"
 	"github.com/bufbuild/buf/private/pkg/storage/storagearchive"
 	"github.com/bufbuild/buf/private/pkg/storage/storagemem"
 	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/bufbuild/buf/private/pkg/stringutil"
 	"go.uber.org/multierr"
 	"go.uber.org/zap"
 	"google.golang.org/protobuf/types/pluginpb"
@@ -47,6 +53,15 @@ type responseWriter struct {
 	responseWriter    appproto.ResponseWriter
 	// If set, create directories if they don't already exist.
 	createOutDirIfNotExists bool
+	// If set, compare the responses with the files on disk instead of
+	// writing them, and write a diff to the checkDiffWriter.
+	checkRunner     command.Runner
+	checkDiffWriter io.Writer
+	// If set, the path of the manifest of previously generated files, relative to
+	// each output directory.
+	checkManifestFilePath string
+	// Set on Close if any output differs from the files on disk.
+	checkOutOfDate bool
 	// Cache the readWriteBuckets by their respective output paths.
 	// These builders are transformed to storage.ReadBuckets and written
 	// to disk once the responseWriter is flushed.
@@ -84,6 +99,9 @@ func newResponseWriter(
 		storageosProvider:       storageosProvider,
 		responseWriter:          appproto.NewResponseWriter(logger),
 		createOutDirIfNotExists: responseWriterOptions.createOutDirIfNotExists,
+		checkRunner:             responseWriterOptions.checkRunner,
+		checkDiffWriter:         responseWriterOptions.checkDiffWriter,
+		checkManifestFilePath:   responseWriterOptions.checkManifestFilePath,
 		readWriteBuckets:        make(map[string]storage.ReadWriteBucket),
 	}
 }
@@ -130,9 +148,14 @@ func (w *responseWriter) Close() error {
 			return err
 		}
 	}
+	checkOutOfDate := w.checkOutOfDate
 	// Re-initialize the cached values to be safe.
 	w.readWriteBuckets = make(map[string]storage.ReadWriteBucket)
 	w.closers = nil
+	w.checkOutOfDate = false
+	if checkOutOfDate {
+		return ErrOutOfDate
+	}
 	return nil
 }
 
@@ -192,7 +215,12 @@ func (w *responseWriter) writeZip(
 	}
 	// OK to use os.Stat instead of os.Lstat here.
 	fileInfo, err := os.Stat(outDirPath)
-	if err != nil {
+	if w.isCheck() {
+		// Nothing is written in check mode, so the directory does not need to exist.
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err != nil {
 		if os.IsNotExist(err) {
 			if createOutDirIfNotExists {
 				if err := os.MkdirAll(outDirPath, 0755); err != nil {
@@ -223,6 +251,12 @@ func (w *responseWriter) writeZip(
 	// Add this readWriteBucket to the set so that other plugins
 	// can write to the same files (re: insertion points).
 	w.readWriteBuckets[outFilePath] = readWriteBucket
+	if w.isCheck() {
+		w.closers = append(w.closers, func() error {
+			return w.checkZip(ctx, readWriteBucket, outFilePath)
+		})
+		return nil
+	}
 	w.closers = append(w.closers, func() (retErr error) {
 		// We're done writing all of the content into this
 		// readWriteBucket, so we zip it when we flush.
@@ -270,6 +304,12 @@ func (w *responseWriter) writeDirectory(
 	// Add this readWriteBucket to the set so that other plugins
 	// can write to the same files (re: insertion points).
 	w.readWriteBuckets[outDirPath] = readWriteBucket
+	if w.isCheck() {
+		w.closers = append(w.closers, func() error {
+			return w.checkDirectory(ctx, readWriteBucket, outDirPath)
+		})
+		return nil
+	}
 	w.closers = append(w.closers, func() error {
 		if createOutDirIfNotExists {
 			if err := os.MkdirAll(outDirPath, 0755); err != nil {
@@ -292,8 +332,242 @@ func (w *responseWriter) writeDirectory(
 	return nil
 }
 
+func (w *responseWriter) isCheck() bool {
+	return w.checkDiffWriter != nil
+}
+
+// checkZip compares the entries of the zip file at outFilePath with the generated files.
+func (w *responseWriter) checkZip(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	outFilePath string,
+) error {
+	existingReadWriteBucket := storagemem.NewReadWriteBucket()
+	data, err := os.ReadFile(outFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := storagearchive.Unzip(
+			ctx,
+			bytes.NewReader(data),
+			int64(len(data)),
+			existingReadWriteBucket,
+			nil,
+			0,
+		); err != nil {
+			return fmt.Errorf("%s: %w", outFilePath, err)
+		}
+	}
+	return w.checkDiff(ctx, existingReadWriteBucket, readBucket, outFilePath)
+}
+
+// checkDirectory compares the files in the directory at outDirPath with the generated files.
+//
+// Existing files are only compared if they are recorded in the manifest at checkManifestFilePath,
+// or if they are in a directory that contains a generated file and have the file suffix of a
+// generated file in that directory, such as ".pb.go". This ensures that files that are not
+// generated, such as hand-written .go files or a go.mod file, are not reported as extra files.
+func (w *responseWriter) checkDirectory(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	outDirPath string,
+) error {
+	existingReadWriteBucket := storagemem.NewReadWriteBucket()
+	dirPathToSuffixes := make(map[string]map[string]struct{})
+	if err := readBucket.Walk(
+		ctx,
+		"",
+		func(objectInfo storage.ObjectInfo) error {
+			suffix := getFileSuffix(objectInfo.Path())
+			if suffix == "" {
+				return nil
+			}
+			dirPath := normalpath.Dir(objectInfo.Path())
+			suffixes, ok := dirPathToSuffixes[dirPath]
+			if !ok {
+				suffixes = make(map[string]struct{})
+				dirPathToSuffixes[dirPath] = suffixes
+			}
+			suffixes[suffix] = struct{}{}
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+	// OK to use os.Stat instead of os.Lstat here.
+	fileInfo, err := os.Stat(outDirPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if !fileInfo.IsDir() {
+			return fmt.Errorf("not a directory: %s", outDirPath)
+		}
+		osReadWriteBucket, err := w.storageosProvider.NewReadWriteBucket(
+			outDirPath,
+			storageos.ReadWriteBucketWithSymlinksIfSupported(),
+		)
+		if err != nil {
+			return err
+		}
+		existingPaths, err := w.getCheckExistingPaths(ctx, osReadWriteBucket, outDirPath, dirPathToSuffixes)
+		if err != nil {
+			return err
+		}
+		for _, existingPath := range existingPaths {
+			data, err := storage.ReadPath(ctx, osReadWriteBucket, existingPath)
+			if err != nil {
+				if storage.IsNotExist(err) {
+					continue
+				}
+				return err
+			}
+			if err := storage.PutPath(ctx, existingReadWriteBucket, existingPath, data); err != nil {
+				return err
+			}
+		}
+	}
+	return w.checkDiff(ctx, existingReadWriteBucket, readBucket, outDirPath)
+}
+
+// getCheckExistingPaths returns the paths of the files in the directory at outDirPath that
+// are compared with the generated files. The paths may not exist.
+func (w *responseWriter) getCheckExistingPaths(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	outDirPath string,
+	dirPathToSuffixes map[string]map[string]struct{},
+) ([]string, error) {
+	pathMap := make(map[string]struct{})
+	if w.checkManifestFilePath != "" {
+		data, err := storage.ReadPath(ctx, readBucket, w.checkManifestFilePath)
+		if err != nil && !storage.IsNotExist(err) {
+			return nil, err
+		}
+		if err == nil {
+			var generatedManifest manifest.Manifest
+			if err := generatedManifest.UnmarshalText(data); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", w.checkManifestFilePath, err)
+			}
+			for _, path := range generatedManifest.Paths() {
+				pathMap[path] = struct{}{}
+			}
+		}
+	}
+	for dirPath, suffixes := range dirPathToSuffixes {
+		dirEntries, err := os.ReadDir(filepath.Join(outDirPath, normalpath.Unnormalize(dirPath)))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, dirEntry := range dirEntries {
+			if dirEntry.IsDir() {
+				continue
+			}
+			if _, ok := suffixes[getFileSuffix(dirEntry.Name())]; ok {
+				pathMap[normalpath.Join(dirPath, dirEntry.Name())] = struct{}{}
+			}
+		}
+	}
+	return stringutil.MapToSortedSlice(pathMap), nil
+}
+
+// checkDiff writes the diff between the existing and generated files to the checkDiffWriter,
+// with paths relative to outPath.
+func (w *responseWriter) checkDiff(
+	ctx context.Context,
+	existingReadBucket storage.ReadBucket,
+	generatedReadBucket storage.ReadBucket,
+	outPath string,
+) error {
+	displayOutPath := outPath
+	if wd, err := os.Getwd(); err == nil {
+		if relOutPath, err := filepath.Rel(wd, outPath); err == nil && !strings.HasPrefix(relOutPath, "..") {
+			displayOutPath = relOutPath
+		}
+	}
+	existingDisplayReadBucket, err := newExternalDirPathReadBucket(ctx, existingReadBucket, displayOutPath)
+	if err != nil {
+		return err
+	}
+	generatedDisplayReadBucket, err := newExternalDirPathReadBucket(ctx, generatedReadBucket, displayOutPath)
+	if err != nil {
+		return err
+	}
+	diffBuffer := bytes.NewBuffer(nil)
+	if err := storage.Diff(
+		ctx,
+		w.checkRunner,
+		diffBuffer,
+		existingDisplayReadBucket,
+		generatedDisplayReadBucket,
+		storage.DiffWithExternalPaths(), // No need to set prefixes as the external paths are the same.
+	); err != nil {
+		return err
+	}
+	if diffBuffer.Len() == 0 {
+		return nil
+	}
+	w.checkOutOfDate = true
+	_, err = w.checkDiffWriter.Write(diffBuffer.Bytes())
+	return err
+}
+
+// getFileSuffix returns the part of the base name of the path from its first dot that
+// is not the first character, for example ".pb.go" for "a/v1/a.pb.go", or the empty
+// string if there is none.
+func getFileSuffix(path string) string {
+	base := normalpath.Base(path)
+	if len(base) < 2 {
+		return ""
+	}
+	if index := strings.IndexByte(base[1:], '.'); index >= 0 {
+		return base[index+1:]
+	}
+	return ""
+}
+
+// newExternalDirPathReadBucket returns a copy of the ReadBucket with external paths
+// that are the paths joined to externalDirPath.
+func newExternalDirPathReadBucket(
+	ctx context.Context,
+	readBucket storage.ReadBucket,
+	externalDirPath string,
+) (storage.ReadBucket, error) {
+	readWriteBucket := storagemem.NewReadWriteBucket()
+	if err := storage.WalkReadObjects(
+		ctx,
+		readBucket,
+		"",
+		func(readObject storage.ReadObject) (retErr error) {
+			writeObjectCloser, err := readWriteBucket.Put(ctx, readObject.Path())
+			if err != nil {
+				return err
+			}
+			defer func() {
+				retErr = multierr.Append(retErr, writeObjectCloser.Close())
+			}()
+			if _, err := io.Copy(writeObjectCloser, readObject); err != nil {
+				return err
+			}
+			return writeObjectCloser.SetExternalPath(
+				filepath.Join(externalDirPath, normalpath.Unnormalize(readObject.Path())),
+			)
+		},
+	); err != nil {
+		return nil, err
+	}
+	return readWriteBucket, nil
+}
+
 type responseWriterOptions struct {
 	createOutDirIfNotExists bool
+	checkRunner             command.Runner
+	checkDiffWriter         io.Writer
+	checkManifestFilePath   string
 }
 
 func newResponseWriterOptions() *responseWriterOptions {
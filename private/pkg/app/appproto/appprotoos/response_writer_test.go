@@ -0,0 +1,122 @@
+// Copyright 2020-2023 Buf Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package appprotoos
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/manifest"
+	"github.com/bufbuild/buf/private/pkg/storage/storageos"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/pluginpb"
+)
+
+func TestResponseWriterCheck(t *testing.T) {
+	t.Parallel()
+	outDirPath := t.TempDir()
+	manifestFilePath := ".buf.gen.manifest"
+	for path, content := range map[string]string{
+		"go.mod":             "module example.com/foo\n",
+		"main.go":            "package main\n",
+		"a/v1/a.pb.go":       "package av1\n",
+		"a/v1/a_helpers.go":  "package av1\n",
+		"a/v1/doc.go":        "package av1\n",
+		"a/v1/a_test.go":     "package av1\n",
+		"b/v1/b.go":          "package bv1\n",
+		"b/v1/b.pb.go":       "package bv1\n",
+		"c/v1/c.pb.go":       "package cv1\n",
+		"c/v1/c_handwritten": "package cv1\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(outDirPath, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(outDirPath, path), []byte(content), 0600))
+	}
+	response := &pluginpb.CodeGeneratorResponse{
+		File: []*pluginpb.CodeGeneratorResponse_File{
+			{
+				Name:    proto.String("a/v1/a.pb.go"),
+				Content: proto.String("package av1\n"),
+			},
+		},
+	}
+	stdout := testResponseWriterCheck(t, outDirPath, "", response)
+	// Generated files in other directories are not known without a manifest.
+	assert.Empty(t, stdout)
+
+	var generatedManifest manifest.Manifest
+	digester, err := manifest.NewDigester(manifest.DigestTypeShake256)
+	require.NoError(t, err)
+	for _, path := range []string{"a/v1/a.pb.go", "b/v1/b.pb.go"} {
+		digest, err := digester.Digest(strings.NewReader(""))
+		require.NoError(t, err)
+		require.NoError(t, generatedManifest.AddEntry(path, *digest))
+	}
+	data, err := generatedManifest.MarshalText()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(outDirPath, manifestFilePath), data, 0600))
+	stdout = testResponseWriterCheck(t, outDirPath, manifestFilePath, response)
+	// Files that were generated before are reported as extra files.
+	assert.Contains(t, stdout, filepath.Join("b", "v1", "b.pb.go"))
+	for _, path := range []string{
+		"go.mod",
+		"main.go",
+		"a_helpers.go",
+		"doc.go",
+		"a_test.go",
+		filepath.Join("b", "v1", "b.go"),
+		"c.pb.go",
+		manifestFilePath,
+	} {
+		assert.NotContains(t, stdout, path)
+	}
+
+	// Files with the suffix of a generated file next to it are reported as extra files.
+	require.NoError(t, os.WriteFile(filepath.Join(outDirPath, "a", "v1", "old.pb.go"), []byte("package av1\n"), 0600))
+	stdout = testResponseWriterCheck(t, outDirPath, "", response)
+	assert.Contains(t, stdout, filepath.Join("a", "v1", "old.pb.go"))
+	assert.NotContains(t, stdout, "a_helpers.go")
+}
+
+func testResponseWriterCheck(
+	t *testing.T,
+	outDirPath string,
+	manifestFilePath string,
+	response *pluginpb.CodeGeneratorResponse,
+) string {
+	t.Helper()
+	stdout := bytes.NewBuffer(nil)
+	responseWriter := NewResponseWriter(
+		zap.NewNop(),
+		storageos.NewProvider(),
+		ResponseWriterWithCheck(command.NewRunner(), stdout),
+		ResponseWriterWithCheckManifestFilePath(manifestFilePath),
+	)
+	require.NoError(t, responseWriter.AddResponse(context.Background(), response, outDirPath))
+	err := responseWriter.Close()
+	if stdout.Len() == 0 {
+		require.NoError(t, err)
+	} else {
+		require.ErrorIs(t, err, ErrOutOfDate)
+	}
+	return stdout.String()
+}
@@ -313,6 +313,25 @@ func OpenRepository(ctx context.Context, gitDirPath string, runner command.Runne
 	return openGitRepository(ctx, gitDirPath, runner, options...)
 }
 
+// ObjectReadCloser is an ObjectReader that must be closed.
+type ObjectReadCloser interface {
+	ObjectReader
+	// Close closes the ObjectReadCloser.
+	Close() error
+}
+
+// OpenObjectReader opens a new ObjectReadCloser for a `.git` directory. The provided path to the
+// `.git` dir need not be normalized or cleaned.
+//
+// Unlike OpenRepository, OpenObjectReader does not read any refs, so it requires neither that the
+// repository has been pushed, nor that a branch is checked out.
+//
+// Internally, OpenObjectReader will spawn a new process to communicate with `git-cat-file`, so the
+// caller must close the ObjectReadCloser to clean up resources.
+func OpenObjectReader(gitDirPath string, runner command.Runner) (ObjectReadCloser, error) {
+	return openObjectReader(gitDirPath, runner)
+}
+
 // OpenRepositoryOption configures the opening of a repository.
 type OpenRepositoryOption func(*openRepositoryOpts) error
 
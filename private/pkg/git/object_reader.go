@@ -20,11 +20,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/bufbuild/buf/private/pkg/command"
+	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"go.uber.org/multierr"
 )
 
@@ -67,7 +69,19 @@ func newObjectReader(gitDirPath string, runner command.Runner) (*objectReader, e
 	}, nil
 }
 
-func (o *objectReader) close() error {
+func openObjectReader(gitDirPath string, runner command.Runner) (*objectReader, error) {
+	gitDirPath = normalpath.Unnormalize(gitDirPath)
+	if err := validateDirPathExists(gitDirPath); err != nil {
+		return nil, err
+	}
+	gitDirPath, err := filepath.Abs(gitDirPath)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectReader(gitDirPath, runner)
+}
+
+func (o *objectReader) Close() error {
 	ctx, cancel := context.WithDeadline(
 		context.Background(),
 		time.Now().Add(exitTime),
@@ -95,7 +95,7 @@ func openGitRepository(
 }
 
 func (r *repository) Close() error {
-	return r.objectReader.close()
+	return r.objectReader.Close()
 }
 
 func (r *repository) Objects() ObjectReader {
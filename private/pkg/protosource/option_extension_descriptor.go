@@ -82,6 +82,10 @@ func (o *optionExtensionDescriptor) OptionExtensionLocation(extensionType protor
 	return nil
 }
 
+func (o *optionExtensionDescriptor) Options() proto.Message {
+	return o.message
+}
+
 func (o *optionExtensionDescriptor) PresentExtensionNumbers() []int32 {
 	fieldNumbersSet := map[int32]struct{}{}
 	var fieldNumbers []int32
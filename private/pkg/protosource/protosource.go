@@ -32,6 +32,7 @@ import (
 
 	"github.com/bufbuild/buf/private/pkg/normalpath"
 	"github.com/bufbuild/buf/private/pkg/protodescriptor"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/descriptorpb"
 )
@@ -151,6 +152,13 @@ type OptionExtensionDescriptor interface {
 	// PresentExtensionNumbers returns field numbers for all options that
 	// have a set value on this descriptor.
 	PresentExtensionNumbers() []int32
+
+	// Options returns the options message of the descriptor, such as *descriptorpb.MessageOptions.
+	//
+	// Options that are not known to buf are kept as unknown fields.
+	// May be a typed nil if no options are set.
+	// NOT a copy. Do not modify.
+	Options() proto.Message
 }
 
 // Location defines source code info location information.